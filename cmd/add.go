@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -17,6 +18,14 @@ func Add() *cobra.Command {
 	var format string
 	var output string
 	var template string
+	var claimsMapping map[string]string
+	var adminOnly bool
+	var prefetch bool
+	var onErrorCommand string
+	var configFile string
+	var refreshToken string
+	var verifyRefresh bool
+	var fileConfig map[string]any // Settings read from --config-file
 
 	cmd := &cobra.Command{
 		Use:   "add <type> <name>",
@@ -26,6 +35,9 @@ func Add() *cobra.Command {
 Examples:
   credctl add command github --command "gh auth token"
   credctl add oauth2-proxy myservice --auth-url "https://..." --template 'export TOKEN={{.token}}'
+  credctl add oauth2 myprov    # prompts for each setting when run in a terminal
+  credctl add oauth2 myprov --config-file myprov.yaml --client_secret @secret.txt
+  credctl add oauth2 myprov ... --refresh-token @old-tool-token.txt --verify-refresh
   
 Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 		DisableFlagParsing: true,
@@ -38,7 +50,9 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 						providerType := args[0]
 						if provider.IsRegistered(providerType) {
 							schema, _ := provider.GetSchema(providerType)
-							provider.AddSchemaFlags(cmd, schema)
+							if err := provider.AddSchemaFlags(cmd, schema); err != nil {
+								return err
+							}
 						}
 					}
 					_ = cmd.Help()
@@ -70,10 +84,34 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 			if err != nil {
 				return err
 			}
-			provider.AddSchemaFlags(cmd, schema)
+			if err := provider.AddSchemaFlags(cmd, schema); err != nil {
+				return fmt.Errorf("provider type '%s': %w", providerType, err)
+			}
 
 			cmd.DisableFlagParsing = false
-			return cmd.ParseFlags(args)
+			if err := cmd.ParseFlags(args); err != nil {
+				return err
+			}
+
+			if configFile != "" {
+				fileConfig, err = provider.LoadConfigFile(configFile, schema)
+				if err != nil {
+					return err
+				}
+				// Required settings may come from the file instead of flags
+				for key := range fileConfig {
+					if flag := cmd.Flags().Lookup(key); flag != nil {
+						_ = cmd.Flags().SetAnnotation(key, cobra.BashCompOneRequiredFlag, []string{"false"})
+					}
+				}
+				return nil
+			}
+
+			// Walk through the schema interactively when no provider flags were given
+			if !schemaFlagsChanged(cmd, schema) && isTerminal(os.Stdin) {
+				return newTerminalWizard().fillFlags(cmd, schema)
+			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			providerType := args[0]
@@ -84,7 +122,8 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 				return fmt.Errorf("unknown provider type '%s': %w\nAvailable types: %v", providerType, err, provider.ListTypes())
 			}
 
-			config, err := provider.ExtractConfig(cmd, schema)
+			// Settings from --config-file, overridden by flags set explicitly
+			config, err := provider.ExtractConfigWithBase(cmd, schema, fileConfig)
 			if err != nil {
 				return fmt.Errorf("failed to extract configuration: %w", err)
 			}
@@ -99,6 +138,18 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 			if template != "" {
 				config[provider.MetadataTemplate] = template
 			}
+			if len(claimsMapping) > 0 {
+				config[provider.MetadataClaimsMapping] = claimsMapping
+			}
+			if adminOnly {
+				config[provider.MetadataAccessPolicy] = provider.AccessPolicyAdminOnly
+			}
+			if prefetch {
+				config[provider.MetadataPrefetch] = true
+			}
+			if onErrorCommand != "" {
+				config[provider.MetadataOnErrorCommand] = onErrorCommand
+			}
 
 			prov, err := provider.New(providerType)
 			if err != nil {
@@ -109,6 +160,21 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 				return fmt.Errorf("failed to initialize provider: %w", err)
 			}
 
+			// Import the refresh token before adding, so a rejected one adds nothing
+			var imported *protocol.SetTokensPayload
+			if refreshToken != "" {
+				value, err := provider.ReadSecretValue(refreshToken, cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				imported, err = importRefreshToken(cmd.Context(), prov, name, value, verifyRefresh)
+				if err != nil {
+					return err
+				}
+			} else if verifyRefresh {
+				return fmt.Errorf("--verify-refresh requires --refresh-token")
+			}
+
 			if runLogin {
 				loginProvider, ok := prov.(provider.LoginProvider)
 				if !ok {
@@ -138,19 +204,94 @@ Available provider types: ` + fmt.Sprintf("%v", provider.ListTypes()),
 			}
 
 			if resp.Status == "error" {
-				return fmt.Errorf("error: %s", resp.Error)
+				return client.NewResponseError(resp)
 			}
 
 			fmt.Printf("Provider '%s' added successfully\n", name)
+
+			if imported != nil {
+				resp, err := client.SendRequest(protocol.Request{Action: "set_tokens", Payload: *imported})
+				if err != nil {
+					return fmt.Errorf("provider added but the refresh token was not imported: %w", err)
+				}
+				if resp.Status == "error" {
+					return fmt.Errorf("provider added but the refresh token was not imported: %w", client.NewResponseError(resp))
+				}
+				fmt.Printf("Refresh token imported for provider '%s'\n", name)
+				return nil
+			}
+
+			// A provider that needs a login fails on its first get until one is run
+			if !runLogin && needsLogin(prov) && !isAuthenticated(name) {
+				fmt.Printf("Provider '%s' added but not authenticated. Run: credctl login %s\n", name, name)
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVar(&runLogin, "run-login", false, "Execute the login command before adding the provider")
-	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing provider")
-	cmd.Flags().StringVar(&format, "format", "", "Default output format for credctl get: json, text, escaped (default: text)")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing provider (cached tokens are kept unless auth settings change)")
+	cmd.Flags().StringVar(&format, "format", "", "Default output format for credctl get: json, json-full, text, escaped, basic-auth, env, aws-process (default: text)")
 	cmd.Flags().StringVar(&output, "output", "", "Default output file path for credctl get")
 	cmd.Flags().StringVar(&template, "template", "", "Go template to format credentials (e.g., 'export TOKEN={{.token}}')")
+	cmd.Flags().StringToStringVar(&claimsMapping, "claims-mapping", nil, "Names for credential fields and JWT claims in templates (e.g., user=token_sub,org=token_custom_org)")
+	cmd.Flags().BoolVar(&adminOnly, "admin-only", false, "Only serve the credential on the admin socket, never on the read-only socket")
+	cmd.Flags().BoolVar(&prefetch, "prefetch", false, "Fetch the credential when the daemon starts, so the first request is served from the cache (non-interactive providers)")
+	cmd.Flags().StringVar(&onErrorCommand, "on-error-command", "", "Shell command the daemon runs when fetching the credential fails (CREDCTL_PROVIDER, CREDCTL_ERROR and CREDCTL_ERROR_TYPE describe the failure)")
+	cmd.Flags().StringVar(&configFile, "config-file", "", "Read the provider settings from a YAML or JSON file (flags override its values)")
+	cmd.Flags().StringVar(&refreshToken, "refresh-token", "", "Import a refresh token issued to another tool, so no login is needed; use @file or - to read it from a file or stdin")
+	cmd.Flags().BoolVar(&verifyRefresh, "verify-refresh", false, "Exchange the imported refresh token once before adding the provider, failing if it is rejected")
 
 	return cmd
 }
+
+// needsLogin reports whether a provider may need the user to run
+// credctl login before it can return a credential
+func needsLogin(prov provider.Provider) bool {
+	if _, ok := prov.(provider.LoginProvider); !ok {
+		return false
+	}
+	return provider.CapabilitiesOf(prov).Interactive
+}
+
+// isAuthenticated reports whether the daemon holds tokens for a provider
+// (e.g. kept by add --force). Errors count as not authenticated.
+func isAuthenticated(name string) bool {
+	info, err := providerTokenInfo(name)
+	if err != nil || info == nil {
+		return false
+	}
+	return info.HasAccessToken || info.HasRefreshToken
+}
+
+// importRefreshToken caches refreshToken in prov and returns the tokens to
+// hand to the daemon once the provider is added. With verify, the refresh
+// token is exchanged once so a revoked or foreign token is rejected up front.
+func importRefreshToken(ctx context.Context, prov provider.Provider, name, refreshToken string, verify bool) (*protocol.SetTokensPayload, error) {
+	tokenCacheProv, ok := prov.(provider.TokenCacheProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider type '%s' does not support importing a refresh token", prov.Type())
+	}
+	tokenCacheProv.SetTokens("", refreshToken, 0)
+
+	if !verify {
+		return &protocol.SetTokensPayload{Name: name, RefreshToken: refreshToken}, nil
+	}
+
+	refresher, ok := prov.(provider.RefreshProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider type '%s' cannot verify a refresh token", prov.Type())
+	}
+	if err := refresher.RefreshTokens(ctx); err != nil {
+		return nil, fmt.Errorf("refresh token rejected: %w", err)
+	}
+
+	// The server may have rotated the refresh token
+	accessToken, newRefreshToken, expiresIn := tokenCacheProv.GetTokens()
+	return &protocol.SetTokensPayload{
+		Name:         name,
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    expiresIn,
+	}, nil
+}
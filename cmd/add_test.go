@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"credctl/internal/paths"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+)
+
+func TestNeedsLogin(t *testing.T) {
+	oauth2 := map[string]any{
+		provider.MetadataClientID:       "my-client",
+		provider.MetadataTokenEndpoint:  "https://idp.example.com/token",
+		provider.MetadataAuthEndpoint:   "https://idp.example.com/authorize",
+		provider.MetadataDeviceEndpoint: "https://idp.example.com/device",
+	}
+	withFlow := func(flow string) map[string]any {
+		config := map[string]any{"flow": flow, provider.MetadataClientSecret: "secret"}
+		for key, value := range oauth2 {
+			config[key] = value
+		}
+		return config
+	}
+
+	tests := []struct {
+		name         string
+		providerType string
+		config       map[string]any
+		want         bool
+	}{
+		{name: "device flow", providerType: "oauth2", config: withFlow("device"), want: true},
+		{name: "auth-code flow", providerType: "oauth2", config: withFlow("auth-code"), want: true},
+		{name: "client credentials", providerType: "oauth2", config: withFlow("client-credentials"), want: false},
+		{name: "command with login", providerType: "command", config: map[string]any{
+			provider.MetadataCommand:      "cat token",
+			provider.MetadataLoginCommand: "fetch-token > token",
+		}, want: true},
+		{name: "command", providerType: "command", config: map[string]any{provider.MetadataCommand: "echo token"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov, err := provider.New(tt.providerType)
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			if err := prov.Init(tt.config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+			if got := needsLogin(prov); got != tt.want {
+				t.Errorf("needsLogin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportRefreshToken(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = r.ParseForm()
+		if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "imported" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"rotated","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	newProvider := func(t *testing.T) provider.Provider {
+		prov, err := provider.New("oauth2")
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		err = prov.Init(map[string]any{
+			"flow":                         "auth-code",
+			provider.MetadataClientID:      "my-client",
+			provider.MetadataTokenEndpoint: server.URL,
+			provider.MetadataAuthEndpoint:  server.URL + "/authorize",
+		})
+		if err != nil {
+			t.Fatalf("Init() error: %v", err)
+		}
+		return prov
+	}
+
+	t.Run("unverified", func(t *testing.T) {
+		requests = 0
+		got, err := importRefreshToken(context.Background(), newProvider(t), "myprov", "imported", false)
+		if err != nil {
+			t.Fatalf("importRefreshToken() error: %v", err)
+		}
+		want := protocol.SetTokensPayload{Name: "myprov", RefreshToken: "imported"}
+		if *got != want {
+			t.Errorf("importRefreshToken() = %+v, want %+v", *got, want)
+		}
+		if requests != 0 {
+			t.Errorf("expected no token request, got %d", requests)
+		}
+	})
+
+	t.Run("verified", func(t *testing.T) {
+		got, err := importRefreshToken(context.Background(), newProvider(t), "myprov", "imported", true)
+		if err != nil {
+			t.Fatalf("importRefreshToken() error: %v", err)
+		}
+		if got.AccessToken != "fresh" || got.RefreshToken != "rotated" || got.ExpiresIn <= 0 {
+			t.Errorf("importRefreshToken() = %+v, want the refreshed tokens", *got)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		_, err := importRefreshToken(context.Background(), newProvider(t), "myprov", "revoked", true)
+		if err == nil || !strings.Contains(err.Error(), "refresh token rejected") {
+			t.Fatalf("error = %v, want a rejected refresh token", err)
+		}
+	})
+
+	t.Run("provider without token cache", func(t *testing.T) {
+		prov, err := provider.New("command")
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		if err := prov.Init(map[string]any{provider.MetadataCommand: "echo token"}); err != nil {
+			t.Fatalf("Init() error: %v", err)
+		}
+		_, err = importRefreshToken(context.Background(), prov, "cmd", "imported", false)
+		if err == nil || !strings.Contains(err.Error(), "does not support importing") {
+			t.Fatalf("error = %v, want an unsupported provider", err)
+		}
+	})
+}
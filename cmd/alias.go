@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// Alias returns the alias command
+func Alias() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "alias <name> <target>",
+		Short: "Add another name for a credential provider",
+		Long: `Add a name that resolves to an existing provider, e.g. so 'credctl get db'
+and 'credctl get prod-db' return the same credential. The alias shares the
+target's configuration and cached tokens, so it never authenticates separately.
+
+Aliases may point to other aliases, as long as the chain doesn't loop.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			target := args[1]
+
+			if name == "" || target == "" {
+				return fmt.Errorf("alias and target names cannot be empty")
+			}
+			if name == target {
+				return fmt.Errorf("provider '%s' cannot be an alias of itself", name)
+			}
+
+			// Catch typos: the target must exist (it may itself be an alias)
+			resp, err := client.SendRequest(protocol.Request{
+				Action:  "describe",
+				Payload: protocol.DescribePayload{Name: target},
+			})
+			if err != nil {
+				return err
+			}
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			req := protocol.Request{
+				Action: "add",
+				Payload: protocol.AddPayload{
+					Name:     name,
+					Type:     "alias",
+					Metadata: map[string]any{provider.MetadataAliasTarget: target},
+					Force:    force,
+				},
+			}
+
+			resp, err = client.SendRequest(req)
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			fmt.Printf("Provider '%s' is now an alias of '%s'\n", name, target)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite an existing provider with that name")
+
+	return cmd
+}
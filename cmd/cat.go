@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+)
+
+func Cat() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cat <name>",
+		Short: "Print the raw credential exactly as the provider returned it",
+		Long: `Print the raw credential bytes returned by the provider, with no template,
+formatting or trailing newline. This is the safe option for scripts:
+
+  TOKEN=$(credctl cat mytoken)`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("provider name cannot be empty")
+			}
+
+			req := protocol.Request{
+				Action: "get",
+				Payload: protocol.GetPayload{
+					Name: name,
+					Raw:  true,
+				},
+			}
+
+			resp, err := client.SendRequest(req)
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return getError(name, resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var getRespPayload protocol.GetResponsePayload
+			if err := json.Unmarshal(payloadBytes, &getRespPayload); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if _, err := os.Stdout.Write(getRespPayload.RawOutput); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"credctl/internal/client"
+	"credctl/internal/clipboard"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+)
+
+// clipboardClearCommand is the hidden command that clears the clipboard after a delay
+const clipboardClearCommand = "clipboard-clear"
+
+// Copy returns the copy command
+func Copy() *cobra.Command {
+	var field string
+	var clearAfter time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "copy <name>",
+		Short: "Copy a credential to the system clipboard",
+		Long: `Fetch a credential and copy it to the clipboard instead of printing it, so it
+doesn't linger in the terminal scrollback. The clipboard tool is detected
+(pbcopy, wl-copy, xclip, xsel or clip.exe); set ` + clipboard.EnvVar + ` to use another.
+
+--clear-after empties the clipboard after a delay, unless it was overwritten
+in the meantime (tools that can't read the clipboard back always clear it).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("provider name cannot be empty")
+			}
+			if clearAfter < 0 {
+				return fmt.Errorf("invalid --clear-after '%s': must not be negative", clearAfter)
+			}
+
+			// Fail before fetching a credential we couldn't copy
+			tool, err := clipboard.Detect()
+			if err != nil {
+				return err
+			}
+
+			resp, err := client.SendRequest(protocol.Request{
+				Action:  "get",
+				Payload: protocol.GetPayload{Name: name},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return getError(name, resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var getResp protocol.GetResponsePayload
+			if err := json.Unmarshal(payloadBytes, &getResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var value string
+			switch {
+			case field != "":
+				value, err = credentialField(getResp.StructuredFields, getResp.HasStructuredFields, field)
+				if err != nil {
+					return err
+				}
+			case getResp.Bundle:
+				return fmt.Errorf("provider '%s' returns several credential fields (%s): select one with --field",
+					name, strings.Join(sortedKeys(getResp.StructuredFields), ", "))
+			default:
+				value = strings.TrimRight(getResp.Output, "\r\n")
+			}
+
+			if err := tool.Write(value); err != nil {
+				return fmt.Errorf("failed to copy to the clipboard: %w", err)
+			}
+
+			if clearAfter > 0 {
+				if err := clearClipboardLater(value, clearAfter); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Copied %s to the clipboard (cleared in %s)\n", name, clearAfter)
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Copied %s to the clipboard\n", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&field, "field", "", "Copy a single field of the provider's structured credentials (e.g. password)")
+	cmd.Flags().DurationVar(&clearAfter, "clear-after", 0, "Clear the clipboard after this long (e.g. 45s; 0 keeps it)")
+
+	return cmd
+}
+
+// clipboardDigest identifies a copied value without keeping it around
+func clipboardDigest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// clearClipboardLater starts a detached credctl process that clears the
+// clipboard after a delay if it still holds value. Only the value's digest
+// is passed on, through a pipe.
+func clearClipboardLater(value string, after time.Duration) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to schedule clipboard clearing: %w", err)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to schedule clipboard clearing: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	// The digest fits in the pipe buffer, so the child can read it after we exit
+	if _, err := writer.WriteString(clipboardDigest(value) + "\n"); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("failed to schedule clipboard clearing: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to schedule clipboard clearing: %w", err)
+	}
+
+	child := exec.Command(executable, clipboardClearCommand, after.String())
+	child.Stdin = reader
+	child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to schedule clipboard clearing: %w", err)
+	}
+	return child.Process.Release()
+}
+
+// ClipboardClear returns the hidden command run by clearClipboardLater
+func ClipboardClear() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    clipboardClearCommand + " <delay>",
+		Short:  "Clear the clipboard after a delay if it still holds a copied credential",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			after, err := time.ParseDuration(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid delay '%s': %w", args[0], err)
+			}
+
+			digest, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("failed to read the copied value's digest: %w", err)
+			}
+			digest = strings.TrimSpace(digest)
+
+			time.Sleep(after)
+
+			tool, err := clipboard.Detect()
+			if err != nil {
+				return err
+			}
+			// Leave a clipboard the user has overwritten since
+			if current, ok := tool.Read(); ok && clipboardDigest(current) != digest {
+				return nil
+			}
+			return tool.Write("")
+		},
+	}
+
+	return cmd
+}
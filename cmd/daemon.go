@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"credctl/internal/client"
 	"credctl/internal/daemon"
 
 	"github.com/spf13/cobra"
@@ -19,10 +21,22 @@ The daemon listens on a Unix socket and handles credential requests.
 To configure your shell, run:
   eval $(credctl daemon)`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Never auto-start from the daemon command itself
+			client.SetAutoStart(false)
+
+			// Refuse to silently reuse a running daemon that speaks another protocol version
+			if _, err := client.Ping(); errors.Is(err, client.ErrVersionMismatch) {
+				return err
+			}
+
 			info, err := daemon.Run()
 			if err != nil {
 				return fmt.Errorf("failed to start daemon: %w", err)
 			}
+			if info == nil {
+				// Daemon process: Run returns once the daemon has shut down
+				return nil
+			}
 
 			// Print ssh-agent style output
 			fmt.Printf("CREDCTL_SOCK=%s; export CREDCTL_SOCK;\n", info.AdminSocket)
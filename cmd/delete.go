@@ -38,7 +38,7 @@ This removes the provider configuration from disk.`,
 			}
 
 			if resp.Status == "error" {
-				return fmt.Errorf("error: %s", resp.Error)
+				return client.NewResponseError(resp)
 			}
 
 			fmt.Printf("Provider '%s' deleted successfully\n", name)
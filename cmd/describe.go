@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+
+	"charm.land/lipgloss/v2"
+	"github.com/spf13/cobra"
+)
+
+// maskedValue replaces sensitive settings in describe output
+const maskedValue = "********"
+
+// describeOutput is the --json form of describe
+type describeOutput struct {
+	Name         string                         `json:"name"`
+	Type         string                         `json:"type"`
+	Metadata     map[string]any                 `json:"metadata"`
+	Capabilities *protocol.ProviderCapabilities `json:"capabilities,omitempty"`
+	Token        *protocol.TokenInfo            `json:"token,omitempty"` // nil for providers that don't cache tokens
+}
+
+// Describe returns the describe command
+func Describe() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show how a provider is configured and the state of its token",
+		Long: `Show a provider's type, its settings (sensitive values such as client
+secrets and passwords are masked), what it needs and supports, and for
+providers that cache tokens whether a token is cached and when it expires.
+Token values are never printed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			resp, err := client.SendRequest(protocol.Request{
+				Action:  "describe",
+				Payload: protocol.DescribePayload{Name: name},
+			})
+			if err != nil {
+				return err
+			}
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+			var describeResp protocol.DescribeResponsePayload
+			if err := json.Unmarshal(payloadBytes, &describeResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			token, err := providerTokenInfo(name)
+			if err != nil {
+				return err
+			}
+
+			schema, _ := provider.GetSchema(describeResp.Type)
+			output := describeOutput{
+				Name:         name,
+				Type:         describeResp.Type,
+				Metadata:     maskSecrets(describeResp.Metadata, schema),
+				Capabilities: describeResp.Capabilities,
+				Token:        token,
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal provider: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+
+			printDescription(cmd.OutOrStdout(), output)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// providerTokenInfo returns the token state of a provider, or nil if the
+// provider doesn't cache tokens
+func providerTokenInfo(name string) (*protocol.TokenInfo, error) {
+	tokens, err := fetchTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tok := range tokens {
+		if tok.Name == name {
+			return &tok, nil
+		}
+	}
+	return nil, nil
+}
+
+// maskSecrets returns a copy of metadata with the schema's sensitive fields masked
+func maskSecrets(metadata map[string]any, schema provider.Schema) map[string]any {
+	masked := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		masked[key] = value
+	}
+	for _, field := range schema.Fields {
+		if _, ok := masked[field.Name]; ok && field.Hidden {
+			masked[field.Name] = maskedValue
+		}
+	}
+	return masked
+}
+
+// formatSetting renders a metadata value on one line
+func formatSetting(value any) string {
+	switch v := value.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ", ")
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, item))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// printDescription renders a provider description as labelled sections
+func printDescription(out io.Writer, d describeOutput) {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("99")).
+		MarginBottom(1)
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("212"))
+
+	keyStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("86")).
+		Bold(true)
+
+	detailStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241"))
+
+	printSection := func(title string, rows [][2]string) {
+		width := 0
+		for _, row := range rows {
+			width = max(width, len(row[0]))
+		}
+		fmt.Fprintln(out, headerStyle.Render(title))
+		for _, row := range rows {
+			fmt.Fprintf(out, "  %s  %s\n", keyStyle.Render(fmt.Sprintf("%-*s", width, row[0])), row[1])
+		}
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintln(out, titleStyle.Render(fmt.Sprintf("Provider: %s (%s)", d.Name, d.Type)))
+
+	keys := make([]string, 0, len(d.Metadata))
+	for key := range d.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	settings := make([][2]string, 0, len(keys))
+	for _, key := range keys {
+		settings = append(settings, [2]string{key, formatSetting(d.Metadata[key])})
+	}
+	printSection("Configuration", settings)
+
+	if c := d.Capabilities; c != nil {
+		printSection("Capabilities", [][2]string{
+			{"network", yesNo(c.NeedsNetwork)},
+			{"refresh", yesNo(c.SupportsRefresh)},
+			{"interactive", yesNo(c.Interactive)},
+			{"structured", yesNo(c.Structured)},
+		})
+	}
+
+	if d.Token == nil {
+		fmt.Fprintln(out, headerStyle.Render("Token"))
+		fmt.Fprintln(out, "  "+detailStyle.Render("This provider doesn't cache tokens"))
+		return
+	}
+	tok := *d.Token
+	access := "none"
+	if tok.HasAccessToken {
+		access = formatTokenExpiry(tok)
+		if tok.TokenType != "" {
+			access = tok.TokenType + ", " + access
+		}
+	}
+	rows := [][2]string{
+		{"access token", access},
+		{"refresh token", yesNo(tok.HasRefreshToken)},
+	}
+	if tok.Subject != "" {
+		rows = append(rows, [2]string{"subject", tok.Subject})
+	}
+	if tok.Issuer != "" {
+		rows = append(rows, [2]string{"issuer", tok.Issuer})
+	}
+	if tok.Audience != "" {
+		rows = append(rows, [2]string{"audience", tok.Audience})
+	}
+	printSection("Token", rows)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	"credctl/internal/provider"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	schema := provider.Schema{Fields: []provider.FieldDef{
+		{Name: "client_id", Type: provider.FieldTypeString},
+		{Name: "client_secret", Type: provider.FieldTypeString, Hidden: true},
+		{Name: "password", Type: provider.FieldTypeString, Hidden: true},
+	}}
+	metadata := map[string]any{
+		"client_id":     "my-client",
+		"client_secret": "s3cret",
+		"format":        "json",
+	}
+
+	masked := maskSecrets(metadata, schema)
+
+	if masked["client_secret"] != maskedValue {
+		t.Errorf("expected client_secret to be masked, got %v", masked["client_secret"])
+	}
+	if masked["client_id"] != "my-client" || masked["format"] != "json" {
+		t.Errorf("expected other settings unchanged, got %v", masked)
+	}
+	if _, ok := masked["password"]; ok {
+		t.Error("expected unset sensitive fields to stay absent")
+	}
+	if metadata["client_secret"] != "s3cret" {
+		t.Error("expected the original metadata to be left untouched")
+	}
+}
+
+func TestFormatSetting(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "string", value: "auth-code", want: "auth-code"},
+		{name: "number", value: float64(8085), want: "8085"},
+		{name: "bool", value: true, want: "true"},
+		{name: "list", value: []any{"openid", "email"}, want: "openid, email"},
+		{name: "map sorted by key", value: map[string]any{"b": "2", "a": "1"}, want: "a=1, b=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSetting(tt.value); got != tt.want {
+				t.Errorf("formatSetting() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// Edit returns the edit command
+func Edit() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Edit a provider configuration in $EDITOR",
+		Long: `Open a provider's configuration in $EDITOR and save the changes.
+
+The edited configuration is validated before it is stored. If it is invalid,
+the editor is reopened with the error shown as a comment. Cached tokens are
+kept unless auth-relevant fields (endpoints, client_id, scopes, ...) change.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("provider name cannot be empty")
+			}
+
+			resp, err := client.SendRequest(protocol.Request{
+				Action: "describe",
+				Payload: protocol.DescribePayload{
+					Name: name,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var describeResp protocol.DescribeResponsePayload
+			if err := json.Unmarshal(payloadBytes, &describeResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			original, err := json.MarshalIndent(provider.StoredProvider{
+				Name: name,
+				Type: describeResp.Type,
+				Data: describeResp.Metadata,
+			}, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal provider: %w", err)
+			}
+
+			tmpFile, err := os.CreateTemp("", "credctl-edit-*.json")
+			if err != nil {
+				return fmt.Errorf("failed to create temp file: %w", err)
+			}
+			tmpPath := tmpFile.Name()
+			_ = tmpFile.Close()
+			defer func() { _ = os.Remove(tmpPath) }()
+
+			content := append(original, '\n')
+			var edited provider.StoredProvider
+			for {
+				if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+					return fmt.Errorf("failed to write temp file: %w", err)
+				}
+
+				if err := runEditor(tmpPath); err != nil {
+					return err
+				}
+
+				content, err = os.ReadFile(tmpPath)
+				if err != nil {
+					return fmt.Errorf("failed to read temp file: %w", err)
+				}
+
+				body := stripComments(content)
+				if len(bytes.TrimSpace(body)) == 0 {
+					fmt.Println("Edit cancelled (empty file)")
+					return nil
+				}
+				if bytes.Equal(bytes.TrimSpace(body), bytes.TrimSpace(original)) {
+					fmt.Println("No changes made")
+					return nil
+				}
+
+				validationErr := validateEdit(body, name, &edited)
+				if validationErr == nil {
+					break
+				}
+
+				// Reopen the editor with the error as a comment
+				content = annotateError(validationErr, body)
+			}
+
+			resp, err = client.SendRequest(protocol.Request{
+				Action: "add",
+				Payload: protocol.AddPayload{
+					Name:     name,
+					Type:     edited.Type,
+					Metadata: edited.Data,
+					Force:    true,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			fmt.Printf("Provider '%s' updated successfully\n", name)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// runEditor opens path in $VISUAL, $EDITOR or vi
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Run through the shell so editors with arguments (e.g. "code --wait") work
+	editorCmd := exec.Command("/bin/sh", "-c", editor+` "$1"`, "sh", path)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+	return nil
+}
+
+// stripComments removes lines starting with '#' (used for error annotations)
+func stripComments(content []byte) []byte {
+	var out []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// annotateError prepends err to body as comments, every line of a multi-line
+// error included, so stripComments removes them all
+func annotateError(err error, body []byte) []byte {
+	var annotated bytes.Buffer
+	for i, line := range strings.Split(err.Error(), "\n") {
+		if i == 0 {
+			line = "error: " + line
+		}
+		fmt.Fprintf(&annotated, "# %s\n", line)
+	}
+	annotated.WriteString("# Fix the configuration below, or empty the file to cancel.\n")
+	annotated.Write(body)
+	return annotated.Bytes()
+}
+
+// validateEdit parses an edited provider and validates it by initializing it
+func validateEdit(body []byte, name string, edited *provider.StoredProvider) error {
+	*edited = provider.StoredProvider{}
+	if err := json.Unmarshal(body, edited); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if edited.Name != name {
+		return fmt.Errorf("renaming is not supported (name must stay '%s')", name)
+	}
+
+	if !provider.IsRegistered(edited.Type) {
+		return fmt.Errorf("unknown provider type '%s', available types: %v", edited.Type, provider.ListTypes())
+	}
+
+	if _, err := provider.FromMetadata(edited.Type, edited.Data); err != nil {
+		return err
+	}
+
+	return nil
+}
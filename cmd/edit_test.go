@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"credctl/internal/provider"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "no comments", content: "{\n  \"name\": \"api\"\n}\n", want: "{\n  \"name\": \"api\"\n}\n"},
+		{name: "comments", content: "# error: invalid JSON\n# Fix it\n{}\n", want: "{}\n"},
+		{name: "indented comment", content: "{\n  # note\n}", want: "{\n}"},
+		{name: "only comments", content: "# one\n# two", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripComments([]byte(tt.content))); got != tt.want {
+				t.Errorf("stripComments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEdit(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		errContains string
+	}{
+		{name: "valid", body: `{"name": "api", "type": "command", "data": {"command": "echo token"}}`},
+		{name: "invalid JSON", body: `{"name": "api",`, errContains: "invalid JSON"},
+		{name: "rename", body: `{"name": "other", "type": "command", "data": {"command": "echo token"}}`, errContains: "renaming is not supported"},
+		{name: "unknown type", body: `{"name": "api", "type": "nope", "data": {}}`, errContains: "unknown provider type 'nope'"},
+		{name: "invalid config", body: `{"name": "api", "type": "command", "data": {}}`, errContains: "required field 'command' is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var edited provider.StoredProvider
+			err := validateEdit([]byte(tt.body), "api", &edited)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if edited.Type != "command" || edited.Data["command"] != "echo token" {
+				t.Errorf("edited = %+v, want the parsed provider", edited)
+			}
+		})
+	}
+}
+
+func TestAnnotateError(t *testing.T) {
+	body := []byte("{\n  \"name\": \"api\"\n}\n")
+	annotated := annotateError(errors.New("invalid config:\n  command is required\n  shell not found"), body)
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(annotated), string(body)), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			t.Errorf("annotation line %q is not a comment", line)
+		}
+	}
+	if !strings.HasPrefix(string(annotated), "# error: invalid config:\n") {
+		t.Errorf("annotated = %q, want the error first", annotated)
+	}
+
+	// Reopening the editor must give back the body once the comments are stripped
+	if got := string(stripComments(annotated)); got != string(body) {
+		t.Errorf("stripComments(annotateError()) = %q, want %q", got, body)
+	}
+}
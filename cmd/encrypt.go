@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"credctl/internal/client"
+	"credctl/internal/encryption"
+	"credctl/internal/paths"
+
+	"github.com/spf13/cobra"
+)
+
+// Encrypt returns the encrypt command
+func Encrypt() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt stored providers and tokens with a passphrase",
+		Long: `Enable at-rest encryption of the credential store and encrypt existing
+provider configurations and cached tokens (AES-256-GCM, key derived from a
+passphrase with Argon2id).
+
+The passphrase is read from ` + encryption.PassphraseEnvVar + ` or prompted for. Once the
+store is encrypted, the daemon asks for it when it starts. Running the command
+again encrypts any files still in plaintext.
+
+Stop the daemon before running this command.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The daemon holds plaintext state and would keep writing it
+			client.SetAutoStart(false)
+			if _, err := client.Ping(); err == nil {
+				return fmt.Errorf("the daemon is running: stop it before encrypting the store")
+			}
+
+			if err := unlockOrSetup(); err != nil {
+				return err
+			}
+
+			providersDir, err := paths.ProvidersDir()
+			if err != nil {
+				return err
+			}
+			tokensDir, err := paths.TokensDir()
+			if err != nil {
+				return err
+			}
+
+			total := 0
+			for _, dir := range []string{providersDir, tokensDir} {
+				n, err := encryption.EncryptDir(dir)
+				total += n
+				if err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Credential store encrypted (%d file(s) converted)\n", total)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// unlockOrSetup unlocks an already encrypted store, or enables encryption
+// with a new passphrase
+func unlockOrSetup() error {
+	if encryption.Enabled() {
+		return encryption.UnlockInteractive()
+	}
+
+	passphrase := os.Getenv(encryption.PassphraseEnvVar)
+	if passphrase == "" {
+		var err error
+		passphrase, err = encryption.ReadPassphrase("New passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirmation, err := encryption.ReadPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirmation {
+			return fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return encryption.Setup(passphrase)
+}
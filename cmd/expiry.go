@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitNoToken is the exit code of expiry when the provider has no cached token:
+// the provider needs a login before it has one
+const ExitNoToken = ExitAuthRequired
+
+// Expiry returns the expiry command
+func Expiry() *cobra.Command {
+	var iso bool
+
+	cmd := &cobra.Command{
+		Use:   "expiry <name>",
+		Short: "Print the seconds until a provider's cached token expires",
+		Long: `Print the remaining lifetime of a provider's cached token without fetching,
+printing or refreshing the token itself. An expired token reports 0.
+
+Exits with code 2 when no token is cached, so scripts can branch:
+
+  if [ "$(credctl expiry mytoken)" -lt 300 ]; then credctl login mytoken; fi`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("provider name cannot be empty")
+			}
+
+			resp, err := client.SendRequest(protocol.Request{
+				Action: "expiry",
+				Payload: protocol.ExpiryPayload{
+					Name: name,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var expiryResp protocol.ExpiryResponsePayload
+			if err := json.Unmarshal(payloadBytes, &expiryResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if !expiryResp.HasToken {
+				return &ExitError{
+					Code: ExitNoToken,
+					Err:  fmt.Errorf("no cached token for provider '%s'", name),
+				}
+			}
+
+			if iso {
+				expiresAt := time.Now().Add(time.Duration(expiryResp.ExpiresIn) * time.Second)
+				fmt.Fprintln(cmd.OutOrStdout(), expiresAt.UTC().Format(time.RFC3339))
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), expiryResp.ExpiresIn)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&iso, "iso", false, "Print the expiry time as RFC3339 instead of seconds remaining")
+
+	return cmd
+}
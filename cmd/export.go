@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"credctl/internal/encryption"
 	"credctl/internal/provider"
 
 	"github.com/spf13/cobra"
@@ -23,6 +24,11 @@ func Export() *cobra.Command {
 		Short: "Export all providers to JSON",
 		Long:  `Export all credential providers to JSON format for backup or distribution.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Provider files may be encrypted at rest
+			if err := encryption.UnlockInteractive(); err != nil {
+				return err
+			}
+
 			// List all providers
 			names, err := provider.List()
 			if err != nil {
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
 	"credctl/internal/client"
 	"credctl/internal/credentials"
@@ -19,6 +23,18 @@ func Get() *cobra.Command {
 	var templateStr string
 	var outputPath string
 	var format string
+	var noPrompt bool
+	var scopes []string
+	var noCache bool
+	var noBrowser bool
+	var appendBlock bool
+	var field string
+	var header bool
+	var envPrefix string
+	var machine string
+	var includeRefresh bool
+	var show bool
+	var noNewline bool
 
 	cmd := &cobra.Command{
 		Use:   "get <name>",
@@ -32,30 +48,42 @@ func Get() *cobra.Command {
 				return fmt.Errorf("provider name cannot be empty")
 			}
 
-			// Send request to daemon (daemon only returns raw output)
-			req := protocol.Request{
-				Action: "get",
-				Payload: protocol.GetPayload{
-					Name: name,
-				},
+			if envPrefix != "" {
+				if err := formatter.ValidateEnvPrefix(envPrefix); err != nil {
+					return err
+				}
+				if format != "" && format != "env" {
+					return fmt.Errorf("an env prefix requires the env format, got '%s'", format)
+				}
+			}
+			if jsonOutput {
+				for _, flag := range []string{"format", "field", "template", "header", "env-prefix", "machine", "include-refresh"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("the --json flag can't be combined with --%s", flag)
+					}
+				}
+			}
+			if machine != "" && format != "" && format != "netrc" {
+				return fmt.Errorf("a netrc machine requires the netrc format, got '%s'", format)
 			}
 
-			resp, err := client.SendRequest(req)
+			// Send request to daemon (daemon only returns raw output), offering
+			// an interactive login and retrying once when one is required
+			payload := protocol.GetPayload{
+				Name:    name,
+				Scopes:  scopes,
+				NoCache: noCache,
+			}
+			resp, err := sendGet(payload, client.SendRequest, canPromptLogin(noPrompt, os.Stdin), confirm, func() error {
+				_, err := runLogin(cmd.Context(), name, os.Stderr, noBrowser, false)
+				return err
+			})
 			if err != nil {
 				return err
 			}
 
 			if resp.Status == "error" {
-				// Handle errors based on error type (structured error handling)
-				switch resp.ErrorType {
-				case protocol.ErrorTypeAuthRequired:
-					return fmt.Errorf("authentication required for provider '%s'\n\nRun: credctl login %s", name, name)
-				case protocol.ErrorTypeDeviceFlowRequired:
-					// Device flow error already has a descriptive message
-					return fmt.Errorf("%s", resp.Error)
-				default:
-					return fmt.Errorf("error: %s", resp.Error)
-				}
+				return getError(name, resp)
 			}
 
 			// Extract output from payload
@@ -78,17 +106,59 @@ func Get() *cobra.Command {
 			effectiveFormat := getEffective(format, metadata, provider.MetadataFormat, "text")
 			effectiveOutput := getEffective(outputPath, metadata, provider.MetadataOutput, "")
 			effectiveTemplate := getEffective(templateStr, metadata, provider.MetadataTemplate, "")
+			if field != "" {
+				// Selecting a field replaces the provider's stored template
+				effectiveTemplate = ""
+			}
+			if header {
+				// The header line replaces the provider's stored template and format
+				effectiveTemplate = ""
+				effectiveFormat = "text"
+			}
+			if envPrefix != "" {
+				// A prefix only makes sense for env exports
+				effectiveFormat = "env"
+			}
+			if machine != "" {
+				effectiveFormat = "netrc"
+			}
+			if jsonOutput {
+				// The credential as a JSON object replaces the provider's stored template and format
+				effectiveTemplate = ""
+				effectiveFormat = "json"
+			}
 
 			// Apply template if specified
 			var finalOutput []byte
 
-			// If template is requested, apply it
-			if effectiveTemplate != "" {
+			if jsonOutput {
+				// All structured fields, or the raw credential as "token"
+				finalOutput, err = credentialJSON(structuredFields, hasStructuredFields, rawOutput)
+				if err != nil {
+					return err
+				}
+			} else if header {
+				// An HTTP Authorization header line
+				value, err := authorizationHeader(structuredFields, hasStructuredFields, rawOutput)
+				if err != nil {
+					return err
+				}
+				finalOutput = []byte("Authorization: " + value)
+			} else if field != "" {
+				// A single field of the structured credentials
+				value, err := credentialField(structuredFields, hasStructuredFields, field)
+				if err != nil {
+					return err
+				}
+				finalOutput = []byte(value)
+			} else if effectiveTemplate != "" {
+				// If template is requested, apply it
 				if !hasStructuredFields {
 					return fmt.Errorf("template requested but provider does not support structured credentials")
 				}
 
 				creds := credentials.New(structuredFields)
+				creds.ApplyClaimsMapping(provider.GetStringMapOrDefault(metadata, provider.MetadataClaimsMapping, nil))
 				templatedOutput, err := credentials.ApplyTemplate(creds, effectiveTemplate)
 				if err != nil {
 					return fmt.Errorf("failed to apply template: %w", err)
@@ -106,39 +176,145 @@ func Get() *cobra.Command {
 				available := formatter.List()
 				return fmt.Errorf("unsupported format '%s', available formats: %v", effectiveFormat, available)
 			}
+			if envFmtr, ok := fmtr.(*formatter.EnvFormatter); ok {
+				envFmtr.Prefix = envPrefix
+			}
+			if netrcFmtr, ok := fmtr.(*formatter.NetrcFormatter); ok {
+				netrcFmtr.Machine = machine
+			}
+			if jsonFullFmtr, ok := fmtr.(*formatter.JSONFullFormatter); ok {
+				jsonFullFmtr.IncludeRefresh = includeRefresh
+			} else if includeRefresh {
+				return fmt.Errorf("--include-refresh requires the json-full format, got '%s'", effectiveFormat)
+			}
 
-			formattedOutput, err := fmtr.Format(finalOutput)
+			_, formatsFields := fmtr.(formatter.FieldsFormatter)
+			if getRespPayload.Bundle && !header && field == "" && effectiveTemplate == "" && !formatsFields {
+				// A bundle has no single value: print it whole as JSON, or ask which field
+				if fmtr.Name() != "json" {
+					return fmt.Errorf("provider '%s' returns several credential fields (%s): select one with --field, or use --template or --format json",
+						name, strings.Join(sortedKeys(structuredFields), ", "))
+				}
+				finalOutput, err = json.Marshal(structuredFields)
+				if err != nil {
+					return fmt.Errorf("failed to marshal credential fields: %w", err)
+				}
+			}
+
+			useFields := field == "" && effectiveTemplate == "" && hasStructuredFields
+			formattedOutput, err := formatCredential(fmtr, structuredFields, useFields, finalOutput)
 			if err != nil {
-				return fmt.Errorf("failed to format output: %w", err)
+				return err
+			}
+
+			if appendBlock && effectiveOutput == "" {
+				return fmt.Errorf("--append requires an output file (--output or the provider's default)")
 			}
 
 			// Handle output destination
 			if effectiveOutput != "" {
-				// Write to file
-				if err := output.Write(formattedOutput, effectiveOutput); err != nil {
+				// Write to file, replacing only this provider's block in append mode
+				write := output.Write
+				if appendBlock {
+					// netrc blocks are per machine, so a provider can serve several hosts
+					blockName := name
+					if machine != "" {
+						blockName = name + "@" + machine
+					}
+					write = func(out []byte, path string) error { return output.WriteBlock(out, path, blockName) }
+				}
+				if err := write(applyNewlinePolicy(formattedOutput, false, noNewline), effectiveOutput); err != nil {
 					return fmt.Errorf("failed to write output: %w", err)
 				}
-				fmt.Printf("Credentials written to %s\n", effectiveOutput)
+				// Keep streams (FIFOs, /dev/stdout) clean for the reading process
+				if !output.IsSpecial(effectiveOutput) {
+					fmt.Printf("Credentials written to %s\n", effectiveOutput)
+				}
 				return nil
 			}
 
 			// Output to stdout
-			fmt.Print(string(formattedOutput))
-			if len(formattedOutput) > 0 && formattedOutput[len(formattedOutput)-1] != '\n' {
-				fmt.Println()
+			if err := guardStdout(name, show, os.Stdout); err != nil {
+				return err
 			}
+			fmt.Print(string(applyNewlinePolicy(formattedOutput, true, noNewline)))
 
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&templateStr, "template", "", "Go template to format credentials (e.g., 'export TOKEN={{.token}}')")
+	cmd.Flags().StringVar(&field, "field", "", "Print a single field of the provider's structured credentials (e.g. password)")
+	cmd.Flags().BoolVar(&header, "header", false, "Print an HTTP 'Authorization: <type> <credential>' header line (e.g. for curl -H)")
 	cmd.Flags().StringVar(&outputPath, "output", "", "Write output to file")
-	cmd.Flags().StringVar(&format, "format", "", "Output format: json, text, escaped (default: text, or provider's default)")
+	cmd.Flags().BoolVar(&appendBlock, "append", false, "Keep the rest of the output file and replace only this provider's '# BEGIN credctl:<name>' block (for shell rc/env files)")
+	cmd.Flags().StringVar(&format, "format", "", "Output format: json, json-full, text, escaped, basic-auth, env, netrc, aws-process (default: text, or provider's default)")
+	cmd.Flags().StringVar(&envPrefix, "env-prefix", "", "Prefix for variable names with --format env (e.g. MYAPP_ gives MYAPP_ACCESS_TOKEN); implies --format env")
+	cmd.Flags().StringVar(&machine, "machine", "", "Host for --format netrc entries (e.g. api.github.com); implies --format netrc")
+	cmd.Flags().BoolVar(&includeRefresh, "include-refresh", false, "Include the refresh token in --format json-full output")
+	cmd.Flags().StringSliceVar(&scopes, "scopes", nil, "Request a token for a subset of the provider's configured scopes (oauth2 client-credentials and refresh flows)")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Ignore cached credentials and fetch fresh ones (e.g. after a token was revoked)")
+	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "When prompted to login, print the authorization URL instead of opening a browser")
+	cmd.Flags().BoolVar(&show, "show", false, "Print the credential to the terminal without asking, even with --confirm-stdout")
+	cmd.Flags().BoolVar(&noPrompt, "no-prompt", false, "Never prompt for login, even when running in a terminal")
+	cmd.Flags().BoolVar(&noNewline, "no-newline", false, "Remove any trailing newline from the output (by default one is added on stdout if missing)")
+
+	cmd.MarkFlagsMutuallyExclusive("field", "template", "header")
+	cmd.MarkFlagsMutuallyExclusive("header", "format")
+	cmd.MarkFlagsMutuallyExclusive("header", "env-prefix")
+	cmd.MarkFlagsMutuallyExclusive("header", "machine")
+	cmd.MarkFlagsMutuallyExclusive("env-prefix", "machine")
+	cmd.MarkFlagsMutuallyExclusive("no-newline", "append")
 
 	return cmd
 }
 
+// formatCredential formats the structured fields when useFields is set and the
+// formatter supports them, otherwise the provider's output. Fields that only
+// wrap the raw output are formatted as that output. Errors formatting the
+// fields (e.g. colliding names) are returned, not retried on the output.
+func formatCredential(fmtr formatter.Formatter, fields map[string]string, useFields bool, output []byte) ([]byte, error) {
+	var formatted []byte
+	var err error
+	if fieldsFmtr, ok := fmtr.(formatter.FieldsFormatter); ok && useFields && !rawFields(fields) {
+		formatted, err = fieldsFmtr.FormatFields(fields)
+	} else {
+		formatted, err = fmtr.Format(output)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to format output: %w", err)
+	}
+	return formatted, nil
+}
+
+// rawFields reports whether structured fields only wrap a provider's raw
+// output (e.g. a command provider with the raw input format), along with the
+// expiry normalized from it
+func rawFields(fields map[string]string) bool {
+	if _, ok := fields["raw"]; !ok {
+		return false
+	}
+	for key := range fields {
+		if key != "raw" && key != credentials.FieldExpiresAt && key != credentials.FieldExpiresIn {
+			return false
+		}
+	}
+	return true
+}
+
+// applyNewlinePolicy returns out as get prints it: on stdout, with a newline
+// added when it doesn't end in one; in files, unchanged. With noNewline, any
+// trailing line breaks are removed instead, wherever it goes.
+func applyNewlinePolicy(out []byte, toStdout, noNewline bool) []byte {
+	if noNewline {
+		return bytes.TrimRight(out, "\r\n")
+	}
+	if toStdout && len(out) > 0 && out[len(out)-1] != '\n' {
+		return append(out, '\n')
+	}
+	return out
+}
+
 // getEffective returns the effective value for a configuration option
 // Priority: flag value > metadata value > default value
 func getEffective(flagValue string, metadata map[string]any, metadataKey string, defaultValue string) string {
@@ -152,3 +328,137 @@ func getEffective(flagValue string, metadata map[string]any, metadataKey string,
 	}
 	return defaultValue
 }
+
+// credentialField returns one field of the structured credentials, listing the
+// available fields when it doesn't exist
+func credentialField(fields map[string]string, hasFields bool, name string) (string, error) {
+	if !hasFields {
+		return "", fmt.Errorf("--field requested but provider does not support structured credentials")
+	}
+	value, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("credential field '%s' not found, available fields: %s", name, strings.Join(sortedKeys(fields), ", "))
+	}
+	return value, nil
+}
+
+// authorizationHeader returns the value of an HTTP Authorization header for a
+// credential: the provider's authorization field, an access token with its
+// token_type (Bearer by default), Basic auth from username and password, or
+// a raw single-line token
+func authorizationHeader(fields map[string]string, hasFields bool, rawOutput string) (string, error) {
+	if !hasFields {
+		fields = map[string]string{"raw": rawOutput}
+	}
+
+	if authorization := fields["authorization"]; authorization != "" {
+		return authorization, nil
+	}
+
+	for _, key := range []string{"access_token", "token"} {
+		if token := fields[key]; token != "" {
+			return tokenTypeScheme(fields["token_type"]) + " " + token, nil
+		}
+	}
+
+	if _, ok := fields["username"]; ok {
+		value, err := (&formatter.BasicAuthFormatter{}).FormatFields(fields)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+
+	// A bare token, e.g. from a command printing just the token
+	if token := strings.TrimSpace(fields["raw"]); token != "" && !strings.ContainsAny(token, " \t\r\n") {
+		return "Bearer " + token, nil
+	}
+
+	return "", fmt.Errorf("no token-like credential for --header (authorization, access_token, token, or username and password fields), available fields: %s", strings.Join(sortedKeys(fields), ", "))
+}
+
+// tokenTypeScheme returns the Authorization scheme for an OAuth2 token_type,
+// defaulting to Bearer and fixing the casing of well-known types
+func tokenTypeScheme(tokenType string) string {
+	switch {
+	case tokenType == "" || strings.EqualFold(tokenType, "bearer"):
+		return "Bearer"
+	case strings.EqualFold(tokenType, "mac"):
+		return "MAC"
+	case strings.EqualFold(tokenType, "basic"):
+		return "Basic"
+	default:
+		return tokenType
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// credentialJSON renders a credential as a JSON object: its structured fields,
+// or {"token": <output>} for providers that only return raw output
+func credentialJSON(fields map[string]string, hasFields bool, rawOutput string) ([]byte, error) {
+	if !hasFields {
+		fields = map[string]string{"token": strings.TrimRight(rawOutput, "\r\n")}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal credential fields: %w", err)
+	}
+	return data, nil
+}
+
+// canPromptLogin reports whether get may offer a login: not with --no-prompt,
+// nor when stdin isn't a terminal (scripts, CI) where nobody can answer
+func canPromptLogin(noPrompt bool, stdin *os.File) bool {
+	return !noPrompt && isTerminal(stdin)
+}
+
+// sendGet sends a get request for payload through send. When the provider
+// needs a login (auth_required, or device_flow_required since the device flow
+// runs on this terminal too) and prompt is set, it asks whether to log in
+// now and, on yes, runs login and retries the get once
+func sendGet(payload protocol.GetPayload, send func(protocol.Request) (protocol.Response, error), prompt bool, ask func(string, bool) bool, login func() error) (protocol.Response, error) {
+	resp, err := send(protocol.Request{Action: "get", Payload: payload})
+	if err != nil || !prompt || resp.Status != "error" {
+		return resp, err
+	}
+	if resp.ErrorType != protocol.ErrorTypeAuthRequired && resp.ErrorType != protocol.ErrorTypeDeviceFlowRequired {
+		return resp, nil
+	}
+	if !ask("Authentication required — login now?", true) {
+		return resp, nil
+	}
+
+	if err := login(); err != nil {
+		return protocol.Response{}, err
+	}
+
+	// The login just produced fresh credentials; don't discard them
+	payload.NoCache = false
+	return send(protocol.Request{Action: "get", Payload: payload})
+}
+
+// getError converts an error response to a "get" request into a user-facing error
+func getError(name string, resp protocol.Response) error {
+	// Handle errors based on error type (structured error handling)
+	switch resp.ErrorType {
+	case protocol.ErrorTypeAuthRequired:
+		return &client.ResponseError{
+			Type:    resp.ErrorType,
+			Message: fmt.Sprintf("authentication required for provider '%s'\n\nRun: credctl login %s", name, name),
+		}
+	case protocol.ErrorTypeDeviceFlowRequired:
+		// Device flow error already has a descriptive message
+		return &client.ResponseError{Type: resp.ErrorType, Message: resp.Error}
+	default:
+		return client.NewResponseError(resp)
+	}
+}
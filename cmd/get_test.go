@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"credctl/internal/formatter"
+	"credctl/internal/protocol"
+
+	"github.com/creack/pty"
+)
+
+func TestCredentialField(t *testing.T) {
+	fields := map[string]string{
+		"username": "bob",
+		"password": "s3cret",
+	}
+
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		hasFields   bool
+		field       string
+		want        string
+		errContains string
+	}{
+		{name: "existing field", fields: fields, hasFields: true, field: "password", want: "s3cret"},
+		{name: "empty value", fields: map[string]string{"x": ""}, hasFields: true, field: "x", want: ""},
+		{name: "missing field lists available ones", fields: fields, hasFields: true, field: "token", errContains: "password, username"},
+		{name: "no structured credentials", field: "token", errContains: "does not support structured credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := credentialField(tt.fields, tt.hasFields, tt.field)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("credentialField() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFieldAndTemplateAreExclusive(t *testing.T) {
+	cmd := Get()
+	cmd.SetArgs([]string{"myprov", "--field", "refresh_token", "--template", "{{.token}}"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	// Rejected while validating flags, before contacting the daemon
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "field") || !strings.Contains(err.Error(), "template") {
+		t.Errorf("expected a mutually exclusive flags error, got %v", err)
+	}
+}
+
+func TestApplyNewlinePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		out       string
+		toStdout  bool
+		noNewline bool
+		want      string
+	}{
+		{name: "stdout adds a newline", out: "token", toStdout: true, want: "token\n"},
+		{name: "stdout keeps an existing newline", out: "token\n", toStdout: true, want: "token\n"},
+		{name: "stdout empty output", out: "", toStdout: true, want: ""},
+		{name: "file is unchanged", out: "token", want: "token"},
+		{name: "file keeps an existing newline", out: "export A=1\n", want: "export A=1\n"},
+		{name: "no-newline on stdout", out: "token", toStdout: true, noNewline: true, want: "token"},
+		{name: "no-newline strips stdout newlines", out: "token\r\n\n", toStdout: true, noNewline: true, want: "token"},
+		{name: "no-newline strips a file newline", out: "export A=1\n", noNewline: true, want: "export A=1"},
+		{name: "no-newline keeps inner newlines", out: "a\nb\n", noNewline: true, want: "a\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyNewlinePolicy([]byte(tt.out), tt.toStdout, tt.noNewline)
+			if string(got) != tt.want {
+				t.Errorf("applyNewlinePolicy(%q, %v, %v) = %q, want %q", tt.out, tt.toStdout, tt.noNewline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNoNewlineAndAppendAreExclusive(t *testing.T) {
+	cmd := Get()
+	cmd.SetArgs([]string{"myprov", "--no-newline", "--append", "--output", "env"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "no-newline") {
+		t.Errorf("expected a mutually exclusive flags error, got %v", err)
+	}
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		hasFields   bool
+		rawOutput   string
+		want        string
+		errContains string
+	}{
+		{
+			name:      "authorization field",
+			fields:    map[string]string{"access_token": "abc", "authorization": "DPoP abc"},
+			hasFields: true,
+			want:      "DPoP abc",
+		},
+		{
+			name:      "bearer access token",
+			fields:    map[string]string{"access_token": "abc"},
+			hasFields: true,
+			want:      "Bearer abc",
+		},
+		{
+			name:      "token_type casing",
+			fields:    map[string]string{"access_token": "abc", "token_type": "bearer"},
+			hasFields: true,
+			want:      "Bearer abc",
+		},
+		{
+			name:      "custom token_type",
+			fields:    map[string]string{"token": "abc", "token_type": "PoP"},
+			hasFields: true,
+			want:      "PoP abc",
+		},
+		{
+			name:      "basic auth",
+			fields:    map[string]string{"username": "bob", "password": "s3cret"},
+			hasFields: true,
+			want:      "Basic Ym9iOnMzY3JldA==",
+		},
+		{
+			name:        "username without password",
+			fields:      map[string]string{"username": "bob"},
+			hasFields:   true,
+			errContains: "password",
+		},
+		{
+			name:      "raw token field",
+			fields:    map[string]string{"raw": "gho_abc\n"},
+			hasFields: true,
+			want:      "Bearer gho_abc",
+		},
+		{
+			name:      "unstructured provider",
+			rawOutput: "abc",
+			want:      "Bearer abc",
+		},
+		{
+			name:        "no token-like field",
+			fields:      map[string]string{"code": "123456", "seconds_remaining": "12"},
+			hasFields:   true,
+			errContains: "code, seconds_remaining",
+		},
+		{
+			name:        "raw output with spaces",
+			fields:      map[string]string{"raw": "export TOKEN=abc\n"},
+			hasFields:   true,
+			errContains: "token-like",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authorizationHeader(tt.fields, tt.hasFields, tt.rawOutput)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("authorizationHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsStdoutConfirmation(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		show     bool
+		terminal bool
+		want     bool
+	}{
+		{name: "disabled", enabled: false, terminal: true, want: false},
+		{name: "terminal", enabled: true, terminal: true, want: true},
+		{name: "pipe", enabled: true, terminal: false, want: false},
+		{name: "terminal with --show", enabled: true, show: true, terminal: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsStdoutConfirmation(tt.enabled, tt.show, tt.terminal); got != tt.want {
+				t.Errorf("needsStdoutConfirmation(%v, %v, %v) = %v, want %v", tt.enabled, tt.show, tt.terminal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardStdout(t *testing.T) {
+	t.Setenv(ConfirmStdoutEnvVar, "1")
+
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pipeReader.Close() }()
+	defer func() { _ = pipeWriter.Close() }()
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pseudo-terminal available: %v", err)
+	}
+	defer func() { _ = ptmx.Close() }()
+	defer func() { _ = tty.Close() }()
+
+	// Nobody can answer a prompt on a piped stdin
+	stdin := os.Stdin
+	os.Stdin = pipeReader
+	defer func() { os.Stdin = stdin }()
+
+	if err := guardStdout("api", false, pipeWriter); err != nil {
+		t.Errorf("piped stdout: unexpected error: %v", err)
+	}
+	if err := guardStdout("api", true, tty); err != nil {
+		t.Errorf("terminal with --show: unexpected error: %v", err)
+	}
+	if err := guardStdout("api", false, tty); err == nil || !strings.Contains(err.Error(), "--show") {
+		t.Errorf("terminal: error = %v, want a refusal mentioning --show", err)
+	}
+
+	t.Setenv(ConfirmStdoutEnvVar, "")
+	if err := guardStdout("api", false, tty); err != nil {
+		t.Errorf("terminal with the safeguard off: unexpected error: %v", err)
+	}
+}
+
+func TestFormatCredential(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		fields      map[string]string
+		useFields   bool
+		output      string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:      "fields",
+			format:    "basic-auth",
+			fields:    map[string]string{"username": "alice", "password": "s3cret"},
+			useFields: true,
+			output:    "ignored",
+			expected:  "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:     "output when fields are not used",
+			format:   "basic-auth",
+			fields:   map[string]string{"username": "alice"},
+			output:   "bob:hunter2",
+			expected: "Basic Ym9iOmh1bnRlcjI=",
+		},
+		{
+			name:      "raw command output",
+			format:    "basic-auth",
+			fields:    map[string]string{"raw": "alice:s3cret"},
+			useFields: true,
+			output:    "alice:s3cret",
+			expected:  "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:        "field errors are not retried on the output",
+			format:      "env",
+			fields:      map[string]string{"api-key": "a", "api_key": "b"},
+			useFields:   true,
+			output:      "token",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fmtr, err := formatter.Get(tt.format)
+			if err != nil {
+				t.Fatalf("formatter.Get(%q) error: %v", tt.format, err)
+			}
+			got, err := formatCredential(fmtr, tt.fields, tt.useFields, []byte(tt.output))
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("formatCredential() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCanPromptLogin(t *testing.T) {
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pipeReader.Close() }()
+	defer func() { _ = pipeWriter.Close() }()
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pseudo-terminal available: %v", err)
+	}
+	defer func() { _ = ptmx.Close() }()
+	defer func() { _ = tty.Close() }()
+
+	if !canPromptLogin(false, tty) {
+		t.Error("terminal: expected a login prompt")
+	}
+	if canPromptLogin(true, tty) {
+		t.Error("terminal with --no-prompt: expected no login prompt")
+	}
+	if canPromptLogin(false, pipeReader) {
+		t.Error("piped stdin: expected no login prompt")
+	}
+}
+
+func TestSendGet(t *testing.T) {
+	authRequired := protocol.Response{Status: "error", Error: "authentication required", ErrorType: protocol.ErrorTypeAuthRequired}
+	deviceFlowRequired := protocol.Response{Status: "error", Error: "device flow requires login", ErrorType: protocol.ErrorTypeDeviceFlowRequired}
+	notFound := protocol.Response{Status: "error", Error: "provider not found", ErrorType: protocol.ErrorTypeNotFound}
+	ok := protocol.Response{Status: "ok"}
+	loginErr := errors.New("login failed: access denied")
+
+	tests := []struct {
+		name      string
+		responses []protocol.Response // Returned by successive sends
+		prompt    bool
+		answer    bool
+		loginErr  error
+		wantSends int
+		wantAsked bool
+		wantLogin bool
+		wantResp  protocol.Response
+		wantErr   error
+	}{
+		{name: "success", responses: []protocol.Response{ok}, prompt: true, wantSends: 1, wantResp: ok},
+		{name: "prompt disabled", responses: []protocol.Response{authRequired}, prompt: false, wantSends: 1, wantResp: authRequired},
+		{name: "other error", responses: []protocol.Response{notFound}, prompt: true, wantSends: 1, wantResp: notFound},
+		{name: "login declined", responses: []protocol.Response{authRequired}, prompt: true, answer: false, wantSends: 1, wantAsked: true, wantResp: authRequired},
+		{name: "login and retry", responses: []protocol.Response{authRequired, ok}, prompt: true, answer: true, wantSends: 2, wantAsked: true, wantLogin: true, wantResp: ok},
+		{name: "device flow", responses: []protocol.Response{deviceFlowRequired, ok}, prompt: true, answer: true, wantSends: 2, wantAsked: true, wantLogin: true, wantResp: ok},
+		{name: "retried once", responses: []protocol.Response{authRequired, authRequired, ok}, prompt: true, answer: true, wantSends: 2, wantAsked: true, wantLogin: true, wantResp: authRequired},
+		{name: "login fails", responses: []protocol.Response{authRequired}, prompt: true, answer: true, loginErr: loginErr, wantSends: 1, wantAsked: true, wantLogin: true, wantErr: loginErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []protocol.GetPayload
+			send := func(req protocol.Request) (protocol.Response, error) {
+				sent = append(sent, req.Payload.(protocol.GetPayload))
+				return tt.responses[len(sent)-1], nil
+			}
+			asked, loggedIn := false, false
+			ask := func(string, bool) bool {
+				asked = true
+				return tt.answer
+			}
+			login := func() error {
+				loggedIn = true
+				return tt.loginErr
+			}
+
+			payload := protocol.GetPayload{Name: "corp", Scopes: []string{"read"}, NoCache: true}
+			resp, err := sendGet(payload, send, tt.prompt, ask, login)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("sendGet() error = %v, want %v", err, tt.wantErr)
+			}
+			if resp.Status != tt.wantResp.Status || resp.ErrorType != tt.wantResp.ErrorType {
+				t.Errorf("sendGet() = %+v, want %+v", resp, tt.wantResp)
+			}
+			if len(sent) != tt.wantSends {
+				t.Errorf("sent %d requests, want %d", len(sent), tt.wantSends)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("asked = %v, want %v", asked, tt.wantAsked)
+			}
+			if loggedIn != tt.wantLogin {
+				t.Errorf("logged in = %v, want %v", loggedIn, tt.wantLogin)
+			}
+			if !sent[0].NoCache {
+				t.Error("the first get must keep --no-cache")
+			}
+			if len(sent) > 1 && (sent[1].NoCache || sent[1].Name != "corp" || len(sent[1].Scopes) != 1) {
+				t.Errorf("retry payload = %+v, want the same get without --no-cache", sent[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"credctl/internal/client"
+	"credctl/internal/formatter"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+)
+
+// GetGroup returns the get-group command
+func GetGroup() *cobra.Command {
+	var format string
+	var show bool
+
+	cmd := &cobra.Command{
+		Use:   "get-group <group>",
+		Short: "Get the credentials of every provider in a group",
+		Long: `Fetch every member of a group in parallel and print their credentials
+together, in the group's order. With the env format (the default), each
+member's variables are prefixed with its name (prod-db → PROD_DB_TOKEN).
+
+Members that fail are reported on stderr; the others are still printed, and
+the command exits non-zero.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if _, err := formatter.Get(format); err != nil {
+				return fmt.Errorf("unsupported format '%s', available formats: %v", format, formatter.List())
+			}
+
+			resp, err := client.SendRequest(protocol.Request{
+				Action:  "get_group",
+				Payload: protocol.GroupPayload{Name: name},
+			})
+			if err != nil {
+				return err
+			}
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+			var groupResp protocol.GetGroupResponsePayload
+			if err := json.Unmarshal(payloadBytes, &groupResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			combined, failed := formatGroup(groupResp.Members, format, jsonOutput, os.Stderr)
+
+			if len(combined) > 0 {
+				if err := guardStdout(name, show, os.Stdout); err != nil {
+					return err
+				}
+				fmt.Print(string(combined))
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d providers in group '%s' failed", failed, len(groupResp.Members), name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "env", "Output format for each member: env, json, text, ... (env variables are prefixed with the member's name)")
+	cmd.Flags().BoolVar(&show, "show", false, "Print the credentials to the terminal without asking, even with --confirm-stdout")
+
+	return cmd
+}
+
+// formatGroup renders the fetched members of a group one after the other,
+// or as a single JSON object keyed by member with asJSON. Members that failed
+// or can't be formatted are reported to errOut and counted in failed.
+func formatGroup(members []protocol.GroupMemberResult, format string, asJSON bool, errOut io.Writer) (combined []byte, failed int) {
+	var buf strings.Builder
+	objects := make(map[string]json.RawMessage, len(members))
+
+	for _, member := range members {
+		var out []byte
+		var err error
+		switch {
+		case member.Result == nil:
+			err = getError(member.Name, protocol.Response{Status: "error", Error: member.Error, ErrorType: member.ErrorType})
+		case asJSON:
+			out, err = credentialJSON(member.Result.StructuredFields, member.Result.HasStructuredFields, member.Result.Output)
+		default:
+			out, err = formatGroupMember(member.Name, member.Result, format)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(errOut, "%s: %v\n", member.Name, err)
+			failed++
+			continue
+		}
+
+		if asJSON {
+			objects[member.Name] = out
+			continue
+		}
+		buf.Write(applyNewlinePolicy(out, true, false))
+	}
+
+	if asJSON {
+		data, err := json.Marshal(objects)
+		if err != nil {
+			_, _ = fmt.Fprintf(errOut, "failed to marshal credentials: %v\n", err)
+			return nil, len(members)
+		}
+		return append(data, '\n'), failed
+	}
+	return []byte(buf.String()), failed
+}
+
+// formatGroupMember formats one member's credential, preferring its
+// structured fields as get does
+func formatGroupMember(name string, result *protocol.GetResponsePayload, format string) ([]byte, error) {
+	fmtr, err := formatter.Get(format)
+	if err != nil {
+		return nil, err
+	}
+	if envFmtr, ok := fmtr.(*formatter.EnvFormatter); ok {
+		envFmtr.Prefix = formatter.EnvPrefixFor(name)
+	}
+
+	if _, formatsFields := fmtr.(formatter.FieldsFormatter); result.Bundle && !formatsFields {
+		if fmtr.Name() != "json" {
+			return nil, fmt.Errorf("several credential fields returned: use --format json or env")
+		}
+		return json.Marshal(result.StructuredFields)
+	}
+	return formatCredential(fmtr, result.StructuredFields, result.HasStructuredFields, []byte(result.Output))
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"credctl/internal/protocol"
+)
+
+func TestFormatGroup(t *testing.T) {
+	members := []protocol.GroupMemberResult{
+		{Name: "github", Result: &protocol.GetResponsePayload{Output: "gho_abc\n"}},
+		{Name: "prod-db", Error: "provider 'prod-db' is admin-only", ErrorType: protocol.ErrorTypePermissionDenied},
+		{Name: "aws", Result: &protocol.GetResponsePayload{
+			StructuredFields:    map[string]string{"access_key_id": "AKIA", "secret_access_key": "secret"},
+			HasStructuredFields: true,
+			Bundle:              true,
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		format     string
+		asJSON     bool
+		want       string
+		wantFailed int
+		wantErrOut []string
+	}{
+		{
+			name:       "env exports of the members that succeeded",
+			format:     "env",
+			want:       "export GITHUB_TOKEN='gho_abc'\nexport AWS_ACCESS_KEY_ID='AKIA'\nexport AWS_SECRET_ACCESS_KEY='secret'\n",
+			wantFailed: 1,
+			wantErrOut: []string{"prod-db: error: provider 'prod-db' is admin-only"},
+		},
+		{
+			name:       "a bundle can't be printed as text",
+			format:     "text",
+			want:       "gho_abc\n",
+			wantFailed: 2,
+			wantErrOut: []string{"prod-db:", "aws: several credential fields"},
+		},
+		{
+			name:       "json object keyed by member",
+			asJSON:     true,
+			want:       `{"aws":{"access_key_id":"AKIA","secret_access_key":"secret"},"github":{"token":"gho_abc"}}` + "\n",
+			wantFailed: 1,
+			wantErrOut: []string{"prod-db:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errOut bytes.Buffer
+			got, failed := formatGroup(members, tt.format, tt.asJSON, &errOut)
+			if string(got) != tt.want {
+				t.Errorf("formatGroup() = %q, want %q", got, tt.want)
+			}
+			if failed != tt.wantFailed {
+				t.Errorf("failed = %d, want %d", failed, tt.wantFailed)
+			}
+			for _, want := range tt.wantErrOut {
+				if !strings.Contains(errOut.String(), want) {
+					t.Errorf("stderr %q does not contain %q", errOut.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+)
+
+// Group returns the group command
+func Group() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "group",
+		Short: "Manage groups of providers fetched together",
+		Long: `Manage named groups of providers. 'credctl get-group <group>' fetches
+every member at once, e.g. to export all the credentials an environment needs.`,
+	}
+
+	cmd.AddCommand(groupAdd())
+	cmd.AddCommand(groupRemove())
+
+	return cmd
+}
+
+// groupAdd returns the group add command
+func groupAdd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "add <group> <provider>...",
+		Short: "Define a group of providers",
+		Long: `Define a group from existing providers (or profiles, as name@profile).
+Members are fetched in the order given.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("group name cannot be empty")
+			}
+
+			req := protocol.Request{
+				Action: "add_group",
+				Payload: protocol.AddGroupPayload{
+					Name:    name,
+					Members: args[1:],
+					Force:   force,
+				},
+			}
+
+			resp, err := client.SendRequest(req)
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			fmt.Printf("Group '%s' added with %d providers\n", name, len(args)-1)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Replace an existing group with that name")
+
+	return cmd
+}
+
+// groupRemove returns the group rm command
+func groupRemove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm <group>",
+		Short: "Delete a group (its providers are kept)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			req := protocol.Request{
+				Action:  "delete_group",
+				Payload: protocol.GroupPayload{Name: name},
+			}
+
+			resp, err := client.SendRequest(req)
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			fmt.Printf("Group '%s' deleted successfully\n", name)
+			return nil
+		},
+	}
+
+	return cmd
+}
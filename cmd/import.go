@@ -5,31 +5,50 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"credctl/internal/client"
+	"credctl/internal/encryption"
 	"credctl/internal/protocol"
 	"credctl/internal/provider"
 	_ "credctl/internal/provider/command" // Import to register providers
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 // ImportedProvider represents a provider from import file
 type ImportedProvider struct {
-	Name string         `json:"name"`
-	Type string         `json:"type"`
-	Data map[string]any `json:"data"`
+	Name string         `json:"name" yaml:"name"`
+	Type string         `json:"type" yaml:"type"`
+	Data map[string]any `json:"data" yaml:"data"`
 }
 
+// importAction describes what import does with a single provider
+type importAction int
+
+const (
+	importCreate  importAction = iota // Provider does not exist yet
+	importSkip                        // Provider exists and is left untouched
+	importReplace                     // Provider exists and is replaced wholesale
+	importMerge                       // Provider exists and imported fields are merged over it
+)
+
 // Import returns the import command
 func Import() *cobra.Command {
 	var overwrite bool
+	var merge bool
 
 	cmd := &cobra.Command{
 		Use:   "import [file]",
 		Short: "Import providers from JSON file or stdin",
-		Long:  `Import credential providers from a JSON file or stdin. By default, skips existing providers.`,
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Import credential providers from a JSON file or stdin (files ending in .yaml
+or .yml are read as YAML). By default, skips existing providers.
+
+Use --overwrite to replace existing providers, or --merge to update them field-by-field
+(imported fields win, fields not present in the import are kept).`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var data []byte
 			var err error
@@ -50,9 +69,13 @@ func Import() *cobra.Command {
 				}
 			}
 
-			// Parse JSON
+			// Parse JSON, or YAML for .yaml/.yml files
 			var importedProviders []ImportedProvider
-			if err := json.Unmarshal(data, &importedProviders); err != nil {
+			if len(args) > 0 && isYAMLFile(args[0]) {
+				if err := yaml.Unmarshal(data, &importedProviders); err != nil {
+					return fmt.Errorf("failed to parse YAML: %w", err)
+				}
+			} else if err := json.Unmarshal(data, &importedProviders); err != nil {
 				return fmt.Errorf("failed to parse JSON: %w", err)
 			}
 
@@ -61,20 +84,34 @@ func Import() *cobra.Command {
 				return nil
 			}
 
+			// Existing providers are read from disk, which may be encrypted at rest
+			if err := encryption.UnlockInteractive(); err != nil {
+				return err
+			}
+
 			// Import each provider via daemon
 			importedCount := 0
 			skipped := 0
 			failed := 0
 
 			for _, prov := range importedProviders {
-				// Check if provider already exists (unless overwrite flag is set)
-				if !overwrite {
-					_, err := provider.Load(prov.Name)
-					if err == nil {
-						fmt.Printf("Skipping '%s' (already exists, use --overwrite to replace)\n", prov.Name)
-						skipped++
-						continue
-					}
+				// Load the existing provider, if any, to decide what to do
+				existing, err := provider.Load(prov.Name)
+				if err != nil {
+					existing = nil
+				}
+
+				action, metadata, err := planImport(prov, existing, overwrite, merge)
+				if err != nil {
+					fmt.Printf("Failed to import '%s': %v\n", prov.Name, err)
+					failed++
+					continue
+				}
+
+				if action == importSkip {
+					fmt.Printf("Skipping '%s' (already exists, use --overwrite or --merge to update)\n", prov.Name)
+					skipped++
+					continue
 				}
 
 				// Send add request to daemon
@@ -83,7 +120,8 @@ func Import() *cobra.Command {
 					Payload: protocol.AddPayload{
 						Name:     prov.Name,
 						Type:     prov.Type,
-						Metadata: prov.Data,
+						Metadata: metadata,
+						Force:    action != importCreate,
 					},
 				}
 
@@ -100,7 +138,11 @@ func Import() *cobra.Command {
 					continue
 				}
 
-				fmt.Printf("Imported '%s'\n", prov.Name)
+				if action == importMerge {
+					fmt.Printf("Merged '%s'\n", prov.Name)
+				} else {
+					fmt.Printf("Imported '%s'\n", prov.Name)
+				}
 				importedCount++
 			}
 
@@ -116,6 +158,45 @@ func Import() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing providers")
+	cmd.Flags().BoolVar(&merge, "merge", false, "Merge imported fields into existing providers")
+	cmd.MarkFlagsMutuallyExclusive("overwrite", "merge")
 
 	return cmd
 }
+
+// isYAMLFile reports whether path names a YAML file
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// planImport decides how an imported provider is applied given the existing
+// provider with the same name (nil if none) and returns the metadata to store.
+// Merged metadata is validated by re-initializing a provider with it.
+func planImport(imported ImportedProvider, existing provider.Provider, overwrite, merge bool) (importAction, map[string]any, error) {
+	if existing == nil {
+		return importCreate, imported.Data, nil
+	}
+
+	switch {
+	case overwrite:
+		return importReplace, imported.Data, nil
+	case merge:
+		if existing.Type() != imported.Type {
+			return importSkip, nil, fmt.Errorf("cannot merge provider of type '%s' into existing provider of type '%s'", imported.Type, existing.Type())
+		}
+
+		merged := existing.Metadata()
+		for key, value := range imported.Data {
+			merged[key] = value
+		}
+
+		if _, err := provider.FromMetadata(imported.Type, merged); err != nil {
+			return importSkip, nil, fmt.Errorf("merged configuration is invalid: %w", err)
+		}
+
+		return importMerge, merged, nil
+	default:
+		return importSkip, nil, nil
+	}
+}
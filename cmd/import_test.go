@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"testing"
+
+	"credctl/internal/provider"
+	_ "credctl/internal/provider/oauth2" // Import to register OAuth2 provider
+)
+
+func TestPlanImport(t *testing.T) {
+	existing, err := provider.FromMetadata("command", map[string]any{
+		provider.MetadataCommand:      "old-command",
+		provider.MetadataLoginCommand: "old-login",
+	})
+	if err != nil {
+		t.Fatalf("failed to create existing provider: %v", err)
+	}
+
+	oauth2Existing, err := provider.FromMetadata("oauth2", map[string]any{
+		provider.MetadataClientID:      "id",
+		provider.MetadataClientSecret:  "secret",
+		provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+		"flow":                         "client-credentials",
+	})
+	if err != nil {
+		t.Fatalf("failed to create existing oauth2 provider: %v", err)
+	}
+
+	imported := ImportedProvider{
+		Name: "test",
+		Type: "command",
+		Data: map[string]any{
+			provider.MetadataCommand: "new-command",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		existing   provider.Provider
+		imported   ImportedProvider
+		overwrite  bool
+		merge      bool
+		wantAction importAction
+		wantData   map[string]any
+		wantErr    bool
+	}{
+		{
+			name:       "new provider is created",
+			existing:   nil,
+			imported:   imported,
+			wantAction: importCreate,
+			wantData:   map[string]any{provider.MetadataCommand: "new-command"},
+		},
+		{
+			name:       "existing provider is skipped by default",
+			existing:   existing,
+			imported:   imported,
+			wantAction: importSkip,
+		},
+		{
+			name:       "overwrite replaces all fields",
+			existing:   existing,
+			imported:   imported,
+			overwrite:  true,
+			wantAction: importReplace,
+			wantData:   map[string]any{provider.MetadataCommand: "new-command"},
+		},
+		{
+			name:       "merge keeps unspecified fields",
+			existing:   existing,
+			imported:   imported,
+			merge:      true,
+			wantAction: importMerge,
+			wantData: map[string]any{
+				provider.MetadataCommand:      "new-command",
+				provider.MetadataLoginCommand: "old-login",
+			},
+		},
+		{
+			name:     "merge rejects type mismatch",
+			existing: existing,
+			imported: ImportedProvider{
+				Name: "test",
+				Type: "oauth2",
+				Data: map[string]any{provider.MetadataClientID: "id"},
+			},
+			merge:   true,
+			wantErr: true,
+		},
+		{
+			name:     "merge validates the result",
+			existing: oauth2Existing,
+			imported: ImportedProvider{
+				Name: "test",
+				Type: "oauth2",
+				Data: map[string]any{"flow": "implicit"},
+			},
+			merge:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, data, err := planImport(tt.imported, tt.existing, tt.overwrite, tt.merge)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if action != tt.wantAction {
+				t.Errorf("action = %v, want %v", action, tt.wantAction)
+			}
+
+			if len(data) != len(tt.wantData) {
+				t.Fatalf("data = %v, want %v", data, tt.wantData)
+			}
+			for key, want := range tt.wantData {
+				if data[key] != want {
+					t.Errorf("data[%q] = %v, want %v", key, data[key], want)
+				}
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"time"
 
 	"credctl/internal/client"
 	"credctl/internal/protocol"
@@ -13,14 +15,38 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Sort orders for credctl list
+const (
+	listSortName = "name"
+	listSortAge  = "age"
+)
+
 // List returns the list command
 func List() *cobra.Command {
+	var showAge bool
+	var sortBy string
+	var porcelain bool
+
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all configured providers",
-		Long:  `List all configured providers with their types.`,
-		Args:  cobra.NoArgs,
+		Long: `List all configured providers with their types.
+
+--since adds when each provider was added or last modified (from its stored
+file), and --sort age lists the oldest providers first, to find stale ones.
+
+--porcelain prints tab-separated name, type and token state (valid, expired,
+refreshable, none, or - for providers that don't cache tokens), unstyled and
+stable across releases, for scripts.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if sortBy != listSortName && sortBy != listSortAge {
+				return fmt.Errorf("invalid --sort '%s': must be %s or %s", sortBy, listSortName, listSortAge)
+			}
+			if porcelain && jsonOutput {
+				return fmt.Errorf("the --json flag can't be combined with --porcelain")
+			}
+
 			// Send request to daemon
 			req := protocol.Request{
 				Action:  "list",
@@ -33,7 +59,7 @@ func List() *cobra.Command {
 			}
 
 			if resp.Status == "error" {
-				return fmt.Errorf("error: %s", resp.Error)
+				return client.NewResponseError(resp)
 			}
 
 			// Extract providers from payload
@@ -47,91 +73,182 @@ func List() *cobra.Command {
 				return fmt.Errorf("failed to parse response: %w", err)
 			}
 
-			// Sort providers by name for consistent output
-			sort.Slice(listResp.Providers, func(i, j int) bool {
-				return listResp.Providers[i].Name < listResp.Providers[j].Name
-			})
-
-			// Display providers with styled output
-			if len(listResp.Providers) == 0 {
-				noProvidersStyle := lipgloss.NewStyle().
-					Foreground(lipgloss.Color("241")).
-					Italic(true)
-				fmt.Println(noProvidersStyle.Render("No providers configured."))
+			sortProviders(listResp.Providers, sortBy)
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(listResp.Providers, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal providers: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
 				return nil
 			}
 
-			// Create styles
-			titleStyle := lipgloss.NewStyle().
-				Bold(true).
-				Foreground(lipgloss.Color("99")).
-				MarginBottom(1)
+			if porcelain {
+				tokens, err := fetchTokens()
+				if err != nil {
+					return err
+				}
+				printProvidersPorcelain(cmd.OutOrStdout(), listResp.Providers, tokens)
+				return nil
+			}
 
-			headerStyle := lipgloss.NewStyle().
-				Bold(true).
-				Foreground(lipgloss.Color("212"))
+			// Sorting by age without showing it would be confusing
+			printProviders(cmd.OutOrStdout(), listResp.Providers, showAge || sortBy == listSortAge, time.Now())
+			return nil
+		},
+	}
 
-			nameStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("86")).
-				Bold(true)
+	cmd.Flags().BoolVar(&showAge, "since", false, "Show when each provider was added or last modified")
+	cmd.Flags().StringVar(&sortBy, "sort", listSortName, "Sort by name or age (oldest first)")
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, "Print one 'name<TAB>type<TAB>token_state' line per provider, a format kept stable across releases for scripts")
 
-			typeStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("141"))
+	cmd.MarkFlagsMutuallyExclusive("porcelain", "since")
 
-			borderStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("240"))
+	return cmd
+}
 
-			// Calculate column widths
-			nameColWidth := len("NAME")
-			typeColWidth := len("TYPE")
-			for _, prov := range listResp.Providers {
-				if len(prov.Name) > nameColWidth {
-					nameColWidth = len(prov.Name)
-				}
-				if len(prov.Type) > typeColWidth {
-					typeColWidth = len(prov.Type)
-				}
-			}
+// sortProviders orders providers by name, or by modification time (oldest
+// first, ties by name) for listSortAge
+func sortProviders(providers []protocol.ProviderInfo, sortBy string) {
+	sort.Slice(providers, func(i, j int) bool {
+		if sortBy == listSortAge && !providers[i].ModifiedAt.Equal(providers[j].ModifiedAt) {
+			return providers[i].ModifiedAt.Before(providers[j].ModifiedAt)
+		}
+		return providers[i].Name < providers[j].Name
+	})
+}
+
+// printProvidersPorcelain prints one tab-separated line per provider: name,
+// type and the state of its cached tokens (see tokenState).
+// The format is a stable interface for scripts: don't change it.
+func printProvidersPorcelain(out io.Writer, providers []protocol.ProviderInfo, tokens []protocol.TokenInfo) {
+	byName := make(map[string]*protocol.TokenInfo, len(tokens))
+	for i := range tokens {
+		byName[tokens[i].Name] = &tokens[i]
+	}
+
+	for _, prov := range providers {
+		fmt.Fprintf(out, "%s\t%s\t%s\n", prov.Name, prov.Type, tokenState(byName[prov.Name]))
+	}
+}
 
-			// Add some padding
-			nameColWidth += 2
-			typeColWidth += 2
-
-			// Print title
-			fmt.Println(titleStyle.Render("Configured Providers"))
-
-			// Print table header
-			nameHeader := headerStyle.Render(fmt.Sprintf("%-*s", nameColWidth, "NAME"))
-			typeHeader := headerStyle.Render(fmt.Sprintf("%-*s", typeColWidth, "TYPE"))
-			separator := borderStyle.Render("│")
-			fmt.Printf("%s %s %s\n", nameHeader, separator, typeHeader)
-
-			// Print separator line
-			nameLine := strings.Repeat("─", nameColWidth)
-			typeLine := strings.Repeat("─", typeColWidth)
-			crossSep := borderStyle.Render("┼")
-			fmt.Printf("%s %s %s\n", borderStyle.Render(nameLine), crossSep, borderStyle.Render(typeLine))
-
-			// Print providers
-			for _, prov := range listResp.Providers {
-				name := nameStyle.Render(fmt.Sprintf("%-*s", nameColWidth, prov.Name))
-				provType := typeStyle.Render(fmt.Sprintf("%-*s", typeColWidth, prov.Type))
-				separator := borderStyle.Render("│")
-				fmt.Printf("%s %s %s\n", name, separator, provType)
+// printProviders renders the providers as a table, with an AGE column if showAge is set
+func printProviders(out io.Writer, providers []protocol.ProviderInfo, showAge bool, now time.Time) {
+	// Display providers with styled output
+	if len(providers) == 0 {
+		noProvidersStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Italic(true)
+		lipgloss.Fprintln(out, noProvidersStyle.Render("No providers configured."))
+		return
+	}
+
+	// Create styles
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("99")).
+		MarginBottom(1)
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("212"))
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("86")).
+		Bold(true)
+
+	typeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141"))
+
+	ageStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245"))
+
+	borderStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+
+	headers := []string{"NAME", "TYPE"}
+	styles := []lipgloss.Style{nameStyle, typeStyle}
+	if showAge {
+		headers = append(headers, "AGE")
+		styles = append(styles, ageStyle)
+	}
+
+	rows := make([][]string, 0, len(providers))
+	for _, prov := range providers {
+		row := []string{prov.Name, prov.Type}
+		if showAge {
+			row = append(row, formatAge(prov.ModifiedAt, now))
+		}
+		rows = append(rows, row)
+	}
+
+	// Calculate column widths (with some padding)
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
 			}
+		}
+	}
+	for i := range widths {
+		widths[i] += 2
+	}
 
-			// Print footer with count
-			footerStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("241")).
-				Italic(true).
-				MarginTop(1)
+	// Print title
+	lipgloss.Fprintln(out, titleStyle.Render("Configured Providers"))
 
-			countMsg := fmt.Sprintf("Total: %d provider(s)", len(listResp.Providers))
-			fmt.Println(footerStyle.Render(countMsg))
+	// Print table header
+	separator := " " + borderStyle.Render("│") + " "
+	cells := make([]string, len(headers))
+	for i, h := range headers {
+		cells[i] = headerStyle.Render(fmt.Sprintf("%-*s", widths[i], h))
+	}
+	lipgloss.Fprintln(out, strings.Join(cells, separator))
 
-			return nil
-		},
+	// Print separator line
+	for i := range headers {
+		cells[i] = borderStyle.Render(strings.Repeat("─", widths[i]))
+	}
+	lipgloss.Fprintln(out, strings.Join(cells, " "+borderStyle.Render("┼")+" "))
+
+	// Print providers
+	for _, row := range rows {
+		for i, cell := range row {
+			cells[i] = styles[i].Render(fmt.Sprintf("%-*s", widths[i], cell))
+		}
+		lipgloss.Fprintln(out, strings.Join(cells, separator))
 	}
 
-	return cmd
+	// Print footer with count
+	footerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true).
+		MarginTop(1)
+
+	countMsg := fmt.Sprintf("Total: %d provider(s)", len(providers))
+	lipgloss.Fprintln(out, footerStyle.Render(countMsg))
+}
+
+// formatAge describes how long ago t was, in the largest whole unit
+func formatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(age/(24*time.Hour)))
+	}
 }
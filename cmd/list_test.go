@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"credctl/internal/protocol"
+)
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{name: "unknown", t: time.Time{}, want: "-"},
+		{name: "seconds", t: now.Add(-30 * time.Second), want: "just now"},
+		{name: "minutes", t: now.Add(-5 * time.Minute), want: "5m ago"},
+		{name: "hours", t: now.Add(-3*time.Hour - 59*time.Minute), want: "3h ago"},
+		{name: "days", t: now.Add(-40 * 24 * time.Hour), want: "40d ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatAge(tt.t, now); got != tt.want {
+				t.Errorf("formatAge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortProviders(t *testing.T) {
+	now := time.Now()
+	providers := func() []protocol.ProviderInfo {
+		return []protocol.ProviderInfo{
+			{Name: "bravo", ModifiedAt: now.Add(-time.Hour)},
+			{Name: "charlie", ModifiedAt: now.Add(-48 * time.Hour)},
+			{Name: "alpha", ModifiedAt: now},
+			{Name: "delta", ModifiedAt: now.Add(-time.Hour)},
+		}
+	}
+	names := func(providers []protocol.ProviderInfo) string {
+		var out []string
+		for _, p := range providers {
+			out = append(out, p.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		sortBy string
+		want   string
+	}{
+		{sortBy: listSortName, want: "alpha,bravo,charlie,delta"},
+		{sortBy: listSortAge, want: "charlie,bravo,delta,alpha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			list := providers()
+			sortProviders(list, tt.sortBy)
+			if got := names(list); got != tt.want {
+				t.Errorf("sortProviders(%s) = %s, want %s", tt.sortBy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintProvidersAgeColumn(t *testing.T) {
+	now := time.Now()
+	providers := []protocol.ProviderInfo{{Name: "github", Type: "command", ModifiedAt: now.Add(-72 * time.Hour)}}
+
+	var out bytes.Buffer
+	printProviders(&out, providers, false, now)
+	if strings.Contains(out.String(), "AGE") {
+		t.Errorf("expected no AGE column by default, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	printProviders(&out, providers, true, now)
+	if !strings.Contains(out.String(), "AGE") || !strings.Contains(out.String(), "3d ago") {
+		t.Errorf("expected an AGE column with 3d ago, got:\n%s", out.String())
+	}
+}
+
+func TestListRejectsUnknownSort(t *testing.T) {
+	cmd := List()
+	cmd.SetArgs([]string{"--sort", "size"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	// Rejected before contacting the daemon
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid --sort") {
+		t.Errorf("expected an invalid --sort error, got %v", err)
+	}
+}
+
+func TestPrintProvidersPorcelain(t *testing.T) {
+	providers := []protocol.ProviderInfo{
+		{Name: "corp", Type: "oauth2"},
+		{Name: "db", Type: "alias"},
+		{Name: "github", Type: "command"},
+		{Name: "legacy", Type: "oauth2"},
+		{Name: "new", Type: "oauth2"},
+		{Name: "sso", Type: "oauth2"},
+	}
+	tokens := []protocol.TokenInfo{
+		{Name: "corp", Type: "oauth2", HasAccessToken: true, HasRefreshToken: true, ExpiresIn: 3600},
+		{Name: "legacy", Type: "oauth2", HasAccessToken: true},
+		{Name: "new", Type: "oauth2"},
+		{Name: "sso", Type: "oauth2", HasAccessToken: true, HasRefreshToken: true},
+	}
+
+	var out bytes.Buffer
+	printProvidersPorcelain(&out, providers, tokens)
+
+	want := "corp\toauth2\tvalid\n" +
+		"db\talias\t-\n" +
+		"github\tcommand\t-\n" +
+		"legacy\toauth2\texpired\n" +
+		"new\toauth2\tnone\n" +
+		"sso\toauth2\trefreshable\n"
+	if out.String() != want {
+		t.Errorf("printProvidersPorcelain() =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestPrintTokensPorcelain(t *testing.T) {
+	tokens := []protocol.TokenInfo{
+		{Name: "corp", Type: "oauth2", HasAccessToken: true, HasRefreshToken: true, TokenType: "Bearer", ExpiresIn: 3600, Subject: "alice"},
+		{Name: "new", Type: "token-exchange"},
+	}
+
+	var out bytes.Buffer
+	printTokensPorcelain(&out, tokens)
+
+	want := "corp\toauth2\tvalid\t3600\n" +
+		"new\ttoken-exchange\tnone\t-\n"
+	if out.String() != want {
+		t.Errorf("printTokensPorcelain() =\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestPrintProvidersNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	var out bytes.Buffer
+	printProviders(&out, []protocol.ProviderInfo{{Name: "github", Type: "command"}}, false, time.Now())
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("expected no escape sequences with NO_COLOR, got %q", out.String())
+	}
+}
+
+func TestListPorcelainRejectsJSON(t *testing.T) {
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	cmd := List()
+	cmd.SetArgs([]string{"--porcelain"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	// Rejected before contacting the daemon
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--porcelain") {
+		t.Errorf("expected a --porcelain error, got %v", err)
+	}
+}
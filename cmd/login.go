@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"credctl/internal/client"
+	"credctl/internal/clock"
+	"credctl/internal/encryption"
+	"credctl/internal/formatter"
 	"credctl/internal/protocol"
 	"credctl/internal/provider"
 
@@ -12,11 +20,28 @@ import (
 )
 
 func Login() *cobra.Command {
+	var noBrowser bool
+	var stepUp bool
+	var timeout time.Duration
+	var printFormat string
+
 	cmd := &cobra.Command{
 		Use:   "login <name>",
 		Short: "Execute the login command for a provider",
-		Long:  `Execute the interactive login command configured for a credential provider.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Execute the interactive login command configured for a credential provider.
+
+With --step-up, OAuth2 providers ask the identity provider to authenticate you
+again (prompt=login) instead of reusing its session, e.g. to reach the
+authentication level set with acr_values for MFA-protected resources.
+
+With --timeout, the login fails once the duration elapses instead of waiting
+for the flow's own limit, e.g. in CI where nobody will complete it.
+
+With --print, the new credential is printed in the given format, as credctl
+get --format would, and progress messages go to stderr:
+
+  eval "$(credctl login corp --print env)"`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 
@@ -24,82 +49,217 @@ func Login() *cobra.Command {
 				return fmt.Errorf("provider name cannot be empty")
 			}
 
-			// Try to get provider info from daemon first
-			req := protocol.Request{
-				Action: "describe",
-				Payload: protocol.DescribePayload{
-					Name: name,
-				},
+			// Keep stdout for the credential when it is printed
+			out := cmd.OutOrStdout()
+			if printFormat != "" {
+				if _, err := formatter.Get(printFormat); err != nil {
+					return fmt.Errorf("unsupported format '%s', available formats: %v", printFormat, formatter.List())
+				}
+				out = cmd.ErrOrStderr()
 			}
 
-			resp, err := client.SendRequest(req)
 			var prov provider.Provider
-			if err == nil && resp.Status == "ok" {
-				// Successfully got provider info from daemon
-				payloadBytes, err := json.Marshal(resp.Payload)
-				if err != nil {
-					return fmt.Errorf("failed to parse daemon response: %w", err)
-				}
+			err := withLoginTimeout(cmd.Context(), timeout, func(ctx context.Context) error {
+				var err error
+				prov, err = runLogin(ctx, name, out, noBrowser, stepUp)
+				return err
+			})
+			if err != nil {
+				return err
+			}
 
-				var describeResp protocol.DescribeResponsePayload
-				if err := json.Unmarshal(payloadBytes, &describeResp); err != nil {
-					return fmt.Errorf("failed to parse daemon response: %w", err)
-				}
+			_, _ = fmt.Fprintf(out, "Login successful for provider '%s'\n", name)
 
-				// Create provider instance from daemon info
-				prov, err = provider.FromMetadata(describeResp.Type, describeResp.Metadata)
-				if err != nil {
-					return fmt.Errorf("failed to create provider from daemon info: %w", err)
-				}
-			} else {
-				// Daemon approach failed, try loading from disk
-				prov, err = provider.Load(name)
-				if err != nil {
-					return fmt.Errorf("failed to load provider: %w", err)
+			if printFormat != "" {
+				if err := guardStdout(name, false, os.Stdout); err != nil {
+					return err
 				}
+				return printLoginCredential(cmd.Context(), cmd.OutOrStdout(), prov, printFormat)
 			}
+			return nil
+		},
+	}
 
-			// Check if provider supports login
-			loginProvider, ok := prov.(provider.LoginProvider)
-			if !ok {
-				return fmt.Errorf("provider '%s' (type: %s) does not support login", name, prov.Type())
-			}
+	cmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the authorization URL instead of opening a browser")
+	cmd.Flags().BoolVar(&stepUp, "step-up", false, "Force re-authentication with the identity provider (e.g. to satisfy acr_values)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Give up on the login after this duration (e.g. 30s; default: the flow's own limit)")
+	cmd.Flags().StringVar(&printFormat, "print", "", "Print the new credential in this format after logging in: json, json-full, text, escaped, basic-auth, env, netrc, aws-process")
 
-			// Execute provider-specific login
-			fmt.Printf("Running login for provider '%s'...\n", name)
-			if err := loginProvider.Login(cmd.Context()); err != nil {
-				return fmt.Errorf("login failed: %w", err)
-			}
+	return cmd
+}
 
-			// If provider supports token caching, send tokens to daemon
-			if tokenCacheProv, ok := prov.(provider.TokenCacheProvider); ok {
-				accessToken, refreshToken, expiresIn := tokenCacheProv.GetTokens()
-				if accessToken != "" {
-					// Send tokens to daemon
-					req := protocol.Request{
-						Action: "set_tokens",
-						Payload: protocol.SetTokensPayload{
-							Name:         name,
-							AccessToken:  accessToken,
-							RefreshToken: refreshToken,
-							ExpiresIn:    expiresIn,
-						},
-					}
-
-					resp, err := client.SendRequest(req)
-					if err != nil {
-						// Log warning but don't fail - login was successful
-						fmt.Printf("Warning: failed to sync tokens with daemon: %v\n", err)
-					} else if resp.Status == "error" {
-						fmt.Printf("Warning: daemon rejected tokens: %s\n", resp.Error)
-					}
-				}
+// withLoginTimeout runs login with a context that expires after timeout, if
+// set, and reports the expiry as a timeout
+func withLoginTimeout(ctx context.Context, timeout time.Duration, login func(context.Context) error) error {
+	if timeout <= 0 {
+		return login(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := login(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &ExitError{
+			Code: ExitTimeout,
+			Err:  fmt.Errorf("login timed out after %gs", timeout.Seconds()),
+		}
+	}
+	return err
+}
+
+// printLoginCredential writes the credential prov obtained during its login
+// to w in format, from the structured fields when the provider and format
+// support them (like credctl get) and from the provider's output otherwise
+func printLoginCredential(ctx context.Context, w io.Writer, prov provider.Provider, format string) error {
+	fmtr, err := formatter.Get(format)
+	if err != nil {
+		return fmt.Errorf("unsupported format '%s', available formats: %v", format, formatter.List())
+	}
+
+	var formatted []byte
+	fieldsFmtr, formatsFields := fmtr.(formatter.FieldsFormatter)
+	if credsProv, ok := prov.(provider.CredentialsProvider); ok && formatsFields {
+		creds, err := credsProv.GetCredentials(ctx)
+		if err == nil && creds != nil && creds.Fields != nil {
+			creds.NormalizeExpiry(clock.Now())
+			formatted, err = fieldsFmtr.FormatFields(creds.Fields)
+			if err != nil {
+				return fmt.Errorf("failed to format credential: %w", err)
 			}
+		}
+	}
 
-			fmt.Printf("Login successful for provider '%s'\n", name)
-			return nil
+	if formatted == nil {
+		output, err := prov.Get(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get credential: %w", err)
+		}
+		formatted, err = fmtr.Format(output)
+		if err != nil {
+			return fmt.Errorf("failed to format credential: %w", err)
+		}
+	}
+
+	if len(formatted) > 0 && formatted[len(formatted)-1] != '\n' {
+		formatted = append(formatted, '\n')
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// runLogin executes the provider-specific login for name, syncs the
+// resulting tokens with the daemon and returns the logged-in provider.
+// Progress messages are written to out. noBrowser overrides the provider's
+// no_browser setting for this login, and stepUp forces the identity provider
+// to authenticate the user again.
+func runLogin(ctx context.Context, name string, out io.Writer, noBrowser, stepUp bool) (provider.Provider, error) {
+	// Try to get provider info from daemon first
+	req := protocol.Request{
+		Action: "describe",
+		Payload: protocol.DescribePayload{
+			Name:    name,
+			Resolve: true,
 		},
 	}
 
-	return cmd
+	resp, err := client.SendRequest(req)
+
+	// Profiles (name@profile) are derived from the provider's own login
+	if err == nil && resp.ErrorType == protocol.ErrorTypeNotFound {
+		if base, _, ok := provider.SplitProfile(name); ok {
+			name = base
+			req.Payload = protocol.DescribePayload{Name: name, Resolve: true}
+			resp, err = client.SendRequest(req)
+		}
+	}
+
+	var prov provider.Provider
+	if err == nil && resp.Status == "ok" {
+		// Successfully got provider info from daemon
+		payloadBytes, err := json.Marshal(resp.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse daemon response: %w", err)
+		}
+
+		var describeResp protocol.DescribeResponsePayload
+		if err := json.Unmarshal(payloadBytes, &describeResp); err != nil {
+			return nil, fmt.Errorf("failed to parse daemon response: %w", err)
+		}
+
+		// Create provider instance from daemon info
+		prov, err = provider.FromMetadata(describeResp.Type, describeResp.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider from daemon info: %w", err)
+		}
+	} else {
+		// Daemon approach failed, try loading from disk
+		if err := encryption.UnlockInteractive(); err != nil {
+			return nil, err
+		}
+		prov, err = provider.Load(name)
+		if err == nil {
+			prov, err = provider.ResolveAlias(prov, provider.Load)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load provider: %w", err)
+		}
+	}
+
+	if noBrowser {
+		metadata := prov.Metadata()
+		metadata[provider.MetadataNoBrowser] = true
+		prov, err = provider.FromMetadata(prov.Type(), metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply --no-browser: %w", err)
+		}
+	}
+
+	// Check if provider supports login
+	loginProvider, ok := prov.(provider.LoginProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' (type: %s) does not support login", name, prov.Type())
+	}
+
+	login := loginProvider.Login
+	if stepUp {
+		stepUpProvider, ok := prov.(provider.StepUpProvider)
+		if !ok {
+			return nil, fmt.Errorf("provider '%s' (type: %s) does not support step-up login", name, prov.Type())
+		}
+		login = stepUpProvider.StepUpLogin
+	}
+
+	// Execute provider-specific login
+	_, _ = fmt.Fprintf(out, "Running login for provider '%s'...\n", name)
+	if err := login(ctx); err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	// If provider supports token caching, send tokens to daemon
+	if tokenCacheProv, ok := prov.(provider.TokenCacheProvider); ok {
+		accessToken, refreshToken, expiresIn := tokenCacheProv.GetTokens()
+		if accessToken != "" {
+			// Send tokens to daemon
+			req := protocol.Request{
+				Action: "set_tokens",
+				Payload: protocol.SetTokensPayload{
+					Name:         name,
+					AccessToken:  accessToken,
+					RefreshToken: refreshToken,
+					ExpiresIn:    expiresIn,
+				},
+			}
+
+			resp, err := client.SendRequest(req)
+			if err != nil {
+				// Log warning but don't fail - login was successful
+				_, _ = fmt.Fprintf(out, "Warning: failed to sync tokens with daemon: %v\n", err)
+			} else if resp.Status == "error" {
+				_, _ = fmt.Fprintf(out, "Warning: daemon rejected tokens: %s\n", resp.Error)
+			}
+		}
+	}
+
+	return prov, nil
 }
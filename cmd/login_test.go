@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"credctl/internal/credentials"
+	"credctl/internal/paths"
+	"credctl/internal/provider"
+)
+
+func TestWithLoginTimeout(t *testing.T) {
+	blockingLogin := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	start := time.Now()
+	err := withLoginTimeout(context.Background(), 50*time.Millisecond, blockingLogin)
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("login returned after %v, want it to stop at the timeout", elapsed)
+	}
+	if err.Error() != "login timed out after 0.05s" {
+		t.Errorf("error = %q, want %q", err.Error(), "login timed out after 0.05s")
+	}
+	if got := exitCode(err); got != ExitTimeout {
+		t.Errorf("exitCode() = %d, want %d", got, ExitTimeout)
+	}
+}
+
+func TestWithLoginTimeoutPassesThrough(t *testing.T) {
+	loginErr := errors.New("access denied")
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		login   func(context.Context) error
+		want    error
+	}{
+		{name: "success", timeout: time.Minute, login: func(context.Context) error { return nil }},
+		{name: "login error", timeout: time.Minute, login: func(context.Context) error { return loginErr }, want: loginErr},
+		{name: "no timeout", login: func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); ok {
+				return errors.New("unexpected deadline")
+			}
+			return nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := withLoginTimeout(context.Background(), tt.timeout, tt.login); !errors.Is(err, tt.want) {
+				t.Errorf("withLoginTimeout() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// loggedInProvider stands for a provider that just completed its login
+type loggedInProvider struct {
+	fields map[string]string
+}
+
+func (p *loggedInProvider) Type() string                     { return "test" }
+func (p *loggedInProvider) Schema() provider.Schema          { return provider.Schema{} }
+func (p *loggedInProvider) Init(config map[string]any) error { return nil }
+func (p *loggedInProvider) Metadata() map[string]any         { return map[string]any{} }
+
+func (p *loggedInProvider) Get(ctx context.Context) ([]byte, error) {
+	return []byte(p.fields["access_token"]), nil
+}
+
+func (p *loggedInProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	return credentials.New(p.fields), nil
+}
+
+func TestPrintLoginCredential(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		contains    []string
+		errContains string
+	}{
+		{name: "env", format: "env", contains: []string{"export ACCESS_TOKEN='new-token'", "export REFRESH_TOKEN='new-refresh'"}},
+		{name: "json-full", format: "json-full", contains: []string{`"access_token":"new-token"`, `"token_type":"Bearer"`}},
+		{name: "text", format: "text", contains: []string{"new-token\n"}},
+		{name: "unknown format", format: "yaml", errContains: "unsupported format 'yaml'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := &loggedInProvider{fields: map[string]string{
+				"access_token":  "new-token",
+				"refresh_token": "new-refresh",
+			}}
+
+			var out bytes.Buffer
+			err := printLoginCredential(context.Background(), &out, prov, tt.format)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunLoginFromDisk(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnvVar, home)
+	// No daemon listens there: runLogin falls back to the stored providers
+	t.Setenv("CREDCTL_SOCK", filepath.Join(home, "missing.sock"))
+
+	save := func(name string, config map[string]any) {
+		t.Helper()
+		prov, err := provider.New("command")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := prov.Init(config); err != nil {
+			t.Fatal(err)
+		}
+		if err := provider.Save(name, prov); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("with-login", map[string]any{
+		provider.MetadataCommand:      "echo token",
+		provider.MetadataLoginCommand: "true",
+	})
+	save("without-login", map[string]any{provider.MetadataCommand: "echo token"})
+
+	tests := []struct {
+		name        string
+		provider    string
+		stepUp      bool
+		errContains string
+	}{
+		{name: "login", provider: "with-login"},
+		{name: "no login command", provider: "without-login", errContains: "no login command configured"},
+		{name: "no step-up support", provider: "with-login", stepUp: true, errContains: "does not support step-up login"},
+		{name: "unknown provider", provider: "missing", errContains: "failed to load provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			prov, err := runLogin(context.Background(), tt.provider, &out, false, tt.stepUp)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if prov == nil || prov.Type() != "command" {
+				t.Errorf("runLogin() returned %v, want the command provider", prov)
+			}
+			if !strings.Contains(out.String(), "Running login for provider 'with-login'") {
+				t.Errorf("output %q does not report the login", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// An empty answer returns defaultYes.
+func confirm(question string, defaultYes bool) bool {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	fmt.Fprintf(os.Stderr, "%s %s ", question, hint)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "":
+		return defaultYes
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ConfirmStdoutEnvVar turns on --confirm-stdout when set to "1"
+const ConfirmStdoutEnvVar = "CREDCTL_CONFIRM_STDOUT"
+
+// needsStdoutConfirmation reports whether printing a credential has to be
+// confirmed first: the safeguard is on, --show wasn't given and the output goes
+// to a terminal, where it could be seen (e.g. on a screen share). Piped and
+// redirected output never is.
+func needsStdoutConfirmation(enabled, show, stdoutIsTerminal bool) bool {
+	return enabled && !show && stdoutIsTerminal
+}
+
+// guardStdout asks before a credential of provider name is printed to stdout
+// when needsStdoutConfirmation says so, and fails when it can't ask or the
+// user declines
+func guardStdout(name string, show bool, stdout *os.File) error {
+	enabled := confirmStdout || os.Getenv(ConfirmStdoutEnvVar) == "1"
+	if !needsStdoutConfirmation(enabled, show, isTerminal(stdout)) {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("refusing to print the credential to a terminal without confirmation: use --show, or pipe or redirect the output")
+	}
+	if !confirm(fmt.Sprintf("Print the credential of '%s' to the terminal?", name), false) {
+		return fmt.Errorf("credential not printed")
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"credctl/internal/provider"
+
+	"charm.land/lipgloss/v2"
+	"github.com/spf13/cobra"
+)
+
+// schemaField is the JSON representation of a provider schema field
+type schemaField struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Default     string   `json:"default,omitempty"`
+	Help        string   `json:"help,omitempty"`
+	Hidden      bool     `json:"hidden,omitempty"`
+	ValidValues []string `json:"valid_values,omitempty"`
+}
+
+// schemaFields converts a provider schema into its JSON representation
+func schemaFields(schema provider.Schema) []schemaField {
+	fields := make([]schemaField, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		fields = append(fields, schemaField{
+			Name:        f.Name,
+			Type:        string(f.Type),
+			Required:    f.Required,
+			Default:     f.Default,
+			Help:        f.Help,
+			Hidden:      f.Hidden,
+			ValidValues: f.ValidValues,
+		})
+	}
+	return fields
+}
+
+// Providers returns the providers command
+func Providers() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "providers [type]",
+		Short: "List provider types or show a provider type's configuration",
+		Long: `List all registered provider types, or show the configuration fields
+accepted by a provider type (as used by 'credctl add <type>').`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return printProviderTypes(jsonOutput)
+			}
+
+			providerType := args[0]
+			schema, err := provider.GetSchema(providerType)
+			if err != nil {
+				return fmt.Errorf("unknown provider type '%s'\nAvailable types: %v", providerType, provider.ListTypes())
+			}
+
+			return printSchema(providerType, schema, jsonOutput)
+		},
+	}
+
+	return cmd
+}
+
+// printProviderTypes prints all registered provider types
+func printProviderTypes(jsonOutput bool) error {
+	types := provider.ListTypes()
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(types, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal provider types: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("99")).
+		MarginBottom(1)
+
+	typeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141"))
+
+	footerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Italic(true).
+		MarginTop(1)
+
+	fmt.Println(titleStyle.Render("Provider Types"))
+	for _, t := range types {
+		fmt.Println(typeStyle.Render(t))
+	}
+	fmt.Println(footerStyle.Render("Run 'credctl providers <type>' to see its configuration"))
+
+	return nil
+}
+
+// printSchema prints the configuration fields of a provider type,
+// grouping required fields before optional ones
+func printSchema(providerType string, schema provider.Schema, jsonOutput bool) error {
+	fields := schemaFields(schema)
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(map[string]any{
+			"type":   providerType,
+			"fields": fields,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("99")).
+		MarginBottom(1)
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("212"))
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("86")).
+		Bold(true)
+
+	typeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("141"))
+
+	detailStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241"))
+
+	// Calculate column widths
+	nameColWidth := 0
+	typeColWidth := 0
+	for _, f := range fields {
+		if len(f.Name)+2 > nameColWidth {
+			nameColWidth = len(f.Name) + 2
+		}
+		if len(f.Type) > typeColWidth {
+			typeColWidth = len(f.Type)
+		}
+	}
+
+	fmt.Println(titleStyle.Render(fmt.Sprintf("Provider type: %s", providerType)))
+
+	printGroup := func(title string, required bool) {
+		var group []schemaField
+		for _, f := range fields {
+			if f.Required == required {
+				group = append(group, f)
+			}
+		}
+		if len(group) == 0 {
+			return
+		}
+
+		fmt.Println(headerStyle.Render(title))
+		for _, f := range group {
+			name := nameStyle.Render(fmt.Sprintf("  --%-*s", nameColWidth, f.Name))
+			fieldType := typeStyle.Render(fmt.Sprintf("%-*s", typeColWidth, f.Type))
+			fmt.Printf("%s %s  %s\n", name, fieldType, f.Help)
+
+			var details []string
+			if f.Default != "" {
+				details = append(details, "default: "+f.Default)
+			}
+			if len(f.ValidValues) > 0 {
+				details = append(details, "one of: "+strings.Join(f.ValidValues, ", "))
+			}
+			if f.Hidden {
+				details = append(details, "sensitive")
+			}
+			if len(details) > 0 {
+				indent := strings.Repeat(" ", 2+2+nameColWidth+1+typeColWidth+2)
+				fmt.Println(indent + detailStyle.Render("("+strings.Join(details, "; ")+")"))
+			}
+		}
+		fmt.Println()
+	}
+
+	printGroup("Required", true)
+	printGroup("Optional", false)
+
+	return nil
+}
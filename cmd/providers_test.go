@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"credctl/internal/provider"
+)
+
+func TestSchemaFields(t *testing.T) {
+	schema := provider.Schema{
+		Fields: []provider.FieldDef{
+			{Name: "secret", Type: provider.FieldTypeString, Required: true, Hidden: true, Help: "Secret"},
+			{Name: "mode", Type: provider.FieldTypeString, Default: "a", ValidValues: []string{"a", "b"}},
+		},
+	}
+
+	data, err := json.Marshal(schemaFields(schema))
+	if err != nil {
+		t.Fatalf("failed to marshal fields: %v", err)
+	}
+
+	expected := `[{"name":"secret","type":"string","required":true,"help":"Secret","hidden":true},` +
+		`{"name":"mode","type":"string","required":false,"default":"a","valid_values":["a","b"]}]`
+	if string(data) != expected {
+		t.Errorf("schemaFields() JSON =\n%s\nwant\n%s", data, expected)
+	}
+}
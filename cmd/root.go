@@ -2,15 +2,33 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
 	"github.com/charmbracelet/fang"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/release-utils/version"
 )
 
+// jsonOutput is set by the global --json flag: commands print their results,
+// and failures, as JSON on stdout for scripts
+var jsonOutput bool
+
+// confirmStdout is set by the global --confirm-stdout flag (or
+// CREDCTL_CONFIRM_STDOUT=1): credentials are only printed to a terminal after
+// a confirmation, see guardStdout
+var confirmStdout bool
+
 // Root returns the root command for credctl
 func Root() *cobra.Command {
+	var autoStart bool
+
 	cmd := &cobra.Command{
 		Use:   "credctl",
 		Short: "A tiny SSH-forwardable credential agent",
@@ -22,16 +40,40 @@ The agent runs as a daemon and communicates via a Unix socket.`,
 		SilenceUsage:      true,
 		SilenceErrors:     true,
 		DisableAutoGenTag: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if autoStart {
+				client.SetAutoStart(true)
+			}
+		},
 	}
 
+	cmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print results and errors as JSON on stdout (errors as {\"status\":\"error\",\"error_type\":...,\"message\":...})")
+	cmd.PersistentFlags().BoolVar(&confirmStdout, "confirm-stdout", false, "Ask before printing a credential to a terminal, unless --show is given (or set "+ConfirmStdoutEnvVar+"=1)")
+	cmd.PersistentFlags().BoolVar(&autoStart, "autostart", false, "Start the daemon if it is not running (or set "+client.AutoStartEnvVar+"=1)")
+
 	cmd.AddCommand(Add())
 	cmd.AddCommand(Get())
+	cmd.AddCommand(Cat())
 	cmd.AddCommand(Delete())
+	cmd.AddCommand(Alias())
+	cmd.AddCommand(Group())
+	cmd.AddCommand(GetGroup())
 	cmd.AddCommand(List())
+	cmd.AddCommand(Describe())
+	cmd.AddCommand(Providers())
 	cmd.AddCommand(Daemon())
 	cmd.AddCommand(Export())
 	cmd.AddCommand(Import())
 	cmd.AddCommand(Login())
+	cmd.AddCommand(Whoami())
+	cmd.AddCommand(Expiry())
+	cmd.AddCommand(Tokens())
+	cmd.AddCommand(Encrypt())
+	cmd.AddCommand(Edit())
+	cmd.AddCommand(RotateSecret())
+	cmd.AddCommand(Version())
+	cmd.AddCommand(Copy())
+	cmd.AddCommand(ClipboardClear())
 
 	return cmd
 }
@@ -46,7 +88,97 @@ func Execute() {
 	info.Description = rootCmd.Short
 	rootCmd.SetVersionTemplate(info.String())
 
-	if err := fang.Execute(context.Background(), rootCmd); err != nil {
-		os.Exit(1)
+	if err := fang.Execute(context.Background(), rootCmd, fang.WithErrorHandler(handleError)); err != nil {
+		os.Exit(exitCode(err))
 	}
 }
+
+// Exit codes, so scripts can branch on why a command failed
+const (
+	ExitGeneric          = 1 // Any other failure
+	ExitAuthRequired     = 2 // The provider needs a login (auth_required, device_flow_required)
+	ExitNotFound         = 3 // No provider with that name (not_found)
+	ExitTimeout          = 4 // The daemon or the provider timed out (timeout)
+	ExitPermissionDenied = 5 // The socket doesn't allow the action (permission_denied)
+	ExitVersionMismatch  = 6 // The daemon speaks an incompatible protocol (version_mismatch)
+)
+
+// exitCode returns the process exit code for a failed command
+func exitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	switch errorType(err) {
+	case protocol.ErrorTypeAuthRequired, protocol.ErrorTypeDeviceFlowRequired:
+		return ExitAuthRequired
+	case protocol.ErrorTypeNotFound:
+		return ExitNotFound
+	case protocol.ErrorTypeTimeout:
+		return ExitTimeout
+	case protocol.ErrorTypePermissionDenied:
+		return ExitPermissionDenied
+	case protocol.ErrorTypeVersionMismatch:
+		return ExitVersionMismatch
+	default:
+		return ExitGeneric
+	}
+}
+
+// jsonError is the --json form of a failed command
+type jsonError struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"error_type"`
+	Message   string `json:"message"`
+}
+
+// handleError reports a failed command, as JSON on stdout with --json
+func handleError(w io.Writer, styles fang.Styles, err error) {
+	if !jsonOutput || writeJSONError(os.Stdout, err) != nil {
+		fang.DefaultErrorHandler(w, styles, err)
+	}
+}
+
+// writeJSONError writes err to out as a jsonError line
+func writeJSONError(out io.Writer, err error) error {
+	data, marshalErr := json.Marshal(jsonError{
+		Status:    "error",
+		ErrorType: errorType(err),
+		Message:   err.Error(),
+	})
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, writeErr := fmt.Fprintln(out, string(data))
+	return writeErr
+}
+
+// errorType classifies err with the protocol's error types
+func errorType(err error) string {
+	var respErr *client.ResponseError
+	switch {
+	case errors.As(err, &respErr):
+		return respErr.Type
+	case errors.Is(err, client.ErrVersionMismatch):
+		return protocol.ErrorTypeVersionMismatch
+	case errors.Is(err, client.ErrDaemonTimeout):
+		return protocol.ErrorTypeTimeout
+	default:
+		return protocol.ErrorTypeGeneric
+	}
+}
+
+// ExitError is an error that exits with a specific code so scripts can branch on it
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
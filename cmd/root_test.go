@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+)
+
+func TestWriteJSONError(t *testing.T) {
+	notFound := client.NewResponseError(protocol.Response{
+		Status:    "error",
+		Error:     "provider not found: nope",
+		ErrorType: protocol.ErrorTypeNotFound,
+	})
+
+	tests := []struct {
+		name      string
+		err       error
+		errorType string
+		message   string
+	}{
+		{
+			name:      "provider not found",
+			err:       notFound,
+			errorType: protocol.ErrorTypeNotFound,
+			message:   "error: provider not found: nope",
+		},
+		{
+			name:      "wrapped daemon error",
+			err:       fmt.Errorf("failed to load: %w", notFound),
+			errorType: protocol.ErrorTypeNotFound,
+			message:   "failed to load: error: provider not found: nope",
+		},
+		{
+			name:      "daemon error without a type",
+			err:       client.NewResponseError(protocol.Response{Status: "error", Error: "boom"}),
+			errorType: protocol.ErrorTypeGeneric,
+			message:   "error: boom",
+		},
+		{
+			name:      "version mismatch",
+			err:       fmt.Errorf("%w: daemon 2.0, client 1.0", client.ErrVersionMismatch),
+			errorType: protocol.ErrorTypeVersionMismatch,
+			message:   "protocol version mismatch: daemon 2.0, client 1.0",
+		},
+		{
+			name:      "local error",
+			err:       errors.New("provider name cannot be empty"),
+			errorType: protocol.ErrorTypeGeneric,
+			message:   "provider name cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeJSONError(&buf, tt.err); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not JSON: %q", buf.String())
+			}
+			if len(got) != 3 {
+				t.Errorf("expected status, error_type and message only, got %v", got)
+			}
+			if got["status"] != "error" {
+				t.Errorf("expected status error, got %v", got["status"])
+			}
+			if got["error_type"] != tt.errorType {
+				t.Errorf("expected error_type %q, got %v", tt.errorType, got["error_type"])
+			}
+			if got["message"] != tt.message {
+				t.Errorf("expected message %q, got %v", tt.message, got["message"])
+			}
+		})
+	}
+}
+
+// fakeDaemon answers every request on a fresh socket with resp, or never
+// answers when resp is nil, and points the client at it
+func fakeDaemon(t *testing.T, resp *protocol.Response) {
+	t.Helper()
+
+	// sun_path is limited to ~100 bytes, too short for t.TempDir() on some systems
+	dir, err := os.MkdirTemp("", "credctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	t.Setenv("CREDCTL_SOCK", path)
+	t.Setenv(client.AutoStartEnvVar, "")
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				if _, err := bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+					return
+				}
+				if resp == nil {
+					// Hold the connection until the client gives up
+					_, _ = io.Copy(io.Discard, conn)
+					return
+				}
+				respJSON, _ := json.Marshal(resp)
+				_, _ = conn.Write(append(respJSON, '\n'))
+			}()
+		}
+	}()
+}
+
+func TestExitCode(t *testing.T) {
+	errorResponse := func(errorType string) *protocol.Response {
+		return &protocol.Response{
+			Version:   protocol.Version,
+			Status:    "error",
+			Error:     "boom",
+			ErrorType: errorType,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		resp     *protocol.Response
+		timeout  string
+		expected int
+	}{
+		{name: "auth required", resp: errorResponse(protocol.ErrorTypeAuthRequired), expected: ExitAuthRequired},
+		{name: "device flow required", resp: errorResponse(protocol.ErrorTypeDeviceFlowRequired), expected: ExitAuthRequired},
+		{name: "not found", resp: errorResponse(protocol.ErrorTypeNotFound), expected: ExitNotFound},
+		{name: "provider timeout", resp: errorResponse(protocol.ErrorTypeTimeout), expected: ExitTimeout},
+		{name: "daemon timeout", timeout: "100ms", expected: ExitTimeout},
+		{name: "permission denied", resp: errorResponse(protocol.ErrorTypePermissionDenied), expected: ExitPermissionDenied},
+		{name: "version mismatch", resp: errorResponse(protocol.ErrorTypeVersionMismatch), expected: ExitVersionMismatch},
+		{name: "generic", resp: errorResponse(protocol.ErrorTypeGeneric), expected: ExitGeneric},
+		{name: "untyped", resp: errorResponse(""), expected: ExitGeneric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeDaemon(t, tt.resp)
+			t.Setenv(client.TimeoutEnvVar, tt.timeout)
+
+			cmd := Get()
+			cmd.SetArgs([]string{"myprov", "--no-prompt"})
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+
+			err := cmd.Execute()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := exitCode(err); got != tt.expected {
+				t.Errorf("exitCode(%v) = %d, want %d", err, got, tt.expected)
+			}
+		})
+	}
+
+	// Commands choosing their own exit code keep it
+	if got := exitCode(&ExitError{Code: 7, Err: errors.New("custom")}); got != 7 {
+		t.Errorf("exitCode(ExitError) = %d, want 7", got)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// RotateSecret returns the rotate-secret command
+func RotateSecret() *cobra.Command {
+	var newSecret string
+	var verify bool
+
+	cmd := &cobra.Command{
+		Use:   "rotate-secret <name> --new-secret <secret|@file|->",
+		Short: "Replace a provider's client secret, keeping its cached tokens",
+		Long: `Replace the client secret of a provider without logging in again: cached
+tokens are kept, so credentials keep being served during the rotation.
+
+With --verify, the daemon first requests tokens with the new secret (a client
+credentials grant, or a refresh for the other flows) and keeps the current
+secret if the token endpoint rejects it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			secret, err := provider.ReadSecretValue(newSecret, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			if secret == "" {
+				return fmt.Errorf("the new client secret cannot be empty")
+			}
+
+			req := protocol.Request{
+				Action: "rotate_secret",
+				Payload: protocol.RotateSecretPayload{
+					Name:   name,
+					Secret: secret,
+					Verify: verify,
+				},
+			}
+
+			resp, err := client.SendRequest(req)
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			if verify {
+				fmt.Printf("Client secret of '%s' rotated (verified with a token request)\n", name)
+			} else {
+				fmt.Printf("Client secret of '%s' rotated\n", name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&newSecret, "new-secret", "", "New client secret; use @file or - to read it from a file or stdin")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Request tokens with the new secret first and keep the current one if that fails")
+	_ = cmd.MarkFlagRequired("new-secret")
+
+	return cmd
+}
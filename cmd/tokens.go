@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
+	"charm.land/lipgloss/v2"
+	"github.com/spf13/cobra"
+)
+
+// Tokens returns the tokens command
+func Tokens() *cobra.Command {
+	var porcelain bool
+
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Show the state of cached tokens",
+		Long: `Show which providers hold a cached access and refresh token, the token type,
+when the access token expires, and for JWT access tokens the subject, issuer
+and audience claims (decoded without verification). Token values are never
+printed.
+
+Useful to find out why a provider keeps asking to re-authenticate.
+
+--porcelain prints tab-separated name, type, token state (valid, expired,
+refreshable or none) and seconds until expiry (- if unknown), unstyled and
+stable across releases.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if porcelain && jsonOutput {
+				return fmt.Errorf("the --json flag can't be combined with --porcelain")
+			}
+
+			tokens, err := fetchTokens()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if tokens == nil {
+					tokens = []protocol.TokenInfo{}
+				}
+				data, err := json.MarshalIndent(tokens, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal tokens: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+
+			if porcelain {
+				printTokensPorcelain(cmd.OutOrStdout(), tokens)
+				return nil
+			}
+
+			printTokens(cmd.OutOrStdout(), tokens)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&porcelain, "porcelain", false, "Print one 'name<TAB>type<TAB>state<TAB>expires_in' line per provider, a format kept stable across releases for scripts")
+
+	return cmd
+}
+
+// fetchTokens returns the token state of every provider that caches tokens
+func fetchTokens() ([]protocol.TokenInfo, error) {
+	resp, err := client.SendRequest(protocol.Request{Action: "tokens"})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == "error" {
+		return nil, client.NewResponseError(resp)
+	}
+
+	payloadBytes, err := json.Marshal(resp.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var tokensResp protocol.TokensResponsePayload
+	if err := json.Unmarshal(payloadBytes, &tokensResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return tokensResp.Tokens, nil
+}
+
+// Token states printed by --porcelain
+const (
+	tokenStateValid       = "valid"       // Unexpired access token
+	tokenStateRefreshable = "refreshable" // No usable access token, but a refresh token
+	tokenStateExpired     = "expired"     // Expired access token and no refresh token
+	tokenStateNone        = "none"        // No tokens: a login (or first fetch) is needed
+	tokenStateUncached    = "-"           // The provider doesn't cache tokens
+)
+
+// tokenState summarizes a provider's cached tokens, nil for providers that
+// don't cache tokens
+func tokenState(tok *protocol.TokenInfo) string {
+	switch {
+	case tok == nil:
+		return tokenStateUncached
+	case tok.HasAccessToken && tok.ExpiresIn > 0:
+		return tokenStateValid
+	case tok.HasRefreshToken:
+		return tokenStateRefreshable
+	case tok.HasAccessToken:
+		return tokenStateExpired
+	default:
+		return tokenStateNone
+	}
+}
+
+// printTokensPorcelain prints one tab-separated line per provider:
+// name, type, token state and seconds until expiry (- if unknown).
+// The format is a stable interface for scripts: don't change it.
+func printTokensPorcelain(out io.Writer, tokens []protocol.TokenInfo) {
+	for _, tok := range tokens {
+		expiresIn := "-"
+		if tok.HasAccessToken {
+			expiresIn = strconv.Itoa(tok.ExpiresIn)
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", tok.Name, tok.Type, tokenState(&tok), expiresIn)
+	}
+}
+
+// printTokens renders the token states as a table
+func printTokens(out io.Writer, tokens []protocol.TokenInfo) {
+	if len(tokens) == 0 {
+		noTokensStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Italic(true)
+		lipgloss.Fprintln(out, noTokensStyle.Render("No providers cache tokens."))
+		return
+	}
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("212"))
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("86")).
+		Bold(true)
+
+	borderStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+
+	headers := []string{"NAME", "ACCESS", "REFRESH", "EXPIRES", "SUBJECT", "ISSUER", "AUDIENCE"}
+	rows := make([][]string, 0, len(tokens))
+	for _, tok := range tokens {
+		access := "-"
+		if tok.HasAccessToken {
+			access = tok.TokenType
+			if access == "" {
+				access = "yes"
+			}
+		}
+		refresh := "-"
+		if tok.HasRefreshToken {
+			refresh = "yes"
+		}
+		rows = append(rows, []string{
+			tok.Name,
+			access,
+			refresh,
+			formatTokenExpiry(tok),
+			orDash(tok.Subject),
+			orDash(tok.Issuer),
+			orDash(tok.Audience),
+		})
+	}
+
+	// Calculate column widths (with some padding)
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for i := range widths {
+		widths[i] += 2
+	}
+
+	separator := borderStyle.Render("│")
+
+	cells := make([]string, len(headers))
+	for i, h := range headers {
+		cells[i] = headerStyle.Render(fmt.Sprintf("%-*s", widths[i], h))
+	}
+	lipgloss.Fprintln(out, strings.Join(cells, " "+separator+" "))
+
+	for i := range headers {
+		cells[i] = borderStyle.Render(strings.Repeat("─", widths[i]))
+	}
+	lipgloss.Fprintln(out, strings.Join(cells, " "+borderStyle.Render("┼")+" "))
+
+	for _, row := range rows {
+		for i, cell := range row {
+			cell = fmt.Sprintf("%-*s", widths[i], cell)
+			if i == 0 {
+				cell = nameStyle.Render(cell)
+			}
+			cells[i] = cell
+		}
+		lipgloss.Fprintln(out, strings.Join(cells, " "+separator+" "))
+	}
+}
+
+// formatTokenExpiry describes when the access token expires
+func formatTokenExpiry(tok protocol.TokenInfo) string {
+	switch {
+	case !tok.HasAccessToken:
+		return "-"
+	case tok.ExpiresIn <= 0:
+		return "expired"
+	default:
+		return "in " + (time.Duration(tok.ExpiresIn) * time.Second).String()
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
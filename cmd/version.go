@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/release-utils/version"
+)
+
+// versionOutput is the --json form of the version command
+type versionOutput struct {
+	version.Info
+	Protocol string                        `json:"protocol"`
+	Daemon   *protocol.PingResponsePayload `json:"daemon,omitempty"`
+}
+
+// Version returns the version command
+func Version() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print build information for credctl and the running daemon",
+		Long: `Print the credctl version, commit, build date and Go version, along with
+the protocol version it speaks. If a daemon is running, its build and protocol
+versions are shown too, which helps spot a daemon left over from an upgrade.
+
+Builds without release ldflags report the module version, or "devel".`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Never start a daemon just to report its version
+			client.SetAutoStart(false)
+
+			info := version.GetVersionInfo()
+			info.Name = cmd.Root().Name()
+			info.Description = cmd.Root().Short
+
+			out := versionOutput{Info: info, Protocol: protocol.Version}
+			if ping, err := client.Ping(); err == nil {
+				out.Daemon = &ping
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(out, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal version: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Print(info.String())
+			fmt.Printf("Protocol:      %s\n", protocol.Version)
+			switch {
+			case out.Daemon == nil:
+				fmt.Println("Daemon:        not running")
+			case out.Daemon.Build == "":
+				fmt.Printf("Daemon:        unknown build (protocol %s, pid %d)\n", out.Daemon.Version, out.Daemon.PID)
+			default:
+				fmt.Printf("Daemon:        %s (protocol %s, pid %d)\n", out.Daemon.Build, out.Daemon.Version, out.Daemon.PID)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"credctl/internal/client"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
+
+	"github.com/spf13/cobra"
+)
+
+// Whoami returns the whoami command
+func Whoami() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "whoami <name>",
+		Short: "Show the identity a provider is authenticated as",
+		Long: `Fetch a token from an OIDC provider and call the issuer's userinfo endpoint
+to show the identity (subject, email, name and groups) the provider is authenticated as.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if name == "" {
+				return fmt.Errorf("provider name cannot be empty")
+			}
+
+			// Look up the provider's issuer
+			resp, err := client.SendRequest(protocol.Request{
+				Action: "describe",
+				Payload: protocol.DescribePayload{
+					Name:    name,
+					Resolve: true,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err := json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var describeResp protocol.DescribeResponsePayload
+			if err := json.Unmarshal(payloadBytes, &describeResp); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			issuer := provider.GetStringOrDefault(describeResp.Metadata, provider.MetadataIssuer, "")
+			if issuer == "" {
+				return fmt.Errorf("provider '%s' (type: %s) has no issuer configured; whoami requires an OIDC provider", name, describeResp.Type)
+			}
+
+			// Get a valid access token
+			resp, err = client.SendRequest(protocol.Request{
+				Action: "get",
+				Payload: protocol.GetPayload{
+					Name: name,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if resp.Status == "error" {
+				if resp.ErrorType == protocol.ErrorTypeAuthRequired {
+					return &client.ResponseError{
+						Type:    resp.ErrorType,
+						Message: fmt.Sprintf("authentication required for provider '%s'\n\nRun: credctl login %s", name, name),
+					}
+				}
+				return client.NewResponseError(resp)
+			}
+
+			payloadBytes, err = json.Marshal(resp.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			var getRespPayload protocol.GetResponsePayload
+			if err := json.Unmarshal(payloadBytes, &getRespPayload); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			accessToken := getRespPayload.StructuredFields["access_token"]
+			if accessToken == "" {
+				accessToken = getRespPayload.Output
+			}
+
+			// Discover and call the userinfo endpoint, through the provider's proxy if set
+			ctx := cmd.Context()
+			if proxy := provider.GetStringOrDefault(describeResp.Metadata, provider.MetadataHTTPProxy, ""); proxy != "" {
+				client, err := common.NewProxyHTTPClient(proxy)
+				if err != nil {
+					return err
+				}
+				ctx = common.WithHTTPClient(ctx, client)
+			}
+
+			allowMismatch := provider.GetBoolOrDefault(describeResp.Metadata, provider.MetadataAllowIssuerMismatch, false)
+			doc, err := common.Discover(ctx, issuer, allowMismatch)
+			if err != nil {
+				return fmt.Errorf("failed to discover OIDC endpoints: %w", err)
+			}
+
+			if doc.UserinfoEndpoint == "" {
+				return fmt.Errorf("issuer %s does not advertise a userinfo endpoint", issuer)
+			}
+
+			claims, err := common.FetchUserInfo(ctx, doc.UserinfoEndpoint, accessToken)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Subject: %s\n", claims.Subject)
+			if claims.Email != "" {
+				verified := ""
+				if claims.EmailVerified {
+					verified = " (verified)"
+				}
+				fmt.Printf("Email:   %s%s\n", claims.Email, verified)
+			}
+			if claims.Name != "" {
+				fmt.Printf("Name:    %s\n", claims.Name)
+			}
+			if len(claims.Groups) > 0 {
+				fmt.Printf("Groups:  %s\n", strings.Join(claims.Groups, ", "))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"credctl/internal/provider"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// wizard walks through a provider schema and asks for each field
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	// readHidden reads a value without echoing it (for Hidden fields)
+	readHidden func() (string, error)
+}
+
+// newTerminalWizard returns a wizard reading from stdin and prompting on stderr
+func newTerminalWizard() *wizard {
+	return &wizard{
+		in:  bufio.NewReader(os.Stdin),
+		out: os.Stderr,
+		readHidden: func() (string, error) {
+			value, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			return string(value), err
+		},
+	}
+}
+
+// schemaFlagsChanged reports whether any schema flag was given on the command line
+func schemaFlagsChanged(cmd *cobra.Command, schema provider.Schema) bool {
+	for _, field := range schema.Fields {
+		if flag := cmd.Flags().Lookup(field.Name); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// fillFlags prompts for every schema field and sets the answers as flags,
+// so the rest of the command handles them exactly like command-line values
+func (w *wizard) fillFlags(cmd *cobra.Command, schema provider.Schema) error {
+	answers, err := w.run(schema)
+	if err != nil {
+		return err
+	}
+	for _, field := range schema.Fields {
+		value, ok := answers[field.Name]
+		if !ok {
+			continue
+		}
+		if err := cmd.Flags().Set(field.Name, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// run asks for each field in schema order and returns the answers in flag
+// syntax. Optional fields left empty without a default are omitted.
+func (w *wizard) run(schema provider.Schema) (map[string]string, error) {
+	fmt.Fprintln(w.out, "Configure the provider (press Enter to accept the default or skip optional fields)")
+
+	answers := make(map[string]string)
+	for _, field := range schema.Fields {
+		value, err := w.ask(field)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			answers[field.Name] = value
+		}
+	}
+	return answers, nil
+}
+
+// ask prompts for one field until it gets a valid answer
+func (w *wizard) ask(field provider.FieldDef) (string, error) {
+	fmt.Fprintf(w.out, "\n%s\n", fieldDescription(field))
+
+	for {
+		fmt.Fprint(w.out, fieldPrompt(field))
+
+		var value string
+		var err error
+		if field.Hidden && w.readHidden != nil {
+			value, err = w.readHidden()
+		} else {
+			value, err = w.in.ReadString('\n')
+			if err == io.EOF && value != "" {
+				err = nil
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return "", fmt.Errorf("input closed before %s was answered", field.Name)
+			}
+			return "", fmt.Errorf("failed to read %s: %w", field.Name, err)
+		}
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			if field.Required && field.Default == "" {
+				fmt.Fprintf(w.out, "  %s is required\n", field.Name)
+				continue
+			}
+			return field.Default, nil
+		}
+
+		normalized, err := validateAnswer(field, value)
+		if err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		return normalized, nil
+	}
+}
+
+// fieldDescription renders the help line shown above a prompt
+func fieldDescription(field provider.FieldDef) string {
+	desc := field.Help
+	if desc == "" {
+		desc = field.Name
+	}
+	if len(field.ValidValues) > 0 {
+		desc += fmt.Sprintf(" (one of: %s)", strings.Join(field.ValidValues, ", "))
+	}
+	switch field.Type {
+	case provider.FieldTypeStringSlice:
+		desc += " (comma-separated)"
+	case provider.FieldTypeStringMap:
+		desc += " (key=value pairs, comma-separated)"
+	}
+	return desc
+}
+
+// fieldPrompt renders the input prompt, e.g. "client_id (required): " or "flow [device]: "
+func fieldPrompt(field provider.FieldDef) string {
+	prompt := field.Name
+	if field.Required {
+		prompt += " (required)"
+	}
+	if field.Default != "" && !field.Hidden {
+		prompt += " [" + field.Default + "]"
+	}
+	return prompt + ": "
+}
+
+// validateAnswer checks an answer against the field type and valid values,
+// returning it in the syntax the corresponding flag accepts
+func validateAnswer(field provider.FieldDef, value string) (string, error) {
+	if len(field.ValidValues) > 0 {
+		valid := false
+		for _, v := range field.ValidValues {
+			if value == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("must be one of: %s", strings.Join(field.ValidValues, ", "))
+		}
+	}
+
+	switch field.Type {
+	case provider.FieldTypeBool:
+		switch strings.ToLower(value) {
+		case "y", "yes", "true":
+			return "true", nil
+		case "n", "no", "false":
+			return "false", nil
+		}
+		return "", fmt.Errorf("must be yes or no")
+
+	case provider.FieldTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("must be a whole number")
+		}
+
+	case provider.FieldTypeStringMap:
+		for _, pair := range strings.Split(value, ",") {
+			if k, _, ok := strings.Cut(pair, "="); !ok || strings.TrimSpace(k) == "" {
+				return "", fmt.Errorf("'%s' is not a key=value pair", pair)
+			}
+		}
+	}
+
+	return value, nil
+}
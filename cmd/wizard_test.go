@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"credctl/internal/provider"
+
+	"github.com/spf13/cobra"
+)
+
+func testWizard(input string, hidden ...string) *wizard {
+	return &wizard{
+		in:  bufio.NewReader(strings.NewReader(input)),
+		out: io.Discard,
+		readHidden: func() (string, error) {
+			if len(hidden) == 0 {
+				return "", io.EOF
+			}
+			value := hidden[0]
+			hidden = hidden[1:]
+			return value, nil
+		},
+	}
+}
+
+var wizardSchema = provider.Schema{
+	Fields: []provider.FieldDef{
+		{Name: "url", Type: provider.FieldTypeString, Required: true, Help: "Service URL"},
+		{Name: "secret", Type: provider.FieldTypeString, Hidden: true, Help: "API secret"},
+		{Name: "method", Type: provider.FieldTypeString, Default: "GET", ValidValues: []string{"GET", "POST"}},
+		{Name: "retries", Type: provider.FieldTypeInt},
+		{Name: "verbose", Type: provider.FieldTypeBool},
+		{Name: "scopes", Type: provider.FieldTypeStringSlice},
+		{Name: "headers", Type: provider.FieldTypeStringMap},
+	},
+}
+
+func TestWizardRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		hidden      []string
+		want        map[string]string
+		shouldError bool
+	}{
+		{
+			name:   "all fields answered",
+			input:  "https://example.com\nPOST\n3\ny\na,b\nX-Env=prod\n",
+			hidden: []string{"s3cr3t"},
+			want: map[string]string{
+				"url":     "https://example.com",
+				"secret":  "s3cr3t",
+				"method":  "POST",
+				"retries": "3",
+				"verbose": "true",
+				"scopes":  "a,b",
+				"headers": "X-Env=prod",
+			},
+		},
+		{
+			name:   "defaults and skipped fields",
+			input:  "https://example.com\n\n\n\n\n\n",
+			hidden: []string{""},
+			want: map[string]string{
+				"url":    "https://example.com",
+				"method": "GET",
+			},
+		},
+		{
+			name:   "invalid answers are asked again",
+			input:  "\nhttps://example.com\nPUT\nPOST\nmany\n2\nmaybe\nno\n\nnot-a-pair\nk=v\n",
+			hidden: []string{""},
+			want: map[string]string{
+				"url":     "https://example.com",
+				"method":  "POST",
+				"retries": "2",
+				"verbose": "false",
+				"headers": "k=v",
+			},
+		},
+		{
+			name:        "input closed before required field",
+			input:       "",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testWizard(tt.input, tt.hidden...).run(wizardSchema)
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("run() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("answer %s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestWizardFillFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	if err := provider.AddSchemaFlags(cmd, wizardSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	if schemaFlagsChanged(cmd, wizardSchema) {
+		t.Fatal("expected no schema flags to be changed")
+	}
+
+	w := testWizard("https://example.com\n\n5\n\nread,write\n\n", "s3cr3t")
+	if err := w.fillFlags(cmd, wizardSchema); err != nil {
+		t.Fatalf("fillFlags() error: %v", err)
+	}
+
+	config, err := provider.ExtractConfig(cmd, wizardSchema)
+	if err != nil {
+		t.Fatalf("ExtractConfig() error: %v", err)
+	}
+
+	if config["url"] != "https://example.com" || config["secret"] != "s3cr3t" || config["method"] != "GET" {
+		t.Errorf("unexpected string values: %v", config)
+	}
+	if config["retries"] != 5 {
+		t.Errorf("retries = %v, want 5", config["retries"])
+	}
+	if scopes, ok := config["scopes"].([]string); !ok || len(scopes) != 2 || scopes[1] != "write" {
+		t.Errorf("scopes = %v, want [read write]", config["scopes"])
+	}
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		t.Errorf("ValidateRequiredFlags() error: %v", err)
+	}
+}
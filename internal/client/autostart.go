@@ -0,0 +1,102 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"credctl/internal/daemon"
+	"credctl/internal/paths"
+)
+
+// AutoStartEnvVar enables starting the daemon on demand when set to "1"
+const AutoStartEnvVar = "CREDCTL_AUTOSTART"
+
+// autoStartTimeout is how long to wait for a freshly started daemon's socket
+const autoStartTimeout = 5 * time.Second
+
+var autoStart = os.Getenv(AutoStartEnvVar) == "1"
+
+// SetAutoStart enables or disables starting the daemon when no socket is reachable
+func SetAutoStart(enabled bool) {
+	autoStart = enabled
+}
+
+// canAutoStart reports whether a failed connection should start a local daemon
+// An explicit CREDCTL_SOCK (e.g. a forwarded socket) is never auto-started
+func canAutoStart() bool {
+	return autoStart && os.Getenv("CREDCTL_SOCK") == ""
+}
+
+// startDaemon starts the daemon and waits for its admin socket to accept connections
+// A lock file ensures concurrent clients start at most one daemon
+func startDaemon() error {
+	dir, err := paths.Home()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lockPath, err := paths.AutoStartLock()
+	if err != nil {
+		return err
+	}
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open auto-start lock: %w", err)
+	}
+	defer func() { _ = lockFile.Close() }()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to acquire auto-start lock: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN) }()
+
+	adminSocketPath, err := paths.AdminSocket()
+	if err != nil {
+		return err
+	}
+
+	// Another client may have started the daemon while we waited for the lock
+	if socketReady(adminSocketPath) {
+		return nil
+	}
+
+	info, err := daemon.Run()
+	if err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	deadline := time.Now().Add(autoStartTimeout)
+	for time.Now().Before(deadline) {
+		if socketReady(adminSocketPath) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return fmt.Errorf("daemon did not start within %s%s", autoStartTimeout, logTail(info.LogFile))
+}
+
+// socketReady reports whether a daemon is answering on socketPath
+func socketReady(socketPath string) bool {
+	return probeSocket(socketPath) == nil
+}
+
+// logTail returns the last lines of the daemon log formatted for an error message
+func logTail(logFile string) string {
+	data, err := os.ReadFile(logFile)
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+	return fmt.Sprintf("\n\nDaemon log (%s):\n%s", logFile, strings.Join(lines, "\n"))
+}
@@ -3,72 +3,215 @@ package client
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"credctl/internal/paths"
 	"credctl/internal/protocol"
 )
 
+// ErrVersionMismatch is returned when the daemon speaks an incompatible protocol version
+var ErrVersionMismatch = errors.New("protocol version mismatch")
+
+// ErrDaemonNotResponding is returned when socket files exist but no daemon answers on them
+var ErrDaemonNotResponding = errors.New("daemon not responding")
+
+// ErrDaemonTimeout is returned when the daemon accepts a request but doesn't answer in time
+var ErrDaemonTimeout = errors.New("daemon did not respond in time")
+
+// ResponseError is an error response from the daemon. It keeps the protocol
+// error type so callers and --json output can branch on it.
+type ResponseError struct {
+	Type    string // One of the protocol.ErrorType* values
+	Message string // User-facing message
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// NewResponseError returns the error carried by resp
+func NewResponseError(resp protocol.Response) *ResponseError {
+	errorType := resp.ErrorType
+	if errorType == "" {
+		errorType = protocol.ErrorTypeGeneric
+	}
+	return &ResponseError{Type: errorType, Message: "error: " + resp.Error}
+}
+
+// TimeoutEnvVar overrides how long a request may wait for the daemon's response,
+// as a duration ("90s") or in seconds; 0 waits indefinitely
+const TimeoutEnvVar = "CREDCTL_TIMEOUT"
+
+// DefaultTimeout bounds a request. It exceeds the daemon's longest provider
+// timeout (a command and its transform, 60 seconds each).
+const DefaultTimeout = 125 * time.Second
+
+// dialTimeout bounds connecting to the daemon socket
+const dialTimeout = 5 * time.Second
+
+// probeTimeout bounds the liveness check of a socket
+const probeTimeout = 2 * time.Second
+
+// requestTimeout returns the request timeout from CREDCTL_TIMEOUT, or DefaultTimeout
+func requestTimeout() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(TimeoutEnvVar))
+	if value == "" {
+		return DefaultTimeout, nil
+	}
+
+	// A bare number is seconds
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s '%s': must be a duration (e.g. 90s) or a number of seconds", TimeoutEnvVar, os.Getenv(TimeoutEnvVar))
+	}
+	return timeout, nil
+}
+
 // ResolveSocketPath returns the Unix socket path
 // Priority order:
 // 1. CREDCTL_SOCK env var (if set)
-// 2. Check if admin socket exists (~/.credctl/agent.sock) - assumes write access
-// 3. Check if read-only socket exists (~/.credctl/agent-readonly.sock)
-// 4. Error if no socket found
+// 2. Admin socket ($CREDCTL_ADMIN_SOCK or $CREDCTL_HOME/agent.sock) if a daemon answers on it - assumes write access
+// 3. Read-only socket ($CREDCTL_READONLY_SOCK or $CREDCTL_HOME/agent-readonly.sock) if a daemon answers on it
+// 4. Error if no socket found or no daemon answers
+// Sockets left behind by a crashed daemon (connection refused) are removed
 func ResolveSocketPath() (string, error) {
 	// Check env var first
 	if sockPath := os.Getenv("CREDCTL_SOCK"); sockPath != "" {
 		return sockPath, nil
 	}
 
-	// Get home directory for default paths
-	homeDir, err := os.UserHomeDir()
+	adminSocketPath, err := paths.AdminSocket()
+	if err != nil {
+		return "", err
+	}
+	readOnlySocketPath, err := paths.ReadOnlySocket()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
 
-	// Check if admin socket exists (assumes write access)
-	adminSocketPath := filepath.Join(homeDir, ".credctl", "agent.sock")
-	if _, err := os.Stat(adminSocketPath); err == nil {
-		return adminSocketPath, nil
+	var stale []string
+	for _, socketPath := range []string{adminSocketPath, readOnlySocketPath} {
+		if _, err := os.Stat(socketPath); err != nil {
+			continue
+		}
+
+		err := probeSocket(socketPath)
+		if err == nil {
+			return socketPath, nil
+		}
+
+		// Nothing listens on the socket any more: the daemon died without cleaning up
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			_ = os.Remove(socketPath)
+		}
+		stale = append(stale, socketPath)
 	}
 
-	// Check if read-only socket exists
-	readOnlySocketPath := filepath.Join(homeDir, ".credctl", "agent-readonly.sock")
-	if _, err := os.Stat(readOnlySocketPath); err == nil {
-		return readOnlySocketPath, nil
+	if len(stale) > 0 {
+		return "", fmt.Errorf("%w: stale socket %s (restart it with 'credctl daemon')", ErrDaemonNotResponding, strings.Join(stale, ", "))
 	}
 
 	// No socket found
 	return "", fmt.Errorf("no credctl socket found (is the daemon running?)")
 }
 
-func SendRequest(req protocol.Request) (protocol.Response, error) {
+// probeSocket pings the daemon on socketPath and waits for any reply
+// Daemons that reject the ping (older or incompatible versions) still count as alive
+func probeSocket(socketPath string) error {
+	conn, err := net.DialTimeout("unix", socketPath, probeTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.SetDeadline(time.Now().Add(probeTimeout)); err != nil {
+		return err
+	}
+
+	reqJSON, err := json.Marshal(protocol.Request{Action: "ping", Version: protocol.Version})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append(reqJSON, '\n')); err != nil {
+		return err
+	}
+
+	if _, err := bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dial connects to the daemon socket
+func dial() (net.Conn, error) {
 	socketPath, err := ResolveSocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w (is the daemon running?)", err)
+	}
+	return conn, nil
+}
+
+// SendRequest sends a request to the daemon and returns its response
+// The request is stamped with the client's protocol version
+func SendRequest(req protocol.Request) (protocol.Response, error) {
+	req.Version = protocol.Version
+
+	timeout, err := requestTimeout()
 	if err != nil {
 		return protocol.Response{}, err
 	}
 
-	conn, err := net.Dial("unix", socketPath)
+	conn, err := dial()
+	if err != nil && canAutoStart() {
+		if startErr := startDaemon(); startErr != nil {
+			return protocol.Response{}, startErr
+		}
+		conn, err = dial()
+	}
 	if err != nil {
-		return protocol.Response{}, fmt.Errorf("failed to connect to daemon: %w (is the daemon running?)", err)
+		return protocol.Response{}, err
 	}
 	defer func() { _ = conn.Close() }()
 
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return protocol.Response{}, fmt.Errorf("failed to set request deadline: %w", err)
+		}
+	}
+
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
 		return protocol.Response{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	if _, err := conn.Write(append(reqJSON, '\n')); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return protocol.Response{}, timeoutError(timeout)
+		}
 		return protocol.Response{}, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	scanner := bufio.NewScanner(conn)
 	if !scanner.Scan() {
 		if err := scanner.Err(); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return protocol.Response{}, timeoutError(timeout)
+			}
 			return protocol.Response{}, fmt.Errorf("failed to read response: %w", err)
 		}
 		return protocol.Response{}, fmt.Errorf("no response received")
@@ -79,5 +222,45 @@ func SendRequest(req protocol.Request) (protocol.Response, error) {
 		return protocol.Response{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	if resp.ErrorType == protocol.ErrorTypeVersionMismatch {
+		return protocol.Response{}, fmt.Errorf("%w: %s", ErrVersionMismatch, resp.Error)
+	}
+	if resp.Version != "" {
+		if err := protocol.CheckVersion(resp.Version, protocol.Version); err != nil {
+			return protocol.Response{}, fmt.Errorf("%w: %v", ErrVersionMismatch, err)
+		}
+	}
+
 	return resp, nil
 }
+
+// timeoutError reports a request that outlived timeout
+func timeoutError(timeout time.Duration) error {
+	return fmt.Errorf("%w (%s): check the daemon log, or raise %s", ErrDaemonTimeout, timeout, TimeoutEnvVar)
+}
+
+// Ping asks the daemon for its protocol version. It can be used to detect
+// a client/daemon version drift before sending real requests.
+func Ping() (protocol.PingResponsePayload, error) {
+	resp, err := SendRequest(protocol.Request{Action: "ping"})
+	if err != nil {
+		return protocol.PingResponsePayload{}, err
+	}
+
+	if resp.Status == "error" {
+		// Daemons older than the ping action don't know about it
+		return protocol.PingResponsePayload{}, fmt.Errorf("%w: daemon version unknown (pre-%s), client version %s — restart the daemon", ErrVersionMismatch, protocol.Version, protocol.Version)
+	}
+
+	payloadBytes, err := json.Marshal(resp.Payload)
+	if err != nil {
+		return protocol.PingResponsePayload{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	var pingResp protocol.PingResponsePayload
+	if err := json.Unmarshal(payloadBytes, &pingResp); err != nil {
+		return protocol.PingResponsePayload{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return pingResp, nil
+}
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"credctl/internal/paths"
+	"credctl/internal/protocol"
+)
+
+// setupHome points CREDCTL_HOME at a short temporary directory (sun_path is limited to ~100 bytes)
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "credctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	t.Setenv(paths.HomeEnvVar, dir)
+	t.Setenv("CREDCTL_SOCK", "")
+	t.Setenv(paths.AdminSocketEnvVar, "")
+	t.Setenv(paths.ReadOnlySocketEnvVar, "")
+	t.Setenv(TimeoutEnvVar, "")
+	SetAutoStart(false)
+	return dir
+}
+
+// deadSocket leaves a socket file with nothing listening on it, like a crashed daemon
+func deadSocket(t *testing.T, path string) {
+	t.Helper()
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+}
+
+// liveSocket serves ping replies on path until the test ends
+func liveSocket(t *testing.T, path string) {
+	t.Helper()
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				if _, err := bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+					return
+				}
+				respJSON, _ := json.Marshal(protocol.Response{
+					Status:  "ok",
+					Version: protocol.Version,
+					Payload: protocol.PingResponsePayload{Version: protocol.Version},
+				})
+				_, _ = conn.Write(append(respJSON, '\n'))
+			}()
+		}
+	}()
+}
+
+// silentSocket accepts connections on path but never replies, like a hung daemon
+func silentSocket(t *testing.T, path string) {
+	t.Helper()
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		_ = l.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+}
+
+func TestResolveSocketPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		admin     string // "", "dead" or "live"
+		readOnly  string
+		want      string // socket file name
+		wantErr   error
+		errSubstr string
+	}{
+		{name: "live admin socket", admin: "live", readOnly: "live", want: "agent.sock"},
+		{name: "dead admin falls back to read-only", admin: "dead", readOnly: "live", want: "agent-readonly.sock"},
+		{name: "only read-only socket", readOnly: "live", want: "agent-readonly.sock"},
+		{name: "dead admin socket", admin: "dead", wantErr: ErrDaemonNotResponding},
+		{name: "both sockets dead", admin: "dead", readOnly: "dead", wantErr: ErrDaemonNotResponding},
+		{name: "no socket", errSubstr: "no credctl socket found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupHome(t)
+			sockets := map[string]string{"agent.sock": tt.admin, "agent-readonly.sock": tt.readOnly}
+			for name, state := range sockets {
+				switch state {
+				case "dead":
+					deadSocket(t, filepath.Join(dir, name))
+				case "live":
+					liveSocket(t, filepath.Join(dir, name))
+				}
+			}
+
+			got, err := ResolveSocketPath()
+			if tt.wantErr != nil || tt.errSubstr != "" {
+				if err == nil {
+					t.Fatalf("expected error, got %s", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				if !strings.Contains(err.Error(), tt.errSubstr) {
+					t.Errorf("expected error containing %q, got %v", tt.errSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if got != filepath.Join(dir, tt.want) {
+				t.Errorf("ResolveSocketPath() = %s, want %s", got, filepath.Join(dir, tt.want))
+			}
+
+			// Dead sockets are cleaned up so the next command reports "no socket"
+			for name, state := range sockets {
+				if _, err := os.Stat(filepath.Join(dir, name)); state == "dead" && err == nil {
+					t.Errorf("expected stale socket %s to be removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveSocketPathFromEnv(t *testing.T) {
+	setupHome(t)
+
+	runtimeDir := t.TempDir()
+	adminSocket := filepath.Join(runtimeDir, "admin.sock")
+	readOnlySocket := filepath.Join(runtimeDir, "readonly.sock")
+	t.Setenv(paths.AdminSocketEnvVar, adminSocket)
+	t.Setenv(paths.ReadOnlySocketEnvVar, readOnlySocket)
+
+	liveSocket(t, readOnlySocket)
+	got, err := ResolveSocketPath()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != readOnlySocket {
+		t.Errorf("ResolveSocketPath() = %s, want %s", got, readOnlySocket)
+	}
+
+	liveSocket(t, adminSocket)
+	got, err = ResolveSocketPath()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != adminSocket {
+		t.Errorf("ResolveSocketPath() = %s, want %s", got, adminSocket)
+	}
+}
+
+func TestSendRequestStaleSocket(t *testing.T) {
+	dir := setupHome(t)
+	deadSocket(t, filepath.Join(dir, "agent.sock"))
+
+	_, err := SendRequest(protocol.Request{Action: "list"})
+	if !errors.Is(err, ErrDaemonNotResponding) {
+		t.Fatalf("expected ErrDaemonNotResponding, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "stale socket") {
+		t.Errorf("expected the stale socket to be named, got %v", err)
+	}
+}
+
+func TestPingLiveSocket(t *testing.T) {
+	dir := setupHome(t)
+	liveSocket(t, filepath.Join(dir, "agent.sock"))
+
+	resp, err := Ping()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Version != protocol.Version {
+		t.Errorf("expected version %s, got %s", protocol.Version, resp.Version)
+	}
+}
+
+func TestSendRequestTimeout(t *testing.T) {
+	dir := setupHome(t)
+	sock := filepath.Join(dir, "hung.sock")
+	silentSocket(t, sock)
+	t.Setenv("CREDCTL_SOCK", sock)
+	t.Setenv(TimeoutEnvVar, "200ms")
+
+	start := time.Now()
+	_, err := SendRequest(protocol.Request{Action: "list"})
+	if !errors.Is(err, ErrDaemonTimeout) {
+		t.Fatalf("expected ErrDaemonTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %s, expected it to give up after 200ms", elapsed)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Duration
+		shouldError bool
+	}{
+		{name: "unset uses default", value: "", expected: DefaultTimeout},
+		{name: "bare number is seconds", value: "90", expected: 90 * time.Second},
+		{name: "duration", value: "2m", expected: 2 * time.Minute},
+		{name: "zero disables the deadline", value: "0", expected: 0},
+		{name: "negative", value: "-1", shouldError: true},
+		{name: "not a duration", value: "soon", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TimeoutEnvVar, tt.value)
+
+			timeout, err := requestTimeout()
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %s", timeout)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if timeout != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// EnvVar names a command that copies its stdin to the clipboard, used instead of the detected tool
+const EnvVar = "CREDCTL_CLIPBOARD"
+
+// ErrNoClipboard is returned when no clipboard tool is available
+var ErrNoClipboard = errors.New("no clipboard tool found")
+
+// Tool is a command line clipboard utility
+type Tool struct {
+	Copy  []string // Command copying its stdin to the clipboard
+	Paste []string // Command printing the clipboard, nil if unknown
+}
+
+// Name returns the tool's executable
+func (t *Tool) Name() string {
+	return t.Copy[0]
+}
+
+// candidate is a tool usable when its executable is installed and env (if set) is non-empty
+type candidate struct {
+	tool Tool
+	env  string
+}
+
+// candidates returns the clipboard tools for the platform, in order of preference
+func candidates() []candidate {
+	switch runtime.GOOS {
+	case "darwin":
+		return []candidate{
+			{tool: Tool{Copy: []string{"pbcopy"}, Paste: []string{"pbpaste"}}},
+		}
+	case "windows":
+		return []candidate{
+			{tool: Tool{Copy: []string{"clip.exe"}}},
+		}
+	default:
+		return []candidate{
+			{tool: Tool{Copy: []string{"wl-copy"}, Paste: []string{"wl-paste", "--no-newline"}}, env: "WAYLAND_DISPLAY"},
+			{tool: Tool{Copy: []string{"xclip", "-selection", "clipboard"}, Paste: []string{"xclip", "-selection", "clipboard", "-o"}}, env: "DISPLAY"},
+			{tool: Tool{Copy: []string{"xsel", "--clipboard", "--input"}, Paste: []string{"xsel", "--clipboard", "--output"}}, env: "DISPLAY"},
+			// WSL: the Windows clipboard
+			{tool: Tool{Copy: []string{"clip.exe"}}},
+		}
+	}
+}
+
+// Detect returns the clipboard tool to use: $CREDCTL_CLIPBOARD if set,
+// otherwise the first installed tool for the platform and session
+func Detect() (*Tool, error) {
+	if command := strings.Fields(os.Getenv(EnvVar)); len(command) > 0 {
+		return &Tool{Copy: command}, nil
+	}
+
+	var tried []string
+	for _, c := range candidates() {
+		tried = append(tried, c.tool.Name())
+		if c.env != "" && os.Getenv(c.env) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(c.tool.Name()); err == nil {
+			tool := c.tool
+			return &tool, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (tried %s): install one, or set %s to a command that reads the value on stdin", ErrNoClipboard, strings.Join(tried, ", "), EnvVar)
+}
+
+// Write replaces the clipboard content with value
+func (t *Tool) Write(value string) error {
+	cmd := exec.Command(t.Copy[0], t.Copy[1:]...)
+	cmd.Stdin = strings.NewReader(value)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to run %s: %w: %s", t.Name(), err, msg)
+		}
+		return fmt.Errorf("failed to run %s: %w", t.Name(), err)
+	}
+	return nil
+}
+
+// Read returns the clipboard content, and false if the tool can't read it
+func (t *Tool) Read() (string, bool) {
+	if len(t.Paste) == 0 {
+		return "", false
+	}
+	output, err := exec.Command(t.Paste[0], t.Paste[1:]...).Output()
+	if err != nil {
+		return "", false
+	}
+	return string(output), true
+}
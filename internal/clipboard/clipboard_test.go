@@ -0,0 +1,77 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "override",
+			env:      "my-clip --quiet",
+			expected: "my-clip",
+		},
+		{
+			name:        "no tool installed",
+			env:         "",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVar, tt.env)
+			t.Setenv("PATH", t.TempDir())
+
+			tool, err := Detect()
+			if tt.shouldError {
+				if !errors.Is(err, ErrNoClipboard) {
+					t.Errorf("expected ErrNoClipboard, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tool.Name() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, tool.Name())
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clipboard")
+	tool := &Tool{Copy: []string{"tee", path}}
+
+	if err := tool.Write("s3cret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "s3cret" {
+		t.Errorf("expected the value on the tool's stdin, got %q", got)
+	}
+
+	if _, ok := tool.Read(); ok {
+		t.Error("expected Read to fail without a paste command")
+	}
+}
+
+func TestWriteFailure(t *testing.T) {
+	tool := &Tool{Copy: []string{"false"}}
+	if err := tool.Write("s3cret"); err == nil {
+		t.Error("expected error from a failing tool")
+	}
+}
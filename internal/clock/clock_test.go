@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetFake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	restore := Set(fake)
+	if got := Now(); !got.Equal(start) {
+		t.Errorf("Now() = %v, want %v", got, start)
+	}
+
+	fake.Advance(90 * time.Second)
+	if got := Since(start); got != 90*time.Second {
+		t.Errorf("Since() = %v, want 90s", got)
+	}
+	if got := Until(start.Add(time.Hour)); got != time.Hour-90*time.Second {
+		t.Errorf("Until() = %v, want %v", got, time.Hour-90*time.Second)
+	}
+
+	restore()
+	if got := Now(); got.Sub(time.Now()).Abs() > time.Minute {
+		t.Errorf("Now() after restore = %v, want the wall clock", got)
+	}
+}
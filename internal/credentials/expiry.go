@@ -0,0 +1,82 @@
+package credentials
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields holding the normalized expiry of structured credentials
+const (
+	FieldExpiresAt = "expires_at" // RFC3339 timestamp
+	FieldExpiresIn = "expires_in" // Seconds remaining (0 if expired)
+	FieldExp       = "exp"        // Unix timestamp, as in JWT claims
+)
+
+// Expiry returns when the credentials expire, from whichever of the
+// expires_at, exp and expires_in fields is present and valid (in that order),
+// and false if none is. expires_at may be RFC3339 or a unix timestamp, and
+// expires_in is taken relative to now.
+func (c *Credentials) Expiry(now time.Time) (time.Time, bool) {
+	if value := c.Get(FieldExpiresAt); value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		if t, ok := parseUnixTime(value); ok {
+			return t, true
+		}
+	}
+
+	if t, ok := parseUnixTime(c.Get(FieldExp)); ok {
+		return t, true
+	}
+
+	if seconds, ok := parseNumber(c.Get(FieldExpiresIn)); ok && seconds >= 0 {
+		return now.Add(time.Duration(seconds * float64(time.Second))).Truncate(time.Second), true
+	}
+
+	return time.Time{}, false
+}
+
+// NormalizeExpiry sets consistent expires_at (RFC3339) and expires_in
+// (seconds remaining) fields from the expiry found by Expiry, so every
+// structured provider exposes its expiry the same way. Credentials without
+// a recognizable expiry are left unchanged.
+func (c *Credentials) NormalizeExpiry(now time.Time) {
+	expiresAt, ok := c.Expiry(now)
+	if !ok {
+		return
+	}
+
+	remaining := int(expiresAt.Sub(now).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	c.Set(FieldExpiresAt, expiresAt.Format(time.RFC3339))
+	c.Set(FieldExpiresIn, strconv.Itoa(remaining))
+}
+
+// parseUnixTime parses a unix timestamp in seconds
+func parseUnixTime(value string) (time.Time, bool) {
+	seconds, ok := parseNumber(value)
+	if !ok || seconds <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// parseNumber parses a decimal number, including the exponent form JSON
+// numbers may be rendered in (e.g. 1.764978527e+09)
+func parseNumber(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
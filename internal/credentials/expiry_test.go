@@ -0,0 +1,91 @@
+package credentials
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeExpiry(t *testing.T) {
+	now := time.Unix(1764970000, 0)
+	expiry := time.Unix(1764973600, 0) // now + 1h
+
+	tests := []struct {
+		name          string
+		fields        map[string]string
+		wantExpiresAt string
+		wantExpiresIn string
+	}{
+		{
+			name:          "RFC3339 expires_at",
+			fields:        map[string]string{"token": "abc", "expires_at": expiry.UTC().Format(time.RFC3339)},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "unix expires_at",
+			fields:        map[string]string{"expires_at": "1764973600"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "exp claim",
+			fields:        map[string]string{"exp": "1764973600"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "exp in exponent form",
+			fields:        map[string]string{"exp": "1.7649736e+09"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "expires_in",
+			fields:        map[string]string{"expires_in": "3600"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "expires_at wins over expires_in",
+			fields:        map[string]string{"expires_at": "1764973600", "expires_in": "60"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "invalid expires_at falls back to exp",
+			fields:        map[string]string{"expires_at": "tomorrow", "exp": "1764973600"},
+			wantExpiresAt: expiry.Format(time.RFC3339),
+			wantExpiresIn: "3600",
+		},
+		{
+			name:          "expired",
+			fields:        map[string]string{"exp": "1764960000"},
+			wantExpiresAt: time.Unix(1764960000, 0).Format(time.RFC3339),
+			wantExpiresIn: "0",
+		},
+		{
+			name:   "no expiry",
+			fields: map[string]string{"token": "abc"},
+		},
+		{
+			name:          "unparseable fields are left alone",
+			fields:        map[string]string{"expires_at": "tomorrow", "expires_in": "soon"},
+			wantExpiresAt: "tomorrow",
+			wantExpiresIn: "soon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds := New(tt.fields)
+			creds.NormalizeExpiry(now)
+
+			if got := creds.Get(FieldExpiresAt); got != tt.wantExpiresAt {
+				t.Errorf("expires_at = %q, want %q", got, tt.wantExpiresAt)
+			}
+			if got := creds.Get(FieldExpiresIn); got != tt.wantExpiresIn {
+				t.Errorf("expires_in = %q, want %q", got, tt.wantExpiresIn)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package credentials
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/go-jose/go-jose/v4/jwt"
@@ -19,6 +21,14 @@ var standardClaims = []string{
 	"jti", // JWT ID
 }
 
+// Identity claims of an ID token exposed as top-level credential fields
+var identityClaims = []string{
+	"sub",    // Subject
+	"email",  // Email address
+	"name",   // Full name
+	"groups", // Group memberships
+}
+
 // EnrichWithJWTClaims inspects all credential fields and if any value
 // looks like a JWT token, it parses the payload and adds the standard
 // claims as additional fields with a suffix.
@@ -66,6 +76,74 @@ func (c *Credentials) EnrichWithJWTClaims() {
 	}
 }
 
+// StandardJWTClaims decodes token as a JWT and returns its standard claims
+// (sub, iss, aud, exp, ...) formatted as strings, and false if token is not a JWT.
+// The signature is NOT verified; use it for display only.
+func StandardJWTClaims(token string) (map[string]string, bool) {
+	claims, ok := parseJWTClaims(token)
+	if !ok {
+		return nil, false
+	}
+
+	result := make(map[string]string)
+	for _, claim := range standardClaims {
+		if claimValue, exists := claims[claim]; exists {
+			result[claim] = formatClaimValue(claimValue)
+		}
+	}
+	return result, true
+}
+
+// IdentityClaims decodes idToken and returns its identity claims (sub, email,
+// name, groups) as strings, and false if idToken is not a JWT. Groups are
+// joined with commas. The signature is NOT verified; callers expose only
+// tokens they obtained from the issuer.
+func IdentityClaims(idToken string) (map[string]string, bool) {
+	claims, ok := parseJWTClaims(idToken)
+	if !ok {
+		return nil, false
+	}
+
+	result := make(map[string]string)
+	for _, claim := range identityClaims {
+		claimValue, exists := claims[claim]
+		if !exists {
+			continue
+		}
+		if list, isList := claimValue.([]any); isList {
+			values := make([]string, 0, len(list))
+			for _, item := range list {
+				values = append(values, formatClaimValue(item))
+			}
+			result[claim] = strings.Join(values, ",")
+			continue
+		}
+		result[claim] = formatClaimValue(claimValue)
+	}
+	return result, true
+}
+
+// JWTClaims decodes token as a JWT and returns all of its claims, and false
+// if token is not a JWT. The signature is NOT verified.
+func JWTClaims(token string) (map[string]any, bool) {
+	return parseJWTClaims(token)
+}
+
+// JWTExpiry decodes token as a JWT and returns its exp claim, and false if
+// token is not a JWT or has no numeric exp. The signature is NOT verified.
+func JWTExpiry(token string) (time.Time, bool) {
+	claims, ok := parseJWTClaims(token)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || exp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(exp), 0), true
+}
+
 // parseJWTClaims attempts to parse a string as a JWT and extract its claims.
 // Returns the claims map and true if successful, nil and false otherwise.
 // Note: This does NOT verify the JWT signature, only decodes the payload.
@@ -267,3 +267,29 @@ func TestApplyTemplateWithJWT(t *testing.T) {
 	}
 }
 
+
+func TestJWTExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		want   int64
+		wantOK bool
+	}{
+		{name: "exp claim", token: createTestJWT(map[string]any{"sub": "user", "exp": 1764978527}), want: 1764978527, wantOK: true},
+		{name: "no exp claim", token: createTestJWT(map[string]any{"sub": "user"})},
+		{name: "non-numeric exp", token: createTestJWT(map[string]any{"exp": "soon"})},
+		{name: "opaque token", token: "gho_abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := JWTExpiry(tt.token)
+			if ok != tt.wantOK {
+				t.Fatalf("JWTExpiry() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Unix() != tt.want {
+				t.Errorf("JWTExpiry() = %d, want %d", got.Unix(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package credentials
+
+import "strings"
+
+// ApplyClaimsMapping adds a field for each entry of mapping (new name to
+// source), so templates can use stable names whichever identity provider
+// issued the token. A source is either a field, including the extracted JWT
+// claims such as token_sub, or <field>_<claim> for any other claim of a JWT
+// field (e.g. token_custom_org). Sources that can't be found are skipped, and
+// the original fields are kept.
+//
+// Example:
+//
+//	mapping: {"user": "token_sub", "org": "token_custom_org"}
+//	template: "{{.user}}@{{.org}}"
+func (c *Credentials) ApplyClaimsMapping(mapping map[string]string) {
+	if c.Fields == nil || len(mapping) == 0 {
+		return
+	}
+
+	c.EnrichWithJWTClaims()
+
+	// Resolve every source before writing, so mappings don't chain
+	mapped := make(map[string]string, len(mapping))
+	for name, source := range mapping {
+		if value, ok := c.Fields[source]; ok {
+			mapped[name] = value
+		} else if value, ok := c.customClaim(source); ok {
+			mapped[name] = value
+		}
+	}
+	for name, value := range mapped {
+		c.Fields[name] = value
+	}
+}
+
+// customClaim resolves <field>_<claim> to a claim of the JWT in field
+func (c *Credentials) customClaim(source string) (string, bool) {
+	for key, value := range c.Fields {
+		claim, ok := strings.CutPrefix(source, key+"_")
+		if !ok || claim == "" {
+			continue
+		}
+		claims, ok := parseJWTClaims(value)
+		if !ok {
+			continue
+		}
+		if claimValue, exists := claims[claim]; exists {
+			return formatClaimValue(claimValue), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"testing"
+)
+
+func TestApplyClaimsMapping(t *testing.T) {
+	jwt := createTestJWT(map[string]any{
+		"sub":        "user-123",
+		"custom_org": "acme",
+		"exp":        1764978527,
+	})
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		mapping  map[string]string
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			name:     "standard claim",
+			fields:   map[string]string{"token": jwt},
+			mapping:  map[string]string{"user": "token_sub"},
+			expected: map[string]string{"user": "user-123", "token": jwt, "token_sub": "user-123"},
+		},
+		{
+			name:     "custom claim",
+			fields:   map[string]string{"token": jwt},
+			mapping:  map[string]string{"org": "token_custom_org"},
+			expected: map[string]string{"org": "acme", "token": jwt},
+		},
+		{
+			name:     "plain field",
+			fields:   map[string]string{"username": "alice", "password": "secret"},
+			mapping:  map[string]string{"user": "username"},
+			expected: map[string]string{"user": "alice", "username": "alice", "password": "secret"},
+		},
+		{
+			name:     "missing source is skipped",
+			fields:   map[string]string{"token": jwt},
+			mapping:  map[string]string{"team": "token_team", "user": "token_sub"},
+			expected: map[string]string{"user": "user-123"},
+			absent:   []string{"team"},
+		},
+		{
+			name:     "mappings do not chain",
+			fields:   map[string]string{"a": "1", "b": "2"},
+			mapping:  map[string]string{"b": "a", "c": "b"},
+			expected: map[string]string{"a": "1", "b": "1", "c": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds := New(tt.fields)
+			creds.ApplyClaimsMapping(tt.mapping)
+
+			for key, want := range tt.expected {
+				if got, ok := creds.Fields[key]; !ok || got != want {
+					t.Errorf("field %q = %q (present: %v), want %q", key, got, ok, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if creds.Has(key) {
+					t.Errorf("field %q should not be set", key)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTemplateWithClaimsMapping(t *testing.T) {
+	jwt := createTestJWT(map[string]any{
+		"sub":        "user-123",
+		"custom_org": "acme",
+	})
+
+	creds := New(map[string]string{"token": jwt})
+	creds.ApplyClaimsMapping(map[string]string{"user": "token_sub", "org": "token_custom_org"})
+
+	result, err := ApplyTemplate(creds, "{{.user}}@{{.org}} {{.token_sub}}")
+	if err != nil {
+		t.Fatalf("ApplyTemplate failed: %v", err)
+	}
+	if string(result) != "user-123@acme user-123" {
+		t.Errorf("got %q", result)
+	}
+}
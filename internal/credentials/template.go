@@ -36,7 +36,7 @@ func ApplyTemplate(creds *Credentials, tmplStr string) ([]byte, error) {
 	creds.EnrichWithJWTClaims()
 
 	// Parse the template
-	tmpl, err := template.New("output").Parse(tmplStr)
+	tmpl, err := parseTemplate(tmplStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid template: %w", err)
 	}
@@ -49,3 +49,14 @@ func ApplyTemplate(creds *Credentials, tmplStr string) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// ValidateTemplate checks that a template string parses
+func ValidateTemplate(tmplStr string) error {
+	_, err := parseTemplate(tmplStr)
+	return err
+}
+
+// parseTemplate parses an output template
+func parseTemplate(tmplStr string) (*template.Template, error) {
+	return template.New("output").Parse(tmplStr)
+}
@@ -7,9 +7,11 @@ import (
 	"log"
 	"net"
 	"os"
-	"path/filepath"
 	"syscall"
+	"time"
 
+	"credctl/internal/encryption"
+	"credctl/internal/paths"
 	"credctl/internal/protocol"
 
 	"github.com/sevlyar/go-daemon"
@@ -53,26 +55,55 @@ func handleConn(conn net.Conn, state *State, readOnly bool) {
 	log.Printf("[%s] processing action=%s", socketType, req.Action)
 
 	var resp protocol.Response
-	switch req.Action {
-	case "add":
-		resp = Add(state, req.Payload, readOnly)
-	case "get":
-		resp = Get(state, req.Payload, readOnly)
-	case "delete":
-		resp = Delete(state, req.Payload, readOnly)
-	case "set_tokens":
-		resp = SetTokens(state, req.Payload, readOnly)
-	case "describe":
-		resp = Describe(state, req.Payload, readOnly)
-	case "list":
-		resp = List(state, req.Payload, readOnly)
-	default:
+	if req.Version == "" {
+		log.Printf("[%s] warning: request without protocol version (client older than %s)", socketType, protocol.Version)
+	}
+
+	if err := checkRequestVersion(req); err != nil {
+		log.Printf("[%s] rejecting request: %v", socketType, err)
 		resp = protocol.Response{
-			Status: "error",
-			Error:  fmt.Sprintf("unknown action: %s", req.Action),
+			Status:    "error",
+			Error:     err.Error(),
+			ErrorType: protocol.ErrorTypeVersionMismatch,
+		}
+	} else {
+		switch req.Action {
+		case "ping":
+			resp = Ping(state, req.Payload, readOnly)
+		case "add":
+			resp = Add(state, req.Payload, readOnly)
+		case "get":
+			resp = Get(state, req.Payload, readOnly)
+		case "delete":
+			resp = Delete(state, req.Payload, readOnly)
+		case "add_group":
+			resp = AddGroup(state, req.Payload, readOnly)
+		case "get_group":
+			resp = GetGroup(state, req.Payload, readOnly)
+		case "delete_group":
+			resp = DeleteGroup(state, req.Payload, readOnly)
+		case "set_tokens":
+			resp = SetTokens(state, req.Payload, readOnly)
+		case "rotate_secret":
+			resp = RotateSecret(state, req.Payload, readOnly)
+		case "expiry":
+			resp = Expiry(state, req.Payload, readOnly)
+		case "tokens":
+			resp = Tokens(state, req.Payload, readOnly)
+		case "describe":
+			resp = Describe(state, req.Payload, readOnly)
+		case "list":
+			resp = List(state, req.Payload, readOnly)
+		default:
+			resp = protocol.Response{
+				Status: "error",
+				Error:  fmt.Sprintf("unknown action: %s", req.Action),
+			}
 		}
 	}
 
+	resp.Version = protocol.Version
+
 	log.Printf("[%s] action=%s status=%s", socketType, req.Action, resp.Status)
 
 	respJSON, err := json.Marshal(resp)
@@ -90,6 +121,15 @@ func handleConn(conn net.Conn, state *State, readOnly bool) {
 	}
 }
 
+// checkRequestVersion rejects requests from clients speaking an incompatible
+// protocol version. Requests without a version (older clients) are accepted.
+func checkRequestVersion(req protocol.Request) error {
+	if req.Version == "" {
+		return nil
+	}
+	return protocol.CheckVersion(protocol.Version, req.Version)
+}
+
 // DaemonInfo contains information about the running daemon
 type DaemonInfo struct {
 	AdminSocket    string
@@ -100,25 +140,46 @@ type DaemonInfo struct {
 
 // Run starts the daemon and returns daemon info for the parent process
 func Run() (*DaemonInfo, error) {
-	homeDir, err := os.UserHomeDir()
+	// Define socket paths
+	adminSocketPath, err := paths.AdminSocket()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
+	}
+	readOnlySocketPath, err := paths.ReadOnlySocket()
+	if err != nil {
+		return nil, err
+	}
+	readOnlyPerms, err := readOnlySocketPerms()
+	if err != nil {
+		return nil, err
+	}
+	idle, err := idleTimeout()
+	if err != nil {
+		return nil, err
+	}
+	pidFile, err := paths.PidFile()
+	if err != nil {
+		return nil, err
+	}
+	logFile, err := paths.LogFile()
+	if err != nil {
+		return nil, err
 	}
-
-	// Define socket paths
-	adminSocketPath := filepath.Join(homeDir, ".credctl", "agent.sock")
-	readOnlySocketPath := filepath.Join(homeDir, ".credctl", "agent-readonly.sock")
-	pidFile := filepath.Join(homeDir, ".credctl", "credctl.pid")
-	logFile := filepath.Join(homeDir, ".credctl", "daemon.log")
 
 	// Create directory for sockets and PID file
-	dir := filepath.Join(homeDir, ".credctl")
+	dir, err := paths.Home()
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// Setup daemon context
+	// Always re-execute as "credctl daemon" so the daemon can be started
+	// from any command (e.g. client auto-start)
 	cntxt := &daemon.Context{
+		Args:        []string{os.Args[0], "daemon"},
 		PidFileName: pidFile,
 		PidFilePerm: 0644,
 		LogFileName: logFile,
@@ -127,6 +188,16 @@ func Run() (*DaemonInfo, error) {
 		Umask:       027,
 	}
 
+	// An encrypted store is unlocked once here, where a terminal is available,
+	// and the passphrase is handed to the daemon through its environment
+	if !daemon.WasReborn() && encryption.Enabled() {
+		passphrase, err := daemonPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		cntxt.Env = append(os.Environ(), encryption.PassphraseEnvVar+"="+passphrase)
+	}
+
 	// Daemonize
 	d, err := cntxt.Reborn()
 	if err != nil {
@@ -146,6 +217,15 @@ func Run() (*DaemonInfo, error) {
 	// Child process continues here
 	log.Printf("daemon started with PID %d", os.Getpid())
 
+	if encryption.Enabled() {
+		if err := encryption.Unlock(os.Getenv(encryption.PassphraseEnvVar)); err != nil {
+			log.Printf("failed to unlock credential store: %v", err)
+			return nil, err
+		}
+		_ = os.Unsetenv(encryption.PassphraseEnvVar)
+		log.Printf("credential store unlocked")
+	}
+
 	// Check if admin socket exists and if daemon is running
 	if _, err := os.Stat(adminSocketPath); err == nil {
 		// Socket file exists, try to connect
@@ -162,30 +242,20 @@ func Run() (*DaemonInfo, error) {
 	// Remove read-only socket if it exists
 	_ = os.Remove(readOnlySocketPath)
 
-	// Create admin listener
-	adminListener, err := net.Listen("unix", adminSocketPath)
+	// Create admin listener (only owner)
+	adminListener, err := listenSocket(adminSocketPath, socketPerms{mode: adminSocketMode})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create admin listener: %w", err)
 	}
 	defer func() { _ = adminListener.Close() }()
 
-	// Set admin socket permissions (only owner)
-	if err := os.Chmod(adminSocketPath, 0600); err != nil {
-		return nil, fmt.Errorf("failed to set admin socket permissions: %w", err)
-	}
-
-	// Create read-only listener
-	readOnlyListener, err := net.Listen("unix", readOnlySocketPath)
+	// Create read-only listener (owner, plus the group if configured)
+	readOnlyListener, err := listenSocket(readOnlySocketPath, readOnlyPerms)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create read-only listener: %w", err)
 	}
 	defer func() { _ = readOnlyListener.Close() }()
 
-	// Set read-only socket permissions (owner and group can access)
-	if err := os.Chmod(readOnlySocketPath, 0600); err != nil {
-		return nil, fmt.Errorf("failed to set read-only socket permissions: %w", err)
-	}
-
 	// Load state from disk
 	state, err := NewState()
 	if err != nil {
@@ -195,44 +265,95 @@ func Run() (*DaemonInfo, error) {
 	log.Printf("listening on admin socket: %s", adminSocketPath)
 	log.Printf("listening on read-only socket: %s", readOnlySocketPath)
 
+	srv := newServer(func(conn net.Conn, readOnly bool) {
+		handleConn(conn, state, readOnly)
+	})
+
 	// Setup signal handler for cleanup
 	cleanup := func() {
 		_ = os.Remove(adminSocketPath)
 		_ = os.Remove(readOnlySocketPath)
 	}
-	daemon.SetSigHandler(termHandler(cleanup), syscall.SIGTERM)
-	daemon.SetSigHandler(termHandler(cleanup), syscall.SIGINT)
+	daemon.SetSigHandler(termHandler(srv, state, cleanup), syscall.SIGTERM)
+	daemon.SetSigHandler(termHandler(srv, state, cleanup), syscall.SIGINT)
+	daemon.SetSigHandler(reloadHandler(state), syscall.SIGHUP)
+
+	go state.sweepTokenCachePeriodically(tokenSweepInterval)
+
+	// Exiting after a quiet period goes through the SIGTERM handler, so it
+	// is as graceful as a stop
+	if idle > 0 {
+		log.Printf("shutting down after %v without requests", idle)
+		ticker := time.NewTicker(idleCheckInterval(idle))
+		defer ticker.Stop()
+		go watchIdle(srv, idle, ticker.C, func() {
+			_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		})
+	}
 
-	// Accept connections from admin socket
-	go func() {
-		for {
-			conn, err := adminListener.Accept()
-			if err != nil {
-				log.Printf("error accepting admin connection: %v", err)
-				continue
-			}
-			go handleConn(conn, state, false) // false = not read-only
-		}
-	}()
+	go srv.serve(adminListener, false)   // false = not read-only
+	go srv.serve(readOnlyListener, true) // true = read-only
 
-	// Accept connections from read-only socket (main goroutine)
-	for {
-		conn, err := readOnlyListener.Accept()
+	// Block until a termination signal has drained in-flight requests
+	if err := daemon.ServeSignals(); err != nil {
+		log.Printf("error serving signals: %v", err)
+	}
+
+	log.Printf("daemon stopped")
+	return nil, nil
+}
+
+// daemonPassphrase returns the verified store passphrase from CREDCTL_PASSPHRASE
+// or, failing that, by prompting on the terminal
+func daemonPassphrase() (string, error) {
+	passphrase := os.Getenv(encryption.PassphraseEnvVar)
+	if passphrase == "" {
+		var err error
+		passphrase, err = encryption.ReadPassphrase("Passphrase for the credential store: ")
 		if err != nil {
-			log.Printf("error accepting read-only connection: %v", err)
-			continue
+			return "", err
 		}
-		go handleConn(conn, state, true) // true = read-only
 	}
 
-	// This line is never reached in normal operation
-	// return nil, nil
+	if err := encryption.Unlock(passphrase); err != nil {
+		return "", err
+	}
+	return passphrase, nil
 }
 
-// termHandler returns a signal handler that cleans up and exits
-func termHandler(cleanup func()) daemon.SignalHandlerFunc {
+// reloadHandler returns a signal handler that reloads providers from disk
+// and keeps the daemon running
+func reloadHandler(state *State) daemon.SignalHandlerFunc {
+	return func(sig os.Signal) error {
+		log.Printf("received signal %v, reloading providers", sig)
+		summary, err := state.Reload()
+		if err != nil {
+			log.Printf("reload failed: %v", err)
+			return nil
+		}
+		log.Printf("reload: %d added %v, %d removed %v, %d updated %v, %d unchanged, %d failed %v",
+			len(summary.Added), summary.Added,
+			len(summary.Removed), summary.Removed,
+			len(summary.Updated), summary.Updated,
+			len(summary.Unchanged),
+			len(summary.Failed), summary.Failed)
+		return nil
+	}
+}
+
+// termHandler returns a signal handler that stops accepting connections,
+// waits for in-flight requests and the on-error commands they started, then
+// cleans up and exits
+func termHandler(srv *server, state *State, cleanup func()) daemon.SignalHandlerFunc {
 	return func(sig os.Signal) error {
 		log.Printf("received signal %v, shutting down", sig)
+		deadline := time.Now().Add(shutdownTimeout)
+		if !srv.shutdown(shutdownTimeout) {
+			log.Printf("timed out after %v waiting for in-flight requests", shutdownTimeout)
+		}
+		if !state.waitHooks(time.Until(deadline)) {
+			log.Printf("timed out after %v waiting for on-error commands", shutdownTimeout)
+		}
 		cleanup()
 		return daemon.ErrStop
 	}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"syscall"
+	"testing"
+
+	"credctl/internal/paths"
+	"credctl/internal/provider"
+)
+
+func TestReloadHandler(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	save := func(name string, config map[string]any) {
+		t.Helper()
+		p := &tokenProvider{}
+		_ = p.Init(config)
+		if err := provider.Save(name, p); err != nil {
+			t.Fatalf("Save(%s) error: %v", name, err)
+		}
+	}
+
+	save("kept", map[string]any{provider.MetadataClientID: "client"})
+	save("edited", map[string]any{provider.MetadataClientID: "client"})
+	save("removed", map[string]any{provider.MetadataClientID: "client"})
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	kept, _ := state.Get("kept")
+	kept.(provider.TokenCacheProvider).SetTokens("kept-access", "kept-refresh", 3600)
+	edited, _ := state.Get("edited")
+	edited.(provider.TokenCacheProvider).SetTokens("edited-access", "edited-refresh", 3600)
+
+	// Change the files behind the daemon's back
+	save("added", map[string]any{provider.MetadataClientID: "client"})
+	save("edited", map[string]any{
+		provider.MetadataClientID: "client",
+		provider.MetadataTemplate: "{{.token}}",
+	})
+	if err := provider.Delete("removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reloadHandler(state)(syscall.SIGHUP); err != nil {
+		t.Fatalf("reload handler should keep the daemon running, got %v", err)
+	}
+
+	providers := state.List()
+	for _, name := range []string{"kept", "edited", "added"} {
+		if _, ok := providers[name]; !ok {
+			t.Errorf("expected provider %s after reload", name)
+		}
+	}
+	if _, ok := providers["removed"]; ok {
+		t.Error("expected removed provider to be dropped")
+	}
+
+	got, _ := state.Get("kept")
+	if got != kept {
+		t.Error("expected unchanged provider to be kept as-is")
+	}
+
+	got, _ = state.Get("edited")
+	if got.Metadata()[provider.MetadataTemplate] != "{{.token}}" {
+		t.Errorf("expected edited provider to be reloaded, got %v", got.Metadata())
+	}
+	if accessToken, _, _ := got.(provider.TokenCacheProvider).GetTokens(); accessToken != "edited-access" {
+		t.Errorf("expected tokens to survive a non-auth change, got %q", accessToken)
+	}
+}
+
+func TestReloadSummary(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	p := &tokenProvider{}
+	_ = p.Init(map[string]any{provider.MetadataClientID: "client"})
+	if err := provider.Save("new", p); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := state.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if len(summary.Added) != 1 || summary.Added[0] != "new" {
+		t.Errorf("expected 'new' to be reported as added, got %+v", summary)
+	}
+
+	summary, err = state.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if len(summary.Unchanged) != 1 || len(summary.Added) != 0 {
+		t.Errorf("expected a second reload to change nothing, got %+v", summary)
+	}
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+)
+
+func AddGroup(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Check permissions
+	if readOnly {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "permission denied: add_group operation not allowed on read-only socket",
+			ErrorType: protocol.ErrorTypePermissionDenied,
+		}
+	}
+
+	var groupPayload protocol.AddGroupPayload
+	if err := decodePayload(payload, &groupPayload); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	if !groupPayload.Force {
+		exists, err := provider.GroupExists(groupPayload.Name)
+		if err != nil {
+			return protocol.Response{
+				Status: "error",
+				Error:  fmt.Sprintf("failed to add group: %v", err),
+			}
+		}
+		if exists {
+			return protocol.Response{
+				Status: "error",
+				Error:  fmt.Sprintf("group '%s' already exists (use --force to replace it)", groupPayload.Name),
+			}
+		}
+	}
+
+	// Catch typos: every member must be a provider, or a profile of one
+	seen := make(map[string]bool, len(groupPayload.Members))
+	for _, member := range groupPayload.Members {
+		if seen[member] {
+			return protocol.Response{
+				Status: "error",
+				Error:  fmt.Sprintf("provider '%s' is listed twice", member),
+			}
+		}
+		seen[member] = true
+
+		if !memberExists(state, member) {
+			return protocol.Response{
+				Status:    "error",
+				Error:     fmt.Sprintf("provider not found: %s", member),
+				ErrorType: protocol.ErrorTypeNotFound,
+			}
+		}
+	}
+
+	group := provider.StoredGroup{Name: groupPayload.Name, Members: groupPayload.Members}
+	if err := provider.SaveGroup(group); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("failed to add group: %v", err),
+		}
+	}
+
+	return protocol.Response{
+		Status: "ok",
+	}
+}
+
+// memberExists reports whether a group member names a provider or a profile
+// of one (name@profile)
+func memberExists(state *State, member string) bool {
+	if _, err := state.Get(member); err == nil {
+		return true
+	}
+	if base, _, ok := provider.SplitProfile(member); ok {
+		if _, err := state.Get(base); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetGroup fetches every member of a group in parallel. A member that fails
+// doesn't fail the request: its error is reported in its own result.
+func GetGroup(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Members are read with the caller's socket permissions
+	var groupPayload protocol.GroupPayload
+	if err := decodePayload(payload, &groupPayload); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	group, err := provider.LoadGroup(groupPayload.Name)
+	if err != nil {
+		return protocol.Response{
+			Status:    "error",
+			Error:     err.Error(),
+			ErrorType: groupErrorType(err),
+		}
+	}
+
+	results := make([]protocol.GroupMemberResult, len(group.Members))
+	var wg sync.WaitGroup
+	for i, member := range group.Members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := protocol.GroupMemberResult{Name: member}
+			resp := Get(state, protocol.GetPayload{Name: member}, readOnly)
+			if resp.Status == "ok" {
+				getResp := resp.Payload.(protocol.GetResponsePayload)
+				result.Result = &getResp
+			} else {
+				result.Error, result.ErrorType = resp.Error, resp.ErrorType
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	return protocol.Response{
+		Status:  "ok",
+		Payload: protocol.GetGroupResponsePayload{Members: results},
+	}
+}
+
+func DeleteGroup(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Check permissions
+	if readOnly {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "permission denied: delete_group operation not allowed on read-only socket",
+			ErrorType: protocol.ErrorTypePermissionDenied,
+		}
+	}
+
+	var groupPayload protocol.GroupPayload
+	if err := decodePayload(payload, &groupPayload); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	if err := provider.DeleteGroup(groupPayload.Name); err != nil {
+		return protocol.Response{
+			Status:    "error",
+			Error:     err.Error(),
+			ErrorType: groupErrorType(err),
+		}
+	}
+
+	return protocol.Response{
+		Status: "ok",
+	}
+}
+
+// groupErrorType returns the error type for a failure to read or delete a group
+func groupErrorType(err error) string {
+	if errors.Is(err, provider.ErrGroupNotFound) {
+		return protocol.ErrorTypeNotFound
+	}
+	return protocol.ErrorTypeGeneric
+}
+
+// decodePayload converts a request payload into its typed form
+func decodePayload(payload interface{}, v any) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payloadBytes, v)
+}
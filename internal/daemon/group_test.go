@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"credctl/internal/paths"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+)
+
+func TestAddGroup(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+	prov := &tokenProvider{}
+	_ = prov.Init(map[string]any{})
+	if err := state.Add("api", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		payload  protocol.AddGroupPayload
+		readOnly bool
+		wantErr  string
+	}{
+		{name: "valid", payload: protocol.AddGroupPayload{Name: "env", Members: []string{"api"}}},
+		{name: "exists", payload: protocol.AddGroupPayload{Name: "env", Members: []string{"api"}}, wantErr: "already exists"},
+		{name: "force replaces", payload: protocol.AddGroupPayload{Name: "env", Members: []string{"api", "api@admin"}, Force: true}},
+		{name: "unknown member", payload: protocol.AddGroupPayload{Name: "other", Members: []string{"api", "typo"}}, wantErr: "provider not found: typo"},
+		{name: "duplicate member", payload: protocol.AddGroupPayload{Name: "other", Members: []string{"api", "api"}}, wantErr: "listed twice"},
+		{name: "no members", payload: protocol.AddGroupPayload{Name: "other"}, wantErr: "at least one member"},
+		{name: "read-only socket", payload: protocol.AddGroupPayload{Name: "other", Members: []string{"api"}}, readOnly: true, wantErr: "permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := AddGroup(state, tt.payload, tt.readOnly)
+			if tt.wantErr != "" {
+				if resp.Status != "error" || !strings.Contains(resp.Error, tt.wantErr) {
+					t.Errorf("expected error containing %q, got status %q (%s)", tt.wantErr, resp.Status, resp.Error)
+				}
+				return
+			}
+			if resp.Status != "ok" {
+				t.Errorf("AddGroup() error: %s", resp.Error)
+			}
+		})
+	}
+
+	group, err := provider.LoadGroup("env")
+	if err != nil {
+		t.Fatalf("LoadGroup() unexpected error: %v", err)
+	}
+	if strings.Join(group.Members, ",") != "api,api@admin" {
+		t.Errorf("expected the replaced members, got %v", group.Members)
+	}
+}
+
+func TestGetGroupPartialFailure(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+	for name, policy := range map[string]string{"api": provider.AccessPolicyAny, "db": provider.AccessPolicyAdminOnly} {
+		prov := &tokenProvider{accessToken: name + "-token"}
+		_ = prov.Init(map[string]any{provider.MetadataAccessPolicy: policy})
+		if err := state.Add(name, prov, true); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+	if resp := AddGroup(state, protocol.AddGroupPayload{Name: "env", Members: []string{"db", "api"}}, false); resp.Status != "ok" {
+		t.Fatalf("AddGroup() error: %s", resp.Error)
+	}
+
+	// The admin-only member fails on the read-only socket; the other is still returned
+	resp := GetGroup(state, protocol.GroupPayload{Name: "env"}, true)
+	if resp.Status != "ok" {
+		t.Fatalf("GetGroup() error: %s", resp.Error)
+	}
+	members := resp.Payload.(protocol.GetGroupResponsePayload).Members
+	if len(members) != 2 || members[0].Name != "db" || members[1].Name != "api" {
+		t.Fatalf("expected results for db and api in group order, got %+v", members)
+	}
+	if members[0].Result != nil || members[0].ErrorType != protocol.ErrorTypePermissionDenied {
+		t.Errorf("expected a permission denied error for db, got %+v", members[0])
+	}
+	if members[1].Result == nil || members[1].Result.Output != "api-token" || members[1].Error != "" {
+		t.Errorf("expected the api token, got %+v", members[1])
+	}
+
+	// Both members are readable on the admin socket
+	resp = GetGroup(state, protocol.GroupPayload{Name: "env"}, false)
+	for _, member := range resp.Payload.(protocol.GetGroupResponsePayload).Members {
+		if member.Result == nil {
+			t.Errorf("expected %s to be fetched on the admin socket, got %s", member.Name, member.Error)
+		}
+	}
+
+	// Deleting the group keeps its members
+	if resp := DeleteGroup(state, protocol.GroupPayload{Name: "env"}, false); resp.Status != "ok" {
+		t.Fatalf("DeleteGroup() error: %s", resp.Error)
+	}
+	if resp := GetGroup(state, protocol.GroupPayload{Name: "env"}, false); resp.ErrorType != protocol.ErrorTypeNotFound {
+		t.Errorf("expected a not found error after deletion, got status %q type %q", resp.Status, resp.ErrorType)
+	}
+	if _, err := state.Get("api"); err != nil {
+		t.Errorf("deleting a group must keep its providers: %v", err)
+	}
+}
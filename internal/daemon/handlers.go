@@ -5,12 +5,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"time"
 
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
 	"credctl/internal/protocol"
 	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
+
+	"sigs.k8s.io/release-utils/version"
 )
 
+func Ping(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Ping is allowed in both modes (no permission check needed)
+	return protocol.Response{
+		Status: "ok",
+		Payload: protocol.PingResponsePayload{
+			Version: protocol.Version,
+			Build:   version.GetVersionInfo().GitVersion,
+			PID:     os.Getpid(),
+		},
+	}
+}
+
 func Add(state *State, payload interface{}, readOnly bool) protocol.Response {
 	// Check permissions
 	if readOnly {
@@ -82,7 +101,7 @@ func Add(state *State, payload interface{}, readOnly bool) protocol.Response {
 }
 
 func Get(state *State, payload interface{}, readOnly bool) protocol.Response {
-	// Get operation is allowed in both modes (no permission check needed)
+	// Get operation is allowed in both modes unless the provider is admin-only
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return protocol.Response{
@@ -99,56 +118,147 @@ func Get(state *State, payload interface{}, readOnly bool) protocol.Response {
 		}
 	}
 
-	// Get provider
+	// Get provider; a name that isn't a provider may select one of its profiles (name@profile)
 	prov, err := state.Get(getPayload.Name)
+	providerName, profileName := getPayload.Name, ""
+	if err != nil {
+		if base, name, ok := provider.SplitProfile(getPayload.Name); ok {
+			if baseProv, baseErr := state.Get(base); baseErr == nil {
+				prov, providerName, profileName, err = baseProv, base, name, nil
+			}
+		}
+	}
 	if err != nil {
 		return protocol.Response{
-			Status: "error",
-			Error:  fmt.Sprintf("provider not found: %s", getPayload.Name),
+			Status:    "error",
+			Error:     fmt.Sprintf("provider not found: %s", getPayload.Name),
+			ErrorType: protocol.ErrorTypeNotFound,
+		}
+	}
+
+	if readOnly && provider.IsAdminOnly(prov.Metadata()) {
+		return protocol.Response{
+			Status:    "error",
+			Error:     fmt.Sprintf("permission denied: provider '%s' is admin-only and cannot be read on the read-only socket", providerName),
+			ErrorType: protocol.ErrorTypePermissionDenied,
 		}
 	}
 
-	// Execute provider Get with timeout
+	// Discard cached credentials if the caller asked for a fresh fetch
+	if getPayload.NoCache {
+		if invalidator, ok := prov.(provider.CacheInvalidator); ok {
+			invalidator.InvalidateCache()
+		}
+	}
+
+	// Execute provider Get with timeout; a token source leading back to this
+	// provider is a cycle
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
+	ctx = common.WithSource(ctx, providerName)
 
-	output, err := prov.Get(ctx)
-	if err != nil {
-		// Check for specific authentication errors using errors.Is()
-		if errors.Is(err, provider.ErrAuthenticationRequired) {
+	if profileName != "" && len(getPayload.Scopes) > 0 {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "a scope override cannot be combined with a profile",
+			ErrorType: protocol.ErrorTypeGeneric,
+		}
+	}
+
+	// A scope override is only honored by providers that can narrow scopes
+	var scopedProv provider.ScopedProvider
+	if len(getPayload.Scopes) > 0 {
+		var ok bool
+		scopedProv, ok = prov.(provider.ScopedProvider)
+		if !ok {
 			return protocol.Response{
 				Status:    "error",
-				Error:     "authentication required",
-				ErrorType: protocol.ErrorTypeAuthRequired,
+				Error:     fmt.Sprintf("provider '%s' does not support scope overrides", providerName),
+				ErrorType: protocol.ErrorTypeGeneric,
 			}
 		}
-		if errors.Is(err, provider.ErrDeviceFlowRequiresLogin) {
+	}
+
+	// A profile is only honored by providers that define profiles
+	var profileProv provider.ProfileProvider
+	if profileName != "" {
+		var ok bool
+		profileProv, ok = prov.(provider.ProfileProvider)
+		if !ok {
 			return protocol.Response{
 				Status:    "error",
-				Error:     err.Error(),
-				ErrorType: protocol.ErrorTypeDeviceFlowRequired,
+				Error:     fmt.Sprintf("provider '%s' does not support profiles", providerName),
+				ErrorType: protocol.ErrorTypeGeneric,
 			}
 		}
-		return protocol.Response{
-			Status:    "error",
-			Error:     fmt.Sprintf("failed to get credential: %v", err),
-			ErrorType: protocol.ErrorTypeGeneric,
+	}
+
+	var output []byte
+	if scopedProv != nil {
+		output, err = scopedProv.GetWithScopes(ctx, getPayload.Scopes)
+	} else if profileProv != nil {
+		output, err = profileProv.GetWithProfile(ctx, profileName)
+	} else {
+		output, err = prov.Get(ctx)
+	}
+
+	// A bundle has no single value; its structured fields are the credential
+	bundle := false
+	if errors.Is(err, provider.ErrCredentialBundle) && !getPayload.Raw {
+		if _, ok := prov.(provider.CredentialsProvider); ok {
+			bundle, err = true, nil
 		}
 	}
 
+	if err != nil {
+		resp := getErrorResponse(err)
+		state.runOnErrorCommand(providerName, prov, resp)
+		return resp
+	}
+
 	// Try to get structured credentials if provider supports it
 	responsePayload := protocol.GetResponsePayload{
 		Output:   string(output),
 		Metadata: prov.Metadata(),
 	}
 
-	if credsProv, ok := prov.(provider.CredentialsProvider); ok {
+	if getPayload.Raw {
+		// Raw requests want the exact bytes; skip structured credentials
+		responsePayload.RawOutput = output
+	} else if scopedProv != nil {
+		creds, err := scopedProv.GetCredentialsWithScopes(ctx, getPayload.Scopes)
+		if err == nil && creds != nil && creds.Fields != nil {
+			creds.NormalizeExpiry(clock.Now())
+			responsePayload.StructuredFields = creds.Fields
+			responsePayload.HasStructuredFields = true
+		}
+	} else if profileProv != nil {
+		creds, err := profileProv.GetCredentialsWithProfile(ctx, profileName)
+		if err == nil && creds != nil && creds.Fields != nil {
+			creds.NormalizeExpiry(clock.Now())
+			responsePayload.StructuredFields = creds.Fields
+			responsePayload.HasStructuredFields = true
+		}
+	} else if credsProv, ok := prov.(provider.CredentialsProvider); ok {
 		creds, err := credsProv.GetCredentials(ctx)
 		if err == nil && creds != nil && creds.Fields != nil {
+			creds.NormalizeExpiry(clock.Now())
 			responsePayload.StructuredFields = creds.Fields
 			responsePayload.HasStructuredFields = true
+		} else if bundle {
+			if err == nil {
+				err = errors.New("provider returned no credential fields")
+			}
+			resp := protocol.Response{
+				Status:    "error",
+				Error:     fmt.Sprintf("failed to get credential: %v", err),
+				ErrorType: protocol.ErrorTypeGeneric,
+			}
+			state.runOnErrorCommand(providerName, prov, resp)
+			return resp
 		}
 	}
+	responsePayload.Bundle = bundle
 
 	// Return output and provider metadata
 	return protocol.Response{
@@ -157,6 +267,37 @@ func Get(state *State, payload interface{}, readOnly bool) protocol.Response {
 	}
 }
 
+// getErrorResponse maps an error returned by a provider's Get to a response
+func getErrorResponse(err error) protocol.Response {
+	// Check for specific authentication errors using errors.Is()
+	if errors.Is(err, provider.ErrAuthenticationRequired) {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "authentication required",
+			ErrorType: protocol.ErrorTypeAuthRequired,
+		}
+	}
+	if errors.Is(err, provider.ErrDeviceFlowRequiresLogin) {
+		return protocol.Response{
+			Status:    "error",
+			Error:     err.Error(),
+			ErrorType: protocol.ErrorTypeDeviceFlowRequired,
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return protocol.Response{
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to get credential: %v", err),
+			ErrorType: protocol.ErrorTypeTimeout,
+		}
+	}
+	return protocol.Response{
+		Status:    "error",
+		Error:     fmt.Sprintf("failed to get credential: %v", err),
+		ErrorType: protocol.ErrorTypeGeneric,
+	}
+}
+
 func Delete(state *State, payload interface{}, readOnly bool) protocol.Response {
 	// Check permissions
 	if readOnly {
@@ -203,6 +344,15 @@ func Delete(state *State, payload interface{}, readOnly bool) protocol.Response
 }
 
 func SetTokens(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Check permissions
+	if readOnly {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "permission denied: set_tokens operation not allowed on read-only socket",
+			ErrorType: protocol.ErrorTypePermissionDenied,
+		}
+	}
+
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return protocol.Response{
@@ -230,8 +380,9 @@ func SetTokens(state *State, payload interface{}, readOnly bool) protocol.Respon
 	prov, err := state.Get(tokensPayload.Name)
 	if err != nil {
 		return protocol.Response{
-			Status: "error",
-			Error:  fmt.Sprintf("provider not found: %s", tokensPayload.Name),
+			Status:    "error",
+			Error:     fmt.Sprintf("provider not found: %s", tokensPayload.Name),
+			ErrorType: protocol.ErrorTypeNotFound,
 		}
 	}
 
@@ -252,8 +403,108 @@ func SetTokens(state *State, payload interface{}, readOnly bool) protocol.Respon
 	}
 }
 
+func RotateSecret(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Check permissions
+	if readOnly {
+		return protocol.Response{
+			Status:    "error",
+			Error:     "permission denied: rotate_secret operation not allowed on read-only socket",
+			ErrorType: protocol.ErrorTypePermissionDenied,
+		}
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	var rotatePayload protocol.RotateSecretPayload
+	if err := json.Unmarshal(payloadBytes, &rotatePayload); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	if rotatePayload.Secret == "" {
+		return protocol.Response{
+			Status: "error",
+			Error:  "the new client secret cannot be empty",
+		}
+	}
+	if _, err := state.GetUnresolved(rotatePayload.Name); err != nil {
+		return protocol.Response{
+			Status:    "error",
+			Error:     fmt.Sprintf("provider not found: %s", rotatePayload.Name),
+			ErrorType: protocol.ErrorTypeNotFound,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := state.RotateSecret(ctx, rotatePayload.Name, rotatePayload.Secret, rotatePayload.Verify); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("failed to rotate secret: %v", err),
+		}
+	}
+
+	return protocol.Response{
+		Status: "ok",
+	}
+}
+
+func Expiry(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Expiry is allowed in both modes (the token itself is never returned)
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	var expiryPayload protocol.ExpiryPayload
+	if err := json.Unmarshal(payloadBytes, &expiryPayload); err != nil {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("invalid payload: %v", err),
+		}
+	}
+
+	prov, err := state.Get(expiryPayload.Name)
+	if err != nil {
+		return protocol.Response{
+			Status:    "error",
+			Error:     fmt.Sprintf("provider not found: %s", expiryPayload.Name),
+			ErrorType: protocol.ErrorTypeNotFound,
+		}
+	}
+
+	tokenProv, ok := prov.(provider.TokenCacheProvider)
+	if !ok {
+		return protocol.Response{
+			Status: "error",
+			Error:  fmt.Sprintf("provider '%s' (type: %s) does not cache tokens", expiryPayload.Name, prov.Type()),
+		}
+	}
+
+	accessToken, refreshToken, expiresIn := tokenProv.GetTokens()
+	return protocol.Response{
+		Status: "ok",
+		Payload: protocol.ExpiryResponsePayload{
+			HasToken:  accessToken != "" || refreshToken != "",
+			ExpiresIn: expiresIn,
+		},
+	}
+}
+
 func Describe(state *State, payload interface{}, readOnly bool) protocol.Response {
-	// Describe operation is allowed in both modes (no permission check needed)
+	// Describe operation is allowed in both modes unless the provider is admin-only
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return protocol.Response{
@@ -277,21 +528,92 @@ func Describe(state *State, payload interface{}, readOnly bool) protocol.Respons
 		}
 	}
 
-	// Get provider from state
-	prov, err := state.Get(describePayload.Name)
+	// Describe an alias itself, so it can be edited and exported as such,
+	// unless the caller wants the provider it stands for
+	get := state.GetUnresolved
+	if describePayload.Resolve {
+		get = state.Get
+	}
+	prov, err := get(describePayload.Name)
 	if err != nil {
 		return protocol.Response{
-			Status: "error",
-			Error:  fmt.Sprintf("provider not found: %s", describePayload.Name),
+			Status:    "error",
+			Error:     fmt.Sprintf("provider not found: %s", describePayload.Name),
+			ErrorType: protocol.ErrorTypeNotFound,
 		}
 	}
 
-	// Return provider type and metadata
+	// The configuration of an admin-only provider holds its secrets too
+	if readOnly && provider.IsAdminOnly(prov.Metadata()) {
+		return protocol.Response{
+			Status:    "error",
+			Error:     fmt.Sprintf("permission denied: provider '%s' is admin-only and cannot be described on the read-only socket", describePayload.Name),
+			ErrorType: protocol.ErrorTypePermissionDenied,
+		}
+	}
+
+	// Return provider type, metadata and capabilities
+	capabilities := provider.CapabilitiesOf(prov)
 	return protocol.Response{
 		Status: "ok",
 		Payload: protocol.DescribeResponsePayload{
 			Type:     prov.Type(),
 			Metadata: prov.Metadata(),
+			Capabilities: &protocol.ProviderCapabilities{
+				NeedsNetwork:    capabilities.NeedsNetwork,
+				SupportsRefresh: capabilities.SupportsRefresh,
+				Interactive:     capabilities.Interactive,
+				Structured:      capabilities.Structured,
+			},
+		},
+	}
+}
+
+func Tokens(state *State, payload interface{}, readOnly bool) protocol.Response {
+	// Tokens is allowed in both modes (token values are never returned)
+	var tokens []protocol.TokenInfo
+	for name := range state.List() {
+		// Aliases share their target's tokens, listed under the target
+		prov, err := state.GetUnresolved(name)
+		if err != nil {
+			continue // Deleted concurrently
+		}
+
+		tokenProv, ok := prov.(provider.TokenCacheProvider)
+		if !ok {
+			continue
+		}
+
+		accessToken, refreshToken, expiresIn := tokenProv.GetTokens()
+		info := protocol.TokenInfo{
+			Name:            name,
+			Type:            prov.Type(),
+			HasAccessToken:  accessToken != "",
+			HasRefreshToken: refreshToken != "",
+		}
+		if accessToken != "" {
+			info.ExpiresIn = expiresIn
+		}
+		if typed, ok := prov.(provider.TokenTypeProvider); ok {
+			info.TokenType = typed.TokenType()
+		}
+		if claims, ok := credentials.StandardJWTClaims(accessToken); ok {
+			info.Subject = claims["sub"]
+			info.Issuer = claims["iss"]
+			info.Audience = claims["aud"]
+		}
+
+		tokens = append(tokens, info)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+
+	return protocol.Response{
+		Status: "ok",
+		Payload: protocol.TokensResponsePayload{
+			Tokens: tokens,
 		},
 	}
 }
@@ -303,10 +625,15 @@ func List(state *State, payload interface{}, readOnly bool) protocol.Response {
 	// Convert map to slice of ProviderInfo
 	var providerList []protocol.ProviderInfo
 	for name, provType := range providers {
-		providerList = append(providerList, protocol.ProviderInfo{
+		info := protocol.ProviderInfo{
 			Name: name,
 			Type: provType,
-		})
+		}
+		// The file's mtime tells when the provider was added or last modified
+		if modTime, err := provider.ModTime(name); err == nil {
+			info.ModifiedAt = modTime
+		}
+		providerList = append(providerList, info)
 	}
 
 	return protocol.Response{
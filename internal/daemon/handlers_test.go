@@ -0,0 +1,637 @@
+package daemon
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"credctl/internal/credentials"
+	"credctl/internal/paths"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2"
+)
+
+func TestExpiry(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	tests := []struct {
+		name          string
+		accessToken   string
+		expiresIn     int
+		wantHasToken  bool
+		wantExpiresIn int
+	}{
+		{name: "cached token", accessToken: "access", expiresIn: 3600, wantHasToken: true, wantExpiresIn: 3600},
+		{name: "expired token", accessToken: "access", expiresIn: 0, wantHasToken: true, wantExpiresIn: 0},
+		{name: "no token", wantHasToken: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := NewState()
+			if err != nil {
+				t.Fatalf("NewState() unexpected error: %v", err)
+			}
+
+			prov := &tokenProvider{}
+			_ = prov.Init(map[string]any{})
+			prov.SetTokens(tt.accessToken, "", tt.expiresIn)
+			if err := state.Add("prov", prov, true); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+
+			// Expiry never returns the token, so it is allowed on the read-only socket
+			resp := Expiry(state, protocol.ExpiryPayload{Name: "prov"}, true)
+			if resp.Status != "ok" {
+				t.Fatalf("Expiry() error: %s", resp.Error)
+			}
+
+			payload := resp.Payload.(protocol.ExpiryResponsePayload)
+			if payload.HasToken != tt.wantHasToken {
+				t.Errorf("HasToken = %v, want %v", payload.HasToken, tt.wantHasToken)
+			}
+			if payload.ExpiresIn != tt.wantExpiresIn {
+				t.Errorf("ExpiresIn = %d, want %d", payload.ExpiresIn, tt.wantExpiresIn)
+			}
+		})
+	}
+
+	t.Run("unknown provider", func(t *testing.T) {
+		state, err := NewState()
+		if err != nil {
+			t.Fatalf("NewState() unexpected error: %v", err)
+		}
+		if resp := Expiry(state, protocol.ExpiryPayload{Name: "missing"}, true); resp.Status != "error" {
+			t.Error("expected error for unknown provider")
+		}
+	})
+}
+
+func TestTokens(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	// Unsigned JWT: claims are decoded for display only
+	enc := base64.RawURLEncoding.EncodeToString
+	jwt := enc([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc([]byte(`{"sub":"alice","iss":"https://idp.example.com","aud":"api"}`)) + "." +
+		enc([]byte("signature"))
+
+	tokens := []struct {
+		name         string
+		accessToken  string
+		refreshToken string
+	}{
+		{name: "jwt", accessToken: jwt, refreshToken: "refresh"},
+		{name: "opaque", accessToken: "opaque-token"},
+		{name: "empty"},
+	}
+	for _, tok := range tokens {
+		prov := &tokenProvider{}
+		_ = prov.Init(map[string]any{})
+		prov.SetTokens(tok.accessToken, tok.refreshToken, 3600)
+		if err := state.Add(tok.name, prov, true); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+
+	resp := Tokens(state, nil, true)
+	if resp.Status != "ok" {
+		t.Fatalf("Tokens() error: %s", resp.Error)
+	}
+
+	payload := resp.Payload.(protocol.TokensResponsePayload)
+	if len(payload.Tokens) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(payload.Tokens))
+	}
+
+	// Sorted by name: empty, jwt, opaque
+	empty, jwtInfo, opaque := payload.Tokens[0], payload.Tokens[1], payload.Tokens[2]
+
+	if empty.HasAccessToken || empty.HasRefreshToken || empty.ExpiresIn != 0 {
+		t.Errorf("unexpected state for provider without tokens: %+v", empty)
+	}
+
+	if !jwtInfo.HasAccessToken || !jwtInfo.HasRefreshToken || jwtInfo.ExpiresIn != 3600 {
+		t.Errorf("unexpected state for jwt provider: %+v", jwtInfo)
+	}
+	if jwtInfo.Subject != "alice" || jwtInfo.Issuer != "https://idp.example.com" || jwtInfo.Audience != "api" {
+		t.Errorf("unexpected claims: %+v", jwtInfo)
+	}
+
+	if !opaque.HasAccessToken || opaque.HasRefreshToken || opaque.Subject != "" {
+		t.Errorf("unexpected state for opaque provider: %+v", opaque)
+	}
+
+	// Token values must never be returned
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{jwt, "opaque-token", "refresh"} {
+		if strings.Contains(string(data), `"`+secret+`"`) {
+			t.Errorf("response leaks token %q", secret)
+		}
+	}
+}
+
+// bundleProvider returns a set of fields with no single value
+type bundleProvider struct {
+	tokenProvider
+	fields map[string]string
+}
+
+func (p *bundleProvider) Type() string { return "bundle-test" }
+
+func (p *bundleProvider) Get(ctx context.Context) ([]byte, error) {
+	return nil, provider.ErrCredentialBundle
+}
+
+func (p *bundleProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	return credentials.New(p.fields), nil
+}
+
+func TestGetCredentialBundle(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	prov := &bundleProvider{fields: map[string]string{
+		"access_key_id":     "AKIA",
+		"secret_access_key": "secret",
+		"session_token":     "session",
+	}}
+	_ = prov.Init(map[string]any{})
+	if err := state.Add("aws", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	resp := Get(state, protocol.GetPayload{Name: "aws"}, true)
+	if resp.Status != "ok" {
+		t.Fatalf("Get() error: %s", resp.Error)
+	}
+	payload := resp.Payload.(protocol.GetResponsePayload)
+	if !payload.Bundle || payload.Output != "" {
+		t.Errorf("expected a bundle without output, got Bundle=%v Output=%q", payload.Bundle, payload.Output)
+	}
+	if !payload.HasStructuredFields || payload.StructuredFields["session_token"] != "session" {
+		t.Errorf("expected the structured fields, got %v", payload.StructuredFields)
+	}
+
+	// Raw requests need a single value
+	resp = Get(state, protocol.GetPayload{Name: "aws", Raw: true}, true)
+	if resp.Status != "error" {
+		t.Error("expected error for a raw request")
+	}
+
+	// A bundle without fields is an error
+	prov.fields = nil
+	if resp := Get(state, protocol.GetPayload{Name: "aws"}, true); resp.Status != "error" {
+		t.Error("expected error when the bundle has no fields")
+	}
+}
+
+func TestGetNormalizesExpiry(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	prov := &bundleProvider{fields: map[string]string{
+		"token": "abc",
+		"exp":   strconv.FormatInt(exp, 10),
+	}}
+	_ = prov.Init(map[string]any{})
+	if err := state.Add("api", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	resp := Get(state, protocol.GetPayload{Name: "api"}, true)
+	if resp.Status != "ok" {
+		t.Fatalf("Get() error: %s", resp.Error)
+	}
+	fields := resp.Payload.(protocol.GetResponsePayload).StructuredFields
+
+	if got, want := fields["expires_at"], time.Unix(exp, 0).Format(time.RFC3339); got != want {
+		t.Errorf("expires_at = %q, want %q", got, want)
+	}
+	if expiresIn, err := strconv.Atoi(fields["expires_in"]); err != nil || expiresIn < 3590 || expiresIn > 3600 {
+		t.Errorf("expected expires_in of about 3600, got %q", fields["expires_in"])
+	}
+}
+
+func TestGetAccessPolicy(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	tests := []struct {
+		name        string
+		policy      string
+		readOnly    bool
+		shouldError bool
+	}{
+		{name: "any on admin socket", policy: provider.AccessPolicyAny},
+		{name: "any on read-only socket", policy: provider.AccessPolicyAny, readOnly: true},
+		{name: "admin-only on admin socket", policy: provider.AccessPolicyAdminOnly},
+		{name: "admin-only on read-only socket", policy: provider.AccessPolicyAdminOnly, readOnly: true, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := NewState()
+			if err != nil {
+				t.Fatalf("NewState() unexpected error: %v", err)
+			}
+
+			prov := &tokenProvider{}
+			_ = prov.Init(map[string]any{provider.MetadataAccessPolicy: tt.policy})
+			if err := state.Add("prov", prov, true); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+
+			resp := Get(state, protocol.GetPayload{Name: "prov"}, tt.readOnly)
+			if tt.shouldError {
+				if resp.Status != "error" || resp.ErrorType != protocol.ErrorTypePermissionDenied {
+					t.Errorf("expected a permission denied error, got status %q type %q", resp.Status, resp.ErrorType)
+				}
+				return
+			}
+			if resp.Status != "ok" {
+				t.Errorf("Get() error: %s", resp.Error)
+			}
+		})
+	}
+}
+
+func TestReadOnlySocketPermissions(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	for name, policy := range map[string]string{"shared": provider.AccessPolicyAny, "secret": provider.AccessPolicyAdminOnly} {
+		prov := &tokenProvider{}
+		_ = prov.Init(map[string]any{provider.MetadataAccessPolicy: policy})
+		if err := state.Add(name, prov, true); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		call     func(readOnly bool) protocol.Response
+		readOnly bool
+		denied   bool
+	}{
+		{
+			name: "set tokens on read-only socket",
+			call: func(ro bool) protocol.Response {
+				return SetTokens(state, protocol.SetTokensPayload{Name: "shared", AccessToken: "x"}, ro)
+			},
+			readOnly: true,
+			denied:   true,
+		},
+		{
+			name: "set tokens on admin socket",
+			call: func(ro bool) protocol.Response {
+				return SetTokens(state, protocol.SetTokensPayload{Name: "shared", AccessToken: "x"}, ro)
+			},
+		},
+		{
+			name:     "describe admin-only on read-only socket",
+			call:     func(ro bool) protocol.Response { return Describe(state, protocol.DescribePayload{Name: "secret"}, ro) },
+			readOnly: true,
+			denied:   true,
+		},
+		{
+			name: "describe admin-only on admin socket",
+			call: func(ro bool) protocol.Response { return Describe(state, protocol.DescribePayload{Name: "secret"}, ro) },
+		},
+		{
+			name:     "describe on read-only socket",
+			call:     func(ro bool) protocol.Response { return Describe(state, protocol.DescribePayload{Name: "shared"}, ro) },
+			readOnly: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.call(tt.readOnly)
+			if tt.denied {
+				if resp.Status != "error" || resp.ErrorType != protocol.ErrorTypePermissionDenied {
+					t.Errorf("expected a permission denied error, got status %q type %q", resp.Status, resp.ErrorType)
+				}
+				return
+			}
+			if resp.Status != "ok" {
+				t.Errorf("unexpected error: %s", resp.Error)
+			}
+		})
+	}
+}
+
+func TestGetAuthCodeRequiresLogin(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	prov := &oauth2.Provider{}
+	err = prov.Init(map[string]any{
+		"flow":                         oauth2.FlowAuthCode,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataAuthEndpoint:  "https://idp.example.com/authorize",
+		provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+	})
+	if err != nil {
+		t.Fatalf("Init() unexpected error: %v", err)
+	}
+	if err := state.Add("web", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	// The daemon can't open a browser: the client has to run credctl login
+	resp := Get(state, protocol.GetPayload{Name: "web"}, false)
+	if resp.Status != "error" || resp.ErrorType != protocol.ErrorTypeAuthRequired {
+		t.Errorf("expected an auth required error, got status %q type %q (%s)", resp.Status, resp.ErrorType, resp.Error)
+	}
+}
+
+// profileProvider serves a distinct token for each of its profiles
+type profileProvider struct {
+	tokenProvider
+}
+
+func (p *profileProvider) Type() string { return "profile-test" }
+
+func (p *profileProvider) Profiles() []string { return []string{"admin"} }
+
+func (p *profileProvider) GetWithProfile(ctx context.Context, profile string) ([]byte, error) {
+	if profile != "admin" {
+		return nil, fmt.Errorf("profile '%s' is not defined", profile)
+	}
+	return []byte("admin-token"), nil
+}
+
+func (p *profileProvider) GetCredentialsWithProfile(ctx context.Context, profile string) (*credentials.Credentials, error) {
+	output, err := p.GetWithProfile(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.New(map[string]string{"access_token": string(output)}), nil
+}
+
+func TestGetProfile(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	add := func(name string, prov provider.Provider) {
+		_ = prov.Init(map[string]any{})
+		if err := state.Add(name, prov, true); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+	add("corp", &profileProvider{tokenProvider{accessToken: "main-token"}})
+	add("plain", &tokenProvider{accessToken: "plain-token"})
+	add("me@admin", &tokenProvider{accessToken: "literal-token"})
+
+	tests := []struct {
+		name       string
+		payload    protocol.GetPayload
+		wantOutput string
+		wantErr    string
+	}{
+		{name: "provider", payload: protocol.GetPayload{Name: "corp"}, wantOutput: "main-token"},
+		{name: "profile", payload: protocol.GetPayload{Name: "corp@admin"}, wantOutput: "admin-token"},
+		{name: "undefined profile", payload: protocol.GetPayload{Name: "corp@dev"}, wantErr: "not defined"},
+		{name: "profile with scopes", payload: protocol.GetPayload{Name: "corp@admin", Scopes: []string{"read"}}, wantErr: "cannot be combined"},
+		{name: "no profile support", payload: protocol.GetPayload{Name: "plain@admin"}, wantErr: "does not support profiles"},
+		{name: "unknown provider", payload: protocol.GetPayload{Name: "other@admin"}, wantErr: "provider not found"},
+		{name: "provider name with separator", payload: protocol.GetPayload{Name: "me@admin"}, wantOutput: "literal-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Get(state, tt.payload, true)
+			if tt.wantErr != "" {
+				if resp.Status != "error" || !strings.Contains(resp.Error, tt.wantErr) {
+					t.Errorf("expected error containing %q, got status %q (%s)", tt.wantErr, resp.Status, resp.Error)
+				}
+				return
+			}
+			if resp.Status != "ok" {
+				t.Fatalf("Get() error: %s", resp.Error)
+			}
+			payload := resp.Payload.(protocol.GetResponsePayload)
+			if payload.Output != tt.wantOutput {
+				t.Errorf("Output = %q, want %q", payload.Output, tt.wantOutput)
+			}
+			if tt.payload.Name == "corp@admin" && payload.StructuredFields["access_token"] != "admin-token" {
+				t.Errorf("expected the profile's structured fields, got %v", payload.StructuredFields)
+			}
+		})
+	}
+}
+
+func TestGetTokenSourceCycle(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	delegating := func(actor string) provider.Provider {
+		prov := &oauth2.Provider{}
+		if err := prov.Init(map[string]any{
+			"flow":                            oauth2.FlowClientCredentials,
+			provider.MetadataClientID:         "my-client",
+			provider.MetadataClientSecret:     "secret",
+			provider.MetadataTokenEndpoint:    "https://idp.example.com/token",
+			provider.MetadataActorTokenSource: actor,
+		}); err != nil {
+			t.Fatalf("Init() error: %v", err)
+		}
+		return prov
+	}
+
+	// A provider can't be added with itself as its source
+	if err := state.Add("self", delegating("self"), true); err == nil {
+		t.Error("expected error adding a provider that is its own actor_token_source")
+	}
+	// Nor through an alias of itself
+	if err := state.Add("a", delegating("a-alias"), true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	alias, _ := provider.New("alias")
+	_ = alias.Init(map[string]any{provider.MetadataAliasTarget: "a"})
+	if err := state.Add("a-alias", alias, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if err := state.Add("a", delegating("a-alias"), true); err == nil {
+		t.Error("expected error re-adding a provider whose source is its own alias")
+	}
+
+	// Cycles the add-time check can't see fail instead of waiting forever
+	if err := provider.Save("loop", delegating("loop")); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := state.Add("a", delegating("b"), true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if err := state.Add("b", delegating("a"), true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"loop", "a", "b"} {
+		done := make(chan protocol.Response, 1)
+		go func() { done <- Get(state, protocol.GetPayload{Name: name}, false) }()
+
+		select {
+		case resp := <-done:
+			if resp.Status != "error" || !strings.Contains(resp.Error, "cycle") {
+				t.Errorf("Get(%s) = %q (%s), want a cycle error", name, resp.Error, resp.Status)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Get(%s) deadlocked on its token source cycle", name)
+		}
+	}
+}
+
+func TestListModifiedAt(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	prov := &tokenProvider{}
+	_ = prov.Init(map[string]any{})
+	if err := state.Add("prov", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	resp := List(state, nil, true)
+	if resp.Status != "ok" {
+		t.Fatalf("List() error: %s", resp.Error)
+	}
+	providers := resp.Payload.(protocol.ListResponsePayload).Providers
+	if len(providers) != 1 {
+		t.Fatalf("expected 1 provider, got %d", len(providers))
+	}
+	if modifiedAt := providers[0].ModifiedAt; modifiedAt.Before(before) || modifiedAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("ModifiedAt = %v, want the time the provider was saved", modifiedAt)
+	}
+}
+
+// failingProvider is a test provider whose Get always fails
+type failingProvider struct {
+	tokenProvider
+	err error
+}
+
+func (p *failingProvider) Get(ctx context.Context) ([]byte, error) {
+	return nil, p.err
+}
+
+func TestGetRunsOnErrorCommand(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "hook.txt")
+	hook := `printf '%s\n%s\n%s' "$CREDCTL_PROVIDER" "$CREDCTL_ERROR_TYPE" "$CREDCTL_ERROR" > ` + out
+
+	prov := &failingProvider{err: errors.New("vault sealed")}
+	_ = prov.Init(map[string]any{provider.MetadataOnErrorCommand: hook})
+	if err := state.Add("flaky", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	resp := Get(state, protocol.GetPayload{Name: "flaky"}, true)
+	state.hooks.Wait()
+	if resp.Status != "error" || resp.ErrorType != protocol.ErrorTypeGeneric {
+		t.Fatalf("expected a generic error, got status %q type %q", resp.Status, resp.ErrorType)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("on-error command did not run: %v", err)
+	}
+	want := "flaky\ngeneric\nfailed to get credential: vault sealed"
+	if string(data) != want {
+		t.Errorf("on-error command saw %q, want %q", data, want)
+	}
+
+	// A failing command doesn't change the error returned
+	broken := &failingProvider{err: provider.ErrAuthenticationRequired}
+	_ = broken.Init(map[string]any{provider.MetadataOnErrorCommand: "echo broken >&2; exit 3"})
+	if err := state.Add("flaky", broken, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	resp = Get(state, protocol.GetPayload{Name: "flaky"}, true)
+	state.hooks.Wait()
+	if resp.ErrorType != protocol.ErrorTypeAuthRequired || resp.Error != "authentication required" {
+		t.Errorf("expected the original error, got %q (%s)", resp.Error, resp.ErrorType)
+	}
+
+	// Successful fetches don't run it
+	if err := os.Remove(out); err != nil {
+		t.Fatal(err)
+	}
+	ok := &tokenProvider{accessToken: "token"}
+	_ = ok.Init(map[string]any{provider.MetadataOnErrorCommand: hook})
+	if err := state.Add("steady", ok, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if resp := Get(state, protocol.GetPayload{Name: "steady"}, true); resp.Status != "ok" {
+		t.Fatalf("Get() error: %s", resp.Error)
+	}
+	state.hooks.Wait()
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("on-error command ran after a successful Get")
+	}
+}
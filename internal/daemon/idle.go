@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"credctl/internal/clock"
+)
+
+// IdleTimeoutEnvVar makes the daemon exit after this long without requests,
+// as a duration ("30m") or in minutes; unset or 0 keeps it running
+const IdleTimeoutEnvVar = "CREDCTL_IDLE_TIMEOUT"
+
+// Bounds of how often the idle watchdog looks at the server
+const (
+	minIdleCheckInterval = time.Second
+	maxIdleCheckInterval = time.Minute
+)
+
+// idleTimeout returns the idle timeout from CREDCTL_IDLE_TIMEOUT, or 0 when
+// idle shutdown is disabled
+func idleTimeout() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(IdleTimeoutEnvVar))
+	if value == "" {
+		return 0, nil
+	}
+
+	// A bare number is minutes
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "m"
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s '%s': must be a duration (e.g. 30m) or a number of minutes", IdleTimeoutEnvVar, os.Getenv(IdleTimeoutEnvVar))
+	}
+	return timeout, nil
+}
+
+// idleCheckInterval returns how often to check for a timeout, so the daemon
+// exits at most a quarter of the timeout (and a minute) late
+func idleCheckInterval(timeout time.Duration) time.Duration {
+	return max(min(timeout/4, maxIdleCheckInterval), minIdleCheckInterval)
+}
+
+// watchIdle calls onIdle once srv has had no request in flight for timeout,
+// checking on every tick, and returns
+func watchIdle(srv *server, timeout time.Duration, tick <-chan time.Time, onIdle func()) {
+	for range tick {
+		if idle := srv.idleFor(clock.Now()); idle >= timeout {
+			log.Printf("no requests for %v, shutting down", idle.Truncate(time.Second))
+			onIdle()
+			return
+		}
+	}
+}
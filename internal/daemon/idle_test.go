@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"credctl/internal/clock"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        time.Duration
+		shouldError bool
+	}{
+		{value: "", want: 0},
+		{value: "0", want: 0},
+		{value: "30", want: 30 * time.Minute},
+		{value: "90s", want: 90 * time.Second},
+		{value: "2h", want: 2 * time.Hour},
+		{value: "-5m", shouldError: true},
+		{value: "soon", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(IdleTimeoutEnvVar, tt.value)
+			got, err := idleTimeout()
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("idleTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchIdleShutsDownAfterInactivity(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	defer clock.Set(fake)()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := newServer(func(conn net.Conn, readOnly bool) {
+		defer func() { _ = conn.Close() }()
+		close(started)
+		<-release
+	})
+
+	l, path := listenUnix(t)
+	go srv.serve(l, true)
+	defer srv.shutdown(time.Second)
+
+	tick := make(chan time.Time)
+	idle := make(chan struct{})
+	go watchIdle(srv, 10*time.Minute, tick, func() { close(idle) })
+
+	// Each tick is only received once the previous check has completed
+	check := func() {
+		t.Helper()
+		select {
+		case tick <- fake.Now():
+		case <-idle:
+		case <-time.After(5 * time.Second):
+			t.Fatal("watchdog stopped receiving ticks")
+		}
+	}
+	assertRunning := func(when string) {
+		t.Helper()
+		check()
+		check()
+		select {
+		case <-idle:
+			t.Fatalf("shut down %s", when)
+		default:
+		}
+	}
+
+	fake.Advance(9 * time.Minute)
+	assertRunning("before the timeout")
+
+	// A request in flight keeps the daemon up however long it takes
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	<-started
+	fake.Advance(30 * time.Minute)
+	assertRunning("while a request was in flight")
+
+	// and restarts the timer when it finishes
+	close(release)
+	for srv.inFlight.Load() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(9 * time.Minute)
+	assertRunning("before the timeout after the last request")
+
+	fake.Advance(time.Minute)
+	check()
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the daemon to shut down after 10 minutes without requests")
+	}
+}
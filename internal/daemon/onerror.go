@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+)
+
+// onErrorTimeout bounds a provider's on-error command
+const onErrorTimeout = 30 * time.Second
+
+// Environment variables describing the failure to an on-error command
+const (
+	onErrorProviderEnvVar  = "CREDCTL_PROVIDER"
+	onErrorMessageEnvVar   = "CREDCTL_ERROR"
+	onErrorErrorTypeEnvVar = "CREDCTL_ERROR_TYPE"
+)
+
+// runOnErrorCommand starts the on-error command configured for prov, if any,
+// after a failed Get. It runs in the background so the failure is answered
+// right away; the command's own failure is only logged.
+func (s *State) runOnErrorCommand(name string, prov provider.Provider, resp protocol.Response) {
+	command := provider.OnErrorCommand(prov.Metadata())
+	if command == "" {
+		return
+	}
+
+	s.hooks.Add(1)
+	go func() {
+		defer s.hooks.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), onErrorTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		cmd.Env = append(os.Environ(),
+			onErrorProviderEnvVar+"="+name,
+			onErrorMessageEnvVar+"="+resp.Error,
+			onErrorErrorTypeEnvVar+"="+resp.ErrorType,
+		)
+		// Don't wait on background processes the command left holding its output
+		cmd.WaitDelay = time.Second
+
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Printf("on-error command for provider '%s' failed: %v: %s", name, err, strings.TrimSpace(string(output)))
+		}
+	}()
+}
+
+// waitHooks waits up to timeout for running on-error commands, so a daemon
+// that is stopping doesn't kill them partway; it reports whether they finished
+func (s *State) waitHooks(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.hooks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
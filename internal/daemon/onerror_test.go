@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitHooks(t *testing.T) {
+	state := &State{}
+	if !state.waitHooks(time.Second) {
+		t.Fatal("waitHooks() timed out without running commands")
+	}
+
+	release := make(chan struct{})
+	state.hooks.Add(1)
+	go func() {
+		<-release
+		state.hooks.Done()
+	}()
+
+	if state.waitHooks(10 * time.Millisecond) {
+		t.Error("waitHooks() returned before the command finished")
+	}
+	close(release)
+	if !state.waitHooks(time.Second) {
+		t.Error("waitHooks() timed out after the command finished")
+	}
+}
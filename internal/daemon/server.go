@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"credctl/internal/clock"
+)
+
+// shutdownTimeout bounds how long shutdown waits for in-flight requests
+const shutdownTimeout = 30 * time.Second
+
+// connHandler handles a single client connection
+type connHandler func(conn net.Conn, readOnly bool)
+
+// server accepts connections on the daemon sockets and tracks in-flight handlers
+// so shutdown can let them finish before the sockets are removed
+type server struct {
+	handle connHandler
+
+	mu        sync.Mutex
+	closing   bool
+	listeners []net.Listener
+	active    sync.WaitGroup
+
+	// Activity seen by the idle watchdog
+	inFlight     atomic.Int64 // Connections being handled
+	lastActivity atomic.Int64 // When a connection last started or finished (unix nanoseconds)
+}
+
+func newServer(handle connHandler) *server {
+	s := &server{handle: handle}
+	s.touch()
+	return s
+}
+
+// touch records activity now
+func (s *server) touch() {
+	s.lastActivity.Store(clock.Now().UnixNano())
+}
+
+// idleFor returns how long the server has been without requests at now, or 0
+// while one is in flight
+func (s *server) idleFor(now time.Time) time.Duration {
+	if s.inFlight.Load() > 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, s.lastActivity.Load()))
+}
+
+// serve accepts connections on l until the listener is closed
+func (s *server) serve(l net.Listener, readOnly bool) {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		_ = l.Close()
+		return
+	}
+	s.listeners = append(s.listeners, l)
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("error accepting connection: %v", err)
+			continue
+		}
+
+		// Register the handler under the lock so shutdown never waits on a
+		// WaitGroup that is still growing
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.active.Add(1)
+		s.mu.Unlock()
+
+		s.inFlight.Add(1)
+		s.touch()
+		go func() {
+			defer s.active.Done()
+			defer func() {
+				s.touch()
+				s.inFlight.Add(-1)
+			}()
+			s.handle(conn, readOnly)
+		}()
+	}
+}
+
+// shutdown stops accepting new connections and waits up to timeout for
+// in-flight requests to finish. It returns false if the timeout expired.
+func (s *server) shutdown(timeout time.Duration) bool {
+	s.mu.Lock()
+	s.closing = true
+	for _, l := range s.listeners {
+		_ = l.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
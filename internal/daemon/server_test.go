@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listenUnix creates a listener on a short socket path (sun_path is limited to ~100 bytes)
+func listenUnix(t *testing.T) (net.Listener, string) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "credctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "test.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, path
+}
+
+func TestShutdownWaitsForInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	srv := newServer(func(conn net.Conn, readOnly bool) {
+		defer func() { _ = conn.Close() }()
+		close(started)
+		<-release
+		_, _ = conn.Write([]byte("done\n"))
+	})
+
+	l, path := listenUnix(t)
+	go srv.serve(l, true)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	<-started
+
+	shutdownDone := make(chan bool)
+	go func() { shutdownDone <- srv.shutdown(5 * time.Second) }()
+
+	// Shutdown must not complete while the handler is still running
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown returned before the in-flight request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// New connections are refused once shutdown has started
+	if c, err := net.Dial("unix", path); err == nil {
+		_ = c.Close()
+		t.Error("expected new connections to be refused during shutdown")
+	}
+
+	close(release)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if line != "done\n" {
+		t.Errorf("expected complete response, got %q", line)
+	}
+
+	if !<-shutdownDone {
+		t.Error("expected shutdown to finish before the timeout")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	srv := newServer(func(conn net.Conn, readOnly bool) {
+		defer func() { _ = conn.Close() }()
+		close(started)
+		<-release
+	})
+
+	l, path := listenUnix(t)
+	go srv.serve(l, false)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	<-started
+
+	if srv.shutdown(50 * time.Millisecond) {
+		t.Error("expected shutdown to report a timeout")
+	}
+}
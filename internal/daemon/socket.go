@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// Environment variables that widen access to the read-only socket, so that
+// e.g. a service account in a shared group can read credentials
+const (
+	ReadOnlySocketModeEnvVar  = "CREDCTL_READONLY_SOCK_MODE"  // Octal permissions (default 0600, at most 0660)
+	ReadOnlySocketGroupEnvVar = "CREDCTL_READONLY_SOCK_GROUP" // Group name or ID owning the socket
+)
+
+// Socket permissions
+const (
+	adminSocketMode    os.FileMode = 0600 // The admin socket is never shared
+	readOnlySocketMode os.FileMode = 0600
+	maxSocketMode      os.FileMode = 0660 // Sockets are never opened to other users
+)
+
+// socketPerms are the permissions applied to a listening socket
+type socketPerms struct {
+	mode  os.FileMode
+	group string // Group name or ID; empty keeps the daemon's group
+}
+
+// readOnlySocketPerms returns the read-only socket permissions configured
+// through the environment
+func readOnlySocketPerms() (socketPerms, error) {
+	perms := socketPerms{
+		mode:  readOnlySocketMode,
+		group: os.Getenv(ReadOnlySocketGroupEnvVar),
+	}
+
+	if value := os.Getenv(ReadOnlySocketModeEnvVar); value != "" {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return perms, fmt.Errorf("invalid %s '%s': must be an octal mode such as 0660", ReadOnlySocketModeEnvVar, value)
+		}
+		perms.mode = os.FileMode(mode)
+		if perms.mode&^maxSocketMode != 0 || perms.mode&0600 != 0600 {
+			return perms, fmt.Errorf("invalid %s '%s': must keep owner read-write and grant at most 0660", ReadOnlySocketModeEnvVar, value)
+		}
+	}
+
+	return perms, nil
+}
+
+// listenSocket listens on a Unix socket at path, creating its directory if
+// needed, and applies perms to the socket file
+func listenSocket(path string, perms socketPerms) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applySocketPerms(path, perms); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// applySocketPerms sets the group (if any) and mode of a socket file
+func applySocketPerms(path string, perms socketPerms) error {
+	if perms.group != "" {
+		gid, err := lookupGroupID(perms.group)
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+
+	if err := os.Chmod(path, perms.mode); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return nil
+}
+
+// lookupGroupID resolves a group name or numeric ID
+func lookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up socket group '%s': %w", group, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
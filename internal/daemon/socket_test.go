@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestReadOnlySocketPerms(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		want        os.FileMode
+		shouldError bool
+	}{
+		{name: "default", want: 0600},
+		{name: "group read-write", mode: "0660", want: 0660},
+		{name: "without leading zero", mode: "640", want: 0640},
+		{name: "world access", mode: "0666", shouldError: true},
+		{name: "owner without write", mode: "0460", shouldError: true},
+		{name: "not octal", mode: "rw-rw----", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ReadOnlySocketModeEnvVar, tt.mode)
+			t.Setenv(ReadOnlySocketGroupEnvVar, "")
+
+			perms, err := readOnlySocketPerms()
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if perms.mode != tt.want {
+				t.Errorf("mode = %o, want %o", perms.mode, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenSocketAppliesPerms(t *testing.T) {
+	// Socket paths are limited to ~100 bytes, too short for some test temp dirs
+	dir, err := os.MkdirTemp("", "credctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	// The runtime directory is created on demand
+	path := filepath.Join(dir, "run", "readonly.sock")
+	listener, err := listenSocket(path, socketPerms{mode: 0660, group: strconv.Itoa(os.Getgid())})
+	if err != nil {
+		t.Fatalf("listenSocket() error: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket not created at the configured path: %v", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("expected a socket, got mode %v", info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != 0660 {
+		t.Errorf("socket permissions = %o, want 660", perm)
+	}
+	if gid := info.Sys().(*syscall.Stat_t).Gid; int(gid) != os.Getgid() {
+		t.Errorf("socket group = %d, want %d", gid, os.Getgid())
+	}
+
+	if _, err := listenSocket(filepath.Join(dir, "other.sock"), socketPerms{mode: 0600, group: "no-such-group-credctl"}); err == nil {
+		t.Error("expected an unknown group to be an error")
+	}
+}
@@ -1,16 +1,32 @@
 package daemon
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"reflect"
+	"sort"
 	"sync"
+	"time"
 
+	"credctl/internal/clock"
 	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
 )
 
+// tokenSweepInterval is how often the daemon sweeps the on-disk token cache
+const tokenSweepInterval = time.Hour
+
+// prefetchTimeout bounds each credential fetched at startup
+const prefetchTimeout = 60 * time.Second
+
 // State represents the daemon's in-memory state
 type State struct {
 	providers map[string]provider.Provider
 	mu        sync.RWMutex
+	hooks     sync.WaitGroup // On-error commands still running
 }
 
 // NewState creates a new daemon state and loads providers from disk
@@ -24,6 +40,14 @@ func NewState() (*State, error) {
 		return nil, err
 	}
 
+	// Providers consuming another provider's credential share the in-memory cache
+	provider.SetLookup(s.Get)
+
+	s.SweepTokenCache()
+
+	// Warm the cache in the background: startup doesn't wait for the network
+	go s.Prefetch()
+
 	return s, nil
 }
 
@@ -49,6 +73,65 @@ func (s *State) LoadAll() error {
 	return nil
 }
 
+// ReloadSummary describes what a Reload changed
+type ReloadSummary struct {
+	Added     []string
+	Removed   []string
+	Updated   []string
+	Unchanged []string
+	Failed    []string // Providers that failed to load; the in-memory version is kept
+}
+
+// Reload re-reads providers from disk, picking up files added, removed or
+// edited outside the daemon. Unchanged providers are kept as-is and updated
+// ones keep their cached tokens when the auth configuration is unchanged.
+func (s *State) Reload() (ReloadSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summary ReloadSummary
+
+	names, err := provider.List()
+	if err != nil {
+		return summary, err
+	}
+
+	onDisk := make(map[string]bool, len(names))
+	for _, name := range names {
+		onDisk[name] = true
+
+		loaded, err := provider.Load(name)
+		if err != nil {
+			log.Printf("reload: failed to load provider %s: %v", name, err)
+			summary.Failed = append(summary.Failed, name)
+			continue
+		}
+
+		existing, ok := s.providers[name]
+		switch {
+		case !ok:
+			summary.Added = append(summary.Added, name)
+		case existing.Type() == loaded.Type() && reflect.DeepEqual(existing.Metadata(), loaded.Metadata()):
+			summary.Unchanged = append(summary.Unchanged, name)
+			continue
+		default:
+			preserveTokens(existing, loaded)
+			summary.Updated = append(summary.Updated, name)
+		}
+		s.providers[name] = loaded
+	}
+
+	for name := range s.providers {
+		if !onDisk[name] {
+			delete(s.providers, name)
+			summary.Removed = append(summary.Removed, name)
+		}
+	}
+	sort.Strings(summary.Removed)
+
+	return summary, nil
+}
+
 // Add adds a provider to memory and persists it to disk
 func (s *State) Add(name string, prov provider.Provider, force bool) error {
 	s.mu.Lock()
@@ -62,21 +145,186 @@ func (s *State) Add(name string, prov provider.Provider, force bool) error {
 		return fmt.Errorf("provider '%s' already exists", name)
 	}
 
+	// An alias must not loop back to itself (a missing target is allowed, e.g.
+	// when importing an alias before the provider it stands for)
+	if _, ok := prov.(provider.AliasProvider); ok {
+		lookup := func(target string) (provider.Provider, error) {
+			if target == name {
+				return prov, nil
+			}
+			return s.getLocked(target)
+		}
+		if _, err := provider.ResolveAlias(prov, lookup); errors.Is(err, provider.ErrAliasCycle) {
+			return fmt.Errorf("invalid alias '%s': %w", name, err)
+		}
+	}
+
+	if err := s.checkTokenSourcesLocked(name, prov); err != nil {
+		return err
+	}
+
 	// Save to disk first
 	if err := provider.Save(name, prov); err != nil {
 		return err
 	}
 
+	// Keep cached tokens when re-adding with an unchanged auth configuration
+	if existing, ok := s.providers[name]; ok {
+		preserveTokens(existing, prov)
+	}
+
 	// Then update memory
 	s.providers[name] = prov
 	return nil
 }
 
-// Get retrieves a provider from memory
+// tokenSourceFields are the fields naming another provider whose credential
+// a provider consumes
+var tokenSourceFields = []string{provider.MetadataActorTokenSource, provider.MetadataSubjectTokenSource}
+
+// checkTokenSourcesLocked rejects a provider whose token source is itself,
+// directly or through aliases: fetching its credential would wait on itself.
+// s.mu must be held.
+func (s *State) checkTokenSourcesLocked(name string, prov provider.Provider) error {
+	lookup := func(target string) (provider.Provider, error) {
+		if target == name {
+			return prov, nil
+		}
+		return s.getLocked(target)
+	}
+
+	for _, field := range tokenSourceFields {
+		source := provider.GetStringOrDefault(prov.Metadata(), field, "")
+		if source == "" {
+			continue
+		}
+		sourceProv, err := lookup(source)
+		if err != nil {
+			// A missing source is allowed, as for aliases
+			continue
+		}
+		if resolved, err := provider.ResolveAlias(sourceProv, lookup); err == nil && resolved == prov {
+			return fmt.Errorf("invalid %s '%s': provider '%s' cannot use its own token", field, source, name)
+		}
+	}
+	return nil
+}
+
+// RotateSecret replaces the client secret of a provider, keeping its cached
+// tokens. With verify, new tokens are first requested with the new secret,
+// and the current secret is kept if that fails.
+func (s *State) RotateSecret(ctx context.Context, name, secret string, verify bool) error {
+	existing, err := s.GetUnresolved(name)
+	if err != nil {
+		return err
+	}
+	if alias, ok := existing.(provider.AliasProvider); ok {
+		return fmt.Errorf("provider '%s' is an alias: rotate the secret of '%s' instead", name, alias.AliasTarget())
+	}
+	if !hasField(existing.Schema(), provider.MetadataClientSecret) {
+		return fmt.Errorf("provider '%s' (type: %s) has no client secret", name, existing.Type())
+	}
+
+	metadata := maps.Clone(existing.Metadata())
+	metadata[provider.MetadataClientSecret] = secret
+	replacement, err := provider.FromMetadata(existing.Type(), metadata)
+	if err != nil {
+		return err
+	}
+	copyTokens(existing, replacement)
+
+	// Verify without holding the lock: other requests keep using the current secret
+	if verify {
+		verifier, ok := replacement.(provider.SecretVerifier)
+		if !ok {
+			return fmt.Errorf("provider '%s' (type: %s) can't verify a client secret", name, existing.Type())
+		}
+		if err := verifier.VerifyClientSecret(ctx); err != nil {
+			return fmt.Errorf("the new client secret was rejected, keeping the current one: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.providers[name]; ok && current != existing {
+		return fmt.Errorf("provider '%s' was modified during the rotation, try again", name)
+	}
+	if err := provider.Save(name, replacement); err != nil {
+		return err
+	}
+	s.providers[name] = replacement
+	return nil
+}
+
+// hasField reports whether a schema defines the named field
+func hasField(schema provider.Schema, name string) bool {
+	for _, field := range schema.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// preserveTokens copies cached tokens from an existing provider to its
+// replacement if the type and auth-relevant configuration are unchanged
+func preserveTokens(existing, replacement provider.Provider) {
+	if existing.Type() != replacement.Type() {
+		return
+	}
+	if provider.AuthConfigChanged(existing.Metadata(), replacement.Metadata()) {
+		return
+	}
+	copyTokens(existing, replacement)
+}
+
+// copyTokens copies the cached tokens of a provider to another one of the same type
+func copyTokens(existing, replacement provider.Provider) {
+	oldCache, ok := existing.(provider.TokenCacheProvider)
+	if !ok {
+		return
+	}
+	newCache, ok := replacement.(provider.TokenCacheProvider)
+	if !ok {
+		return
+	}
+
+	accessToken, refreshToken, expiresIn := oldCache.GetTokens()
+	if expiresIn <= 0 {
+		// Expired access token: keep only the refresh token
+		accessToken = ""
+	}
+	if accessToken == "" && refreshToken == "" {
+		return
+	}
+	newCache.SetTokens(accessToken, refreshToken, expiresIn)
+}
+
+// Get retrieves a provider from memory, resolving aliases to the provider
+// they stand for
 func (s *State) Get(name string) (provider.Provider, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	prov, err := s.getLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	return provider.ResolveAlias(prov, s.getLocked)
+}
+
+// GetUnresolved retrieves a provider from memory as configured: an alias is
+// returned itself rather than its target
+func (s *State) GetUnresolved(name string) (provider.Provider, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.getLocked(name)
+}
+
+// getLocked retrieves a provider without resolving aliases; s.mu must be held
+func (s *State) getLocked(name string) (provider.Provider, error) {
 	prov, exists := s.providers[name]
 	if !exists {
 		// Try loading from disk as fallback (Load already sets the name)
@@ -112,3 +360,86 @@ func (s *State) List() map[string]string {
 	}
 	return result
 }
+
+// Prefetch fetches the credential of every provider configured with prefetch,
+// so the first request is served from the cache. Providers that may need the
+// user (e.g. a device flow login) are skipped. Failures are logged only.
+func (s *State) Prefetch() {
+	s.mu.RLock()
+	var targets []string
+	for name, prov := range s.providers {
+		if provider.IsPrefetch(prov.Metadata()) {
+			targets = append(targets, name)
+		}
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, name := range targets {
+		prov, err := s.Get(name)
+		if err != nil {
+			continue
+		}
+		if provider.CapabilitiesOf(prov).Interactive {
+			log.Printf("prefetch: skipping provider '%s': it may need the user to log in", name)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+			defer cancel()
+			if _, err := prov.Get(common.WithSource(ctx, name)); err != nil && !errors.Is(err, provider.ErrCredentialBundle) {
+				log.Printf("prefetch: provider '%s' failed: %v", name, err)
+				return
+			}
+			log.Printf("prefetch: provider '%s' ready", name)
+		}()
+	}
+	wg.Wait()
+}
+
+// SweepTokenCache removes token cache files no provider can use any more:
+// entries of deleted or reconfigured providers, and expired entries without
+// a refresh token. The sweep is skipped if a stored provider fails to load,
+// as its entry can't be told apart from an orphaned one.
+func (s *State) SweepTokenCache() {
+	names, err := provider.List()
+	if err != nil {
+		log.Printf("token cache sweep skipped: %v", err)
+		return
+	}
+
+	inUse := make(map[string]bool)
+	for _, name := range names {
+		// Aliases have no cache entry of their own, and may point nowhere
+		prov, err := s.GetUnresolved(name)
+		if err != nil {
+			log.Printf("token cache sweep skipped: provider '%s' failed to load: %v", name, err)
+			return
+		}
+		if cached, ok := prov.(provider.SharedCacheProvider); ok {
+			inUse[cached.SharedCacheKey()] = true
+		}
+	}
+
+	swept, err := common.SweepSharedCache(inUse, clock.Now())
+	if err != nil {
+		log.Printf("token cache sweep failed: %v", err)
+	}
+	for _, entry := range swept {
+		log.Printf("token cache sweep: removed %s (%s)", entry.Path, entry.Reason)
+	}
+}
+
+// sweepTokenCachePeriodically runs SweepTokenCache every interval, for the
+// lifetime of the daemon
+func (s *State) sweepTokenCachePeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.SweepTokenCache()
+	}
+}
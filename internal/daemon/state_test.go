@@ -0,0 +1,412 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"credctl/internal/paths"
+	"credctl/internal/protocol"
+	"credctl/internal/provider"
+	_ "credctl/internal/provider/alias"
+	"credctl/internal/provider/oauth2/common"
+)
+
+// tokenProvider is a test provider that caches tokens
+type tokenProvider struct {
+	config       map[string]any
+	accessToken  string
+	refreshToken string
+	expiresIn    int
+}
+
+func (p *tokenProvider) Type() string { return "state-test" }
+
+func (p *tokenProvider) Schema() provider.Schema { return provider.Schema{} }
+
+func (p *tokenProvider) Init(config map[string]any) error {
+	p.config = config
+	return nil
+}
+
+func (p *tokenProvider) Get(ctx context.Context) ([]byte, error) {
+	return []byte(p.accessToken), nil
+}
+
+func (p *tokenProvider) Metadata() map[string]any { return p.config }
+
+func (p *tokenProvider) SetTokens(accessToken, refreshToken string, expiresIn int) {
+	p.accessToken, p.refreshToken, p.expiresIn = accessToken, refreshToken, expiresIn
+}
+
+func (p *tokenProvider) GetTokens() (string, string, int) {
+	return p.accessToken, p.refreshToken, p.expiresIn
+}
+
+func TestStateAddPreservesTokens(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	tests := []struct {
+		name          string
+		newConfig     map[string]any
+		wantPreserved bool
+	}{
+		{
+			name: "non-auth field change keeps tokens",
+			newConfig: map[string]any{
+				provider.MetadataClientID: "client",
+				provider.MetadataTemplate: "export TOKEN={{.access_token}}",
+			},
+			wantPreserved: true,
+		},
+		{
+			name: "client_id change invalidates tokens",
+			newConfig: map[string]any{
+				provider.MetadataClientID: "other-client",
+			},
+			wantPreserved: false,
+		},
+		{
+			name: "endpoint change invalidates tokens",
+			newConfig: map[string]any{
+				provider.MetadataClientID:      "client",
+				provider.MetadataTokenEndpoint: "https://other.example.com/token",
+			},
+			wantPreserved: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := NewState()
+			if err != nil {
+				t.Fatalf("NewState() unexpected error: %v", err)
+			}
+
+			original := &tokenProvider{}
+			_ = original.Init(map[string]any{provider.MetadataClientID: "client"})
+			original.SetTokens("access", "refresh", 3600)
+
+			if err := state.Add("prov", original, true); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+
+			replacement := &tokenProvider{}
+			_ = replacement.Init(tt.newConfig)
+
+			if err := state.Add("prov", replacement, true); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+
+			got, err := state.Get("prov")
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+
+			accessToken, refreshToken, _ := got.(provider.TokenCacheProvider).GetTokens()
+			if tt.wantPreserved {
+				if accessToken != "access" || refreshToken != "refresh" {
+					t.Errorf("expected tokens to be preserved, got access=%q refresh=%q", accessToken, refreshToken)
+				}
+			} else if accessToken != "" || refreshToken != "" {
+				t.Errorf("expected tokens to be invalidated, got access=%q refresh=%q", accessToken, refreshToken)
+			}
+		})
+	}
+}
+
+// sharedCacheProvider is a test provider with an entry in the shared token cache
+type sharedCacheProvider struct {
+	tokenProvider
+}
+
+func (p *sharedCacheProvider) Type() string { return "shared-cache-test" }
+
+func (p *sharedCacheProvider) SharedCacheKey() string {
+	return common.SharedCacheKey(provider.GetStringOrDefault(p.config, provider.MetadataClientID, ""))
+}
+
+func TestNewStateSweepsTokenCache(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("shared-cache-test", func() provider.Provider {
+		return &sharedCacheProvider{}
+	})
+
+	for _, clientID := range []string{"kept", "expired"} {
+		prov := &sharedCacheProvider{}
+		_ = prov.Init(map[string]any{provider.MetadataClientID: clientID})
+		if err := provider.Save(clientID, prov); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+
+	entries := map[string]*common.TokenCache{
+		"kept":    {AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Now().Add(-time.Hour)},
+		"expired": {AccessToken: "access", ExpiresAt: time.Now().Add(-time.Hour)},
+		"deleted": {AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for clientID, tokens := range entries {
+		if err := common.StoreSharedTokens(common.SharedCacheKey(clientID), tokens); err != nil {
+			t.Fatalf("StoreSharedTokens() error: %v", err)
+		}
+	}
+
+	if _, err := NewState(); err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	tokensDir, err := paths.TokensDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for clientID, wantKept := range map[string]bool{"kept": true, "expired": false, "deleted": false} {
+		key := common.SharedCacheKey(clientID)
+		_, err := os.Stat(filepath.Join(tokensDir, key+".json"))
+		if kept := err == nil; kept != wantKept {
+			t.Errorf("%s entry kept = %v, want %v", clientID, kept, wantKept)
+		}
+	}
+
+	// The orphaned entry's lock file goes with it
+	if _, err := os.Stat(filepath.Join(tokensDir, common.SharedCacheKey("deleted")+".json.lock")); !os.IsNotExist(err) {
+		t.Error("expected the orphaned lock file to be removed")
+	}
+}
+
+// prefetchProvider reports every Get on fetched
+type prefetchProvider struct {
+	tokenProvider
+	fetched chan string
+}
+
+func (p *prefetchProvider) Type() string { return "prefetch-test" }
+
+func (p *prefetchProvider) Get(ctx context.Context) ([]byte, error) {
+	p.fetched <- provider.GetStringOrDefault(p.config, provider.MetadataClientID, "")
+	return []byte("token"), nil
+}
+
+func TestNewStatePrefetches(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	fetched := make(chan string, 2)
+	provider.Register("prefetch-test", func() provider.Provider {
+		return &prefetchProvider{fetched: fetched}
+	})
+
+	for name, prefetch := range map[string]bool{"warm": true, "cold": false} {
+		prov := &prefetchProvider{}
+		config := map[string]any{provider.MetadataClientID: name}
+		provider.DaemonOptions{Prefetch: prefetch}.AddToMetadata(config)
+		_ = prov.Init(config)
+		if err := provider.Save(name, prov); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+
+	if _, err := NewState(); err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+
+	select {
+	case name := <-fetched:
+		if name != "warm" {
+			t.Errorf("prefetched %q, want %q", name, "warm")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the prefetch provider to be fetched at startup")
+	}
+
+	select {
+	case name := <-fetched:
+		t.Errorf("provider %q fetched without prefetch", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStateResolvesAliases(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("state-test", func() provider.Provider {
+		return &tokenProvider{}
+	})
+
+	state := &State{providers: make(map[string]provider.Provider)}
+	db := &tokenProvider{}
+	_ = db.Init(map[string]any{provider.MetadataClientID: "client"})
+	if err := state.Add("db", db, false); err != nil {
+		t.Fatalf("Add(db) error: %v", err)
+	}
+
+	newAlias := func(target string) provider.Provider {
+		prov, err := provider.New("alias")
+		if err != nil {
+			t.Fatalf("New(alias) error: %v", err)
+		}
+		if err := prov.Init(map[string]any{provider.MetadataAliasTarget: target}); err != nil {
+			t.Fatalf("Init() error: %v", err)
+		}
+		return prov
+	}
+
+	if err := state.Add("prod-db", newAlias("db"), false); err != nil {
+		t.Fatalf("Add(prod-db) error: %v", err)
+	}
+	if err := state.Add("main-db", newAlias("prod-db"), false); err != nil {
+		t.Fatalf("Add(main-db) error: %v", err)
+	}
+
+	// Every name resolves to the same instance, so the token cache is shared
+	for _, name := range []string{"prod-db", "main-db"} {
+		prov, err := state.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%s) error: %v", name, err)
+		}
+		if prov != db {
+			t.Errorf("Get(%s) = %T, want the db provider", name, prov)
+		}
+	}
+	db.SetTokens("shared", "", 3600)
+	prov, _ := state.Get("main-db")
+	if token, _ := prov.Get(context.Background()); string(token) != "shared" {
+		t.Errorf("alias token = %q, want %q", token, "shared")
+	}
+
+	unresolved, err := state.GetUnresolved("prod-db")
+	if err != nil {
+		t.Fatalf("GetUnresolved() error: %v", err)
+	}
+	if unresolved.Type() != "alias" {
+		t.Errorf("GetUnresolved() type = %q, want alias", unresolved.Type())
+	}
+
+	// Pointing db back at one of its aliases would loop
+	if err := state.Add("db", newAlias("main-db"), true); !errors.Is(err, provider.ErrAliasCycle) {
+		t.Errorf("Add(db -> main-db) error = %v, want %v", err, provider.ErrAliasCycle)
+	}
+	if err := state.Add("self", newAlias("self"), false); !errors.Is(err, provider.ErrAliasCycle) {
+		t.Errorf("Add(self -> self) error = %v, want %v", err, provider.ErrAliasCycle)
+	}
+	if prov, err := state.Get("db"); err != nil || prov != db {
+		t.Errorf("Get(db) = %v, %v after a rejected alias, want the db provider", prov, err)
+	}
+}
+
+// rotateProvider has a client secret, which the token endpoint accepts unless it is "bad"
+type rotateProvider struct {
+	tokenProvider
+	verified int
+}
+
+func (p *rotateProvider) Type() string { return "rotate-test" }
+
+func (p *rotateProvider) Schema() provider.Schema {
+	return provider.Schema{Fields: []provider.FieldDef{
+		{Name: provider.MetadataClientSecret, Type: provider.FieldTypeString, Hidden: true},
+	}}
+}
+
+func (p *rotateProvider) VerifyClientSecret(ctx context.Context) error {
+	p.verified++
+	if provider.GetStringOrDefault(p.config, provider.MetadataClientSecret, "") == "bad" {
+		return errors.New("invalid_client")
+	}
+	p.accessToken = "verified-token"
+	return nil
+}
+
+func TestRotateSecret(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	provider.Register("rotate-test", func() provider.Provider {
+		return &rotateProvider{}
+	})
+
+	tests := []struct {
+		name       string
+		secret     string
+		verify     bool
+		wantErr    string
+		wantSecret string
+		wantToken  string
+	}{
+		{name: "verify then commit", secret: "new", verify: true, wantSecret: "new", wantToken: "verified-token"},
+		{name: "rollback when rejected", secret: "bad", verify: true, wantErr: "rejected", wantSecret: "old", wantToken: "cached-token"},
+		{name: "commit without verifying", secret: "bad", wantSecret: "bad", wantToken: "cached-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := NewState()
+			if err != nil {
+				t.Fatalf("NewState() unexpected error: %v", err)
+			}
+			prov := &rotateProvider{}
+			_ = prov.Init(map[string]any{provider.MetadataClientSecret: "old"})
+			if err := state.Add("svc", prov, true); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+			prov.SetTokens("cached-token", "refresh", 3600)
+
+			err = state.RotateSecret(context.Background(), "svc", tt.secret, tt.verify)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RotateSecret() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("RotateSecret() unexpected error: %v", err)
+			}
+
+			current, err := state.Get("svc")
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+			if got := provider.GetStringOrDefault(current.Metadata(), provider.MetadataClientSecret, ""); got != tt.wantSecret {
+				t.Errorf("in-memory secret = %q, want %q", got, tt.wantSecret)
+			}
+			if token, _, _ := current.(provider.TokenCacheProvider).GetTokens(); token != tt.wantToken {
+				t.Errorf("cached token = %q, want %q", token, tt.wantToken)
+			}
+
+			stored, err := provider.Load("svc")
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if got := provider.GetStringOrDefault(stored.Metadata(), provider.MetadataClientSecret, ""); got != tt.wantSecret {
+				t.Errorf("stored secret = %q, want %q", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestRotateSecretRequiresClientSecret(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	state, err := NewState()
+	if err != nil {
+		t.Fatalf("NewState() unexpected error: %v", err)
+	}
+	prov := &tokenProvider{}
+	_ = prov.Init(map[string]any{})
+	if err := state.Add("plain", prov, true); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	if err := state.RotateSecret(context.Background(), "plain", "new", false); err == nil || !strings.Contains(err.Error(), "has no client secret") {
+		t.Errorf("expected an error for a provider without a client secret, got %v", err)
+	}
+
+	resp := RotateSecret(state, protocol.RotateSecretPayload{Name: "plain", Secret: "new"}, true)
+	if resp.ErrorType != protocol.ErrorTypePermissionDenied {
+		t.Errorf("expected a permission denied error on the read-only socket, got status %q type %q", resp.Status, resp.ErrorType)
+	}
+}
@@ -0,0 +1,370 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"credctl/internal/paths"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// PassphraseEnvVar supplies the store passphrase without prompting
+const PassphraseEnvVar = "CREDCTL_PASSPHRASE"
+
+// magic prefixes every encrypted file, so plaintext files stay readable
+var magic = []byte("CREDCTL-ENC1\n")
+
+// checkPlaintext is sealed into the key file to detect a wrong passphrase
+var checkPlaintext = []byte("credctl")
+
+// Argon2id parameters (RFC 9106 second recommended option)
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	keySize      = 32 // AES-256
+	saltSize     = 16
+)
+
+var (
+	// ErrLocked is returned when the store is encrypted and no passphrase was given
+	ErrLocked = errors.New("credential store is encrypted: set " + PassphraseEnvVar + " or run interactively to enter the passphrase")
+
+	// ErrWrongPassphrase is returned when the passphrase does not match the store
+	ErrWrongPassphrase = errors.New("wrong passphrase for the credential store")
+)
+
+// keyFile is the on-disk format of the key derivation parameters
+// The key itself is never stored
+type keyFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Check   []byte `json:"check"`
+}
+
+var (
+	mu      sync.Mutex
+	key     []byte
+	keyPath string // Key file the cached key was derived for
+)
+
+// Enabled reports whether the credential store is encrypted
+func Enabled() bool {
+	path, err := paths.KeyFile()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// IsEncrypted reports whether data was produced by Seal
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Setup enables encryption with a new passphrase and unlocks the store
+func Setup(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+
+	path, err := paths.KeyFile()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("credential store is already encrypted")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	kf := keyFile{
+		Version: 1,
+		KDF:     "argon2id",
+		Salt:    salt,
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+	}
+	derived := deriveKey(passphrase, kf)
+
+	kf.Check, err = seal(derived, checkPlaintext)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key, keyPath = derived, path
+	return nil
+}
+
+// Unlock derives the store key from passphrase and keeps it in memory
+func Unlock(passphrase string) error {
+	path, err := paths.KeyFile()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+	if kf.KDF != "argon2id" {
+		return fmt.Errorf("unsupported key derivation '%s' in %s", kf.KDF, path)
+	}
+
+	derived := deriveKey(passphrase, kf)
+	if check, err := open(derived, kf.Check); err != nil || !bytes.Equal(check, checkPlaintext) {
+		return ErrWrongPassphrase
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key, keyPath = derived, path
+	return nil
+}
+
+// Lock forgets the in-memory key
+func Lock() {
+	mu.Lock()
+	defer mu.Unlock()
+	key, keyPath = nil, ""
+}
+
+// UnlockInteractive unlocks an encrypted store from CREDCTL_PASSPHRASE, or by
+// prompting when stdin is a terminal. It does nothing if the store is not
+// encrypted or already unlocked.
+func UnlockInteractive() error {
+	if !Enabled() {
+		return nil
+	}
+	if _, err := currentKey(); err == nil {
+		return nil
+	}
+
+	passphrase := os.Getenv(PassphraseEnvVar)
+	if passphrase == "" {
+		var err error
+		passphrase, err = ReadPassphrase("Passphrase for the credential store: ")
+		if err != nil {
+			return err
+		}
+	}
+	return Unlock(passphrase)
+}
+
+// ReadPassphrase prompts on stderr and reads a passphrase from the terminal without echo
+func ReadPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", ErrLocked
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// Seal encrypts data if the store is encrypted, and returns it unchanged otherwise
+func Seal(data []byte) ([]byte, error) {
+	if !Enabled() {
+		return data, nil
+	}
+	k, err := currentKey()
+	if err != nil {
+		return nil, err
+	}
+	return seal(k, data)
+}
+
+// Open decrypts data produced by Seal; plaintext data is returned unchanged
+func Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	k, err := currentKey()
+	if err != nil {
+		return nil, err
+	}
+	return open(k, data)
+}
+
+// EncryptDir encrypts every plaintext .json file in dir in place and returns
+// how many files were converted. Files are replaced atomically.
+func EncryptDir(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	converted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return converted, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if IsEncrypted(data) {
+			continue
+		}
+
+		sealed, err := Seal(data)
+		if err != nil {
+			return converted, err
+		}
+		if err := writeAtomic(path, sealed); err != nil {
+			return converted, err
+		}
+		converted++
+	}
+
+	return converted, nil
+}
+
+// currentKey returns the unlocked key, unlocking from CREDCTL_PASSPHRASE if needed
+func currentKey() ([]byte, error) {
+	path, err := paths.KeyFile()
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	k, kp := key, keyPath
+	mu.Unlock()
+	if k != nil && kp == path {
+		return k, nil
+	}
+
+	passphrase := os.Getenv(PassphraseEnvVar)
+	if passphrase == "" {
+		return nil, ErrLocked
+	}
+	if err := Unlock(passphrase); err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return key, nil
+}
+
+func deriveKey(passphrase string, kf keyFile) []byte {
+	return argon2.IDKey([]byte(passphrase), kf.Salt, kf.Time, kf.Memory, kf.Threads, keySize)
+}
+
+// seal encrypts with AES-256-GCM: magic || nonce || ciphertext
+func seal(k, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append([]byte{}, magic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, magic), nil
+}
+
+func open(k, data []byte) ([]byte, error) {
+	gcm, err := newGCM(k)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, magic)
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: data is corrupt or was encrypted with another passphrase")
+	}
+	return plaintext, nil
+}
+
+func newGCM(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// writeAtomic replaces path with data (mode 0600) via a temp file and rename
+func writeAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package encryption
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"credctl/internal/paths"
+)
+
+// setupStore points credctl at a fresh home and forgets any cached key
+func setupStore(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnvVar, home)
+	t.Setenv(PassphraseEnvVar, "")
+	Lock()
+	t.Cleanup(Lock)
+	return home
+}
+
+func TestPlaintextStoreIsUnchanged(t *testing.T) {
+	setupStore(t)
+
+	if Enabled() {
+		t.Fatal("expected encryption to be disabled by default")
+	}
+
+	data := []byte(`{"name":"x"}`)
+	sealed, err := Seal(data)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if string(sealed) != string(data) {
+		t.Errorf("expected plaintext to pass through, got %q", sealed)
+	}
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	setupStore(t)
+
+	if err := Setup("correct horse"); err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	if err := Setup("again"); err == nil {
+		t.Error("expected error when the store is already encrypted")
+	}
+
+	data := []byte(`{"client_secret":"s3cr3t"}`)
+	sealed, err := Seal(data)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if !IsEncrypted(sealed) {
+		t.Fatal("expected sealed data to be marked as encrypted")
+	}
+
+	opened, err := Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if string(opened) != string(data) {
+		t.Errorf("Open() = %q, want %q", opened, data)
+	}
+
+	// Plaintext written before encryption was enabled stays readable
+	if opened, err := Open([]byte("plain")); err != nil || string(opened) != "plain" {
+		t.Errorf("Open(plaintext) = %q, %v", opened, err)
+	}
+
+	// Tampering is detected
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := Open(sealed); err == nil {
+		t.Error("expected error for tampered data")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	setupStore(t)
+
+	if err := Setup("pw"); err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	sealed, err := Seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	Lock()
+
+	if _, err := Open(sealed); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if _, err := Seal([]byte("x")); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected Seal() to refuse writing plaintext to a locked store, got %v", err)
+	}
+
+	if err := Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	// The environment unlocks lazily
+	t.Setenv(PassphraseEnvVar, "pw")
+	opened, err := Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("Open() = %q, want %q", opened, "secret")
+	}
+}
+
+func TestEncryptDir(t *testing.T) {
+	home := setupStore(t)
+
+	dir := filepath.Join(home, "providers")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.json":   `{"name":"a"}`,
+		"b.json":   `{"name":"b"}`,
+		"note.txt": "not a provider",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Setup("pw"); err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+
+	converted, err := EncryptDir(dir)
+	if err != nil {
+		t.Fatalf("EncryptDir() error: %v", err)
+	}
+	if converted != 2 {
+		t.Errorf("expected 2 files converted, got %d", converted)
+	}
+
+	for _, name := range []string{"a.json", "b.json"} {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(data) {
+			t.Errorf("expected %s to be encrypted", name)
+		}
+		opened, err := Open(data)
+		if err != nil || string(opened) != files[name] {
+			t.Errorf("Open(%s) = %q, %v", name, opened, err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("expected %s to keep 0600 permissions", name)
+		}
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "note.txt")); string(data) != files["note.txt"] {
+		t.Error("expected non-JSON files to be left alone")
+	}
+
+	// Running again is a no-op
+	if converted, err := EncryptDir(dir); err != nil || converted != 0 {
+		t.Errorf("expected second run to convert nothing, got %d, %v", converted, err)
+	}
+}
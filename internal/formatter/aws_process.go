@@ -0,0 +1,94 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
+)
+
+// AWSProcessFormatter prints the JSON object expected from an AWS SDK
+// credential_process command, built from structured AWS credential fields
+type AWSProcessFormatter struct{}
+
+// awsProcessCredentials is the credential_process output (Version 1)
+type awsProcessCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+// Field names accepted for each key, in order of preference: credctl's
+// snake_case names, the AWS config/env names and the STS response names
+var (
+	awsAccessKeyIDFields     = []string{"access_key_id", "aws_access_key_id", "AccessKeyId"}
+	awsSecretAccessKeyFields = []string{"secret_access_key", "aws_secret_access_key", "SecretAccessKey"}
+	awsSessionTokenFields    = []string{"session_token", "aws_session_token", "SessionToken"}
+)
+
+func init() {
+	RegisterFormatter("aws-process", func() Formatter {
+		return &AWSProcessFormatter{}
+	})
+}
+
+func (f *AWSProcessFormatter) Name() string {
+	return "aws-process"
+}
+
+// Format accepts a JSON object of credential fields
+func (f *AWSProcessFormatter) Format(output []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(output)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("aws-process format requires access_key_id and secret_access_key fields")
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON credentials: %w", err)
+	}
+	return f.FormatFields(fields)
+}
+
+// FormatFields maps the access key, secret key, session token and expiry
+// (expires_at, exp, expires_in or Expiration) to the credential_process keys
+// This implements the FieldsFormatter interface
+func (f *AWSProcessFormatter) FormatFields(fields map[string]string) ([]byte, error) {
+	creds := awsProcessCredentials{
+		Version:         1,
+		AccessKeyID:     firstField(fields, awsAccessKeyIDFields),
+		SecretAccessKey: firstField(fields, awsSecretAccessKeyFields),
+		SessionToken:    firstField(fields, awsSessionTokenFields),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws-process format requires access_key_id and secret_access_key fields")
+	}
+
+	// Without an expiration, the SDK treats the credentials as long-lived
+	if expiresAt, ok := credentials.New(fields).Expiry(clock.Now()); ok {
+		creds.Expiration = expiresAt.UTC().Format(time.RFC3339)
+	} else if expiration, err := time.Parse(time.RFC3339, fields["Expiration"]); err == nil {
+		creds.Expiration = expiration.UTC().Format(time.RFC3339)
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	return data, nil
+}
+
+// firstField returns the value of the first of names present in fields
+func firstField(fields map[string]string, names []string) string {
+	for _, name := range names {
+		if value := fields[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package formatter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"credctl/internal/clock"
+)
+
+func TestAWSProcessFormatterFields(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	defer clock.Set(fake)()
+
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		want        string
+		shouldError bool
+	}{
+		{
+			name: "temporary credentials",
+			fields: map[string]string{
+				"access_key_id":     "ASIAEXAMPLE",
+				"secret_access_key": "secret",
+				"session_token":     "session",
+				"expires_at":        "2026-10-15T13:00:00Z",
+				"expires_in":        "3600",
+			},
+			want: `{"Version":1,"AccessKeyId":"ASIAEXAMPLE","SecretAccessKey":"secret","SessionToken":"session","Expiration":"2026-10-15T13:00:00Z"}`,
+		},
+		{
+			name: "long-lived keys omit the session token and expiration",
+			fields: map[string]string{
+				"aws_access_key_id":     "AKIAEXAMPLE",
+				"aws_secret_access_key": "secret",
+			},
+			want: `{"Version":1,"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}`,
+		},
+		{
+			name: "STS response names",
+			fields: map[string]string{
+				"AccessKeyId":     "ASIAEXAMPLE",
+				"SecretAccessKey": "secret",
+				"SessionToken":    "session",
+				"Expiration":      "2026-10-15T14:00:00+02:00",
+			},
+			want: `{"Version":1,"AccessKeyId":"ASIAEXAMPLE","SecretAccessKey":"secret","SessionToken":"session","Expiration":"2026-10-15T12:00:00Z"}`,
+		},
+		{
+			name:   "expiration from expires_in",
+			fields: map[string]string{"access_key_id": "ASIAEXAMPLE", "secret_access_key": "secret", "expires_in": "900"},
+			want:   `{"Version":1,"AccessKeyId":"ASIAEXAMPLE","SecretAccessKey":"secret","Expiration":"2026-10-15T12:15:00Z"}`,
+		},
+		{
+			name:        "missing secret key",
+			fields:      map[string]string{"access_key_id": "ASIAEXAMPLE"},
+			shouldError: true,
+		},
+		{
+			name:        "not AWS credentials",
+			fields:      map[string]string{"access_token": "abc"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &AWSProcessFormatter{}
+			result, err := f.FormatFields(tt.fields)
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error, got %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("FormatFields() = %s, want %s", result, tt.want)
+			}
+
+			// The SDKs require Version 1 as a number
+			var got map[string]any
+			if err := json.Unmarshal(result, &got); err != nil {
+				t.Fatalf("output is not a JSON object: %v", err)
+			}
+			if got["Version"] != float64(1) {
+				t.Errorf("Version = %v, want 1", got["Version"])
+			}
+		})
+	}
+}
+
+func TestAWSProcessFormatterJSON(t *testing.T) {
+	f := &AWSProcessFormatter{}
+
+	result, err := f.Format([]byte(`{"access_key_id":"AKIAEXAMPLE","secret_access_key":"secret"}` + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(result) != `{"Version":1,"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}` {
+		t.Errorf("Format() = %s", result)
+	}
+
+	if _, err := f.Format([]byte("AKIAEXAMPLE")); err == nil {
+		t.Error("expected error for raw output")
+	}
+}
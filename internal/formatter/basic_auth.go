@@ -0,0 +1,64 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// BasicAuthFormatter builds an HTTP Basic Authorization header value
+// ("Basic <base64(username:password)>") from username/password credentials
+type BasicAuthFormatter struct{}
+
+func init() {
+	RegisterFormatter("basic-auth", func() Formatter {
+		return &BasicAuthFormatter{}
+	})
+}
+
+func (f *BasicAuthFormatter) Name() string {
+	return "basic-auth"
+}
+
+// Format accepts either a JSON object with "username" and "password" keys
+// or a raw "username:password" string
+func (f *BasicAuthFormatter) Format(output []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(output)
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]string
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON credentials: %w", err)
+		}
+		return f.FormatFields(fields)
+	}
+
+	if !bytes.Contains(trimmed, []byte(":")) {
+		return nil, fmt.Errorf("basic-auth format requires 'username:password' output or username and password fields")
+	}
+
+	return basicAuth(string(trimmed)), nil
+}
+
+// FormatFields builds the header from structured username and password fields
+// This implements the FieldsFormatter interface
+func (f *BasicAuthFormatter) FormatFields(fields map[string]string) ([]byte, error) {
+	username, hasUsername := fields["username"]
+	password, hasPassword := fields["password"]
+
+	switch {
+	case !hasUsername && !hasPassword:
+		return nil, fmt.Errorf("basic-auth format requires username and password fields")
+	case !hasUsername:
+		return nil, fmt.Errorf("basic-auth format requires a username field")
+	case !hasPassword:
+		return nil, fmt.Errorf("basic-auth format requires a password field")
+	}
+
+	return basicAuth(username + ":" + password), nil
+}
+
+func basicAuth(userPass string) []byte {
+	return []byte("Basic " + base64.StdEncoding.EncodeToString([]byte(userPass)))
+}
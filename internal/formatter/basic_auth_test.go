@@ -0,0 +1,127 @@
+package formatter
+
+import (
+	"testing"
+)
+
+func TestBasicAuthFormatter(t *testing.T) {
+	// base64("alice:s3cret") = YWxpY2U6czNjcmV0
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "raw colon-delimited input",
+			input:    "alice:s3cret",
+			expected: "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:     "raw input with trailing newline",
+			input:    "alice:s3cret\n",
+			expected: "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:     "json input",
+			input:    `{"username": "alice", "password": "s3cret"}`,
+			expected: "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:        "json missing password",
+			input:       `{"username": "alice"}`,
+			shouldError: true,
+		},
+		{
+			name:        "raw input without colon",
+			input:       "just-a-token",
+			shouldError: true,
+		},
+	}
+
+	f := &BasicAuthFormatter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := f.Format([]byte(tt.input))
+
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, string(result))
+			}
+		})
+	}
+}
+
+func TestBasicAuthFormatterFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "username and password",
+			fields:   map[string]string{"username": "alice", "password": "s3cret", "other": "ignored"},
+			expected: "Basic YWxpY2U6czNjcmV0",
+		},
+		{
+			name:        "missing username",
+			fields:      map[string]string{"password": "s3cret"},
+			shouldError: true,
+		},
+		{
+			name:        "missing password",
+			fields:      map[string]string{"username": "alice"},
+			shouldError: true,
+		},
+		{
+			name:        "no fields",
+			fields:      map[string]string{"raw": "alice:s3cret"},
+			shouldError: true,
+		},
+	}
+
+	f := &BasicAuthFormatter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := f.FormatFields(tt.fields)
+
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, string(result))
+			}
+		})
+	}
+}
+
+func TestBasicAuthFormatterRegistered(t *testing.T) {
+	found := false
+	for _, name := range List() {
+		if name == "basic-auth" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("basic-auth formatter not registered, got %v", List())
+	}
+}
@@ -0,0 +1,148 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvFormatter prints credentials as shell export statements, one per
+// structured field (access_token → ACCESS_TOKEN), or TOKEN for raw output
+type EnvFormatter struct {
+	// Prefix is prepended to every variable name (MYAPP_ → MYAPP_ACCESS_TOKEN)
+	Prefix string
+}
+
+// envRawVariable names the variable holding a raw (unstructured) credential
+const envRawVariable = "TOKEN"
+
+// envNamePattern matches a portable shell variable name
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvPrefix checks that prefix can start a shell variable name
+func ValidateEnvPrefix(prefix string) error {
+	if prefix != "" && !envNamePattern.MatchString(prefix) {
+		return fmt.Errorf("invalid env prefix '%s': must start with a letter or _ and contain only letters, digits and _", prefix)
+	}
+	return nil
+}
+
+// EnvPrefixFor returns a variable prefix derived from a provider name
+// (prod-db → PROD_DB_), which keeps the exports of several providers apart
+func EnvPrefixFor(name string) string {
+	return envVariableName(name) + "_"
+}
+
+func init() {
+	RegisterFormatter("env", func() Formatter {
+		return &EnvFormatter{}
+	})
+}
+
+func (f *EnvFormatter) Name() string {
+	return "env"
+}
+
+// Format accepts either a JSON object, exported field by field, or a raw
+// token, exported as TOKEN
+func (f *EnvFormatter) Format(output []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(output)
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]any
+		if err := json.Unmarshal(trimmed, &fields); err == nil {
+			values := make(map[string]string, len(fields))
+			for key, value := range fields {
+				if s, ok := value.(string); ok {
+					values[key] = s
+					continue
+				}
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode field %s: %w", key, err)
+				}
+				values[key] = string(encoded)
+			}
+			return f.FormatFields(values)
+		}
+	}
+
+	name, err := f.variableName(envRawVariable)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(exportLine(name, string(trimmed))), nil
+}
+
+// FormatFields exports every structured field, sorted by variable name
+// This implements the FieldsFormatter interface
+func (f *EnvFormatter) FormatFields(fields map[string]string) ([]byte, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("env format requires at least one credential field")
+	}
+
+	values := make(map[string]string, len(fields))
+	for key, value := range fields {
+		name, err := f.variableName(key)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := values[name]; ok && existing != value {
+			return nil, fmt.Errorf("fields map to the same variable %s", name)
+		}
+		values[name] = value
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	for _, name := range names {
+		buf.WriteString(exportLine(name, values[name]))
+	}
+	return []byte(buf.String()), nil
+}
+
+// variableName returns the prefixed variable name for a field
+func (f *EnvFormatter) variableName(field string) (string, error) {
+	if err := ValidateEnvPrefix(f.Prefix); err != nil {
+		return "", err
+	}
+
+	name := envVariableName(f.Prefix + field)
+	if !envNamePattern.MatchString(name) {
+		return "", fmt.Errorf("field %s does not map to a valid variable name", field)
+	}
+	return name, nil
+}
+
+// envVariableName turns a field name into a shell variable name:
+// upper case, with characters other than letters, digits and _ replaced by _
+func envVariableName(field string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, field)
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// exportLine renders an export statement with the value single-quoted
+func exportLine(name, value string) string {
+	return "export " + name + "='" + strings.ReplaceAll(value, "'", `'\''`) + "'\n"
+}
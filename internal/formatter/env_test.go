@@ -0,0 +1,179 @@
+package formatter
+
+import (
+	"testing"
+)
+
+func TestEnvFormatterFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name: "oauth2 tokens",
+			fields: map[string]string{
+				"access_token":  "abc",
+				"refresh_token": "def",
+			},
+			expected: "export ACCESS_TOKEN='abc'\nexport REFRESH_TOKEN='def'\n",
+		},
+		{
+			name:     "names are sanitized",
+			fields:   map[string]string{"api-key": "k", "2fa.code": "123"},
+			expected: "export API_KEY='k'\nexport _2FA_CODE='123'\n",
+		},
+		{
+			name:     "single quotes are escaped",
+			fields:   map[string]string{"password": "it's"},
+			expected: "export PASSWORD='it'\\''s'\n",
+		},
+		{
+			name:        "colliding names",
+			fields:      map[string]string{"api-key": "a", "api_key": "b"},
+			shouldError: true,
+		},
+		{
+			name:        "no fields",
+			fields:      map[string]string{},
+			shouldError: true,
+		},
+	}
+
+	f := &EnvFormatter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.FormatFields(tt.fields)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("FormatFields() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvFormatterPrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		fields      map[string]string
+		raw         string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "prefixed fields",
+			prefix:   "MYAPP_",
+			fields:   map[string]string{"access_token": "abc", "expires_in": "3600"},
+			expected: "export MYAPP_ACCESS_TOKEN='abc'\nexport MYAPP_EXPIRES_IN='3600'\n",
+		},
+		{
+			name:     "prefix is upper-cased",
+			prefix:   "myapp_",
+			fields:   map[string]string{"token": "abc"},
+			expected: "export MYAPP_TOKEN='abc'\n",
+		},
+		{
+			name:     "field starting with a digit keeps the prefix",
+			prefix:   "APP_",
+			fields:   map[string]string{"2fa.code": "123"},
+			expected: "export APP_2FA_CODE='123'\n",
+		},
+		{
+			name:     "raw token",
+			prefix:   "GH_",
+			raw:      "gho_abc123\n",
+			expected: "export GH_TOKEN='gho_abc123'\n",
+		},
+		{
+			name:     "prefix derived from a provider name",
+			prefix:   EnvPrefixFor("prod-db"),
+			raw:      "s3cret",
+			expected: "export PROD_DB_TOKEN='s3cret'\n",
+		},
+		{
+			name:        "prefix starting with a digit",
+			prefix:      "1APP_",
+			fields:      map[string]string{"token": "abc"},
+			shouldError: true,
+		},
+		{
+			name:        "prefix with a dash",
+			prefix:      "MY-APP_",
+			raw:         "abc",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &EnvFormatter{Prefix: tt.prefix}
+
+			var got []byte
+			var err error
+			if tt.fields != nil {
+				got, err = f.FormatFields(tt.fields)
+			} else {
+				got, err = f.Format([]byte(tt.raw))
+			}
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvFormatterRaw(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "raw token",
+			input:    "gho_abc123\n",
+			expected: "export TOKEN='gho_abc123'\n",
+		},
+		{
+			name:     "json object",
+			input:    `{"username": "alice", "port": 5432}`,
+			expected: "export PORT='5432'\nexport USERNAME='alice'\n",
+		},
+		{
+			name:     "invalid json is a raw value",
+			input:    "{not json",
+			expected: "export TOKEN='{not json'\n",
+		},
+	}
+
+	f := &EnvFormatter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Format([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Format() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
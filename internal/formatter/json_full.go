@@ -0,0 +1,94 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
+)
+
+// JSONFullFormatter prints a token object with a stable schema
+// (access_token, token_type, expires_at, expires_in, refresh_token) built from
+// the structured credentials, so sidecars parse the same keys whatever the
+// provider. Fields the credential doesn't have are omitted.
+type JSONFullFormatter struct {
+	// IncludeRefresh emits the refresh token, which is left out by default
+	IncludeRefresh bool
+}
+
+// fullToken is the object printed by JSONFullFormatter
+type fullToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	ExpiresIn    *int   `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+func init() {
+	RegisterFormatter("json-full", func() Formatter {
+		return &JSONFullFormatter{}
+	})
+}
+
+func (f *JSONFullFormatter) Name() string {
+	return "json-full"
+}
+
+// Format accepts either a JSON object of credential fields or a raw token
+func (f *JSONFullFormatter) Format(output []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(output)
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]string
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON credentials: %w", err)
+		}
+		return f.FormatFields(fields)
+	}
+
+	if len(trimmed) == 0 || bytes.ContainsAny(trimmed, " \t\r\n") {
+		return nil, fmt.Errorf("json-full format requires a single-line token or an access_token field")
+	}
+	return f.FormatFields(map[string]string{"token": string(trimmed)})
+}
+
+// FormatFields builds the token object from an access_token (or token) field,
+// its token_type (Bearer by default), the normalized expiry and, with
+// IncludeRefresh, the refresh_token
+// This implements the FieldsFormatter interface
+func (f *JSONFullFormatter) FormatFields(fields map[string]string) ([]byte, error) {
+	token := fullToken{AccessToken: fields["access_token"], TokenType: fields["token_type"]}
+	if token.AccessToken == "" {
+		token.AccessToken = fields["token"]
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("json-full format requires an access_token or token field")
+	}
+	if token.TokenType == "" {
+		token.TokenType = "Bearer"
+	}
+
+	now := clock.Now()
+	if expiresAt, ok := credentials.New(fields).Expiry(now); ok {
+		remaining := int(expiresAt.Sub(now).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		token.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		token.ExpiresIn = &remaining
+	}
+
+	if f.IncludeRefresh {
+		token.RefreshToken = fields["refresh_token"]
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token: %w", err)
+	}
+	return data, nil
+}
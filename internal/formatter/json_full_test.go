@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJSONFullFormatterFields(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	tests := []struct {
+		name           string
+		fields         map[string]string
+		includeRefresh bool
+		wantKeys       []string
+		want           map[string]any
+		shouldError    bool
+	}{
+		{
+			name: "oauth2 credentials",
+			fields: map[string]string{
+				"access_token":  "abc",
+				"token_type":    "DPoP",
+				"expires_at":    expiresAt,
+				"expires_in":    "3600",
+				"refresh_token": "refresh",
+				"id_token":      "id",
+				"authorization": "DPoP abc",
+			},
+			wantKeys: []string{"access_token", "expires_at", "expires_in", "token_type"},
+			want:     map[string]any{"access_token": "abc", "token_type": "DPoP", "expires_at": expiresAt},
+		},
+		{
+			name: "refresh token included",
+			fields: map[string]string{
+				"access_token":  "abc",
+				"expires_at":    expiresAt,
+				"refresh_token": "refresh",
+			},
+			includeRefresh: true,
+			wantKeys:       []string{"access_token", "expires_at", "expires_in", "refresh_token", "token_type"},
+			want:           map[string]any{"refresh_token": "refresh"},
+		},
+		{
+			name:     "token without expiry",
+			fields:   map[string]string{"token": "ghp_abc"},
+			wantKeys: []string{"access_token", "token_type"},
+			want:     map[string]any{"access_token": "ghp_abc", "token_type": "Bearer"},
+		},
+		{
+			name:           "missing refresh token is omitted",
+			fields:         map[string]string{"access_token": "abc"},
+			includeRefresh: true,
+			wantKeys:       []string{"access_token", "token_type"},
+		},
+		{
+			name:     "expired token",
+			fields:   map[string]string{"access_token": "abc", "expires_at": "2020-01-01T00:00:00Z"},
+			wantKeys: []string{"access_token", "expires_at", "expires_in", "token_type"},
+			want:     map[string]any{"expires_in": float64(0)},
+		},
+		{
+			name:        "no token",
+			fields:      map[string]string{"username": "alice", "password": "s3cret"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &JSONFullFormatter{IncludeRefresh: tt.includeRefresh}
+			result, err := f.FormatFields(tt.fields)
+			if tt.shouldError {
+				if err == nil {
+					t.Fatalf("expected error, got %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(result, &got); err != nil {
+				t.Fatalf("output is not a JSON object: %v", err)
+			}
+			keys := make([]string, 0, len(got))
+			for key := range got {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONFullFormatterRawToken(t *testing.T) {
+	f := &JSONFullFormatter{}
+
+	result, err := f.Format([]byte("ghp_abc\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(result) != `{"access_token":"ghp_abc","token_type":"Bearer"}` {
+		t.Errorf("Format() = %s", result)
+	}
+
+	if _, err := f.Format([]byte("two words")); err == nil {
+		t.Error("expected error for multi-word output")
+	}
+}
@@ -0,0 +1,87 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NetrcFormatter prints a ~/.netrc entry
+// ("machine <host> login <username> password <password>") from
+// username/password credentials, for curl, git and other netrc readers
+type NetrcFormatter struct {
+	// Machine is the host the entry applies to
+	Machine string
+}
+
+func init() {
+	RegisterFormatter("netrc", func() Formatter {
+		return &NetrcFormatter{}
+	})
+}
+
+func (f *NetrcFormatter) Name() string {
+	return "netrc"
+}
+
+// Format accepts either a JSON object with "username" and "password" keys
+// or a raw "username:password" string
+func (f *NetrcFormatter) Format(output []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(output)
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]string
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON credentials: %w", err)
+		}
+		return f.FormatFields(fields)
+	}
+
+	username, password, ok := strings.Cut(string(trimmed), ":")
+	if !ok {
+		return nil, fmt.Errorf("netrc format requires 'username:password' output or username and password fields")
+	}
+	return f.entry(username, password)
+}
+
+// FormatFields builds the entry from structured username and password fields
+// This implements the FieldsFormatter interface
+func (f *NetrcFormatter) FormatFields(fields map[string]string) ([]byte, error) {
+	username, hasUsername := fields["username"]
+	password, hasPassword := fields["password"]
+
+	switch {
+	case !hasUsername && !hasPassword:
+		return nil, fmt.Errorf("netrc format requires username and password fields")
+	case !hasUsername:
+		return nil, fmt.Errorf("netrc format requires a username field")
+	case !hasPassword:
+		return nil, fmt.Errorf("netrc format requires a password field")
+	}
+
+	return f.entry(username, password)
+}
+
+// entry renders a single-line netrc entry for the configured machine
+func (f *NetrcFormatter) entry(username, password string) ([]byte, error) {
+	if f.Machine == "" {
+		return nil, fmt.Errorf("netrc format requires a machine (the host the entry applies to)")
+	}
+
+	// netrc tokens are whitespace-separated and can't be quoted portably
+	for _, token := range []struct{ name, value string }{
+		{"machine", f.Machine},
+		{"username", username},
+		{"password", password},
+	} {
+		if token.value == "" {
+			return nil, fmt.Errorf("netrc %s cannot be empty", token.name)
+		}
+		if strings.ContainsAny(token.value, " \t\r\n") {
+			return nil, fmt.Errorf("netrc %s cannot contain whitespace", token.name)
+		}
+	}
+
+	return []byte("machine " + f.Machine + " login " + username + " password " + password + "\n"), nil
+}
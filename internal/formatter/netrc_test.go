@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"testing"
+)
+
+func TestNetrcFormatterFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		machine     string
+		fields      map[string]string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "username and password",
+			machine:  "api.github.com",
+			fields:   map[string]string{"username": "alice", "password": "s3cret"},
+			expected: "machine api.github.com login alice password s3cret\n",
+		},
+		{
+			name:     "extra fields are ignored",
+			machine:  "example.com",
+			fields:   map[string]string{"username": "alice", "password": "s3cret", "expires_at": "2026-01-01T00:00:00Z"},
+			expected: "machine example.com login alice password s3cret\n",
+		},
+		{
+			name:        "missing machine",
+			fields:      map[string]string{"username": "alice", "password": "s3cret"},
+			shouldError: true,
+		},
+		{
+			name:        "missing username",
+			machine:     "example.com",
+			fields:      map[string]string{"password": "s3cret"},
+			shouldError: true,
+		},
+		{
+			name:        "missing password",
+			machine:     "example.com",
+			fields:      map[string]string{"username": "alice"},
+			shouldError: true,
+		},
+		{
+			name:        "no fields",
+			machine:     "example.com",
+			fields:      map[string]string{},
+			shouldError: true,
+		},
+		{
+			name:        "password with whitespace",
+			machine:     "example.com",
+			fields:      map[string]string{"username": "alice", "password": "two words"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &NetrcFormatter{Machine: tt.machine}
+			got, err := f.FormatFields(tt.fields)
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("FormatFields() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNetrcFormatterRaw(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		shouldError bool
+	}{
+		{
+			name:     "username:password",
+			input:    "alice:s3cret\n",
+			expected: "machine example.com login alice password s3cret\n",
+		},
+		{
+			name:     "json object",
+			input:    `{"username": "alice", "password": "s3cret"}`,
+			expected: "machine example.com login alice password s3cret\n",
+		},
+		{
+			name:        "bare token",
+			input:       "gho_abc123",
+			shouldError: true,
+		},
+	}
+
+	f := &NetrcFormatter{Machine: "example.com"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Format([]byte(tt.input))
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Format() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -11,6 +11,15 @@ type Formatter interface {
 	Format(output []byte) ([]byte, error)
 }
 
+// FieldsFormatter is an optional interface for formatters that can format
+// structured credential fields directly (used when no template is set)
+type FieldsFormatter interface {
+	Formatter
+
+	// FormatFields formats structured credential fields
+	FormatFields(fields map[string]string) ([]byte, error)
+}
+
 // FormatterFactory is a function that creates a new formatter instance
 type FormatterFactory func() Formatter
 
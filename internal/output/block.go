@@ -0,0 +1,135 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// blockMarkers returns the lines delimiting the managed block for name
+func blockMarkers(name string) (begin, end string) {
+	return "# BEGIN credctl:" + name, "# END credctl:" + name
+}
+
+// WriteBlock writes output into a managed block of filePath, delimited by
+// "# BEGIN credctl:<name>" and "# END credctl:<name>" lines. The block is
+// replaced if present and appended otherwise; the rest of the file is kept.
+// This is meant for shell rc and env files shared with other content.
+func WriteBlock(output []byte, filePath, name string) error {
+	if filePath == "" {
+		return fmt.Errorf("file path cannot be empty")
+	}
+	if name == "" {
+		return fmt.Errorf("block name cannot be empty")
+	}
+
+	filePath, err := expandHome(filePath)
+	if err != nil {
+		return err
+	}
+
+	block := renderBlock(output, name)
+
+	if IsSpecial(filePath) {
+		return writeSpecial(block, filePath)
+	}
+
+	mode := os.FileMode(0600)
+	existing, err := os.ReadFile(filePath)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(filePath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	updated, err := replaceBlock(existing, block, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return writeAtomic(filePath, updated, mode)
+}
+
+// renderBlock wraps output in the block markers for name
+func renderBlock(output []byte, name string) []byte {
+	begin, end := blockMarkers(name)
+
+	var buf bytes.Buffer
+	buf.WriteString(begin + "\n")
+	buf.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(end + "\n")
+	return buf.Bytes()
+}
+
+// replaceBlock returns content with the block for name replaced by block,
+// or with block appended if content has none
+func replaceBlock(content, block []byte, name string) ([]byte, error) {
+	begin, end := blockMarkers(name)
+	lines := bytes.SplitAfter(content, []byte("\n"))
+
+	start := -1
+	for i, line := range lines {
+		trimmed := string(bytes.TrimRight(line, "\r\n"))
+		switch {
+		case trimmed == begin && start < 0:
+			start = i
+		case trimmed == end && start >= 0:
+			var out []byte
+			for _, l := range lines[:start] {
+				out = append(out, l...)
+			}
+			out = append(out, block...)
+			for _, l := range lines[i+1:] {
+				out = append(out, l...)
+			}
+			return out, nil
+		}
+	}
+	if start >= 0 {
+		return nil, fmt.Errorf("unterminated block: '%s' has no matching '%s'", begin, end)
+	}
+
+	out := append([]byte{}, content...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return append(out, block...), nil
+}
+
+// writeAtomic replaces filePath with data via a temp file and rename, so
+// readers (e.g. a shell sourcing the file) never see a partial write
+func writeAtomic(filePath string, data []byte, mode os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmpFile.Chmod(mode); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteBlock(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string // "" means the file does not exist
+		output      string
+		want        string
+		shouldError bool
+	}{
+		{
+			name:   "first write creates the file",
+			output: "export TOKEN=abc",
+			want:   "# BEGIN credctl:x\nexport TOKEN=abc\n# END credctl:x\n",
+		},
+		{
+			name:     "rewrite replaces the block",
+			existing: "# BEGIN credctl:x\nexport TOKEN=old\n# END credctl:x\n",
+			output:   "export TOKEN=new\n",
+			want:     "# BEGIN credctl:x\nexport TOKEN=new\n# END credctl:x\n",
+		},
+		{
+			name:     "unrelated content is kept",
+			existing: "alias ll='ls -l'\n# BEGIN credctl:x\nexport TOKEN=old\nexport EXTRA=1\n# END credctl:x\nexport PATH=$PATH:~/bin\n",
+			output:   "export TOKEN=new",
+			want:     "alias ll='ls -l'\n# BEGIN credctl:x\nexport TOKEN=new\n# END credctl:x\nexport PATH=$PATH:~/bin\n",
+		},
+		{
+			name:     "block is appended after existing content",
+			existing: "export PATH=$PATH:~/bin",
+			output:   "export TOKEN=abc",
+			want:     "export PATH=$PATH:~/bin\n# BEGIN credctl:x\nexport TOKEN=abc\n# END credctl:x\n",
+		},
+		{
+			name:     "other providers' blocks are left alone",
+			existing: "# BEGIN credctl:y\nexport Y=1\n# END credctl:y\n",
+			output:   "export TOKEN=abc",
+			want:     "# BEGIN credctl:y\nexport Y=1\n# END credctl:y\n# BEGIN credctl:x\nexport TOKEN=abc\n# END credctl:x\n",
+		},
+		{
+			name:        "unterminated block",
+			existing:    "# BEGIN credctl:x\nexport TOKEN=old\n",
+			output:      "export TOKEN=new",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "env")
+			if tt.existing != "" {
+				if err := os.WriteFile(filePath, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := WriteBlock([]byte(tt.output), filePath, "x")
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				// The file must not be touched
+				if content, _ := os.ReadFile(filePath); string(content) != tt.existing {
+					t.Errorf("file changed on error: %q", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("content = %q, want %q", content, tt.want)
+			}
+
+			// New files are private; existing files keep their permissions
+			wantMode := os.FileMode(0600)
+			if tt.existing != "" {
+				wantMode = 0644
+			}
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != wantMode {
+				t.Errorf("mode = %o, want %o", info.Mode().Perm(), wantMode)
+			}
+		})
+	}
+}
+
+func TestWriteBlockIsIdempotent(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "env")
+
+	for i := 0; i < 3; i++ {
+		if err := WriteBlock([]byte("export TOKEN=abc\n"), filePath, "x"); err != nil {
+			t.Fatalf("WriteBlock() error: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# BEGIN credctl:x\nexport TOKEN=abc\n# END credctl:x\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
@@ -11,17 +11,19 @@ import (
 // - Expands ~ to home directory
 // - Creates parent directories if they don't exist
 // - Default permissions 0600
+// - Existing FIFOs and devices (e.g. /dev/stdout) are written as-is
 func Write(output []byte, filePath string) error {
 	if filePath == "" {
 		return fmt.Errorf("file path cannot be empty")
 	}
 
-	if len(filePath) > 0 && filePath[0] == '~' {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
-		}
-		filePath = filepath.Join(homeDir, filePath[1:])
+	filePath, err := expandHome(filePath)
+	if err != nil {
+		return err
+	}
+
+	if IsSpecial(filePath) {
+		return writeSpecial(output, filePath)
 	}
 
 	dir := filepath.Dir(filePath)
@@ -36,3 +38,45 @@ func Write(output []byte, filePath string) error {
 	return nil
 }
 
+// IsSpecial reports whether filePath is an existing named pipe, device or
+// socket rather than a regular file
+func IsSpecial(filePath string) bool {
+	filePath, err := expandHome(filePath)
+	if err != nil {
+		return false
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	return info.Mode()&(os.ModeNamedPipe|os.ModeDevice|os.ModeCharDevice|os.ModeSocket) != 0
+}
+
+// writeSpecial writes to an existing FIFO or device without creating,
+// truncating or changing the permissions of the target
+func writeSpecial(output []byte, filePath string) error {
+	f, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(output); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(filePath string) (string, error) {
+	if len(filePath) > 0 && filePath[0] == '~' {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		filePath = filepath.Join(homeDir, filePath[1:])
+	}
+	return filePath, nil
+}
+
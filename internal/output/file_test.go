@@ -1,8 +1,10 @@
 package output
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 )
 
@@ -111,3 +113,71 @@ func TestWriteHomeDirExpansion(t *testing.T) {
 	}
 }
 
+func TestWriteFIFO(t *testing.T) {
+	fifoPath := filepath.Join(t.TempDir(), "creds.fifo")
+	if err := syscall.Mkfifo(fifoPath, 0640); err != nil {
+		t.Skipf("named pipes not supported: %v", err)
+	}
+
+	if !IsSpecial(fifoPath) {
+		t.Fatal("expected FIFO to be detected as special")
+	}
+
+	// Opening a FIFO for writing blocks until a reader is connected
+	received := make(chan []byte)
+	go func() {
+		f, err := os.Open(fifoPath)
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer func() { _ = f.Close() }()
+		data, _ := io.ReadAll(f)
+		received <- data
+	}()
+
+	// Not valid JSON: nothing is validated for streams
+	if err := Write([]byte("token-123"), fifoPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := string(<-received); got != "token-123" {
+		t.Errorf("expected %q, got %q", "token-123", got)
+	}
+
+	// Permissions are left untouched
+	info, err := os.Stat(fifoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected permissions 0640 to be kept, got %o", info.Mode().Perm())
+	}
+}
+
+func TestIsSpecial(t *testing.T) {
+	regular := filepath.Join(t.TempDir(), "regular.txt")
+	if err := os.WriteFile(regular, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "regular file", path: regular, expected: false},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing"), expected: false},
+		{name: "directory", path: t.TempDir(), expected: false},
+		{name: "null device", path: os.DevNull, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSpecial(tt.path); got != tt.expected {
+				t.Errorf("IsSpecial(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
@@ -0,0 +1,95 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// HomeEnvVar is the environment variable that relocates the credctl directory
+const HomeEnvVar = "CREDCTL_HOME"
+
+// Environment variables that place the daemon sockets outside the credctl
+// directory (e.g. under $XDG_RUNTIME_DIR)
+const (
+	AdminSocketEnvVar    = "CREDCTL_ADMIN_SOCK"
+	ReadOnlySocketEnvVar = "CREDCTL_READONLY_SOCK"
+)
+
+// Home returns the base directory for credctl state
+// Priority order:
+// 1. CREDCTL_HOME env var (if set)
+// 2. ~/.credctl
+func Home() (string, error) {
+	if dir := os.Getenv(HomeEnvVar); dir != "" {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".credctl"), nil
+}
+
+// join returns a path relative to the credctl base directory
+func join(elem ...string) (string, error) {
+	dir, err := Home()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// ProvidersDir returns the directory where provider configurations are stored
+func ProvidersDir() (string, error) {
+	return join("providers")
+}
+
+// GroupsDir returns the directory where provider groups are stored
+func GroupsDir() (string, error) {
+	return join("groups")
+}
+
+// TokensDir returns the directory where the shared token cache is stored
+func TokensDir() (string, error) {
+	return join("tokens")
+}
+
+// KeyFile returns the path of the at-rest encryption key parameters
+func KeyFile() (string, error) {
+	return join("encryption.json")
+}
+
+// AdminSocket returns the path of the daemon's admin socket
+// (CREDCTL_ADMIN_SOCK if set)
+func AdminSocket() (string, error) {
+	if path := os.Getenv(AdminSocketEnvVar); path != "" {
+		return path, nil
+	}
+	return join("agent.sock")
+}
+
+// ReadOnlySocket returns the path of the daemon's read-only socket
+// (CREDCTL_READONLY_SOCK if set)
+func ReadOnlySocket() (string, error) {
+	if path := os.Getenv(ReadOnlySocketEnvVar); path != "" {
+		return path, nil
+	}
+	return join("agent-readonly.sock")
+}
+
+// PidFile returns the path of the daemon's PID file
+func PidFile() (string, error) {
+	return join("credctl.pid")
+}
+
+// LogFile returns the path of the daemon's log file
+func LogFile() (string, error) {
+	return join("daemon.log")
+}
+
+// AutoStartLock returns the path of the lock file that serializes daemon auto-start
+func AutoStartLock() (string, error) {
+	return join("autostart.lock")
+}
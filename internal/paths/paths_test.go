@@ -0,0 +1,91 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(HomeEnvVar, dir)
+
+	home, err := Home()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if home != dir {
+		t.Errorf("Home() = %q, want %q", home, dir)
+	}
+
+	providersDir, err := ProvidersDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "providers"); providersDir != want {
+		t.Errorf("ProvidersDir() = %q, want %q", providersDir, want)
+	}
+
+	adminSocket, err := AdminSocket()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "agent.sock"); adminSocket != want {
+		t.Errorf("AdminSocket() = %q, want %q", adminSocket, want)
+	}
+}
+
+func TestSocketsFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(HomeEnvVar, dir)
+
+	tests := []struct {
+		name     string
+		env      string
+		value    string
+		resolve  func() (string, error)
+		fallback string
+	}{
+		{name: "admin", env: AdminSocketEnvVar, value: "/run/user/1000/credctl/admin.sock", resolve: AdminSocket, fallback: "agent.sock"},
+		{name: "read-only", env: ReadOnlySocketEnvVar, value: "/run/user/1000/credctl/readonly.sock", resolve: ReadOnlySocket, fallback: "agent-readonly.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.env, "")
+			got, err := tt.resolve()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filepath.Join(dir, tt.fallback); got != want {
+				t.Errorf("without %s: got %q, want %q", tt.env, got, want)
+			}
+
+			t.Setenv(tt.env, tt.value)
+			got, err = tt.resolve()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("with %s: got %q, want %q", tt.env, got, tt.value)
+			}
+		})
+	}
+}
+
+func TestHomeDefault(t *testing.T) {
+	t.Setenv(HomeEnvVar, "")
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+
+	home, err := Home()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(userHome, ".credctl"); home != want {
+		t.Errorf("Home() = %q, want %q", home, want)
+	}
+}
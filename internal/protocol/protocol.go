@@ -1,7 +1,18 @@
 package protocol
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Version is the protocol version spoken by this build
+// The major component must match between client and daemon
+const Version = "1.0"
+
 // Request represents a request to the daemon
 type Request struct {
+	Version string      `json:"version,omitempty"`
 	Action  string      `json:"action"`
 	Payload interface{} `json:"payload"`
 }
@@ -16,7 +27,10 @@ type AddPayload struct {
 
 // GetPayload is the payload for the "get" action
 type GetPayload struct {
-	Name string `json:"name"`
+	Name    string   `json:"name"`
+	Scopes  []string `json:"scopes,omitempty"`   // Optional subset of the provider's scopes for this request
+	NoCache bool     `json:"no_cache,omitempty"` // Discard cached credentials and fetch fresh ones
+	Raw     bool     `json:"raw,omitempty"`      // Return the provider's exact bytes in RawOutput
 }
 
 // DeletePayload is the payload for the "delete" action
@@ -32,8 +46,16 @@ type SetTokensPayload struct {
 	ExpiresIn    int    `json:"expires_in"` // seconds until expiration
 }
 
+// RotateSecretPayload is the payload for the "rotate_secret" action
+type RotateSecretPayload struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`           // New client secret (or an env: reference)
+	Verify bool   `json:"verify,omitempty"` // Request tokens with the new secret before committing it
+}
+
 // Response represents a response from the daemon
 type Response struct {
+	Version   string      `json:"version,omitempty"`
 	Status    string      `json:"status"`
 	Error     string      `json:"error,omitempty"`
 	ErrorType string      `json:"error_type,omitempty"` // Type of error for client handling
@@ -44,35 +66,129 @@ type Response struct {
 const (
 	ErrorTypeAuthRequired       = "auth_required"
 	ErrorTypeDeviceFlowRequired = "device_flow_required"
+	ErrorTypeVersionMismatch    = "version_mismatch"
+	ErrorTypePermissionDenied   = "permission_denied"
+	ErrorTypeNotFound           = "not_found"
+	ErrorTypeTimeout            = "timeout"
 	ErrorTypeGeneric            = "generic"
 )
 
+// MajorVersion returns the major component of a protocol version
+func MajorVersion(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
+
+// CheckVersion returns an error if the daemon and client protocol versions
+// have different major versions
+func CheckVersion(daemonVersion, clientVersion string) error {
+	if MajorVersion(daemonVersion) != MajorVersion(clientVersion) {
+		return fmt.Errorf("daemon version %s, client version %s — restart the daemon", daemonVersion, clientVersion)
+	}
+	return nil
+}
+
 // GetResponsePayload is the payload of response for "get"
 type GetResponsePayload struct {
 	Output              string            `json:"output"`
 	Metadata            map[string]any    `json:"metadata,omitempty"`
 	StructuredFields    map[string]string `json:"structured_fields,omitempty"` // Credenciales estructuradas si el provider las soporta
 	HasStructuredFields bool              `json:"has_structured_fields"`       // Indica si structured_fields está disponible
+	RawOutput           []byte            `json:"raw_output,omitempty"`        // Exact provider output (base64 on the wire), set for raw requests
+	Bundle              bool              `json:"bundle,omitempty"`            // Output is empty: the credential is the set of structured fields
+}
+
+// AddGroupPayload is the payload for the "add_group" action
+type AddGroupPayload struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	Force   bool     `json:"force,omitempty"` // Replace an existing group
+}
+
+// GroupPayload is the payload for the "get_group" and "delete_group" actions
+type GroupPayload struct {
+	Name string `json:"name"`
+}
+
+// GroupMemberResult is the outcome of fetching one member of a group
+type GroupMemberResult struct {
+	Name      string              `json:"name"`
+	Error     string              `json:"error,omitempty"`
+	ErrorType string              `json:"error_type,omitempty"`
+	Result    *GetResponsePayload `json:"result,omitempty"` // Set when the member was fetched
+}
+
+// GetGroupResponsePayload is the payload of response for "get_group",
+// with one result per member in the group's order
+type GetGroupResponsePayload struct {
+	Members []GroupMemberResult `json:"members"`
+}
+
+// ExpiryPayload is the payload for the "expiry" action
+type ExpiryPayload struct {
+	Name string `json:"name"`
+}
+
+// ExpiryResponsePayload is the payload of response for "expiry"
+type ExpiryResponsePayload struct {
+	HasToken  bool `json:"has_token"`  // Whether the provider has a cached token
+	ExpiresIn int  `json:"expires_in"` // Seconds until the cached token expires (0 if expired)
+}
+
+// TokenInfo describes a provider's cached tokens without including them
+type TokenInfo struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	HasAccessToken  bool   `json:"has_access_token"`
+	HasRefreshToken bool   `json:"has_refresh_token"`
+	TokenType       string `json:"token_type,omitempty"`
+	ExpiresIn       int    `json:"expires_in"`        // Seconds until the access token expires (0 if expired or absent)
+	Subject         string `json:"subject,omitempty"` // JWT access token claims (unverified)
+	Issuer          string `json:"issuer,omitempty"`
+	Audience        string `json:"audience,omitempty"`
+}
+
+// TokensResponsePayload is the payload of response for "tokens"
+type TokensResponsePayload struct {
+	Tokens []TokenInfo `json:"tokens"`
 }
 
 // DescribePayload is the payload for the "describe" action
 type DescribePayload struct {
-	Name string `json:"name"`
+	Name    string `json:"name"`
+	Resolve bool   `json:"resolve,omitempty"` // Describe the provider an alias stands for
 }
 
 // DescribeResponsePayload is the payload of response for "describe"
 type DescribeResponsePayload struct {
-	Type     string         `json:"type"`
-	Metadata map[string]any `json:"metadata"`
+	Type         string                `json:"type"`
+	Metadata     map[string]any        `json:"metadata"`
+	Capabilities *ProviderCapabilities `json:"capabilities,omitempty"` // nil from daemons predating capabilities
+}
+
+// ProviderCapabilities describes what a provider needs and supports
+type ProviderCapabilities struct {
+	NeedsNetwork    bool `json:"needs_network"`
+	SupportsRefresh bool `json:"supports_refresh"`
+	Interactive     bool `json:"interactive"`
+	Structured      bool `json:"structured"`
 }
 
 // ProviderInfo represents information about a provider
 type ProviderInfo struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	ModifiedAt time.Time `json:"modified_at,omitzero"` // When the provider was added or last modified
 }
 
 // ListResponsePayload is the payload of response for "list"
 type ListResponsePayload struct {
 	Providers []ProviderInfo `json:"providers"`
 }
+
+// PingResponsePayload is the payload of response for "ping"
+type PingResponsePayload struct {
+	Version string `json:"version"`         // Protocol version
+	Build   string `json:"build,omitempty"` // credctl release the daemon was built from
+	PID     int    `json:"pid"`
+}
@@ -0,0 +1,29 @@
+package protocol
+
+import "testing"
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		daemonVersion string
+		clientVersion string
+		wantErr       bool
+	}{
+		{name: "same version", daemonVersion: "1.0", clientVersion: "1.0"},
+		{name: "minor drift is compatible", daemonVersion: "1.0", clientVersion: "1.3"},
+		{name: "major mismatch", daemonVersion: "1.0", clientVersion: "2.0", wantErr: true},
+		{name: "major only", daemonVersion: "2", clientVersion: "2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVersion(tt.daemonVersion, tt.clientVersion)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package provider
+
+import "fmt"
+
+// Access policies (MetadataAccessPolicy)
+const (
+	AccessPolicyAny       = "any"        // Readable on both the admin and read-only sockets
+	AccessPolicyAdminOnly = "admin-only" // Readable on the admin socket only
+)
+
+// LoadAccessPolicy reads the access policy from provider config (default AccessPolicyAny)
+func LoadAccessPolicy(config map[string]any) (string, error) {
+	policy := GetStringOrDefault(config, MetadataAccessPolicy, AccessPolicyAny)
+	switch policy {
+	case AccessPolicyAny, AccessPolicyAdminOnly:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid %s '%s': must be %s or %s", MetadataAccessPolicy, policy, AccessPolicyAny, AccessPolicyAdminOnly)
+	}
+}
+
+// IsAdminOnly reports whether a provider's metadata restricts it to the admin socket
+func IsAdminOnly(metadata map[string]any) bool {
+	return GetStringOrDefault(metadata, MetadataAccessPolicy, AccessPolicyAny) == AccessPolicyAdminOnly
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestLoadAccessPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		want        string
+		adminOnly   bool
+		shouldError bool
+	}{
+		{name: "default", config: map[string]any{}, want: AccessPolicyAny},
+		{name: "any", config: map[string]any{MetadataAccessPolicy: "any"}, want: AccessPolicyAny},
+		{name: "admin-only", config: map[string]any{MetadataAccessPolicy: "admin-only"}, want: AccessPolicyAdminOnly, adminOnly: true},
+		{name: "invalid", config: map[string]any{MetadataAccessPolicy: "nobody"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadAccessPolicy(tt.config)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoadAccessPolicy() = %q, want %q", got, tt.want)
+			}
+
+			// Only a non-default policy is stored
+			metadata := map[string]any{}
+			DaemonOptions{AccessPolicy: got}.AddToMetadata(metadata)
+			if IsAdminOnly(metadata) != tt.adminOnly {
+				t.Errorf("IsAdminOnly() = %v, want %v", IsAdminOnly(metadata), tt.adminOnly)
+			}
+			if _, stored := metadata[MetadataAccessPolicy]; stored != tt.adminOnly {
+				t.Errorf("metadata = %v, want access_policy stored only for admin-only", metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAliasCycle is returned when an alias chain leads back to itself
+var ErrAliasCycle = errors.New("alias cycle")
+
+// AliasProvider is an optional interface for providers that stand for
+// another provider, sharing its configuration and cached tokens
+type AliasProvider interface {
+	Provider
+
+	// AliasTarget returns the name of the provider this one stands for
+	AliasTarget() string
+}
+
+// ResolveAlias follows prov's alias chain, looking targets up with get, and
+// returns the provider at its end. Providers that aren't aliases are
+// returned as-is.
+func ResolveAlias(prov Provider, get func(name string) (Provider, error)) (Provider, error) {
+	var chain []string
+	seen := make(map[string]bool)
+	for {
+		alias, ok := prov.(AliasProvider)
+		if !ok {
+			return prov, nil
+		}
+
+		target := alias.AliasTarget()
+		chain = append(chain, target)
+		if seen[target] {
+			return nil, fmt.Errorf("%w: %s", ErrAliasCycle, strings.Join(chain, " -> "))
+		}
+		seen[target] = true
+
+		next, err := get(target)
+		if err != nil {
+			return nil, fmt.Errorf("alias target '%s': %w", target, err)
+		}
+		prov = next
+	}
+}
@@ -0,0 +1,71 @@
+package alias
+
+import (
+	"context"
+	"fmt"
+
+	"credctl/internal/provider"
+)
+
+// Provider is another name for an existing provider: both resolve to the
+// same configuration and token cache. The daemon resolves aliases when
+// looking providers up, so Get is only reached outside of it.
+type Provider struct {
+	target string
+}
+
+func init() {
+	provider.Register("alias", func() provider.Provider {
+		return &Provider{}
+	})
+}
+
+func (p *Provider) Type() string {
+	return "alias"
+}
+
+func (p *Provider) Schema() provider.Schema {
+	return provider.Schema{
+		Fields: []provider.FieldDef{
+			{
+				Name:     provider.MetadataAliasTarget,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Help:     "Name of the provider this alias stands for",
+			},
+		},
+	}
+}
+
+func (p *Provider) Init(config map[string]any) error {
+	if err := provider.ValidateConfig(config, p.Schema()); err != nil {
+		return err
+	}
+
+	p.target = provider.GetStringOrDefault(config, provider.MetadataAliasTarget, "")
+	if p.target == "" {
+		return fmt.Errorf("%s is required", provider.MetadataAliasTarget)
+	}
+	return nil
+}
+
+// Get retrieves the credential of the provider the alias resolves to
+func (p *Provider) Get(ctx context.Context) ([]byte, error) {
+	target, err := provider.ResolveAlias(p, provider.Lookup)
+	if err != nil {
+		return nil, err
+	}
+	return target.Get(ctx)
+}
+
+func (p *Provider) Metadata() map[string]any {
+	return map[string]any{
+		provider.MetadataAliasTarget: p.target,
+	}
+}
+
+// AliasTarget returns the name of the provider this alias stands for
+// This implements the AliasProvider interface
+func (p *Provider) AliasTarget() string {
+	return p.target
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+// mockAlias is a test provider standing for another one
+type mockAlias struct {
+	MockProvider
+	target string
+}
+
+func (m *mockAlias) AliasTarget() string {
+	return m.target
+}
+
+func TestResolveAlias(t *testing.T) {
+	db := &MockProvider{providerType: "mock"}
+	providers := map[string]Provider{
+		"db":       db,
+		"prod-db":  &mockAlias{target: "db"},
+		"main-db":  &mockAlias{target: "prod-db"},
+		"dangling": &mockAlias{target: "missing"},
+		"a":        &mockAlias{target: "b"},
+		"b":        &mockAlias{target: "a"},
+		"self":     &mockAlias{target: "self"},
+	}
+	get := func(name string) (Provider, error) {
+		prov, ok := providers[name]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return prov, nil
+	}
+
+	tests := []struct {
+		name      string
+		wantCycle bool
+		wantErr   bool
+	}{
+		{name: "db"},
+		{name: "prod-db"},
+		{name: "main-db"},
+		{name: "dangling", wantErr: true},
+		{name: "a", wantCycle: true},
+		{name: "self", wantCycle: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveAlias(providers[tt.name], get)
+			if tt.wantCycle {
+				if !errors.Is(err, ErrAliasCycle) {
+					t.Fatalf("ResolveAlias() error = %v, want %v", err, ErrAliasCycle)
+				}
+				return
+			}
+			if tt.wantErr {
+				if err == nil || errors.Is(err, ErrAliasCycle) {
+					t.Fatalf("ResolveAlias() error = %v, want a lookup error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveAlias() error: %v", err)
+			}
+			if got != db {
+				t.Errorf("ResolveAlias() = %v, want the db provider", got)
+			}
+		})
+	}
+}
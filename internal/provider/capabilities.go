@@ -0,0 +1,34 @@
+package provider
+
+// Capabilities describes what a provider needs and supports, so the daemon
+// and CLI can decide how to retry, refresh or diagnose it
+type Capabilities struct {
+	NeedsNetwork    bool // Fetching a credential makes network calls
+	SupportsRefresh bool // Expiring credentials are renewed without user interaction
+	Interactive     bool // Credentials may need the user (e.g. credctl login in a browser)
+	Structured      bool // Credentials are also exposed as named fields
+}
+
+// CapabilitiesProvider is an optional interface for providers that report
+// their capabilities
+type CapabilitiesProvider interface {
+	Provider
+
+	// Capabilities reports what the provider, as configured, needs and supports
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities a provider reports or, for providers
+// that don't, those implied by the optional interfaces it implements
+func CapabilitiesOf(p Provider) Capabilities {
+	if capProv, ok := p.(CapabilitiesProvider); ok {
+		return capProv.Capabilities()
+	}
+
+	_, interactive := p.(LoginProvider)
+	_, structured := p.(CredentialsProvider)
+	return Capabilities{
+		Interactive: interactive,
+		Structured:  structured,
+	}
+}
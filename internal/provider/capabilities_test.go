@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+// loginMockProvider is a test provider that supports login
+type loginMockProvider struct {
+	MockProvider
+}
+
+func (m *loginMockProvider) Login(ctx context.Context) error { return nil }
+
+// capabilitiesMockProvider is a test provider that reports its capabilities
+type capabilitiesMockProvider struct {
+	MockProvider
+	capabilities Capabilities
+}
+
+func (m *capabilitiesMockProvider) Capabilities() Capabilities { return m.capabilities }
+
+func TestCapabilitiesOf(t *testing.T) {
+	reported := Capabilities{NeedsNetwork: true, SupportsRefresh: true}
+
+	tests := []struct {
+		name     string
+		provider Provider
+		want     Capabilities
+	}{
+		{name: "plain provider", provider: &MockProvider{}, want: Capabilities{}},
+		{name: "login implies interactive", provider: &loginMockProvider{}, want: Capabilities{Interactive: true}},
+		{name: "reported capabilities win", provider: &capabilitiesMockProvider{capabilities: reported}, want: reported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapabilitiesOf(tt.provider); got != tt.want {
+				t.Errorf("CapabilitiesOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
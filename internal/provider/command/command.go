@@ -1,11 +1,16 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,14 +18,26 @@ import (
 	"credctl/internal/provider"
 )
 
+// defaultShell runs commands unless the provider configures another shell
+const defaultShell = "/bin/sh"
+
+// defaultMaxOutputBytes caps a command's output so a runaway command can't exhaust the daemon's memory
+const defaultMaxOutputBytes = 1 << 20
+
 // CommandProvider executes shell commands to retrieve credentials
 type CommandProvider struct {
 	command      string
 	loginCommand string
 	inputFormat  string
-	template     string
-	format       string
-	output       string
+	shell        string
+	env          map[string]string
+	workingDir   string
+	jsonQuery    string
+	transform    string // Command that post-processes the output (read from stdin)
+	maxOutput    int    // Largest output read from the command or transform
+	outputOpts   provider.OutputOptions
+	daemonOpts   provider.DaemonOptions
+	loginTTY     bool // Run the login command in a pseudo-terminal
 }
 
 func init() {
@@ -56,34 +73,176 @@ func (p *CommandProvider) Schema() provider.Schema {
 				ValidValues: []string{"raw", "json", "env"},
 				Help:        "Format of command output: raw (default), json, or env (KEY=VALUE)",
 			},
+			{
+				Name:     provider.MetadataShell,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Default:  defaultShell,
+				Help:     "Shell used to run the command and login command (invoked as <shell> -c <command>)",
+			},
+			{
+				Name:     provider.MetadataEnv,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Environment variables to set or override as KEY=VALUE (repeatable)",
+			},
+			{
+				Name:     provider.MetadataWorkingDir,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Working directory for the command and login command",
+			},
+			{
+				Name:     provider.MetadataJSONQuery,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Path of the JSON value to return as the credential, e.g. data.credentials[0].token",
+			},
+			{
+				Name:     provider.MetadataTransform,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Command that receives the output on stdin and returns the credential, e.g. 'jq -r .token' or 'base64 -d'",
+			},
+			{
+				Name:     provider.MetadataMaxOutputBytes,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  strconv.Itoa(defaultMaxOutputBytes),
+				Help:     "Largest output accepted from the command (and transform), in bytes; larger output is an error",
+			},
+			{
+				Name:     provider.MetadataLoginTTY,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Run the login command in a pseudo-terminal, for tools that expect one (e.g. aws sso login); credctl login must then run in a terminal",
+			},
 		},
 	}
 }
 
 // Init initializes the provider with the given configuration
 func (p *CommandProvider) Init(config map[string]any) error {
-	p.command = config[provider.MetadataCommand].(string)
+	if err := provider.ValidateConfig(config, p.Schema()); err != nil {
+		return err
+	}
+
+	p.command = provider.GetStringOrDefault(config, provider.MetadataCommand, "")
+	if p.command == "" {
+		return fmt.Errorf("command is required")
+	}
 	p.loginCommand = provider.GetStringOrDefault(config, provider.MetadataLoginCommand, "")
 	p.inputFormat = provider.GetStringOrDefault(config, provider.MetadataInputFormat, "raw")
-	p.template = provider.GetStringOrDefault(config, provider.MetadataTemplate, "")
-	p.format = provider.GetStringOrDefault(config, provider.MetadataFormat, "text")
-	p.output = provider.GetStringOrDefault(config, provider.MetadataOutput, "")
+	p.shell = provider.GetStringOrDefault(config, provider.MetadataShell, defaultShell)
+	if _, err := exec.LookPath(p.shell); err != nil {
+		return fmt.Errorf("shell '%s' not found: %w", p.shell, err)
+	}
+	p.env = provider.GetStringMapOrDefault(config, provider.MetadataEnv, nil)
+	p.workingDir = provider.GetStringOrDefault(config, provider.MetadataWorkingDir, "")
+	p.jsonQuery = provider.GetStringOrDefault(config, provider.MetadataJSONQuery, "")
+	if p.jsonQuery != "" {
+		if _, err := parseQuery(p.jsonQuery); err != nil {
+			return err
+		}
+	}
+	p.transform = provider.GetStringOrDefault(config, provider.MetadataTransform, "")
+	p.maxOutput = provider.GetIntOrDefault(config, provider.MetadataMaxOutputBytes, defaultMaxOutputBytes)
+	if p.maxOutput <= 0 {
+		return fmt.Errorf("invalid %s %d: must be positive", provider.MetadataMaxOutputBytes, p.maxOutput)
+	}
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+	p.loginTTY = provider.GetBoolOrDefault(config, provider.MetadataLoginTTY, false)
 	return nil
 }
 
 // Get retrieves the credential by executing the configured command
+// The output goes through transform first; with json_query set, only the
+// selected value is returned
 func (p *CommandProvider) Get(ctx context.Context) ([]byte, error) {
-	// Execute command with timeout
+	output, err := p.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.jsonQuery == "" {
+		return output, nil
+	}
+
+	value, err := queryJSON(output, p.jsonQuery)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// run executes the configured command, pipes its output through the
+// transform command if one is set, and returns the trimmed result
+func (p *CommandProvider) run(ctx context.Context) ([]byte, error) {
+	output, err := p.execute(ctx, p.command, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.transform == "" {
+		return output, nil
+	}
+
+	output, err = p.execute(ctx, p.transform, output)
+	if err != nil {
+		return nil, fmt.Errorf("transform command failed: %w", err)
+	}
+	return output, nil
+}
+
+// execute runs script with a timeout, feeding it stdin, and returns its
+// output with trailing newlines trimmed
+func (p *CommandProvider) execute(ctx context.Context, script string, stdin []byte) ([]byte, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(timeoutCtx, "/bin/sh", "-c", p.command)
+	cmd := p.shellCommand(timeoutCtx, script)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
 
-	// Capture stdout
-	stdout, err := cmd.Output()
+	// Capture stdout, reading one byte past the limit to detect larger output
+	pipe, err := cmd.StdoutPipe()
 	if err != nil {
+		return nil, fmt.Errorf("failed to capture command output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	maxOutput := p.maxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutputBytes
+	}
+	stdout, readErr := io.ReadAll(io.LimitReader(pipe, int64(maxOutput)+1))
+	if readErr == nil && len(stdout) > maxOutput {
+		// Stop the command instead of draining the rest of its output
+		cancel()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("command output too large: more than %d bytes (raise %s if this is expected)", maxOutput, provider.MetadataMaxOutputBytes)
+	}
+	if err := cmd.Wait(); err != nil {
+		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("command timed out: %w", context.DeadlineExceeded)
+		}
 		return nil, err
 	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read command output: %w", readErr)
+	}
 
 	// Trim trailing newlines
 	result := strings.TrimRight(string(stdout), "\r\n")
@@ -103,30 +262,99 @@ func (p *CommandProvider) Metadata() map[string]any {
 		metadata[provider.MetadataInputFormat] = p.inputFormat
 	}
 
-	// Preserve template, format and output from config (set by cmd/add.go global flags)
-	if p.template != "" {
-		metadata[provider.MetadataTemplate] = p.template
+	if p.shell != "" && p.shell != defaultShell {
+		metadata[provider.MetadataShell] = p.shell
+	}
+
+	if len(p.env) > 0 {
+		metadata[provider.MetadataEnv] = p.env
+	}
+
+	if p.workingDir != "" {
+		metadata[provider.MetadataWorkingDir] = p.workingDir
+	}
+
+	if p.jsonQuery != "" {
+		metadata[provider.MetadataJSONQuery] = p.jsonQuery
+	}
+
+	if p.transform != "" {
+		metadata[provider.MetadataTransform] = p.transform
 	}
 
-	if p.format != "" && p.format != "text" {
-		metadata[provider.MetadataFormat] = p.format
+	if p.maxOutput != 0 && p.maxOutput != defaultMaxOutputBytes {
+		metadata[provider.MetadataMaxOutputBytes] = p.maxOutput
 	}
 
-	if p.output != "" {
-		metadata[provider.MetadataOutput] = p.output
+	if p.loginTTY {
+		metadata[provider.MetadataLoginTTY] = true
 	}
 
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+
 	return metadata
 }
 
-// Login performs interactive authentication by executing the login command
+// shellCommand builds the command that runs script in the configured shell,
+// environment and working directory
+func (p *CommandProvider) shellCommand(ctx context.Context, script string) *exec.Cmd {
+	shell := p.shell
+	if shell == "" {
+		shell = defaultShell
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", script)
+	cmd.Dir = p.workingDir
+
+	if len(p.env) > 0 {
+		keys := make([]string, 0, len(p.env))
+		for k := range p.env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		// Later entries take precedence, so overrides go after the inherited environment
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+p.env[k])
+		}
+	}
+
+	return cmd
+}
+
+// Capabilities reports that commands run locally and need the user only when
+// a login_command is configured
+// This implements the CapabilitiesProvider interface
+func (p *CommandProvider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{
+		Interactive: p.loginCommand != "",
+		Structured:  true,
+	}
+}
+
+// Login performs interactive authentication by executing the login command,
+// attached to credctl's terminal or, with login_tty, to a pseudo-terminal
 // This implements the LoginProvider interface
 func (p *CommandProvider) Login(ctx context.Context) error {
 	if p.loginCommand == "" {
 		return fmt.Errorf("no login command configured")
 	}
 
-	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", p.loginCommand)
+	cmd := p.shellCommand(ctx, p.loginCommand)
+	if p.loginTTY {
+		// Fail fast instead of hanging on a prompt nobody can answer (e.g. in the daemon)
+		if !stdinIsTerminal() {
+			return fmt.Errorf("login command requires a terminal (login_tty is set): run credctl login in an interactive terminal")
+		}
+		if err := runInPTY(cmd); err != nil {
+			return fmt.Errorf("login command failed: %w", err)
+		}
+		return nil
+	}
+
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -141,8 +369,8 @@ func (p *CommandProvider) Login(ctx context.Context) error {
 // GetCredentials returns the credentials in a structured format
 // This implements the CredentialsProvider interface
 func (p *CommandProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
-	// Get raw output from command
-	output, err := p.Get(ctx)
+	// Get the full (transformed) command output (json_query only narrows Get)
+	output, err := p.run(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -179,30 +407,33 @@ func parseJSON(data []byte) (map[string]string, error) {
 
 	fields := make(map[string]string)
 	for key, value := range jsonData {
-		// Convert all values to strings
-		switch v := value.(type) {
-		case string:
-			fields[key] = v
-		case float64:
-			fields[key] = fmt.Sprintf("%g", v)
-		case bool:
-			fields[key] = fmt.Sprintf("%t", v)
-		case nil:
-			fields[key] = ""
-		default:
-			// For complex types (arrays, objects), marshal back to JSON string
-			jsonBytes, err := json.Marshal(v)
-			if err != nil {
-				fields[key] = fmt.Sprintf("%v", v)
-			} else {
-				fields[key] = string(jsonBytes)
-			}
-		}
+		fields[key] = stringValue(value)
 	}
 
 	return fields, nil
 }
 
+// stringValue converts a decoded JSON value to a string
+func stringValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case nil:
+		return ""
+	default:
+		// For complex types (arrays, objects), marshal back to JSON string
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(jsonBytes)
+	}
+}
+
 // parseEnv parses KEY=VALUE format into a map
 func parseEnv(data []byte) (map[string]string, error) {
 	fields := make(map[string]string)
@@ -2,9 +2,15 @@ package command
 
 import (
 	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"credctl/internal/provider"
+
+	"github.com/creack/pty"
 )
 
 func TestParseJSON(t *testing.T) {
@@ -339,6 +345,29 @@ func TestCommandProvider_Init(t *testing.T) {
 				inputFormat:  "env",
 			},
 		},
+		{
+			name: "missing command",
+			config: map[string]any{
+				provider.MetadataLoginCommand: "login",
+			},
+			shouldError: true,
+		},
+		{
+			name: "invalid input format",
+			config: map[string]any{
+				provider.MetadataCommand:     "get-token",
+				provider.MetadataInputFormat: "yaml",
+			},
+			shouldError: true,
+		},
+		{
+			name: "missing shell",
+			config: map[string]any{
+				provider.MetadataCommand: "get-token",
+				provider.MetadataShell:   "/nonexistent/shell",
+			},
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -372,3 +401,315 @@ func TestCommandProvider_Init(t *testing.T) {
 		})
 	}
 }
+
+func TestGet_ShellEnvAndWorkingDir(t *testing.T) {
+	workingDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		config   map[string]any
+		expected string
+	}{
+		{
+			name: "env var is set",
+			config: map[string]any{
+				provider.MetadataCommand: `printf "%s" "$CREDCTL_TEST_TOKEN"`,
+				provider.MetadataEnv:     map[string]string{"CREDCTL_TEST_TOKEN": "from-env"},
+			},
+			expected: "from-env",
+		},
+		{
+			name: "env var overrides inherited value",
+			config: map[string]any{
+				provider.MetadataCommand: `printf "%s" "$HOME"`,
+				provider.MetadataEnv:     map[string]string{"HOME": "/overridden"},
+			},
+			expected: "/overridden",
+		},
+		{
+			name: "working directory",
+			config: map[string]any{
+				provider.MetadataCommand:    "pwd",
+				provider.MetadataWorkingDir: workingDir,
+			},
+			expected: workingDir,
+		},
+		{
+			name: "custom shell",
+			config: map[string]any{
+				provider.MetadataCommand: `printf "%s" "$0"`,
+				provider.MetadataShell:   "sh",
+			},
+			expected: "sh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CommandProvider{}
+			if err := p.Init(tt.config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			output, err := p.Get(context.Background())
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+
+			if string(output) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	if _, err := exec.LookPath("jq"); err != nil {
+		t.Skip("jq not installed")
+	}
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expected    string
+		shouldError bool
+	}{
+		{
+			name: "jq extracts the token",
+			config: map[string]any{
+				provider.MetadataCommand:   `echo '{"token":"abc123","expires_in":3600}'`,
+				provider.MetadataTransform: "jq -r .token",
+			},
+			expected: "abc123",
+		},
+		{
+			name: "base64 decode",
+			config: map[string]any{
+				provider.MetadataCommand:   `printf "c2VjcmV0"`,
+				provider.MetadataTransform: "base64 -d",
+			},
+			expected: "secret",
+		},
+		{
+			name: "json_query applies to the transformed output",
+			config: map[string]any{
+				provider.MetadataCommand:   `echo '{"data":{"token":"abc123"}}'`,
+				provider.MetadataTransform: "jq .data",
+				provider.MetadataJSONQuery: "token",
+			},
+			expected: "abc123",
+		},
+		{
+			name: "failing transform",
+			config: map[string]any{
+				provider.MetadataCommand:   `echo "not json"`,
+				provider.MetadataTransform: "jq -r .token",
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CommandProvider{}
+			if err := p.Init(tt.config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			output, err := p.Get(context.Background())
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestGetCredentials_Transform(t *testing.T) {
+	if _, err := exec.LookPath("jq"); err != nil {
+		t.Skip("jq not installed")
+	}
+
+	p := &CommandProvider{}
+	err := p.Init(map[string]any{
+		provider.MetadataCommand:     `echo '{"creds":{"user":"bob","pass":"s3cret"}}'`,
+		provider.MetadataTransform:   "jq -c .creds",
+		provider.MetadataInputFormat: "json",
+	})
+	if err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if creds.Get("user") != "bob" || creds.Get("pass") != "s3cret" {
+		t.Errorf("expected fields from the transformed output, got %v", creds.Fields)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          map[string]any
+		wantInteractive bool
+	}{
+		{name: "command only", config: map[string]any{provider.MetadataCommand: "echo token"}},
+		{name: "with login command", config: map[string]any{provider.MetadataCommand: "echo token", provider.MetadataLoginCommand: "gh auth login"}, wantInteractive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CommandProvider{}
+			if err := p.Init(tt.config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			want := provider.Capabilities{Interactive: tt.wantInteractive, Structured: true}
+			if got := provider.CapabilitiesOf(p); got != want {
+				t.Errorf("CapabilitiesOf() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGet_MaxOutputBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expected    string
+		shouldError bool
+	}{
+		{
+			name: "output at the limit",
+			config: map[string]any{
+				provider.MetadataCommand:        `printf 0123456789`,
+				provider.MetadataMaxOutputBytes: 10,
+			},
+			expected: "0123456789",
+		},
+		{
+			name: "output over the limit",
+			config: map[string]any{
+				provider.MetadataCommand:        `printf 0123456789x`,
+				provider.MetadataMaxOutputBytes: 10,
+			},
+			shouldError: true,
+		},
+		{
+			name: "endless output is cut off",
+			config: map[string]any{
+				provider.MetadataCommand: `yes`,
+			},
+			shouldError: true,
+		},
+		{
+			name: "transform output over the limit",
+			config: map[string]any{
+				provider.MetadataCommand:        `printf abc`,
+				provider.MetadataTransform:      `cat; printf 0123456789`,
+				provider.MetadataMaxOutputBytes: 10,
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &CommandProvider{}
+			if err := p.Init(tt.config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			output, err := p.Get(context.Background())
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got %d bytes", len(output))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestLogin_TTY(t *testing.T) {
+	tests := []struct {
+		name       string
+		loginTTY   bool
+		isTerminal bool
+		wantRun    bool
+		wantErr    string
+	}{
+		{name: "no terminal fails before running", loginTTY: true, isTerminal: false, wantErr: "requires a terminal"},
+		{name: "terminal runs in a pseudo-terminal", loginTTY: true, isTerminal: true, wantRun: true},
+		{name: "without login_tty no terminal is needed", loginTTY: false, isTerminal: false, wantRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.loginTTY && tt.isTerminal {
+				ptmx, tty, err := pty.Open()
+				if err != nil {
+					t.Skipf("pseudo-terminals unavailable: %v", err)
+				}
+				_ = ptmx.Close()
+				_ = tty.Close()
+			}
+
+			original := stdinIsTerminal
+			stdinIsTerminal = func() bool { return tt.isTerminal }
+			defer func() { stdinIsTerminal = original }()
+
+			// The login command records whether its stdin and stderr are terminals
+			marker := filepath.Join(t.TempDir(), "login")
+			p := &CommandProvider{}
+			err := p.Init(map[string]any{
+				provider.MetadataCommand:      "echo token",
+				provider.MetadataLoginCommand: `if [ -t 0 ] && [ -t 2 ]; then echo tty; else echo none; fi > "$LOGIN_MARKER"`,
+				provider.MetadataEnv:          map[string]string{"LOGIN_MARKER": marker},
+				provider.MetadataLoginTTY:     tt.loginTTY,
+			})
+			if err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			err = p.Login(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Login() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Login() error: %v", err)
+			}
+
+			data, readErr := os.ReadFile(marker)
+			if !tt.wantRun {
+				if readErr == nil {
+					t.Errorf("login command ran, want it not to")
+				}
+				return
+			}
+			if readErr != nil {
+				t.Fatalf("login command did not run: %v", readErr)
+			}
+			if tt.loginTTY && strings.TrimSpace(string(data)) != "tty" {
+				t.Errorf("login command saw %q, want a terminal", strings.TrimSpace(string(data)))
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// querySegment is one step of a JSON query: an object key or an array index
+type querySegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s querySegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return s.key
+}
+
+// parseQuery parses a dot/bracket path such as data.credentials[0].token
+// Keys containing dots or brackets can be quoted: data["my.key"]
+func parseQuery(query string) ([]querySegment, error) {
+	query = strings.TrimPrefix(query, ".")
+	if query == "" {
+		return nil, fmt.Errorf("empty json query")
+	}
+
+	var segments []querySegment
+	for i := 0; i < len(query); {
+		switch query[i] {
+		case '.':
+			// A dot separates segments; it can't start a segment or be doubled
+			if i == 0 || i+1 >= len(query) || query[i+1] == '.' || query[i+1] == '[' {
+				return nil, fmt.Errorf("invalid json query '%s': unexpected '.' at position %d", query, i)
+			}
+			i++
+
+		case '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json query '%s': missing ']'", query)
+			}
+			inner := query[i+1 : i+end]
+			if unquoted, err := strconv.Unquote(inner); err == nil && strings.HasPrefix(inner, `"`) {
+				segments = append(segments, querySegment{key: unquoted})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid json query '%s': '[%s]' is not an array index or quoted key", query, inner)
+				}
+				segments = append(segments, querySegment{index: index, isIndex: true})
+			}
+			i += end + 1
+			if i < len(query) && query[i] != '.' && query[i] != '[' {
+				return nil, fmt.Errorf("invalid json query '%s': expected '.' or '[' after ']'", query)
+			}
+
+		default:
+			end := strings.IndexAny(query[i:], ".[")
+			if end < 0 {
+				end = len(query) - i
+			}
+			segments = append(segments, querySegment{key: query[i : i+end]})
+			i += end
+		}
+	}
+
+	return segments, nil
+}
+
+// evalQuery walks decoded JSON along the query segments
+func evalQuery(data any, segments []querySegment) (any, error) {
+	current := data
+	path := ""
+
+	for _, seg := range segments {
+		if seg.isIndex {
+			arr, ok := current.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", describePath(path))
+			}
+			if seg.index >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range at %s (length %d)", seg.index, describePath(path), len(arr))
+			}
+			current = arr[seg.index]
+			path += seg.String()
+			continue
+		}
+
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", describePath(path))
+		}
+		value, ok := obj[seg.key]
+		if !ok {
+			return nil, fmt.Errorf("key '%s' not found at %s", seg.key, describePath(path))
+		}
+		current = value
+		if path != "" {
+			path += "."
+		}
+		path += seg.key
+	}
+
+	return current, nil
+}
+
+// describePath names a position in the document for error messages
+func describePath(path string) string {
+	if path == "" {
+		return "the document root"
+	}
+	return "'" + path + "'"
+}
+
+// queryJSON extracts the value at query from a JSON document as a string
+// Strings are returned unquoted; objects and arrays as compact JSON
+func queryJSON(data []byte, query string) (string, error) {
+	segments, err := parseQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("output is not valid JSON: %w", err)
+	}
+
+	value, err := evalQuery(doc, segments)
+	if err != nil {
+		return "", fmt.Errorf("json query '%s': %w", query, err)
+	}
+
+	return stringValue(value), nil
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"credctl/internal/provider"
+)
+
+func TestQueryJSON(t *testing.T) {
+	doc := `{
+		"data": {
+			"credentials": [
+				{"token": "first", "expires_in": 3600},
+				{"token": "second", "scopes": ["read", "write"]}
+			],
+			"my.key": "dotted",
+			"active": true,
+			"empty": null
+		}
+	}`
+
+	tests := []struct {
+		name        string
+		query       string
+		expected    string
+		shouldError bool
+	}{
+		{name: "nested object", query: "data.credentials[0].token", expected: "first"},
+		{name: "leading dot", query: ".data.credentials[1].token", expected: "second"},
+		{name: "number", query: "data.credentials[0].expires_in", expected: "3600"},
+		{name: "boolean", query: "data.active", expected: "true"},
+		{name: "null", query: "data.empty", expected: ""},
+		{name: "array value as JSON", query: "data.credentials[1].scopes", expected: `["read","write"]`},
+		{name: "chained index", query: "data.credentials[1].scopes[1]", expected: "write"},
+		{name: "quoted key", query: `data["my.key"]`, expected: "dotted"},
+		{name: "object value as JSON", query: "data.credentials[0]", expected: `{"expires_in":3600,"token":"first"}`},
+		{name: "missing key", query: "data.missing", shouldError: true},
+		{name: "index out of range", query: "data.credentials[5].token", shouldError: true},
+		{name: "index on object", query: "data[0]", shouldError: true},
+		{name: "key on array", query: "data.credentials.token", shouldError: true},
+		{name: "key on string", query: "data.active.value", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := queryJSON([]byte(doc), tt.query)
+
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none (result %q)", result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		segments    int
+		shouldError bool
+	}{
+		{name: "single key", query: "token", segments: 1},
+		{name: "dots and index", query: "a.b[0].c", segments: 4},
+		{name: "consecutive indices", query: "a[0][1]", segments: 3},
+		{name: "empty", query: "", shouldError: true},
+		{name: "double dot", query: "a..b", shouldError: true},
+		{name: "trailing dot", query: "a.", shouldError: true},
+		{name: "unclosed bracket", query: "a[0", shouldError: true},
+		{name: "negative index", query: "a[-1]", shouldError: true},
+		{name: "non-numeric index", query: "a[x]", shouldError: true},
+		{name: "garbage after bracket", query: "a[0]b", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := parseQuery(tt.query)
+
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if len(segments) != tt.segments {
+				t.Errorf("expected %d segments, got %d", tt.segments, len(segments))
+			}
+		})
+	}
+}
+
+func TestGet_JSONQuery(t *testing.T) {
+	p := &CommandProvider{}
+	err := p.Init(map[string]any{
+		provider.MetadataCommand:     `echo '{"data":{"credentials":[{"token":"abc123"}]},"region":"us-east-1"}'`,
+		provider.MetadataInputFormat: "json",
+		provider.MetadataJSONQuery:   "data.credentials[0].token",
+	})
+	if err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	output, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(output) != "abc123" {
+		t.Errorf("expected %q, got %q", "abc123", output)
+	}
+
+	// Structured credentials still expose the full document
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if creds.Get("region") != "us-east-1" || !creds.Has("data") {
+		t.Errorf("expected full fields, got %v", creds.Fields)
+	}
+
+	if err := p.Init(map[string]any{
+		provider.MetadataCommand:   "echo {}",
+		provider.MetadataJSONQuery: "a..b",
+	}); err == nil {
+		t.Error("expected Init() to reject an invalid json_query")
+	}
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal reports whether credctl's stdin is a terminal the login
+// command can be attached to (replaced in tests)
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// runInPTY runs cmd with a pseudo-terminal as its stdin, stdout and stderr,
+// relaying it to credctl's own terminal until the command exits
+func runInPTY(cmd *exec.Cmd) error {
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to start a pseudo-terminal: %w", err)
+	}
+	defer func() { _ = ptmx.Close() }()
+
+	// Keep the pseudo-terminal the size of ours
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer func() {
+		signal.Stop(resize)
+		close(resize)
+	}()
+	go func() {
+		for range resize {
+			_ = pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	resize <- syscall.SIGWINCH
+
+	// Raw mode hands every keystroke (including Ctrl-C) to the login command
+	fd := int(os.Stdin.Fd())
+	if state, err := term.MakeRaw(fd); err == nil {
+		defer func() { _ = term.Restore(fd, state) }()
+	}
+
+	go func() { _, _ = io.Copy(ptmx, os.Stdin) }()
+	// Reading fails (EIO) once the command exits and closes its side
+	_, _ = io.Copy(os.Stdout, ptmx)
+
+	return cmd.Wait()
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileKeys are the keys a config file may set besides the schema fields
+// (the output options, access policy, prefetch and on-error command every
+// provider accepts)
+var configFileKeys = []string{MetadataFormat, MetadataOutput, MetadataTemplate, MetadataAccessPolicy, MetadataPrefetch, MetadataOnErrorCommand, MetadataClaimsMapping}
+
+// configFileKeyTypes are the types of configFileKeys that aren't strings
+var configFileKeyTypes = map[string]FieldType{
+	MetadataClaimsMapping: FieldTypeStringMap,
+}
+
+// LoadConfigFile reads a provider config from a YAML or JSON file (.json files
+// are parsed as JSON, anything else as YAML) and converts its values to the
+// types of the schema fields. Unknown keys are an error.
+func LoadConfigFile(path string, schema Schema) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw, err := DecodeConfigData(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]FieldDef, len(schema.Fields))
+	for _, field := range schema.Fields {
+		fields[field.Name] = field
+	}
+
+	config := make(map[string]any, len(raw))
+	for key, value := range raw {
+		field, ok := fields[key]
+		if !ok {
+			if !slices.Contains(configFileKeys, key) {
+				return nil, fmt.Errorf("unknown field '%s' in config file (valid fields: %s)", key, strings.Join(configFileFieldNames(schema), ", "))
+			}
+			field = FieldDef{Name: key, Type: FieldTypeString}
+			if fieldType, ok := configFileKeyTypes[key]; ok {
+				field.Type = fieldType
+			}
+		}
+
+		converted, err := convertConfigValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s' in config file: %w", key, err)
+		}
+		config[key] = converted
+	}
+
+	return config, nil
+}
+
+// DecodeConfigData parses data as JSON when path ends in .json and as YAML
+// (a superset of JSON) otherwise
+func DecodeConfigData(data []byte, path string) (map[string]any, error) {
+	var raw map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON config file: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config file: %w", err)
+		}
+	}
+	return raw, nil
+}
+
+// convertConfigValue converts a decoded JSON/YAML value to the Go type used
+// for the field's type in provider configs
+func convertConfigValue(field FieldDef, value any) (any, error) {
+	switch field.Type {
+	case FieldTypeString:
+		return scalarString(value)
+
+	case FieldTypeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+
+	case FieldTypeInt:
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("must be an integer")
+			}
+			return int(n), nil
+		}
+		return nil, fmt.Errorf("must be an integer")
+
+	case FieldTypeStringSlice:
+		switch v := value.(type) {
+		case string:
+			// Comma-separated, like the flag
+			return strings.Split(v, ","), nil
+		case []any:
+			result := make([]string, 0, len(v))
+			for _, item := range v {
+				str, err := scalarString(item)
+				if err != nil {
+					return nil, fmt.Errorf("must be a list of strings")
+				}
+				result = append(result, str)
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("must be a list of strings")
+
+	case FieldTypeStringMap:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("must be a map of strings")
+		}
+		result := make(map[string]string, len(m))
+		for key, item := range m {
+			str, err := scalarString(item)
+			if err != nil {
+				return nil, fmt.Errorf("must be a map of strings")
+			}
+			result[key] = str
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field type %s", field.Type)
+}
+
+// scalarString returns a string, number or bool as a string (YAML reads
+// unquoted values such as 12345 or true as numbers and bools)
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, int64, float64, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("must be a string")
+}
+
+// configFileFieldNames returns the sorted keys a config file may set
+func configFileFieldNames(schema Schema) []string {
+	names := append([]string(nil), configFileKeys...)
+	for _, field := range schema.Fields {
+		names = append(names, field.Name)
+	}
+	sort.Strings(names)
+	return names
+}
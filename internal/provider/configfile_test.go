@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// configFileSchema has a field of every type
+var configFileSchema = Schema{Fields: []FieldDef{
+	{Name: "client_id", Type: FieldTypeString, Required: true},
+	{Name: "issuer", Type: FieldTypeString},
+	{Name: "no_browser", Type: FieldTypeBool},
+	{Name: "redirect_port", Type: FieldTypeInt, Default: "8085"},
+	{Name: "scopes", Type: FieldTypeStringSlice},
+	{Name: "auth_params", Type: FieldTypeStringMap},
+}}
+
+// writeConfigFile writes content to a file named name in a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	want := map[string]any{
+		"client_id":     "12345",
+		"no_browser":    true,
+		"redirect_port": 9000,
+		"scopes":        []string{"openid", "email"},
+		"auth_params":   map[string]string{"prompt": "consent"},
+		"format":        "json",
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		want        map[string]any
+		errContains string
+	}{
+		{
+			name: "yaml",
+			file: "prov.yaml",
+			content: `client_id: 12345
+no_browser: true
+redirect_port: 9000
+scopes: [openid, email]
+auth_params:
+  prompt: consent
+format: json
+`,
+			want: want,
+		},
+		{
+			name:    "json",
+			file:    "prov.json",
+			content: `{"client_id": "12345", "no_browser": true, "redirect_port": 9000, "scopes": ["openid", "email"], "auth_params": {"prompt": "consent"}, "format": "json"}`,
+			want:    want,
+		},
+		{
+			name:    "comma-separated list",
+			file:    "prov.yml",
+			content: "scopes: openid,email\n",
+			want:    map[string]any{"scopes": []string{"openid", "email"}},
+		},
+		{
+			name:    "claims mapping",
+			file:    "prov.yaml",
+			content: "claims_mapping:\n  user: token_sub\n",
+			want:    map[string]any{"claims_mapping": map[string]string{"user": "token_sub"}},
+		},
+		{name: "unknown field", file: "prov.yaml", content: "client_secrt: x\n", errContains: "unknown field 'client_secrt'"},
+		{name: "wrong type", file: "prov.yaml", content: "redirect_port: high\n", errContains: "must be an integer"},
+		{name: "fractional int", file: "prov.json", content: `{"redirect_port": 80.5}`, errContains: "must be an integer"},
+		{name: "invalid JSON", file: "prov.json", content: "client_id: x\n", errContains: "failed to parse JSON"},
+		{name: "invalid YAML", file: "prov.yaml", content: "client_id: [x\n", errContains: "failed to parse YAML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadConfigFile(writeConfigFile(t, tt.file, tt.content), configFileSchema)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, tt.want) {
+				t.Errorf("config = %#v, want %#v", config, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractConfigWithBase(t *testing.T) {
+	base := map[string]any{
+		"client_id":     "from-file",
+		"issuer":        "https://file.example.com",
+		"redirect_port": 9000,
+	}
+
+	tests := []struct {
+		name        string
+		base        map[string]any
+		args        []string
+		want        map[string]any
+		shouldError bool
+	}{
+		{
+			name: "file only",
+			base: base,
+			want: map[string]any{"client_id": "from-file", "issuer": "https://file.example.com", "redirect_port": 9000},
+		},
+		{
+			name: "flags only",
+			args: []string{"--client_id", "from-flag"},
+			want: map[string]any{"client_id": "from-flag", "redirect_port": 8085},
+		},
+		{
+			name: "flags win over the file",
+			base: base,
+			args: []string{"--client_id", "from-flag", "--redirect_port", "7000"},
+			want: map[string]any{"client_id": "from-flag", "issuer": "https://file.example.com", "redirect_port": 7000},
+		},
+		{
+			name:        "required field in neither",
+			base:        map[string]any{"issuer": "https://file.example.com"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			if err := AddSchemaFlags(cmd, configFileSchema); err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := ExtractConfigWithBase(cmd, configFileSchema, tt.base)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(config, tt.want) {
+				t.Errorf("config = %#v, want %#v", config, tt.want)
+			}
+		})
+	}
+}
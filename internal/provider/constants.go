@@ -3,12 +3,28 @@ package provider
 // Metadata field keys - constants to avoid hardcoding strings
 const (
 	// Common fields
-	MetadataCommand      = "command"
-	MetadataLoginCommand = "login_command"
-	MetadataTemplate     = "template"     // Go template for output formatting
-	MetadataInputFormat  = "input_format" // Format of command output (raw, json, env, yaml)
-	MetadataFormat       = "format"       // Output format for credctl get (json, text, escaped)
-	MetadataOutput       = "output"       // Default output file path
+	MetadataCommand        = "command"
+	MetadataLoginCommand   = "login_command"
+	MetadataTemplate       = "template"         // Go template for output formatting
+	MetadataInputFormat    = "input_format"     // Format of command output (raw, json, env, yaml)
+	MetadataFormat         = "format"           // Output format for credctl get (json, text, escaped)
+	MetadataOutput         = "output"           // Default output file path
+	MetadataAccessPolicy   = "access_policy"    // Which daemon sockets may read the credential
+	MetadataPrefetch       = "prefetch"         // Fetch the credential when the daemon starts
+	MetadataOnErrorCommand = "on_error_command" // Command run when fetching the credential fails
+	MetadataAliasTarget    = "target"           // Provider an alias stands for
+	MetadataClaimsMapping  = "claims_mapping"   // Stable template names for credential fields and JWT claims
+)
+
+// Command provider metadata field keys
+const (
+	MetadataShell          = "shell"            // Shell used to run commands (default /bin/sh)
+	MetadataEnv            = "env"              // Environment variables to set or override
+	MetadataWorkingDir     = "working_dir"      // Working directory for commands
+	MetadataJSONQuery      = "json_query"       // Path of the JSON value returned as the credential
+	MetadataTransform      = "transform"        // Command the output is piped through before parsing
+	MetadataMaxOutputBytes = "max_output_bytes" // Largest command output read before failing
+	MetadataLoginTTY       = "login_tty"        // Run the login command in a pseudo-terminal
 )
 
 // OIDC metadata field keys
@@ -22,4 +38,94 @@ const (
 	MetadataDeviceEndpoint = "device_endpoint"
 	MetadataRedirectPort   = "redirect_port"
 	MetadataRedirectURI    = "redirect_uri"
+	MetadataCallbackHost   = "callback_bind_host"
+	MetadataNoBrowser      = "no_browser"
+	MetadataBrowserCommand = "browser_command"
+	MetadataAuthParams     = "auth_params"
+	MetadataTokenParams    = "token_params"
+	MetadataProfiles       = "profiles" // Named scope sets and audiences, selected with name@profile
+	MetadataUsername       = "username"
+	MetadataPassword       = "password"
+	MetadataHTTPProxy      = "http_proxy"
+	MetadataHTTPHeaders    = "http_headers"
+
+	// Token response field mapping (non-standard token endpoints)
+	MetadataAccessTokenField  = "access_token_field"
+	MetadataRefreshTokenField = "refresh_token_field"
+	MetadataExpiresInField    = "expires_in_field"
+	MetadataRawResponse       = "raw_response" // Return the whole token endpoint response instead of the access token
+
+	// ID token validation
+	MetadataExpectedAudiences = "expected_audiences"
+	MetadataSkipClientIDCheck = "skip_client_id_check"
+	MetadataACRValues         = "acr_values"         // Required authentication context classes (step-up)
+	MetadataAllowedClockSkew  = "allowed_clock_skew" // Seconds of clock skew with the issuer tolerated for ID tokens
+
+	// OIDC discovery
+	MetadataAllowIssuerMismatch = "allow_issuer_mismatch"
+	MetadataProbeDeviceEndpoint = "probe_device_endpoint" // Look for an undiscovered device endpoint at conventional paths
+
+	// Transport security
+	MetadataAllowInsecureEndpoints = "allow_insecure_endpoints"
+
+	// Token cache
+	MetadataExpiryBuffer = "expiry_buffer_seconds"
+
+	// Client authentication (RFC 8705 mutual TLS)
+	MetadataClientAuthMethod = "client_auth_method"
+	MetadataClientCert       = "client_cert"
+	MetadataClientKey        = "client_key"
+
+	// Pushed authorization requests (RFC 9126)
+	MetadataPAREndpoint = "pushed_authorization_request_endpoint"
+
+	// Token exchange (RFC 8693)
+	MetadataSubjectTokenSource = "subject_token_source"
+	MetadataSubjectTokenType   = "subject_token_type"
+	MetadataAudience           = "audience"
+	MetadataRequestedTokenType = "requested_token_type"
+
+	// Delegation for the client credentials flow (act on behalf of a subject)
+	MetadataSubject          = "subject"
+	MetadataActorTokenSource = "actor_token_source"
+	MetadataActorTokenType   = "actor_token_type"
+)
+
+// Plugin metadata field keys
+const (
+	MetadataPluginPath    = "plugin_path"
+	MetadataPluginConfig  = "plugin_config"
+	MetadataPluginTimeout = "plugin_timeout"
 )
+
+// TOTP metadata field keys
+const (
+	MetadataTOTPSecret    = "secret"
+	MetadataTOTPDigits    = "digits"
+	MetadataTOTPPeriod    = "period"
+	MetadataTOTPAlgorithm = "algorithm"
+)
+
+// AuthRelevantKeys lists metadata keys that affect how tokens are issued.
+// Cached tokens are only kept across a re-add when none of these change.
+var AuthRelevantKeys = []string{
+	MetadataIssuer,
+	MetadataClientID,
+	MetadataClientSecret,
+	MetadataScopes,
+	MetadataTokenEndpoint,
+	MetadataAuthEndpoint,
+	MetadataDeviceEndpoint,
+	MetadataUsername,
+	MetadataPassword,
+	MetadataClientAuthMethod,
+	MetadataClientCert,
+	MetadataSubjectTokenSource,
+	MetadataAudience,
+	MetadataRequestedTokenType,
+	MetadataSubject,
+	MetadataActorTokenSource,
+	MetadataACRValues,
+	"flow",     // oauth2 grant type
+	"auth_url", // oauth2-proxy endpoint
+}
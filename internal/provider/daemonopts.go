@@ -0,0 +1,51 @@
+package provider
+
+// DaemonOptions are the settings every provider accepts that the daemon
+// applies around it, set at add-time via --admin-only, --prefetch and
+// --on-error-command
+type DaemonOptions struct {
+	AccessPolicy   string // Which daemon sockets may read the credential
+	Prefetch       bool   // Fetch the credential when the daemon starts
+	OnErrorCommand string // Run when fetching the credential fails
+}
+
+// LoadDaemonOptions reads the daemon settings from provider config
+func LoadDaemonOptions(config map[string]any) (DaemonOptions, error) {
+	accessPolicy, err := LoadAccessPolicy(config)
+	if err != nil {
+		return DaemonOptions{}, err
+	}
+
+	return DaemonOptions{
+		AccessPolicy:   accessPolicy,
+		Prefetch:       GetBoolOrDefault(config, MetadataPrefetch, false),
+		OnErrorCommand: GetStringOrDefault(config, MetadataOnErrorCommand, ""),
+	}, nil
+}
+
+// AddToMetadata stores non-default daemon settings in provider metadata
+func (o DaemonOptions) AddToMetadata(metadata map[string]any) {
+	if o.AccessPolicy != "" && o.AccessPolicy != AccessPolicyAny {
+		metadata[MetadataAccessPolicy] = o.AccessPolicy
+	}
+
+	if o.Prefetch {
+		metadata[MetadataPrefetch] = true
+	}
+
+	if o.OnErrorCommand != "" {
+		metadata[MetadataOnErrorCommand] = o.OnErrorCommand
+	}
+}
+
+// IsPrefetch reports whether a provider's metadata asks for its credential
+// to be fetched when the daemon starts
+func IsPrefetch(metadata map[string]any) bool {
+	return GetBoolOrDefault(metadata, MetadataPrefetch, false)
+}
+
+// OnErrorCommand returns the command a provider's metadata asks the daemon to
+// run when fetching its credential fails ("" when there is none)
+func OnErrorCommand(metadata map[string]any) string {
+	return GetStringOrDefault(metadata, MetadataOnErrorCommand, "")
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadDaemonOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		want        DaemonOptions
+		metadata    map[string]any
+		shouldError bool
+	}{
+		{
+			name:     "defaults",
+			config:   map[string]any{},
+			want:     DaemonOptions{AccessPolicy: AccessPolicyAny},
+			metadata: map[string]any{},
+		},
+		{
+			name: "all settings",
+			config: map[string]any{
+				MetadataAccessPolicy:   AccessPolicyAdminOnly,
+				MetadataPrefetch:       true,
+				MetadataOnErrorCommand: "notify-send credctl",
+			},
+			want: DaemonOptions{AccessPolicy: AccessPolicyAdminOnly, Prefetch: true, OnErrorCommand: "notify-send credctl"},
+			metadata: map[string]any{
+				MetadataAccessPolicy:   AccessPolicyAdminOnly,
+				MetadataPrefetch:       true,
+				MetadataOnErrorCommand: "notify-send credctl",
+			},
+		},
+		{
+			name:        "invalid access policy",
+			config:      map[string]any{MetadataAccessPolicy: "nobody"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadDaemonOptions(tt.config)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoadDaemonOptions() = %+v, want %+v", got, tt.want)
+			}
+
+			metadata := map[string]any{}
+			got.AddToMetadata(metadata)
+			if !reflect.DeepEqual(metadata, tt.metadata) {
+				t.Errorf("AddToMetadata() = %v, want %v", metadata, tt.metadata)
+			}
+			if IsPrefetch(metadata) != tt.want.Prefetch || OnErrorCommand(metadata) != tt.want.OnErrorCommand {
+				t.Errorf("metadata %v doesn't read back as %+v", metadata, tt.want)
+			}
+		})
+	}
+}
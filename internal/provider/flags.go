@@ -1,28 +1,46 @@
 package provider
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 )
 
+// Base64FlagSuffix names the companion flag of a sensitive string field that
+// marks its value as base64-encoded (e.g. --client_secret_b64)
+const Base64FlagSuffix = "_b64"
+
 // AddSchemaFlags adds flags to a cobra command based on the provider schema
-// Panics if there's a flag collision between providers
-func AddSchemaFlags(cmd *cobra.Command, schema Schema) {
+// Returns an error, without adding any flag, if a field collides with a flag
+// already defined on the command (e.g. by another provider's schema)
+func AddSchemaFlags(cmd *cobra.Command, schema Schema) error {
+	// Check every field first so a collision leaves the command untouched
+	seen := make(map[string]bool, len(schema.Fields))
 	for _, field := range schema.Fields {
-		flagName := field.Name
-
-		// Check if flag already exists (collision detection)
-		if cmd.Flags().Lookup(flagName) != nil {
-			panic(fmt.Sprintf("flag collision detected: --%s is defined by multiple providers", flagName))
+		for _, flagName := range schemaFlagNames(field) {
+			if cmd.Flags().Lookup(flagName) != nil || seen[flagName] {
+				return fmt.Errorf("flag collision detected: --%s is already defined", flagName)
+			}
+			seen[flagName] = true
 		}
+	}
+
+	for _, field := range schema.Fields {
+		flagName := field.Name
 
 		switch field.Type {
 		case FieldTypeString:
 			defaultVal := field.Default
 			cmd.Flags().String(flagName, defaultVal, field.Help)
+			if field.Hidden {
+				cmd.Flags().Bool(flagName+Base64FlagSuffix, false, fmt.Sprintf("Decode --%s from base64 (standard or URL-safe)", flagName))
+			}
 
 		case FieldTypeBool:
 			defaultVal := field.Default == "true"
@@ -43,6 +61,9 @@ func AddSchemaFlags(cmd *cobra.Command, schema Schema) {
 				defaultVal = strings.Split(field.Default, ",")
 			}
 			cmd.Flags().StringSlice(flagName, defaultVal, field.Help)
+
+		case FieldTypeStringMap:
+			cmd.Flags().StringToString(flagName, nil, field.Help)
 		}
 
 		// Mark as required if needed
@@ -50,11 +71,23 @@ func AddSchemaFlags(cmd *cobra.Command, schema Schema) {
 			_ = cmd.MarkFlagRequired(flagName)
 		}
 	}
+
+	return nil
 }
 
 // ExtractConfig extracts configuration values from cobra command flags
 func ExtractConfig(cmd *cobra.Command, schema Schema) (map[string]any, error) {
+	return ExtractConfigWithBase(cmd, schema, nil)
+}
+
+// ExtractConfigWithBase extracts configuration values from cobra command flags
+// over a base config (e.g. from a config file): flags that were set win, and
+// base values win over flag defaults
+func ExtractConfigWithBase(cmd *cobra.Command, schema Schema, base map[string]any) (map[string]any, error) {
 	config := make(map[string]any)
+	for key, value := range base {
+		config[key] = value
+	}
 
 	for _, field := range schema.Fields {
 		flagName := field.Name
@@ -65,6 +98,11 @@ func ExtractConfig(cmd *cobra.Command, schema Schema) (map[string]any, error) {
 			continue
 		}
 
+		// Keep the base value unless the flag was set
+		if _, ok := base[field.Name]; ok && !flag.Changed {
+			continue
+		}
+
 		// If flag wasn't changed and it's not required, use default
 		if !flag.Changed && !field.Required {
 			if field.Default != "" {
@@ -90,6 +128,16 @@ func ExtractConfig(cmd *cobra.Command, schema Schema) (map[string]any, error) {
 		case FieldTypeString:
 			var val string
 			val, err = cmd.Flags().GetString(flagName)
+			if err == nil && field.Hidden {
+				val, err = ReadSecretValue(val, cmd.InOrStdin())
+			}
+			if err == nil && field.Hidden {
+				var encoded bool
+				encoded, err = cmd.Flags().GetBool(flagName + Base64FlagSuffix)
+				if err == nil && encoded {
+					val, err = decodeBase64Secret(val)
+				}
+			}
 			if err == nil && val != "" {
 				config[field.Name] = val
 			}
@@ -114,6 +162,13 @@ func ExtractConfig(cmd *cobra.Command, schema Schema) (map[string]any, error) {
 			if err == nil && len(val) > 0 {
 				config[field.Name] = val
 			}
+
+		case FieldTypeStringMap:
+			var val map[string]string
+			val, err = cmd.Flags().GetStringToString(flagName)
+			if err == nil && len(val) > 0 {
+				config[field.Name] = val
+			}
 		}
 
 		if err != nil {
@@ -128,3 +183,55 @@ func ExtractConfig(cmd *cobra.Command, schema Schema) (map[string]any, error) {
 
 	return config, nil
 }
+
+// ReadSecretValue resolves a sensitive flag value so secrets stay out of shell history:
+// "@path" reads the value from a file and "-" reads it from stdin
+// (trailing newlines are trimmed). Other values are returned unchanged.
+func ReadSecretValue(val string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case val == "-":
+		data, err = io.ReadAll(stdin)
+	case strings.HasPrefix(val, "@"):
+		data, err = os.ReadFile(strings.TrimPrefix(val, "@"))
+	default:
+		return val, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// decodeBase64Secret decodes a base64 flag value, standard or URL-safe, with
+// or without padding. The result must be text, as configs are stored as JSON.
+func decodeBase64Secret(val string) (string, error) {
+	val = strings.TrimSpace(val)
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(val, "-_") {
+		encoding = base64.URLEncoding
+	}
+	if !strings.HasSuffix(val, "=") {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+
+	data, err := encoding.DecodeString(val)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 secret: %w", err)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("decoded base64 secret is not valid UTF-8 text")
+	}
+	return string(data), nil
+}
+
+// schemaFlagNames returns the flags AddSchemaFlags defines for a field
+func schemaFlagNames(field FieldDef) []string {
+	if field.Type == FieldTypeString && field.Hidden {
+		return []string{field.Name, field.Name + Base64FlagSuffix}
+	}
+	return []string{field.Name}
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAddSchemaFlagsCollision(t *testing.T) {
+	first := Schema{Fields: []FieldDef{
+		{Name: "endpoint", Type: FieldTypeString},
+		{Name: "region", Type: FieldTypeString, Help: "first provider's region"},
+	}}
+	second := Schema{Fields: []FieldDef{
+		{Name: "bucket", Type: FieldTypeString},
+		{Name: "region", Type: FieldTypeInt, Help: "second provider's region"},
+	}}
+
+	tests := []struct {
+		name        string
+		schemas     []Schema
+		shouldError bool
+	}{
+		{
+			name:    "single schema",
+			schemas: []Schema{first},
+		},
+		{
+			name:        "two schemas sharing a custom field",
+			schemas:     []Schema{first, second},
+			shouldError: true,
+		},
+		{
+			name:        "duplicate field within a schema",
+			schemas:     []Schema{{Fields: []FieldDef{{Name: "x", Type: FieldTypeString}, {Name: "x", Type: FieldTypeBool}}}},
+			shouldError: true,
+		},
+		{
+			name:        "field colliding with a command flag",
+			schemas:     []Schema{{Fields: []FieldDef{{Name: "force", Type: FieldTypeBool}}}},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("force", false, "command flag")
+
+			var err error
+			for _, schema := range tt.schemas {
+				if err = AddSchemaFlags(cmd, schema); err != nil {
+					break
+				}
+			}
+
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// A rejected schema adds none of its flags, and earlier ones are kept
+	cmd := &cobra.Command{}
+	if err := AddSchemaFlags(cmd, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSchemaFlags(cmd, second); err == nil {
+		t.Fatal("expected collision error")
+	}
+	if cmd.Flags().Lookup("bucket") != nil {
+		t.Error("expected no flags from the colliding schema")
+	}
+	if flag := cmd.Flags().Lookup("region"); flag == nil || flag.Usage != "first provider's region" {
+		t.Error("expected the first schema's flag to be kept")
+	}
+}
+
+func TestReadSecretValue(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		value       string
+		stdin       string
+		want        string
+		shouldError bool
+	}{
+		{name: "literal value", value: "plain", want: "plain"},
+		{name: "empty value", value: "", want: ""},
+		{name: "from file", value: "@" + secretFile, want: "from-file"},
+		{name: "from stdin", value: "-", stdin: "from-stdin\r\n", want: "from-stdin"},
+		{name: "missing file", value: "@" + filepath.Join(t.TempDir(), "missing"), shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSecretValue(tt.value, strings.NewReader(tt.stdin))
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadSecretValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractConfigBase64Secret(t *testing.T) {
+	schema := Schema{Fields: []FieldDef{
+		{Name: "client_id", Type: FieldTypeString},
+		{Name: "client_secret", Type: FieldTypeString, Hidden: true},
+	}}
+
+	// Values awkward to quote on a command line
+	pem := "-----BEGIN KEY-----\nMIIB+/==\n-----END KEY-----\n"
+
+	tests := []struct {
+		name        string
+		args        []string
+		want        string
+		shouldError bool
+	}{
+		{name: "plain value", args: []string{"--client_secret", "plain"}, want: "plain"},
+		{name: "standard encoding", args: []string{"--client_secret", "czNjciJ0Jz0K", "--client_secret_b64"}, want: "s3cr\"t'=\n"},
+		{name: "URL-safe unpadded", args: []string{"--client_secret", base64.RawURLEncoding.EncodeToString([]byte(pem)), "--client_secret_b64"}, want: pem},
+		{name: "URL-safe padded", args: []string{"--client_secret", base64.URLEncoding.EncodeToString([]byte("?>?>")), "--client_secret_b64"}, want: "?>?>"},
+		{name: "invalid base64", args: []string{"--client_secret", "not base64!", "--client_secret_b64"}, shouldError: true},
+		{name: "binary value", args: []string{"--client_secret", base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe}), "--client_secret_b64"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			if err := AddSchemaFlags(cmd, schema); err != nil {
+				t.Fatal(err)
+			}
+			if cmd.Flags().Lookup("client_id_b64") != nil {
+				t.Error("expected no base64 flag for a non-sensitive field")
+			}
+			if err := cmd.Flags().Parse(append([]string{"--client_id", "id"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := ExtractConfig(cmd, schema)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := config["client_secret"]; got != tt.want {
+				t.Errorf("client_secret = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"credctl/internal/paths"
+)
+
+// ErrGroupNotFound is returned for a group that doesn't exist
+var ErrGroupNotFound = errors.New("group not found")
+
+// StoredGroup is a named set of providers fetched together, as stored in JSON
+type StoredGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// SaveGroup persists a group to disk
+func SaveGroup(group StoredGroup) error {
+	if group.Name == "" {
+		return fmt.Errorf("group name cannot be empty")
+	}
+	if len(group.Members) == 0 {
+		return fmt.Errorf("group '%s' needs at least one member", group.Name)
+	}
+
+	filePath, err := groupFilePath(group.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return fmt.Errorf("failed to create groups directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(group, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal group: %w", err)
+	}
+
+	return withStoreLock(func() error {
+		return writeFileAtomic(filePath, data)
+	})
+}
+
+// LoadGroup reads a group from disk
+func LoadGroup(name string) (StoredGroup, error) {
+	filePath, err := groupFilePath(name)
+	if err != nil {
+		return StoredGroup{}, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return StoredGroup{}, fmt.Errorf("%w: %s", ErrGroupNotFound, name)
+		}
+		return StoredGroup{}, fmt.Errorf("failed to read group file: %w", err)
+	}
+
+	var group StoredGroup
+	if err := json.Unmarshal(data, &group); err != nil {
+		return StoredGroup{}, fmt.Errorf("failed to parse group %s: %w", name, err)
+	}
+	group.Name = name
+	return group, nil
+}
+
+// GroupExists checks if a group exists on disk
+func GroupExists(name string) (bool, error) {
+	filePath, err := groupFilePath(name)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(filePath)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// DeleteGroup removes a group from disk (its members are kept)
+func DeleteGroup(name string) error {
+	filePath, err := groupFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	return withStoreLock(func() error {
+		if err := os.Remove(filePath); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%w: %s", ErrGroupNotFound, name)
+			}
+			return fmt.Errorf("failed to delete group file: %w", err)
+		}
+		return nil
+	})
+}
+
+func groupFilePath(name string) (string, error) {
+	if name == "" || filepath.Base(name) != name || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid group name '%s'", name)
+	}
+
+	dir, err := paths.GroupsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
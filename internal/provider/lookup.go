@@ -0,0 +1,25 @@
+package provider
+
+import "sync"
+
+var (
+	lookupMu sync.RWMutex
+	lookup   = Load
+)
+
+// SetLookup sets how providers that consume another provider's credential
+// (e.g. token-exchange) resolve it by name. The daemon points this at its
+// in-memory state so cached tokens are shared; the default loads from disk.
+func SetLookup(fn func(name string) (Provider, error)) {
+	lookupMu.Lock()
+	defer lookupMu.Unlock()
+	lookup = fn
+}
+
+// Lookup resolves a provider by name
+func Lookup(name string) (Provider, error) {
+	lookupMu.RLock()
+	fn := lookup
+	lookupMu.RUnlock()
+	return fn(name)
+}
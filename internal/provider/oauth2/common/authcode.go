@@ -58,14 +58,53 @@ type CallbackResult struct {
 	Params url.Values // All query parameters received in the callback
 }
 
+// DefaultCallbackHost is the interface the callback server binds to unless configured otherwise
+const DefaultCallbackHost = "127.0.0.1"
+
+// ValidateCallbackHost checks that a configured bind host is an IP address or "localhost"
+func ValidateCallbackHost(host string) error {
+	if host == "" || host == "localhost" || net.ParseIP(host) != nil {
+		return nil
+	}
+	return fmt.Errorf("invalid callback_bind_host %q: must be an IP address (e.g. 127.0.0.1, ::1) or localhost", host)
+}
+
+// CallbackAddr returns the listen address for the callback server,
+// binding to the loopback interface when no host is configured
+func CallbackAddr(host string, port int) string {
+	if host == "" {
+		host = DefaultCallbackHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// CallbackRedirectURI builds a redirect URI whose hostname matches the bind host
+// Unspecified addresses (0.0.0.0, ::) are reached through localhost.
+func CallbackRedirectURI(host string, port int, path string) string {
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	u := url.URL{Scheme: "http", Host: CallbackAddr(host, port), Path: path}
+	return u.String()
+}
+
+// isLoopbackHost reports whether a redirect URI hostname points at this machine
+func isLoopbackHost(hostname string) bool {
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
 // StartCallbackServer starts a local HTTP server and waits for a callback
 // This is a generic function that can be used by multiple authentication flows
 // It returns all query parameters received without performing any validation
-func StartCallbackServer(ctx context.Context, port int, path string) (*CallbackResult, error) {
+func StartCallbackServer(ctx context.Context, host string, port int, path string) (*CallbackResult, error) {
 	resultChan := make(chan *CallbackResult, 1)
 	errChan := make(chan error, 1)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	listener, err := net.Listen("tcp", CallbackAddr(host, port))
 	if err != nil {
 		return nil, fmt.Errorf("failed to start callback server: %w", err)
 	}
@@ -99,6 +138,12 @@ func StartCallbackServer(ctx context.Context, port int, path string) (*CallbackR
 		}
 	}()
 
+	// A caller deadline replaces the default limit
+	timeout := time.After(5 * time.Minute)
+	if _, ok := ctx.Deadline(); ok {
+		timeout = nil
+	}
+
 	var result *CallbackResult
 	select {
 	case result = <-resultChan:
@@ -108,7 +153,7 @@ func StartCallbackServer(ctx context.Context, port int, path string) (*CallbackR
 	case <-ctx.Done():
 		_ = server.Close()
 		return nil, ctx.Err()
-	case <-time.After(5 * time.Minute):
+	case <-timeout:
 		_ = server.Close()
 		return nil, fmt.Errorf("authentication timed out")
 	}
@@ -122,12 +167,18 @@ func StartCallbackServer(ctx context.Context, port int, path string) (*CallbackR
 
 // AuthCodeFlowParams contains parameters for authorization code flow
 type AuthCodeFlowParams struct {
-	AuthEndpoint string
-	ClientID     string
-	Scopes       []string
-	RedirectURI  string
-	RedirectPort int
-	UsePKCE      bool // If true, use PKCE extension
+	AuthEndpoint   string
+	ClientID       string
+	Scopes         []string
+	RedirectURI    string
+	RedirectPort   int
+	CallbackHost   string            // Interface for the callback server (default 127.0.0.1)
+	NoBrowser      bool              // Print the authorization URL instead of opening a browser
+	BrowserCommand string            // Command that opens the authorization URL (default: platform browser)
+	UsePKCE        bool              // If true, use PKCE extension
+	ExtraParams    map[string]string // Additional authorization request parameters (e.g., prompt, login_hint)
+	PAREndpoint    string            // If set, push the request parameters there first (RFC 9126)
+	ClientSecret   string            // Client authentication at the PAR endpoint
 }
 
 // AuthenticateAuthCodeFlow performs OAuth2 authorization code flow (with optional PKCE)
@@ -153,7 +204,7 @@ func AuthenticateAuthCodeFlow(ctx context.Context, params AuthCodeFlowParams) (c
 	isLocalhost := true
 
 	if redirectURI == "" {
-		redirectURI = fmt.Sprintf("http://localhost:%d/callback", params.RedirectPort)
+		redirectURI = CallbackRedirectURI(params.CallbackHost, params.RedirectPort, callbackPath)
 	} else {
 		parsedURI, err := url.Parse(redirectURI)
 		if err != nil {
@@ -161,7 +212,7 @@ func AuthenticateAuthCodeFlow(ctx context.Context, params AuthCodeFlowParams) (c
 		}
 
 		hostname := parsedURI.Hostname()
-		isLocalhost = hostname == "localhost" || hostname == "127.0.0.1"
+		isLocalhost = isLoopbackHost(hostname) || hostname == params.CallbackHost
 
 		if isLocalhost {
 			if parsedURI.Port() != "" {
@@ -177,34 +228,18 @@ func AuthenticateAuthCodeFlow(ctx context.Context, params AuthCodeFlowParams) (c
 		}
 	}
 
-	// Build authorization URL using oauth2.Config
-	config := &oauth2.Config{
-		ClientID:    params.ClientID,
-		RedirectURL: redirectURI,
-		Scopes:      params.Scopes,
-		Endpoint: oauth2.Endpoint{
-			AuthURL: params.AuthEndpoint,
-		},
-	}
-
-	// Add PKCE parameters if enabled
-	var authCodeOptions []oauth2.AuthCodeOption
-	if params.UsePKCE {
-		authCodeOptions = append(authCodeOptions,
-			oauth2.SetAuthURLParam("code_challenge", codeChallenge),
-			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-		)
+	authURL, err := authorizationURL(ctx, params, redirectURI, state, codeChallenge)
+	if err != nil {
+		return "", "", "", err
 	}
 
-	authURL := config.AuthCodeURL(state, authCodeOptions...)
-
-	if err := OpenBrowser(authURL); err != nil {
-		return "", "", "", fmt.Errorf("failed to open browser: %w", err)
+	if err := PresentAuthURL(authURL, params.NoBrowser, params.BrowserCommand); err != nil {
+		return "", "", "", err
 	}
 
 	if isLocalhost {
 		// Use the generic callback server
-		result, err := StartCallbackServer(ctx, serverPort, callbackPath)
+		result, err := StartCallbackServer(ctx, params.CallbackHost, serverPort, callbackPath)
 		if err != nil {
 			return "", "", "", err
 		}
@@ -242,3 +277,48 @@ func AuthenticateAuthCodeFlow(ctx context.Context, params AuthCodeFlowParams) (c
 
 	return code, codeVerifier, redirectURI, nil
 }
+
+// authorizationURL returns the URL the user is sent to. With a PAR endpoint the
+// request parameters are pushed first and the URL only carries the request_uri.
+func authorizationURL(ctx context.Context, params AuthCodeFlowParams, redirectURI, state, codeChallenge string) (string, error) {
+	authURL := buildAuthURL(params, redirectURI, state, codeChallenge)
+	if params.PAREndpoint == "" {
+		return authURL, nil
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	requestURI, err := PushAuthorizationRequest(ctx, params.PAREndpoint, params.ClientID, params.ClientSecret, parsed.Query())
+	if err != nil {
+		return "", err
+	}
+
+	return buildPushedAuthURL(params.AuthEndpoint, params.ClientID, requestURI)
+}
+
+// buildAuthURL builds the authorization URL including PKCE and any extra parameters
+func buildAuthURL(params AuthCodeFlowParams, redirectURI, state, codeChallenge string) string {
+	config := &oauth2.Config{
+		ClientID:    params.ClientID,
+		RedirectURL: redirectURI,
+		Scopes:      params.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL: params.AuthEndpoint,
+		},
+	}
+
+	// Extra parameters go first so they cannot override PKCE (the other
+	// parameters the flow sets are rejected by CheckReservedParams)
+	authCodeOptions := ParamsToOptions(params.ExtraParams)
+	if params.UsePKCE {
+		authCodeOptions = append(authCodeOptions,
+			oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	}
+
+	return config.AuthCodeURL(state, authCodeOptions...)
+}
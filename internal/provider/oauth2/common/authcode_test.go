@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestBuildAuthURLExtraParams(t *testing.T) {
+	params := AuthCodeFlowParams{
+		AuthEndpoint: "https://idp.example.com/authorize",
+		ClientID:     "my-client",
+		Scopes:       []string{"openid", "email"},
+		UsePKCE:      true,
+		ExtraParams: map[string]string{
+			"prompt":         "consent",
+			"login_hint":     "user@example.com",
+			"code_challenge": "attacker-controlled",
+		},
+	}
+
+	authURL := buildAuthURL(params, "http://localhost:8085/callback", "state123", "challenge123")
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("failed to parse auth URL: %v", err)
+	}
+	query := parsed.Query()
+
+	expected := map[string]string{
+		"client_id":             "my-client",
+		"state":                 "state123",
+		"prompt":                "consent",
+		"login_hint":            "user@example.com",
+		"code_challenge":        "challenge123",
+		"code_challenge_method": "S256",
+	}
+	for key, want := range expected {
+		if got := query.Get(key); got != want {
+			t.Errorf("param %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCallbackAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{name: "defaults to IPv4 loopback", host: "", expected: "127.0.0.1:8085"},
+		{name: "IPv6 loopback", host: "::1", expected: "[::1]:8085"},
+		{name: "specific interface", host: "192.168.1.10", expected: "192.168.1.10:8085"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CallbackAddr(tt.host, 8085); got != tt.expected {
+				t.Errorf("CallbackAddr() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCallbackRedirectURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{name: "default host", host: "", expected: "http://127.0.0.1:8085/callback"},
+		{name: "IPv6 loopback", host: "::1", expected: "http://[::1]:8085/callback"},
+		{name: "localhost", host: "localhost", expected: "http://localhost:8085/callback"},
+		{name: "unspecified IPv4", host: "0.0.0.0", expected: "http://localhost:8085/callback"},
+		{name: "unspecified IPv6", host: "::", expected: "http://localhost:8085/callback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CallbackRedirectURI(tt.host, 8085, "/callback"); got != tt.expected {
+				t.Errorf("CallbackRedirectURI() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateCallbackHost(t *testing.T) {
+	tests := []struct {
+		host        string
+		shouldError bool
+	}{
+		{host: ""},
+		{host: "localhost"},
+		{host: "127.0.0.1"},
+		{host: "::1"},
+		{host: "not a host", shouldError: true},
+		{host: "example.com", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := ValidateCallbackHost(tt.host)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStartCallbackServerBindsLoopback(t *testing.T) {
+	// Reserve a free port, then release it for the callback server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type outcome struct {
+		result *CallbackResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := StartCallbackServer(ctx, "", port, "/callback")
+		done <- outcome{result, err}
+	}()
+
+	callbackURL := CallbackRedirectURI("", port, "/callback") + "?code=abc"
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get(callbackURL); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("callback server not reachable on loopback: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("StartCallbackServer() error: %v", got.err)
+	}
+	if got.result.Params.Get("code") != "abc" {
+		t.Errorf("code = %q, want %q", got.result.Params.Get("code"), "abc")
+	}
+}
@@ -2,10 +2,18 @@ package common
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 )
 
+// BrowserEnvVar names a command that opens URLs, used when a provider sets no browser_command
+const BrowserEnvVar = "CREDCTL_BROWSER"
+
+// browserOpener launches the system browser (replaced in tests)
+var browserOpener = OpenBrowser
+
 func formatHyperlink(url, text string) string {
 	return fmt.Sprintf("\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", url, text)
 }
@@ -26,3 +34,67 @@ func OpenBrowser(url string) error {
 
 	return cmd.Start()
 }
+
+// OpenBrowserCommand opens url with a user-supplied command such as wslview or
+// "firefox --new-window"; the URL is appended as the last argument
+func OpenBrowserCommand(command, url string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("browser command is empty")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], url)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the process without blocking the flow on it
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// resolveBrowserCommand returns the configured browser command, falling back to $CREDCTL_BROWSER
+func resolveBrowserCommand(browserCommand string) string {
+	if browserCommand != "" {
+		return browserCommand
+	}
+	return os.Getenv(BrowserEnvVar)
+}
+
+// IsHeadless reports whether a local browser is unlikely to be available
+// (SSH session, or Linux without a display server)
+func IsHeadless() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return true
+	}
+	return runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+// PresentAuthURL opens authURL in the browser, or prints it to stderr for the
+// user to open manually when noBrowser is set or the session is headless.
+// browserCommand (or $CREDCTL_BROWSER) replaces the platform default and is
+// trusted to work without a local display (WSL, remote forwarding helpers).
+func PresentAuthURL(authURL string, noBrowser bool, browserCommand string) error {
+	browserCommand = resolveBrowserCommand(browserCommand)
+
+	if !noBrowser && browserCommand == "" && IsHeadless() {
+		fmt.Fprintf(os.Stderr, "Warning: no display detected (headless or SSH session), not opening a browser\n")
+		noBrowser = true
+	}
+
+	if noBrowser {
+		fmt.Fprintf(os.Stderr, "\nOpen this URL in a browser to authenticate:\n\n  %s\n\nWaiting for the callback...\n", formatHyperlink(authURL, authURL))
+		return nil
+	}
+
+	if browserCommand != "" {
+		if err := OpenBrowserCommand(browserCommand, authURL); err != nil {
+			return fmt.Errorf("failed to run browser command '%s': %w", browserCommand, err)
+		}
+		return nil
+	}
+
+	if err := browserOpener(authURL); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return nil
+}
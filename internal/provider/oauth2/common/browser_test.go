@@ -0,0 +1,178 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubBrowser replaces the browser opener and returns a pointer to its call count
+func stubBrowser(t *testing.T) *int {
+	t.Helper()
+	calls := 0
+	original := browserOpener
+	browserOpener = func(string) error {
+		calls++
+		return nil
+	}
+	t.Cleanup(func() { browserOpener = original })
+	return &calls
+}
+
+// setDesktopEnv simulates a local desktop session
+func setDesktopEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("SSH_TTY", "")
+	t.Setenv("DISPLAY", ":0")
+}
+
+func TestIsHeadless(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected bool
+	}{
+		{
+			name:     "desktop session",
+			env:      map[string]string{},
+			expected: false,
+		},
+		{
+			name:     "ssh connection",
+			env:      map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22"},
+			expected: true,
+		},
+		{
+			name:     "ssh tty",
+			env:      map[string]string{"SSH_TTY": "/dev/pts/0"},
+			expected: true,
+		},
+		{
+			name:     "no display",
+			env:      map[string]string{"DISPLAY": ""},
+			expected: runtime.GOOS == "linux",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDesktopEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := IsHeadless(); got != tt.expected {
+				t.Errorf("IsHeadless() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPresentAuthURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		noBrowser bool
+		headless  bool
+		opened    int
+	}{
+		{name: "opens browser by default", opened: 1},
+		{name: "no_browser skips browser", noBrowser: true, opened: 0},
+		{name: "headless skips browser", headless: true, opened: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setDesktopEnv(t)
+			t.Setenv(BrowserEnvVar, "")
+			if tt.headless {
+				t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+			}
+			calls := stubBrowser(t)
+
+			if err := PresentAuthURL("https://idp.example.com/authorize", tt.noBrowser, ""); err != nil {
+				t.Fatalf("PresentAuthURL() error: %v", err)
+			}
+			if *calls != tt.opened {
+				t.Errorf("browser opened %d times, want %d", *calls, tt.opened)
+			}
+		})
+	}
+}
+
+// browserScript writes a script that records its arguments, and returns the
+// script path and the file the arguments are written to
+func browserScript(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "browser.sh")
+	body := "#!/bin/sh\necho \"$@\" > " + argsFile + ".tmp && mv " + argsFile + ".tmp " + argsFile + "\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return script, argsFile
+}
+
+// waitForFile returns the content of path once the browser command wrote it
+func waitForFile(t *testing.T, path string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("browser command did not run")
+	return ""
+}
+
+func TestPresentAuthURLBrowserCommand(t *testing.T) {
+	const authURL = "https://idp.example.com/authorize?client_id=my-client&state=abc"
+
+	tests := []struct {
+		name    string
+		command string
+		env     string
+		args    string
+	}{
+		{name: "configured command", command: "SCRIPT", args: authURL},
+		{name: "command with arguments", command: "SCRIPT --new-window", args: "--new-window " + authURL},
+		{name: "CREDCTL_BROWSER", env: "SCRIPT", args: authURL},
+		{name: "configured command wins over CREDCTL_BROWSER", command: "SCRIPT --configured", env: "SCRIPT --env", args: "--configured " + authURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, argsFile := browserScript(t)
+			// A custom command is used even without a display
+			t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+			t.Setenv(BrowserEnvVar, strings.ReplaceAll(tt.env, "SCRIPT", script))
+			calls := stubBrowser(t)
+
+			if err := PresentAuthURL(authURL, false, strings.ReplaceAll(tt.command, "SCRIPT", script)); err != nil {
+				t.Fatalf("PresentAuthURL() error: %v", err)
+			}
+			if got := waitForFile(t, argsFile); got != tt.args {
+				t.Errorf("browser command args = %q, want %q", got, tt.args)
+			}
+			if *calls != 0 {
+				t.Error("expected the platform browser not to be used")
+			}
+		})
+	}
+
+	t.Run("missing command", func(t *testing.T) {
+		t.Setenv(BrowserEnvVar, "")
+		if err := PresentAuthURL(authURL, false, "/nonexistent/browser"); err == nil {
+			t.Error("expected error for a missing browser command")
+		}
+	})
+}
@@ -3,14 +3,19 @@ package common
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 
 	"charm.land/lipgloss/v2"
 	"golang.org/x/oauth2"
 )
 
 // AuthenticateDeviceFlow performs OAuth2 device authorization flow
-func AuthenticateDeviceFlow(ctx context.Context, deviceEndpoint, tokenEndpoint, clientID, clientSecret string, scopes []string) (*TokenCache, error) {
+// authParams are added to the device authorization request and tokenParams to the token polling requests
+func AuthenticateDeviceFlow(ctx context.Context, deviceEndpoint, tokenEndpoint, clientID, clientSecret string, scopes []string, authParams, tokenParams map[string]string) (*TokenCache, error) {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
@@ -23,7 +28,7 @@ func AuthenticateDeviceFlow(ctx context.Context, deviceEndpoint, tokenEndpoint,
 	}
 
 	// Request device authorization
-	deviceAuth, err := config.DeviceAuth(ctx)
+	deviceAuth, err := config.DeviceAuth(ctx, ParamsToOptions(authParams)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request device authorization: %w", err)
 	}
@@ -32,7 +37,7 @@ func AuthenticateDeviceFlow(ctx context.Context, deviceEndpoint, tokenEndpoint,
 	displayDeviceAuthInstructions(deviceAuth)
 
 	// Poll for token
-	token, err := config.DeviceAccessToken(ctx, deviceAuth)
+	token, err := config.DeviceAccessToken(ctx, deviceAuth, ParamsToOptions(tokenParams)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get device token: %w", err)
 	}
@@ -72,3 +77,65 @@ func displayDeviceAuthInstructions(deviceAuth *oauth2.DeviceAuthResponse) {
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, boldStyle.Render("Waiting for authentication..."))
 }
+
+// azureADHosts are Microsoft identity platform hosts whose discovery
+// documents may omit device_authorization_endpoint (older Azure AD tenants)
+var azureADHosts = []string{
+	"login.microsoftonline.com",
+	"login.microsoftonline.us",
+	"login.chinacloudapi.cn",
+	"login.partner.microsoftonline.cn",
+}
+
+// IsAzureADIssuer reports whether issuer is hosted by the Microsoft identity platform
+func IsAzureADIssuer(issuer string) bool {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(azureADHosts, strings.ToLower(u.Hostname()))
+}
+
+// DeviceEndpointCandidates returns the conventional device authorization
+// endpoint paths to try when discovery doesn't advertise one: the
+// "devicecode" sibling of the token endpoint (…/oauth2/v2.0/token →
+// …/oauth2/v2.0/devicecode) and <issuer>/oauth2/v2.0/devicecode
+func DeviceEndpointCandidates(issuer, tokenEndpoint string) []string {
+	var candidates []string
+	if u, err := url.Parse(tokenEndpoint); err == nil && u.Host != "" && strings.HasSuffix(u.Path, "/token") {
+		u.Path = strings.TrimSuffix(u.Path, "token") + "devicecode"
+		u.RawQuery = ""
+		candidates = append(candidates, u.String())
+	}
+	if issuer != "" {
+		// Azure AD v2.0 issuers end in /v2.0, under which the endpoints don't live
+		base := strings.TrimSuffix(strings.TrimSuffix(issuer, "/"), "/v2.0")
+		if candidate := base + "/oauth2/v2.0/devicecode"; !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// ProbeDeviceEndpoint returns the first candidate that exists, checked with
+// a HEAD request, or "" if none does. Any response but 404/410 or a server
+// error counts: a device endpoint only accepts POST, so 400 or 405 are expected.
+func ProbeDeviceEndpoint(ctx context.Context, candidates []string) string {
+	client := httpClientFromContext(ctx, defaultHTTPClient)
+	for _, candidate := range candidates {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, candidate, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone && resp.StatusCode < 500 {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsAzureADIssuer(t *testing.T) {
+	tests := []struct {
+		issuer string
+		want   bool
+	}{
+		{issuer: "https://login.microsoftonline.com/contoso/v2.0", want: true},
+		{issuer: "https://LOGIN.microsoftonline.com/contoso/", want: true},
+		{issuer: "https://sts.windows.net/contoso/", want: false},
+		{issuer: "https://accounts.google.com", want: false},
+		{issuer: "https://login.microsoftonline.com.evil.example/", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.issuer, func(t *testing.T) {
+			if got := IsAzureADIssuer(tt.issuer); got != tt.want {
+				t.Errorf("IsAzureADIssuer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceEndpointCandidates(t *testing.T) {
+	tests := []struct {
+		name          string
+		issuer        string
+		tokenEndpoint string
+		want          []string
+	}{
+		{
+			name:          "azure v2.0",
+			issuer:        "https://login.microsoftonline.com/contoso/v2.0",
+			tokenEndpoint: "https://login.microsoftonline.com/contoso/oauth2/v2.0/token",
+			want:          []string{"https://login.microsoftonline.com/contoso/oauth2/v2.0/devicecode"},
+		},
+		{
+			name:          "azure v1",
+			issuer:        "https://login.microsoftonline.com/contoso/",
+			tokenEndpoint: "https://login.microsoftonline.com/contoso/oauth2/token",
+			want: []string{
+				"https://login.microsoftonline.com/contoso/oauth2/devicecode",
+				"https://login.microsoftonline.com/contoso/oauth2/v2.0/devicecode",
+			},
+		},
+		{
+			name:          "token endpoint without a token path",
+			issuer:        "https://idp.example.com",
+			tokenEndpoint: "https://idp.example.com/connect/issue",
+			want:          []string{"https://idp.example.com/oauth2/v2.0/devicecode"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeviceEndpointCandidates(tt.issuer, tt.tokenEndpoint); !slices.Equal(got, tt.want) {
+				t.Errorf("DeviceEndpointCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+
+	"credctl/internal/provider"
+)
+
+// ValidateEndpoint checks that an endpoint URL uses HTTPS, so that client
+// secrets and tokens sent to it are never in cleartext. Plain HTTP is accepted
+// for loopback hosts (local test IdPs), and for any host when allowInsecure is
+// set, with a warning. An empty endpoint is valid (not configured).
+func ValidateEndpoint(name, endpoint string, allowInsecure bool) error {
+	if endpoint == "" {
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid %s '%s': must be an absolute URL", name, endpoint)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopbackHost(u.Hostname()) {
+			return nil
+		}
+		if allowInsecure {
+			fmt.Fprintf(os.Stderr, "Warning: %s %s is not HTTPS, credentials sent to it are not encrypted\n", name, endpoint)
+			return nil
+		}
+		return fmt.Errorf("%s '%s' must use https: plain http is only allowed for localhost (set %s to override)", name, endpoint, provider.MetadataAllowInsecureEndpoints)
+	default:
+		return fmt.Errorf("invalid %s '%s': scheme must be https", name, endpoint)
+	}
+}
+
+// ValidateEndpoints runs ValidateEndpoint on each endpoint, keyed by name
+func ValidateEndpoints(endpoints map[string]string, allowInsecure bool) error {
+	for _, name := range slices.Sorted(maps.Keys(endpoints)) {
+		if err := ValidateEndpoint(name, endpoints[name], allowInsecure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
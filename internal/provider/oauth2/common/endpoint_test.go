@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		endpoint      string
+		allowInsecure bool
+		shouldError   bool
+	}{
+		{name: "not configured", endpoint: ""},
+		{name: "https", endpoint: "https://idp.example.com/token"},
+		{name: "http localhost", endpoint: "http://localhost:8080/token"},
+		{name: "http IPv4 loopback", endpoint: "http://127.0.0.1:8080/token"},
+		{name: "http IPv6 loopback", endpoint: "http://[::1]:8080/token"},
+		{name: "http remote", endpoint: "http://idp.example.com/token", shouldError: true},
+		{name: "http remote allowed", endpoint: "http://idp.example.com/token", allowInsecure: true},
+		{name: "localhost lookalike", endpoint: "http://localhost.example.com/token", shouldError: true},
+		{name: "other scheme", endpoint: "ftp://idp.example.com/token", shouldError: true},
+		{name: "relative URL", endpoint: "/token", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEndpoint("token_endpoint", tt.endpoint, tt.allowInsecure)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth 2.0 Token Exchange (RFC 8693) identifiers
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeIDToken     = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeRequest holds the parameters of a token exchange request
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string   // Defaults to TokenTypeAccessToken
+	Audience           string   // Logical name of the target service
+	Scopes             []string // Scopes requested for the issued token
+	RequestedTokenType string   // Optional type of token to issue
+}
+
+// ExchangeToken exchanges a subject token for a token for another audience or
+// scope (RFC 8693) and returns it with the issued_token_type the server reported
+func ExchangeToken(ctx context.Context, tokenEndpoint, clientID, clientSecret string, req TokenExchangeRequest) (*TokenCache, string, error) {
+	subjectTokenType := req.SubjectTokenType
+	if subjectTokenType == "" {
+		subjectTokenType = TokenTypeAccessToken
+	}
+
+	// Reuse the client credentials config, which allows overriding grant_type
+	// and handles client authentication
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenEndpoint,
+		Scopes:       req.Scopes,
+		EndpointParams: url.Values{
+			"grant_type":         {GrantTypeTokenExchange},
+			"subject_token":      {req.SubjectToken},
+			"subject_token_type": {subjectTokenType},
+		},
+	}
+	if clientSecret == "" {
+		config.AuthStyle = oauth2.AuthStyleInParams
+	}
+	if req.Audience != "" {
+		config.EndpointParams.Set("audience", req.Audience)
+	}
+	if req.RequestedTokenType != "" {
+		config.EndpointParams.Set("requested_token_type", req.RequestedTokenType)
+	}
+
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	issuedTokenType, _ := token.Extra("issued_token_type").(string)
+	return OAuth2TokenToCache(token), issuedTokenType, nil
+}
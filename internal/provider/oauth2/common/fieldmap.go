@@ -0,0 +1,141 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// TokenFieldMapping names the fields a non-standard token endpoint uses
+// instead of access_token, refresh_token and expires_in
+type TokenFieldMapping struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    string
+
+	// KeepRaw also hands the original response body to OAuth2TokenToCache
+	// (under RawResponseField), for providers that return it as is
+	KeepRaw bool
+}
+
+// RawResponseField carries the original token endpoint response through
+// golang.org/x/oauth2, which keeps unknown fields but can't enumerate them
+const RawResponseField = "credctl_raw_response"
+
+// IsZero reports whether responses are left untouched
+func (m TokenFieldMapping) IsZero() bool {
+	return m == TokenFieldMapping{}
+}
+
+// NewTokenFieldClient returns a client that rewrites JSON responses from
+// tokenEndpoint so mapped fields appear under their standard names. Every
+// grant (code exchange, device polling, refresh, client credentials) keeps
+// going through golang.org/x/oauth2, which only understands standard fields.
+// base may be nil to use the default transport.
+func NewTokenFieldClient(base *http.Client, tokenEndpoint string, mapping TokenFieldMapping) *http.Client {
+	client := &http.Client{}
+	if base != nil {
+		*client = *base
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	client.Transport = &tokenFieldTransport{
+		base:          transport,
+		tokenEndpoint: tokenEndpoint,
+		mapping:       mapping,
+	}
+	return client
+}
+
+// tokenFieldTransport renames mapped fields in token endpoint responses
+type tokenFieldTransport struct {
+	base          http.RoundTripper
+	tokenEndpoint string
+	mapping       TokenFieldMapping
+}
+
+func (t *tokenFieldTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !sameEndpoint(req.URL, t.tokenEndpoint) {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if remapped, ok := t.remap(body); ok {
+		body = remapped
+		// Homegrown services don't always label JSON, and x/oauth2 would parse it as a form
+		resp.Header.Set("Content-Type", "application/json")
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// remap returns body with mapped fields copied to their standard names (and
+// the original body when kept), and false if nothing changed (e.g. the body is not a JSON object)
+func (t *tokenFieldTransport) remap(body []byte) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+
+	changed := false
+	if t.mapping.KeepRaw {
+		raw, err := json.Marshal(string(body))
+		if err != nil {
+			return nil, false
+		}
+		fields[RawResponseField] = raw
+		changed = true
+	}
+
+	rename := func(custom, standard string) {
+		if custom == "" || custom == standard {
+			return
+		}
+		value, ok := fields[custom]
+		if !ok {
+			return
+		}
+		if _, exists := fields[standard]; exists {
+			return
+		}
+		fields[standard] = value
+		changed = true
+	}
+	rename(t.mapping.AccessToken, "access_token")
+	rename(t.mapping.RefreshToken, "refresh_token")
+	rename(t.mapping.ExpiresIn, "expires_in")
+
+	if !changed {
+		return nil, false
+	}
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// sameEndpoint reports whether u addresses endpoint (query strings are ignored)
+func sameEndpoint(u *url.URL, endpoint string) bool {
+	e, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == e.Scheme && u.Host == e.Host && u.Path == e.Path
+}
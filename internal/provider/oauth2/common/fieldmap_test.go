@@ -0,0 +1,165 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenFieldClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		contentType  string
+		mapping      TokenFieldMapping
+		wantAccess   string
+		wantRefresh  string
+		wantLifetime time.Duration
+		shouldError  bool
+	}{
+		{
+			name:         "jwt field",
+			body:         `{"jwt":"abc","ttl":600}`,
+			contentType:  "application/json",
+			mapping:      TokenFieldMapping{AccessToken: "jwt", ExpiresIn: "ttl"},
+			wantAccess:   "abc",
+			wantLifetime: 600 * time.Second,
+		},
+		{
+			name:         "camelCase fields without JSON content type",
+			body:         `{"accessToken":"abc","refreshToken":"ref","expiresIn":"1200"}`,
+			contentType:  "text/plain",
+			mapping:      TokenFieldMapping{AccessToken: "accessToken", RefreshToken: "refreshToken", ExpiresIn: "expiresIn"},
+			wantAccess:   "abc",
+			wantRefresh:  "ref",
+			wantLifetime: 1200 * time.Second,
+		},
+		{
+			name:         "standard field wins",
+			body:         `{"access_token":"standard","jwt":"custom","expires_in":3600}`,
+			contentType:  "application/json",
+			mapping:      TokenFieldMapping{AccessToken: "jwt"},
+			wantAccess:   "standard",
+			wantLifetime: time.Hour,
+		},
+		{
+			name:        "mapped field missing",
+			body:        `{"token":"abc"}`,
+			contentType: "application/json",
+			mapping:     TokenFieldMapping{AccessToken: "jwt"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			tokenEndpoint := server.URL + "/token"
+			client := NewTokenFieldClient(nil, tokenEndpoint, tt.mapping)
+			ctx := WithHTTPClient(context.Background(), client)
+
+			tokens, err := GetClientCredentialsToken(ctx, tokenEndpoint, "client", "secret", nil, nil, nil)
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tokens.AccessToken != tt.wantAccess {
+				t.Errorf("access token = %q, want %q", tokens.AccessToken, tt.wantAccess)
+			}
+			if tokens.RefreshToken != tt.wantRefresh {
+				t.Errorf("refresh token = %q, want %q", tokens.RefreshToken, tt.wantRefresh)
+			}
+
+			lifetime := time.Until(tokens.ExpiresAt)
+			if lifetime < tt.wantLifetime-time.Minute || lifetime > tt.wantLifetime {
+				t.Errorf("lifetime = %v, want about %v", lifetime, tt.wantLifetime)
+			}
+		})
+	}
+}
+
+func TestTokenFieldClientKeepRaw(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mapping    TokenFieldMapping
+		wantAccess string
+	}{
+		{
+			name:       "vendor fields",
+			body:       `{"access_token":"abc","expires_in":600,"vendor":{"region":"eu"},"scope_list":["a","b"]}`,
+			mapping:    TokenFieldMapping{KeepRaw: true},
+			wantAccess: "abc",
+		},
+		{
+			name:       "remapped fields",
+			body:       `{"jwt":"abc","ttl":600}`,
+			mapping:    TokenFieldMapping{AccessToken: "jwt", ExpiresIn: "ttl", KeepRaw: true},
+			wantAccess: "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			tokenEndpoint := server.URL + "/token"
+			client := NewTokenFieldClient(nil, tokenEndpoint, tt.mapping)
+			ctx := WithHTTPClient(context.Background(), client)
+
+			tokens, err := GetClientCredentialsToken(ctx, tokenEndpoint, "client", "secret", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tokens.AccessToken != tt.wantAccess {
+				t.Errorf("access token = %q, want %q", tokens.AccessToken, tt.wantAccess)
+			}
+			// The original body, not the remapped one
+			if tokens.RawResponse != tt.body {
+				t.Errorf("raw response = %s, want %s", tokens.RawResponse, tt.body)
+			}
+		})
+	}
+}
+
+func TestTokenFieldClientLeavesOtherEndpointsAlone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jwt":"abc"}`))
+	}))
+	defer server.Close()
+
+	client := NewTokenFieldClient(nil, server.URL+"/token", TokenFieldMapping{AccessToken: "jwt"})
+
+	resp, err := client.Get(server.URL + "/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["access_token"]; ok {
+		t.Errorf("expected responses from other endpoints to be untouched, got %v", body)
+	}
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/release-utils/version"
+)
+
+// UserAgent is the User-Agent sent on every outbound HTTP request
+var UserAgent = "credctl/" + version.GetVersionInfo().GitVersion
+
+// defaultHTTPClient is used for the HTTP calls of providers without a client
+// of their own, so they still carry the User-Agent. It is bounded so a bad
+// issuer or token endpoint can't hang the daemon.
+var defaultHTTPClient = WithRequestHeaders(nil, nil)
+
+// WithRequestHeaders returns a copy of base whose requests carry UserAgent and
+// the given static headers (e.g. for an IdP behind an auth gateway). Headers
+// already set on a request are kept. base may be nil to start from the default
+// transport.
+func WithRequestHeaders(base *http.Client, headers map[string]string) *http.Client {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if base != nil {
+		*client = *base
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	header := make(http.Header, len(headers)+1)
+	header.Set("User-Agent", UserAgent)
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	client.Transport = &headerTransport{base: transport, header: header}
+	return client
+}
+
+// ValidateRequestHeaders checks that configured headers are valid HTTP header names
+func ValidateRequestHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid http_headers name '%s'", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid http_headers value for '%s': must not contain newlines", name)
+		}
+	}
+	return nil
+}
+
+// headerTransport adds static headers to each request
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, values := range t.header {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverSendsUserAgent(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"issuer":"http://` + r.Host + `","token_endpoint":"http://` + r.Host + `/token"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		headers map[string]string
+	}{
+		{name: "default client", ctx: context.Background()},
+		{name: "nil provider client", ctx: WithHTTPClient(context.Background(), nil)},
+		{
+			name:    "provider client with headers",
+			headers: map[string]string{"X-Gateway-Key": "secret"},
+			ctx:     WithHTTPClient(context.Background(), WithRequestHeaders(nil, map[string]string{"X-Gateway-Key": "secret"})),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if _, err := Discover(tt.ctx, server.URL, false); err != nil {
+				t.Fatalf("Discover() error: %v", err)
+			}
+			if ua := got.Get("User-Agent"); !strings.HasPrefix(ua, "credctl/") {
+				t.Errorf("User-Agent = %q, want credctl/<version>", ua)
+			}
+			for name, value := range tt.headers {
+				if got.Get(name) != value {
+					t.Errorf("%s = %q, want %q", name, got.Get(name), value)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRequestHeadersKeepsRequestHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := WithRequestHeaders(nil, map[string]string{"Accept": "text/plain", "X-Extra": "1"})
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if got.Get("Accept") != "application/json" || got.Get("X-Extra") != "1" {
+		t.Errorf("unexpected headers: %v", got)
+	}
+	if req.Header.Get("X-Extra") != "" {
+		t.Error("expected the caller's request to be left unmodified")
+	}
+}
+
+func TestValidateRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		shouldError bool
+	}{
+		{name: "none"},
+		{name: "valid", headers: map[string]string{"X-Api-Key": "abc"}},
+		{name: "space in name", headers: map[string]string{"X Api": "abc"}, shouldError: true},
+		{name: "colon in name", headers: map[string]string{"X-Api:": "abc"}, shouldError: true},
+		{name: "newline in value", headers: map[string]string{"X-Api": "abc\r\nX-Evil: 1"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRequestHeaders(tt.headers)
+			if (err != nil) != tt.shouldError {
+				t.Errorf("ValidateRequestHeaders() error = %v, shouldError %v", err, tt.shouldError)
+			}
+		})
+	}
+}
@@ -4,21 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"credctl/internal/clock"
 	"credctl/internal/provider"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
 )
 
-// IsTokenValid checks if a token is still valid (with 30 second buffer)
-func IsTokenValid(tokens *TokenCache) bool {
+// DefaultExpiryBuffer is how long before its expiry a cached token stops being used
+const DefaultExpiryBuffer = 30 * time.Second
+
+// IsTokenValid checks if a token is still valid for at least buffer, so it
+// doesn't expire while a request using it is in flight
+func IsTokenValid(tokens *TokenCache, buffer time.Duration) bool {
 	if tokens == nil || tokens.AccessToken == "" {
 		return false
 	}
-	return time.Now().Add(30 * time.Second).Before(tokens.ExpiresAt)
+	return clock.Now().Add(buffer).Before(tokens.ExpiresAt)
+}
+
+// GetExpiryBuffer reads expiry_buffer_seconds from config (default DefaultExpiryBuffer)
+func GetExpiryBuffer(config map[string]any) (time.Duration, error) {
+	seconds := provider.GetIntOrDefault(config, provider.MetadataExpiryBuffer, int(DefaultExpiryBuffer/time.Second))
+	if seconds < 0 {
+		return 0, fmt.Errorf("invalid %s %d: must not be negative", provider.MetadataExpiryBuffer, seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
 }
 
 // GetScopes extracts scopes from config
@@ -26,10 +46,12 @@ func GetScopes(config map[string]any) []string {
 	return provider.GetStringSliceOrDefault(config, provider.MetadataScopes, nil)
 }
 
-// GetScopesOrDefault returns scopes from config or defaults to ["openid"] for OIDC
-func GetScopesOrDefault(config map[string]any) []string {
+// GetScopesOrDefault returns scopes from config, defaulting to ["openid"] when
+// withOpenID is set (OIDC flows that sign a user in; openid is meaningless,
+// and sometimes rejected, for the client credentials grant)
+func GetScopesOrDefault(config map[string]any, withOpenID bool) []string {
 	scopes := GetScopes(config)
-	if len(scopes) == 0 {
+	if len(scopes) == 0 && withOpenID {
 		return []string{"openid"}
 	}
 	return scopes
@@ -43,32 +65,150 @@ type DiscoveryDocument struct {
 	DeviceEndpoint        string `json:"device_authorization_endpoint"`
 	UserinfoEndpoint      string `json:"userinfo_endpoint"`
 	JwksURI               string `json:"jwks_uri"`
+	PAREndpoint           string `json:"pushed_authorization_request_endpoint"`
 }
 
-// Discover fetches the OIDC discovery document from an issuer
-func Discover(issuer string) (*DiscoveryDocument, error) {
+// MaxDiscoveryDocumentSize bounds how much of a discovery response is read
+const MaxDiscoveryDocumentSize = 1 << 20
+
+// NewProxyHTTPClient returns an HTTP client that sends every request through
+// proxyURL, regardless of the HTTP_PROXY/HTTPS_PROXY environment
+func NewProxyHTTPClient(proxyURL string) (*http.Client, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http_proxy '%s': %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid http_proxy '%s': scheme must be http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid http_proxy '%s': missing host", proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
+
+// WithHTTPClient returns a context whose OAuth2, OIDC and discovery calls use client
+// A nil client uses the default client, which sets the User-Agent
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// httpClientFromContext returns the client set by WithHTTPClient, or fallback
+func httpClientFromContext(ctx context.Context, fallback *http.Client) *http.Client {
+	if client, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		return client
+	}
+	return fallback
+}
+
+// Discover fetches the OIDC discovery document from an issuer and checks that
+// the document names that issuer (OIDC Discovery section 4.3). allowIssuerMismatch
+// downgrades a mismatch to a warning, for IdPs known to report another issuer.
+func Discover(ctx context.Context, issuer string, allowIssuerMismatch bool) (*DiscoveryDocument, error) {
 	wellKnownURL := fmt.Sprintf("%s/.well-known/openid-configuration", strings.TrimSuffix(issuer, "/"))
 
-	resp, err := http.Get(wellKnownURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnownURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	resp, err := httpClientFromContext(ctx, defaultHTTPClient).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document from %s: %w", wellKnownURL, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("discovery endpoint returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("discovery endpoint %s returned status %d", wellKnownURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxDiscoveryDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery document from %s: %w", wellKnownURL, err)
+	}
+	if len(body) > MaxDiscoveryDocumentSize {
+		return nil, fmt.Errorf("discovery document from %s exceeds %d bytes: check the issuer URL", wellKnownURL, MaxDiscoveryDocumentSize)
 	}
 
 	var doc DiscoveryDocument
-	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
-		return nil, fmt.Errorf("failed to parse discovery document: %w", err)
+	if err := json.Unmarshal(body, &doc); err != nil {
+		if contentType := resp.Header.Get("Content-Type"); !isJSONContentType(contentType) {
+			return nil, fmt.Errorf("discovery endpoint %s returned %q instead of JSON: check the issuer URL", wellKnownURL, contentType)
+		}
+		return nil, fmt.Errorf("failed to parse discovery document from %s: %w", wellKnownURL, err)
+	}
+
+	if !sameIssuer(doc.Issuer, issuer) {
+		if !allowIssuerMismatch {
+			return nil, fmt.Errorf("discovery document from %s has issuer %q, expected %q (set allow_issuer_mismatch to accept it)", wellKnownURL, doc.Issuer, issuer)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: discovery document from %s has issuer %q, expected %q\n", wellKnownURL, doc.Issuer, issuer)
 	}
 
 	return &doc, nil
 }
 
+// sameIssuer compares issuer identifiers, ignoring a trailing slash
+func sameIssuer(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// isJSONContentType reports whether a Content-Type header denotes JSON
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// FetchUserInfo calls the OIDC userinfo endpoint with the given access token
+// and returns the standard claims it reports
+func FetchUserInfo(ctx context.Context, userinfoEndpoint, accessToken string) (*StandardClaims, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userinfoEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create userinfo request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClientFromContext(ctx, defaultHTTPClient).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call userinfo endpoint: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("userinfo endpoint rejected the access token (status %d): the token may be opaque or not valid for this issuer", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo endpoint returned status %d", resp.StatusCode)
+	}
+
+	var claims StandardClaims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse userinfo response: %w", err)
+	}
+
+	return &claims, nil
+}
+
 // NewOIDCProvider creates an OIDC provider for the given issuer
-func NewOIDCProvider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+// tokenIssuer, if set, is the issuer expected in ID tokens when it differs from
+// the discovery issuer (see allow_issuer_mismatch)
+func NewOIDCProvider(ctx context.Context, issuer, tokenIssuer string) (*oidc.Provider, error) {
+	if tokenIssuer != "" {
+		ctx = oidc.InsecureIssuerURLContext(ctx, tokenIssuer)
+	}
 	provider, err := oidc.NewProvider(ctx, issuer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
@@ -77,21 +217,106 @@ func NewOIDCProvider(ctx context.Context, issuer string) (*oidc.Provider, error)
 }
 
 // NewIDTokenVerifier creates an ID token verifier with the given configuration
-func NewIDTokenVerifier(provider *oidc.Provider, clientID string) *oidc.IDTokenVerifier {
-	return provider.Verifier(&oidc.Config{
-		ClientID: clientID,
-	})
+// skipClientIDCheck accepts tokens whose audience is not the client (brokered tokens)
+// With a clock skew, VerifyIDToken checks the token's exp and nbf instead of go-oidc.
+func NewIDTokenVerifier(provider *oidc.Provider, clientID string, skipClientIDCheck bool, skew time.Duration) *oidc.IDTokenVerifier {
+	return provider.Verifier(idTokenConfig(clientID, skipClientIDCheck, skew))
+}
+
+// idTokenConfig builds the verifier configuration shared by all ID token verifiers
+func idTokenConfig(clientID string, skipClientIDCheck bool, skew time.Duration) *oidc.Config {
+	return &oidc.Config{
+		ClientID:          clientID,
+		SkipClientIDCheck: skipClientIDCheck,
+		SkipExpiryCheck:   skew > 0,
+		Now:               clock.Now,
+	}
+}
+
+// ValidateAudiences checks that a verified ID token lists every expected audience
+// The aud claim may be a single string or an array; both are normalized by go-oidc.
+func ValidateAudiences(idToken *oidc.IDToken, expected []string) error {
+	for _, want := range expected {
+		if !slices.Contains(idToken.Audience, want) {
+			return fmt.Errorf("ID token audience %v does not include expected audience %q", idToken.Audience, want)
+		}
+	}
+	return nil
+}
+
+// ValidateACR checks that a verified ID token's acr claim is one of the
+// requested Authentication Context Class References (acr_values)
+func ValidateACR(idToken *oidc.IDToken, acrValues []string) error {
+	if len(acrValues) == 0 {
+		return nil
+	}
+
+	var claims struct {
+		ACR string `json:"acr"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return fmt.Errorf("failed to extract claims: %w", err)
+	}
+	if claims.ACR == "" {
+		return fmt.Errorf("ID token has no acr claim, expected one of %v", acrValues)
+	}
+	if !slices.Contains(acrValues, claims.ACR) {
+		return fmt.Errorf("ID token acr %q does not meet the requested acr_values %v", claims.ACR, acrValues)
+	}
+	return nil
 }
 
 // VerifyIDToken verifies an ID token and returns the verified token
-func VerifyIDToken(ctx context.Context, verifier *oidc.IDTokenVerifier, rawIDToken string) (*oidc.IDToken, error) {
+// skew must be the one the verifier was created with: the token is then
+// accepted until skew after its expiry, and from skew (at least go-oidc's
+// 5 minutes) before its nbf.
+func VerifyIDToken(ctx context.Context, verifier *oidc.IDTokenVerifier, rawIDToken string, skew time.Duration) (*oidc.IDToken, error) {
 	idToken, err := verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify ID token: %w", err)
 	}
+	if skew > 0 {
+		if err := checkIDTokenTimes(idToken, clock.Now(), skew); err != nil {
+			return nil, fmt.Errorf("failed to verify ID token: %w", err)
+		}
+	}
 	return idToken, nil
 }
 
+// notBeforeLeeway is the clock skew go-oidc always allows for the nbf claim
+const notBeforeLeeway = 5 * time.Minute
+
+// checkIDTokenTimes checks an ID token's exp and nbf claims against now,
+// allowing for skew between our clock and the issuer's
+func checkIDTokenTimes(idToken *oidc.IDToken, now time.Time, skew time.Duration) error {
+	if idToken.Expiry.Add(skew).Before(now) {
+		return fmt.Errorf("token expired at %s (allowed clock skew %s)", idToken.Expiry.Format(time.RFC3339), skew)
+	}
+
+	var claims struct {
+		NotBefore *float64 `json:"nbf"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return fmt.Errorf("failed to read nbf claim: %w", err)
+	}
+	if claims.NotBefore != nil {
+		notBefore := time.Unix(int64(*claims.NotBefore), 0)
+		if now.Add(max(skew, notBeforeLeeway)).Before(notBefore) {
+			return fmt.Errorf("token not valid before %s (allowed clock skew %s)", notBefore.Format(time.RFC3339), skew)
+		}
+	}
+	return nil
+}
+
+// GetAllowedClockSkew reads allowed_clock_skew (seconds, default 0) from config
+func GetAllowedClockSkew(config map[string]any) (time.Duration, error) {
+	seconds := provider.GetIntOrDefault(config, provider.MetadataAllowedClockSkew, 0)
+	if seconds < 0 {
+		return 0, fmt.Errorf("invalid %s %d: must not be negative", provider.MetadataAllowedClockSkew, seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
 // ExtractClaims extracts claims from an ID token into the provided struct
 func ExtractClaims(idToken *oidc.IDToken, claims interface{}) error {
 	if err := idToken.Claims(claims); err != nil {
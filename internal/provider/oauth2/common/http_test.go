@@ -0,0 +1,572 @@
+package common
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"credctl/internal/clock"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+func TestFetchUserInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"sub":"1234","email":"user@example.com","email_verified":true,"name":"Test User","groups":["dev","ops"]}`))
+	}))
+	defer server.Close()
+
+	claims, err := FetchUserInfo(context.Background(), server.URL, "good-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Subject != "1234" || claims.Email != "user@example.com" || !claims.EmailVerified || claims.Name != "Test User" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if len(claims.Groups) != 2 {
+		t.Errorf("expected 2 groups, got %v", claims.Groups)
+	}
+
+	_, err = FetchUserInfo(context.Background(), server.URL, "opaque-token")
+	if err == nil {
+		t.Fatal("expected error for rejected token")
+	}
+	if !strings.Contains(err.Error(), "rejected the access token") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestIDTokenAudienceValidation(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	const clientID = "my-client"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	// signToken issues an ID token with the given aud claim (string or array)
+	signToken := func(aud any) string {
+		claims := map[string]any{
+			"iss": issuer,
+			"sub": "user",
+			"aud": aud,
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		raw, err := jwt.Signed(signer).Claims(claims).Serialize()
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name              string
+		aud               any
+		expectedAudiences []string
+		skipClientIDCheck bool
+		shouldError       bool
+	}{
+		{
+			name: "single string aud",
+			aud:  clientID,
+		},
+		{
+			name:              "array aud with expected audience",
+			aud:               []string{clientID, "https://api.example.com"},
+			expectedAudiences: []string{"https://api.example.com"},
+		},
+		{
+			name:              "expected audience missing",
+			aud:               []string{clientID},
+			expectedAudiences: []string{"https://api.example.com"},
+			shouldError:       true,
+		},
+		{
+			name:        "mismatched aud",
+			aud:         "other-client",
+			shouldError: true,
+		},
+		{
+			name:              "brokered token with client id check skipped",
+			aud:               []string{"broker", "https://api.example.com"},
+			expectedAudiences: []string{"https://api.example.com"},
+			skipClientIDCheck: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySet := &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&key.PublicKey}}
+			verifier := oidc.NewVerifier(issuer, keySet, idTokenConfig(clientID, tt.skipClientIDCheck, 0))
+
+			idToken, err := VerifyIDToken(context.Background(), verifier, signToken(tt.aud), 0)
+			if err == nil {
+				err = ValidateAudiences(idToken, tt.expectedAudiences)
+			}
+
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateACR(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	const clientID = "my-client"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	keySet := &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&key.PublicKey}}
+	verifier := oidc.NewVerifier(issuer, keySet, idTokenConfig(clientID, false, 0))
+
+	tests := []struct {
+		name        string
+		acr         string
+		acrValues   []string
+		shouldError bool
+	}{
+		{name: "no acr required", acr: "", acrValues: nil},
+		{name: "acr matches", acr: "urn:mfa", acrValues: []string{"urn:mfa"}},
+		{name: "acr is one of several", acr: "phr", acrValues: []string{"phrh", "phr"}},
+		{name: "weaker acr", acr: "urn:password", acrValues: []string{"urn:mfa"}, shouldError: true},
+		{name: "missing acr", acr: "", acrValues: []string{"urn:mfa"}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]any{
+				"iss": issuer,
+				"sub": "user",
+				"aud": clientID,
+				"iat": time.Now().Unix(),
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}
+			if tt.acr != "" {
+				claims["acr"] = tt.acr
+			}
+			raw, err := jwt.Signed(signer).Claims(claims).Serialize()
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			idToken, err := VerifyIDToken(context.Background(), verifier, raw, 0)
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error: %v", err)
+			}
+
+			err = ValidateACR(idToken, tt.acrValues)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		errContains string
+	}{
+		{
+			name:        "valid document",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"issuer":"ISSUER","token_endpoint":"https://idp.example.com/token"}`,
+		},
+		{
+			name:        "html page",
+			contentType: "text/html; charset=utf-8",
+			status:      http.StatusOK,
+			body:        "<html><body>Login</body></html>",
+			errContains: "instead of JSON",
+		},
+		{
+			name:        "oversized response",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"issuer":"` + strings.Repeat("a", MaxDiscoveryDocumentSize) + `"}`,
+			errContains: "exceeds",
+		},
+		{
+			name:        "error status",
+			contentType: "application/json",
+			status:      http.StatusNotFound,
+			body:        `{}`,
+			errContains: "status 404",
+		},
+		{
+			name:        "malformed json",
+			contentType: "application/json",
+			status:      http.StatusOK,
+			body:        `{"issuer":`,
+			errContains: "failed to parse discovery document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			tt.body = strings.ReplaceAll(tt.body, "ISSUER", server.URL)
+
+			doc, err := Discover(context.Background(), server.URL, false)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if doc.TokenEndpoint != "https://idp.example.com/token" {
+					t.Errorf("TokenEndpoint = %q", doc.TokenEndpoint)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.errContains)
+			}
+			if !strings.Contains(err.Error(), server.URL) {
+				t.Errorf("error = %q, want to mention the discovery URL", err.Error())
+			}
+		})
+	}
+}
+
+func TestNewProxyHTTPClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		proxyURL    string
+		shouldError bool
+	}{
+		{name: "http proxy", proxyURL: "http://proxy.corp:3128"},
+		{name: "socks5 proxy", proxyURL: "socks5://127.0.0.1:1080"},
+		{name: "unsupported scheme", proxyURL: "ftp://proxy.corp", shouldError: true},
+		{name: "missing host", proxyURL: "http://", shouldError: true},
+		{name: "no scheme", proxyURL: "proxy.corp:3128", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProxyHTTPClient(tt.proxyURL)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscoverUsesContextClient(t *testing.T) {
+	var proxiedURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedURL = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"issuer":"http://issuer.invalid","token_endpoint":"http://issuer.invalid/token"}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewProxyHTTPClient(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Discover(WithHTTPClient(context.Background(), client), "http://issuer.invalid", false)
+	if err != nil {
+		t.Fatalf("Discover() error: %v", err)
+	}
+	if doc.TokenEndpoint != "http://issuer.invalid/token" {
+		t.Errorf("unexpected token endpoint %q", doc.TokenEndpoint)
+	}
+	if proxiedURL != "http://issuer.invalid/.well-known/openid-configuration" {
+		t.Errorf("expected discovery to go through the proxy, proxy saw %q", proxiedURL)
+	}
+}
+
+func TestDiscoverValidatesIssuer(t *testing.T) {
+	tests := []struct {
+		name                string
+		docIssuer           string // "" uses the server URL
+		configuredSuffix    string
+		allowIssuerMismatch bool
+		shouldError         bool
+	}{
+		{
+			name: "matching issuer",
+		},
+		{
+			name:             "configured issuer with trailing slash",
+			configuredSuffix: "/",
+		},
+		{
+			name:      "document issuer with trailing slash",
+			docIssuer: "SERVER/",
+		},
+		{
+			name:        "mismatched issuer",
+			docIssuer:   "https://evil.example.com",
+			shouldError: true,
+		},
+		{
+			name:                "mismatch allowed",
+			docIssuer:           "https://login.example.com/tenant",
+			allowIssuerMismatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var serverURL string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issuer := strings.ReplaceAll(tt.docIssuer, "SERVER", serverURL)
+				if issuer == "" {
+					issuer = serverURL
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"issuer":%q,"token_endpoint":"https://idp.example.com/token"}`, issuer)
+			}))
+			defer server.Close()
+			serverURL = server.URL
+
+			doc, err := Discover(context.Background(), server.URL+tt.configuredSuffix, tt.allowIssuerMismatch)
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), "allow_issuer_mismatch") {
+					t.Errorf("error = %q, want to mention allow_issuer_mismatch", err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.TokenEndpoint != "https://idp.example.com/token" {
+				t.Errorf("TokenEndpoint = %q", doc.TokenEndpoint)
+			}
+		})
+	}
+}
+
+func TestIsTokenValid(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokens    *TokenCache
+		expiresIn time.Duration
+		buffer    time.Duration
+		want      bool
+	}{
+		{name: "nil tokens", buffer: DefaultExpiryBuffer, want: false},
+		{name: "no access token", tokens: &TokenCache{RefreshToken: "r"}, expiresIn: time.Hour, buffer: DefaultExpiryBuffer, want: false},
+		{name: "well before the buffer", expiresIn: time.Hour, buffer: DefaultExpiryBuffer, want: true},
+		{name: "just outside the buffer", expiresIn: 35 * time.Second, buffer: DefaultExpiryBuffer, want: true},
+		{name: "just inside the buffer", expiresIn: 25 * time.Second, buffer: DefaultExpiryBuffer, want: false},
+		{name: "inside a larger buffer", expiresIn: 90 * time.Second, buffer: 2 * time.Minute, want: false},
+		{name: "zero buffer before expiry", expiresIn: 5 * time.Second, buffer: 0, want: true},
+		{name: "zero buffer after expiry", expiresIn: -time.Second, buffer: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tt.tokens
+			if tokens == nil && tt.expiresIn != 0 {
+				tokens = &TokenCache{AccessToken: "a"}
+			}
+			if tokens != nil {
+				tokens.ExpiresAt = time.Now().Add(tt.expiresIn)
+			}
+			if got := IsTokenValid(tokens, tt.buffer); got != tt.want {
+				t.Errorf("IsTokenValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetExpiryBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		want        time.Duration
+		shouldError bool
+	}{
+		{name: "default", config: map[string]any{}, want: DefaultExpiryBuffer},
+		{name: "configured", config: map[string]any{"expiry_buffer_seconds": 120}, want: 2 * time.Minute},
+		{name: "from JSON", config: map[string]any{"expiry_buffer_seconds": float64(60)}, want: time.Minute},
+		{name: "disabled", config: map[string]any{"expiry_buffer_seconds": 0}, want: 0},
+		{name: "negative", config: map[string]any{"expiry_buffer_seconds": -1}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetExpiryBuffer(tt.config)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetExpiryBuffer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTokenValidAcrossExpiry(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	defer clock.Set(fake)()
+
+	tokens := &TokenCache{AccessToken: "a", ExpiresAt: fake.Now().Add(time.Minute)}
+
+	fake.Advance(29 * time.Second)
+	if !IsTokenValid(tokens, DefaultExpiryBuffer) {
+		t.Error("expected the token to be valid 31s before expiry")
+	}
+	fake.Advance(2 * time.Second)
+	if IsTokenValid(tokens, DefaultExpiryBuffer) {
+		t.Error("expected the token to be invalid 29s before expiry (inside the buffer)")
+	}
+	if !IsTokenValid(tokens, 0) {
+		t.Error("expected the token to be valid before expiry without a buffer")
+	}
+	fake.Advance(30 * time.Second)
+	if IsTokenValid(tokens, 0) {
+		t.Error("expected the token to be invalid after expiry")
+	}
+}
+
+func TestVerifyIDTokenClockSkew(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	const clientID = "my-client"
+
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	defer clock.Set(fake)()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	keySet := &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&key.PublicKey}}
+
+	// Issued by an IdP whose clock is 10 minutes ahead of ours
+	issuedAt := fake.Now().Add(10 * time.Minute)
+	raw, err := jwt.Signed(signer).Claims(map[string]any{
+		"iss": issuer,
+		"sub": "user",
+		"aud": clientID,
+		"iat": issuedAt.Unix(),
+		"nbf": issuedAt.Unix(),
+		"exp": issuedAt.Add(time.Minute).Unix(),
+	}).Serialize()
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	verify := func(skew time.Duration) error {
+		verifier := oidc.NewVerifier(issuer, keySet, idTokenConfig(clientID, false, skew))
+		_, err := VerifyIDToken(context.Background(), verifier, raw, skew)
+		return err
+	}
+
+	// go-oidc's 5 minute nbf leeway isn't enough, a 15 minute skew is
+	if err := verify(0); err == nil {
+		t.Error("expected the token to be rejected before its nbf without a skew")
+	}
+	if err := verify(15 * time.Minute); err != nil {
+		t.Errorf("unexpected error with a 15m skew before nbf: %v", err)
+	}
+
+	// Past exp, the token is only accepted within the skew
+	fake.Advance(12 * time.Minute)
+	if err := verify(0); err == nil {
+		t.Error("expected the expired token to be rejected without a skew")
+	}
+	if err := verify(2 * time.Minute); err != nil {
+		t.Errorf("unexpected error with a 2m skew 1m past exp: %v", err)
+	}
+	fake.Advance(2 * time.Minute)
+	if err := verify(2 * time.Minute); err == nil {
+		t.Error("expected the token to be rejected 3m past exp with a 2m skew")
+	}
+}
+
+func TestGetAllowedClockSkew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		want        time.Duration
+		shouldError bool
+	}{
+		{name: "default", config: map[string]any{}, want: 0},
+		{name: "configured", config: map[string]any{"allowed_clock_skew": 120}, want: 2 * time.Minute},
+		{name: "from JSON", config: map[string]any{"allowed_clock_skew": float64(60)}, want: time.Minute},
+		{name: "negative", config: map[string]any{"allowed_clock_skew": -1}, shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAllowedClockSkew(tt.config)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetAllowedClockSkew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithClientCertificate returns a copy of base that presents the certificate
+// in certFile/keyFile (PEM) on TLS connections, for mutual TLS client
+// authentication (RFC 8705 tls_client_auth). base may be nil to start from
+// the default transport.
+func WithClientCertificate(base *http.Client, certFile, keyFile string) (*http.Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if base != nil {
+		*client = *base
+	}
+
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("cannot add a client certificate to transport %T", client.Transport)
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	client.Transport = transport
+
+	return client, nil
+}
@@ -0,0 +1,124 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed client certificate and key as PEM files
+func writeClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "my-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestClientCredentialsWithTLSClientAuth(t *testing.T) {
+	clientCert, certFile, keyFile := writeClientCert(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "my-client" {
+			t.Error("expected the client certificate to be presented")
+		}
+		if got := r.PostForm.Get("client_id"); got != "my-client" {
+			t.Errorf("client_id = %q, want %q", got, "my-client")
+		}
+		if r.PostForm.Has("client_secret") || r.Header.Get("Authorization") != "" {
+			t.Error("expected no client secret to be sent")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"mtls-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	// server.Client() trusts the test server's certificate
+	client, err := WithClientCertificate(server.Client(), certFile, keyFile)
+	if err != nil {
+		t.Fatalf("WithClientCertificate() error: %v", err)
+	}
+
+	ctx := WithHTTPClient(context.Background(), client)
+	tokens, err := GetClientCredentialsToken(ctx, server.URL+"/token", "my-client", "", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GetClientCredentialsToken() error: %v", err)
+	}
+	if tokens.AccessToken != "mtls-token" {
+		t.Errorf("AccessToken = %q, want %q", tokens.AccessToken, "mtls-token")
+	}
+
+	// Without the certificate the handshake is rejected
+	ctx = WithHTTPClient(context.Background(), server.Client())
+	if _, err := GetClientCredentialsToken(ctx, server.URL+"/token", "my-client", "", nil, nil, nil); err == nil {
+		t.Error("expected error without a client certificate")
+	}
+}
+
+func TestWithClientCertificateErrors(t *testing.T) {
+	_, certFile, keyFile := writeClientCert(t)
+
+	if _, err := WithClientCertificate(nil, certFile, filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Error("expected error for a missing key file")
+	}
+	if _, err := WithClientCertificate(nil, keyFile, certFile); err == nil {
+		t.Error("expected error for swapped certificate and key")
+	}
+
+	// Wrapped transports can't carry a certificate
+	wrapped := NewTokenFieldClient(nil, "https://idp.example.com/token", TokenFieldMapping{AccessToken: "token"})
+	if _, err := WithClientCertificate(wrapped, certFile, keyFile); err == nil {
+		t.Error("expected error for a non-*http.Transport transport")
+	}
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// parResponse is the pushed authorization response (RFC 9126 section 2.2)
+type parResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// parError is an OAuth2 error response from the PAR endpoint
+type parError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// PushAuthorizationRequest posts the authorization request parameters to a
+// pushed authorization request endpoint (RFC 9126) and returns the request_uri
+// that stands in for them at the authorization endpoint. The client
+// authenticates with HTTP basic auth when clientSecret is set, otherwise it
+// only identifies itself with client_id (public or mutual TLS clients).
+func PushAuthorizationRequest(ctx context.Context, parEndpoint, clientID, clientSecret string, params url.Values) (string, error) {
+	form := url.Values{}
+	for key, values := range params {
+		form[key] = values
+	}
+	form.Set("client_id", clientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create pushed authorization request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before basic auth
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := httpClientFromContext(ctx, defaultHTTPClient).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call pushed authorization request endpoint: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read pushed authorization response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		var errResp parError
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			return "", fmt.Errorf("pushed authorization request rejected: %s - %s", errResp.Error, errResp.ErrorDescription)
+		}
+		return "", fmt.Errorf("pushed authorization request endpoint returned status %d", resp.StatusCode)
+	}
+
+	var parResp parResponse
+	if err := json.Unmarshal(body, &parResp); err != nil {
+		return "", fmt.Errorf("failed to parse pushed authorization response: %w", err)
+	}
+	if parResp.RequestURI == "" {
+		return "", fmt.Errorf("pushed authorization response has no request_uri")
+	}
+
+	return parResp.RequestURI, nil
+}
+
+// buildPushedAuthURL builds the authorization URL that only references the
+// pushed request (RFC 9126 section 4)
+func buildPushedAuthURL(authEndpoint, clientID, requestURI string) (string, error) {
+	u, err := url.Parse(authEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("client_id", clientID)
+	query.Set("request_uri", requestURI)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
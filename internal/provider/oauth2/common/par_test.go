@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizationURLWithPAR(t *testing.T) {
+	var pushed url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		pushed = r.PostForm
+		if user, pass, ok := r.BasicAuth(); !ok || user != "my-client" || pass != "s3cret" {
+			t.Errorf("basic auth = %q/%q, want my-client/s3cret", user, pass)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"request_uri":"urn:ietf:params:oauth:request_uri:abc123","expires_in":60}`))
+	}))
+	defer server.Close()
+
+	params := AuthCodeFlowParams{
+		AuthEndpoint: "https://idp.example.com/authorize",
+		ClientID:     "my-client",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"openid", "email"},
+		UsePKCE:      true,
+		ExtraParams:  map[string]string{"prompt": "consent"},
+		PAREndpoint:  server.URL + "/par",
+	}
+
+	authURL, err := authorizationURL(context.Background(), params, "http://127.0.0.1:8085/callback", "state123", "challenge123")
+	if err != nil {
+		t.Fatalf("authorizationURL() error: %v", err)
+	}
+
+	// Step 1: all authorization parameters went to the PAR endpoint
+	expected := map[string]string{
+		"client_id":             "my-client",
+		"response_type":         "code",
+		"redirect_uri":          "http://127.0.0.1:8085/callback",
+		"scope":                 "openid email",
+		"state":                 "state123",
+		"prompt":                "consent",
+		"code_challenge":        "challenge123",
+		"code_challenge_method": "S256",
+	}
+	for key, want := range expected {
+		if got := pushed.Get(key); got != want {
+			t.Errorf("pushed param %s = %q, want %q", key, got, want)
+		}
+	}
+
+	// Step 2: the browser URL only references the pushed request
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("failed to parse auth URL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, "https://idp.example.com/authorize?") {
+		t.Errorf("auth URL = %q, want the authorization endpoint", authURL)
+	}
+	query := parsed.Query()
+	if got := query.Get("request_uri"); got != "urn:ietf:params:oauth:request_uri:abc123" {
+		t.Errorf("request_uri = %q, want the pushed request_uri", got)
+	}
+	if got := query.Get("client_id"); got != "my-client" {
+		t.Errorf("client_id = %q, want %q", got, "my-client")
+	}
+	if len(query) != 2 {
+		t.Errorf("auth URL carries extra parameters: %v", query)
+	}
+}
+
+func TestAuthorizationURLWithoutPAR(t *testing.T) {
+	params := AuthCodeFlowParams{
+		AuthEndpoint: "https://idp.example.com/authorize",
+		ClientID:     "my-client",
+	}
+
+	authURL, err := authorizationURL(context.Background(), params, "http://127.0.0.1:8085/callback", "state123", "")
+	if err != nil {
+		t.Fatalf("authorizationURL() error: %v", err)
+	}
+	if authURL != buildAuthURL(params, "http://127.0.0.1:8085/callback", "state123", "") {
+		t.Errorf("auth URL = %q, want the plain authorization URL", authURL)
+	}
+}
+
+func TestPushAuthorizationRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSecret string
+		status       int
+		body         string
+		want         string
+		shouldError  bool
+	}{
+		{
+			name:   "public client",
+			status: http.StatusCreated,
+			body:   `{"request_uri":"urn:example:1","expires_in":90}`,
+			want:   "urn:example:1",
+		},
+		{
+			name:         "confidential client accepts 200",
+			clientSecret: "s3cret",
+			status:       http.StatusOK,
+			body:         `{"request_uri":"urn:example:2","expires_in":90}`,
+			want:         "urn:example:2",
+		},
+		{
+			name:        "error response",
+			status:      http.StatusBadRequest,
+			body:        `{"error":"invalid_request","error_description":"bad redirect_uri"}`,
+			shouldError: true,
+		},
+		{
+			name:        "missing request_uri",
+			status:      http.StatusCreated,
+			body:        `{"expires_in":90}`,
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("failed to parse form: %v", err)
+				}
+				if got := r.PostForm.Get("client_id"); got != "my-client" {
+					t.Errorf("client_id = %q, want %q", got, "my-client")
+				}
+				if _, _, ok := r.BasicAuth(); ok != (tt.clientSecret != "") {
+					t.Errorf("basic auth present = %v, want %v", ok, tt.clientSecret != "")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := PushAuthorizationRequest(context.Background(), server.URL, "my-client", tt.clientSecret, url.Values{"response_type": {"code"}})
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PushAuthorizationRequest() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("request_uri = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"credctl/internal/clock"
+	"credctl/internal/encryption"
+	"credctl/internal/paths"
+)
+
+// SharedCacheTTL is how long a shared cache entry is trusted after it was stored
+const SharedCacheTTL = 24 * time.Hour
+
+// sharedEntry is the on-disk format of a shared token cache entry
+type sharedEntry struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	RawResponse  string    `json:"raw_response,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	StoredAt     time.Time `json:"stored_at"`
+}
+
+// SharedCacheKey returns the shared cache key for a provider identity
+// (e.g. client_id, token_endpoint and scopes)
+func SharedCacheKey(identity ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(identity, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// sharedCachePath returns the cache file path for a key
+func sharedCachePath(key string) (string, error) {
+	dir, err := paths.TokensDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+".json"), nil
+}
+
+// LoadSharedTokens returns tokens stored in the shared cache by any process,
+// or nil if there is no usable entry. Corrupt or stale entries are removed.
+func LoadSharedTokens(key string) *TokenCache {
+	path, err := sharedCachePath(key)
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	// A locked store can't read the entry, but it isn't corrupt either
+	data, err = encryption.Open(data)
+	if err != nil {
+		return nil
+	}
+
+	var entry sharedEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.AccessToken == "" && entry.RefreshToken == "" {
+		_ = os.Remove(path)
+		return nil
+	}
+
+	if clock.Since(entry.StoredAt) > SharedCacheTTL {
+		_ = os.Remove(path)
+		return nil
+	}
+
+	return &TokenCache{
+		AccessToken:  entry.AccessToken,
+		RefreshToken: entry.RefreshToken,
+		TokenType:    entry.TokenType,
+		IDToken:      entry.IDToken,
+		RawResponse:  entry.RawResponse,
+		ExpiresAt:    entry.ExpiresAt,
+	}
+}
+
+// RemoveSharedTokens deletes a shared cache entry if it exists
+func RemoveSharedTokens(key string) error {
+	path, err := sharedCachePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove token cache: %w", err)
+	}
+	return nil
+}
+
+// StoreSharedTokens writes tokens to the shared cache
+// Writers are serialized with a lock file and the entry is replaced atomically,
+// so concurrent readers never observe a partial write.
+func StoreSharedTokens(key string, tokens *TokenCache) error {
+	if tokens == nil {
+		return nil
+	}
+
+	path, err := sharedCachePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create tokens directory: %w", err)
+	}
+
+	lockFile, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open token cache lock: %w", err)
+	}
+	defer func() { _ = lockFile.Close() }()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock token cache: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN) }()
+
+	data, err := json.Marshal(sharedEntry{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+		IDToken:      tokens.IDToken,
+		RawResponse:  tokens.RawResponse,
+		ExpiresAt:    tokens.ExpiresAt,
+		StoredAt:     clock.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+
+	data, err = encryption.Seal(data)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+
+	return nil
+}
+
+// SweptEntry describes a shared cache file removed by SweepSharedCache
+type SweptEntry struct {
+	Path   string
+	Reason string
+}
+
+// SweepSharedCache removes shared cache files that can no longer be used:
+// entries (with their lock and temporary files) whose key is not in inUse,
+// and entries of keys in use that are past SharedCacheTTL or hold an expired
+// access token without a refresh token. Entries that can't be read (e.g. a
+// locked encrypted store) are kept.
+func SweepSharedCache(inUse map[string]bool, now time.Time) ([]SweptEntry, error) {
+	dir, err := paths.TokensDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read tokens directory: %w", err)
+	}
+
+	var swept []SweptEntry
+	for _, file := range files {
+		name := file.Name()
+		key, _, found := strings.Cut(name, ".json")
+		if !found || file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+
+		if !inUse[key] {
+			if err := os.Remove(path); err == nil {
+				swept = append(swept, SweptEntry{Path: path, Reason: "no provider uses it"})
+			}
+			continue
+		}
+
+		if name != key+".json" {
+			continue
+		}
+		if reason := sweepStaleEntry(path, now); reason != "" {
+			swept = append(swept, SweptEntry{Path: path, Reason: reason})
+		}
+	}
+
+	return swept, nil
+}
+
+// sweepStaleEntry removes the entry at path if it can no longer be used and
+// returns why, or "" if it was kept. It holds the entry's lock so a
+// concurrent StoreSharedTokens is never undone.
+func sweepStaleEntry(path string, now time.Time) string {
+	lockFile, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = lockFile.Close() }()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return ""
+	}
+	defer func() { _ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN) }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	data, err = encryption.Open(data)
+	if err != nil {
+		return ""
+	}
+
+	var reason string
+	var entry sharedEntry
+	switch {
+	case json.Unmarshal(data, &entry) != nil || entry.AccessToken == "" && entry.RefreshToken == "":
+		reason = "corrupt entry"
+	case now.Sub(entry.StoredAt) > SharedCacheTTL:
+		reason = fmt.Sprintf("stored more than %s ago", SharedCacheTTL)
+	case entry.RefreshToken == "" && now.After(entry.ExpiresAt):
+		reason = "expired without a refresh token"
+	default:
+		return ""
+	}
+
+	if err := os.Remove(path); err != nil {
+		return ""
+	}
+	return reason
+}
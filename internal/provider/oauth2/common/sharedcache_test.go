@@ -0,0 +1,202 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"credctl/internal/paths"
+)
+
+func TestSharedTokensRoundTrip(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	key := SharedCacheKey("client", "https://example.com/token", "openid")
+	if got := LoadSharedTokens(key); got != nil {
+		t.Fatalf("expected cache miss, got %+v", got)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := StoreSharedTokens(key, &TokenCache{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		TokenType:    "Bearer",
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		t.Fatalf("StoreSharedTokens() error: %v", err)
+	}
+
+	got := LoadSharedTokens(key)
+	if got == nil {
+		t.Fatal("expected cache hit, got nil")
+	}
+	if got.AccessToken != "access" || got.RefreshToken != "refresh" || got.TokenType != "Bearer" {
+		t.Errorf("unexpected tokens: %+v", got)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+	}
+
+	path, _ := sharedCachePath(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat cache file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("cache file permissions = %o, want 600", perm)
+	}
+}
+
+func TestSharedCacheKey(t *testing.T) {
+	a := SharedCacheKey("client", "endpoint", "openid")
+	if a != SharedCacheKey("client", "endpoint", "openid") {
+		t.Error("SharedCacheKey() must be deterministic")
+	}
+	if a == SharedCacheKey("client", "endpoint", "openid email") {
+		t.Error("different scopes must produce different keys")
+	}
+	if SharedCacheKey("ab", "c") == SharedCacheKey("a", "bc") {
+		t.Error("identity parts must not be ambiguous")
+	}
+}
+
+func TestLoadSharedTokensDiscardsBadEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "corrupt file",
+			data: []byte("{not json"),
+		},
+		{
+			name: "empty entry",
+			data: []byte(`{"stored_at":"` + time.Now().Format(time.RFC3339) + `"}`),
+		},
+		{
+			name: "stale entry",
+			data: mustMarshal(t, sharedEntry{
+				AccessToken: "old",
+				ExpiresAt:   time.Now().Add(time.Hour),
+				StoredAt:    time.Now().Add(-SharedCacheTTL - time.Minute),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+			key := SharedCacheKey(tt.name)
+			path, err := sharedCachePath(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := LoadSharedTokens(key); got != nil {
+				t.Errorf("expected nil, got %+v", got)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Error("expected bad entry to be removed")
+			}
+		})
+	}
+}
+
+func TestSweepSharedCache(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		files    map[string][]byte // file name suffix -> content
+		inUse    bool
+		wantKept bool
+	}{
+		{
+			name:     "valid entry in use",
+			files:    map[string][]byte{".json": mustMarshal(t, sharedEntry{AccessToken: "a", ExpiresAt: now.Add(time.Hour), StoredAt: now})},
+			inUse:    true,
+			wantKept: true,
+		},
+		{
+			name:     "expired entry with a refresh token",
+			files:    map[string][]byte{".json": mustMarshal(t, sharedEntry{AccessToken: "a", RefreshToken: "r", ExpiresAt: now.Add(-time.Hour), StoredAt: now})},
+			inUse:    true,
+			wantKept: true,
+		},
+		{
+			name:  "expired entry without a refresh token",
+			files: map[string][]byte{".json": mustMarshal(t, sharedEntry{AccessToken: "a", ExpiresAt: now.Add(-time.Hour), StoredAt: now})},
+			inUse: true,
+		},
+		{
+			name:  "stale entry",
+			files: map[string][]byte{".json": mustMarshal(t, sharedEntry{RefreshToken: "r", StoredAt: now.Add(-SharedCacheTTL - time.Minute)})},
+			inUse: true,
+		},
+		{
+			name:  "corrupt entry",
+			files: map[string][]byte{".json": []byte("{not json")},
+			inUse: true,
+		},
+		{
+			name: "orphaned entry and leftovers",
+			files: map[string][]byte{
+				".json":         mustMarshal(t, sharedEntry{AccessToken: "a", ExpiresAt: now.Add(time.Hour), StoredAt: now}),
+				".json.lock":    nil,
+				".json.tmp-123": []byte("partial"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+			key := SharedCacheKey(tt.name)
+			path, err := sharedCachePath(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Dir(path)
+			for suffix, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, key+suffix), data, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			swept, err := SweepSharedCache(map[string]bool{key: tt.inUse}, now)
+			if err != nil {
+				t.Fatalf("SweepSharedCache() error: %v", err)
+			}
+
+			_, statErr := os.Stat(path)
+			if kept := statErr == nil; kept != tt.wantKept {
+				t.Errorf("entry kept = %v, want %v (swept: %v)", kept, tt.wantKept, swept)
+			}
+			if !tt.inUse && len(swept) != len(tt.files) {
+				t.Errorf("expected all %d files of the orphaned entry to be swept, got %v", len(tt.files), swept)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"credctl/internal/provider"
+)
+
+// sourcesKey records the token sources being resolved in a context
+type sourcesKey struct{}
+
+// SourceToken returns the current credential of the credctl provider named by
+// a token source field (e.g. subject_token_source) as a single token
+func SourceToken(ctx context.Context, field, name string) (string, error) {
+	// Guard against providers using each other's tokens in a loop
+	visited, _ := ctx.Value(sourcesKey{}).([]string)
+	for _, seen := range visited {
+		if seen == name {
+			return "", fmt.Errorf("%s cycle: %s -> %s", field, strings.Join(visited, " -> "), name)
+		}
+	}
+	ctx = WithSource(ctx, name)
+
+	source, err := provider.Lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", field, err)
+	}
+
+	output, err := source.Get(ctx)
+	if err != nil {
+		if errors.Is(err, provider.ErrCredentialBundle) {
+			return "", fmt.Errorf("%s '%s' returns several credential fields, not a single token", field, name)
+		}
+		return "", fmt.Errorf("failed to get token from %s '%s': %w", field, name, err)
+	}
+
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", fmt.Errorf("%s '%s' returned an empty token", field, name)
+	}
+	return token, nil
+}
+
+// WithSource records that the credential of the provider name is being
+// resolved in ctx, so a token source leading back to it is reported as a
+// cycle rather than waiting on the provider it started from
+func WithSource(ctx context.Context, name string) context.Context {
+	visited, _ := ctx.Value(sourcesKey{}).([]string)
+	return context.WithValue(ctx, sourcesKey{}, append(visited[:len(visited):len(visited)], name))
+}
@@ -3,15 +3,14 @@ package common
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
 // RefreshAccessToken refreshes an OAuth2 access token using a refresh token
-func RefreshAccessToken(tokenEndpoint, clientID, clientSecret, refreshToken string) (*TokenCache, error) {
-	ctx := context.Background()
-
+func RefreshAccessToken(ctx context.Context, tokenEndpoint, clientID, clientSecret, refreshToken string) (*TokenCache, error) {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
@@ -33,10 +32,80 @@ func RefreshAccessToken(tokenEndpoint, clientID, clientSecret, refreshToken stri
 	return OAuth2TokenToCache(newToken), nil
 }
 
-// ExchangeCodeForTokens exchanges an authorization code for tokens
-func ExchangeCodeForTokens(tokenEndpoint, clientID, clientSecret, code, redirectURI, codeVerifier string) (*TokenCache, error) {
-	ctx := context.Background()
+// RefreshAccessTokenWithScopes refreshes an access token requesting only the
+// given scopes (RFC 6749 section 6 allows narrowing the scope on refresh)
+// extraParams are sent as additional form values on the token request
+func RefreshAccessTokenWithScopes(ctx context.Context, tokenEndpoint, clientID, clientSecret, refreshToken string, scopes []string, extraParams map[string]string) (*TokenCache, error) {
+	params := url.Values{}
+	for key, value := range extraParams {
+		params.Set(key, value)
+	}
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", refreshToken)
+
+	// oauth2.Config refreshes never send a scope, so reuse the client credentials
+	// config, which allows overriding grant_type and handles client authentication
+	config := &clientcredentials.Config{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		TokenURL:       tokenEndpoint,
+		Scopes:         scopes,
+		EndpointParams: params,
+	}
+
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return OAuth2TokenToCache(token), nil
+}
+
+// ValidateScopeSubset returns an error if any requested scope is not configured
+func ValidateScopeSubset(requested, configured []string) error {
+	allowed := make(map[string]bool, len(configured))
+	for _, scope := range configured {
+		allowed[scope] = true
+	}
+
+	for _, scope := range requested {
+		if !allowed[scope] {
+			return fmt.Errorf("scope '%s' is not one of the provider's configured scopes %v", scope, configured)
+		}
+	}
+	return nil
+}
+
+// ParamsToOptions converts a map of extra request parameters into oauth2 options
+func ParamsToOptions(params map[string]string) []oauth2.AuthCodeOption {
+	opts := make([]oauth2.AuthCodeOption, 0, len(params))
+	for key, value := range params {
+		opts = append(opts, oauth2.SetAuthURLParam(key, value))
+	}
+	return opts
+}
+
+// ReservedAuthParams are authorization request parameters the flow sets
+// itself; auth_params must not replace them (state is the CSRF check)
+var ReservedAuthParams = []string{"client_id", "redirect_uri", "response_type", "scope", "state", "code_challenge", "code_challenge_method", "request_uri"}
+
+// ReservedTokenParams are token request parameters the grant sets itself
+var ReservedTokenParams = []string{"grant_type", "code", "code_verifier", "refresh_token"}
 
+// CheckReservedParams rejects extra request parameters (configured in field)
+// that would replace one of the reserved parameters
+func CheckReservedParams(field string, params map[string]string, reserved []string) error {
+	for _, key := range reserved {
+		if _, ok := params[key]; ok {
+			return fmt.Errorf("%s cannot set '%s': it is set by the flow", field, key)
+		}
+	}
+	return nil
+}
+
+// ExchangeCodeForTokens exchanges an authorization code for tokens
+// extraParams are sent as additional form values on the token request
+func ExchangeCodeForTokens(ctx context.Context, tokenEndpoint, clientID, clientSecret, code, redirectURI, codeVerifier string, extraParams map[string]string) (*TokenCache, error) {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
@@ -46,7 +115,7 @@ func ExchangeCodeForTokens(tokenEndpoint, clientID, clientSecret, code, redirect
 		RedirectURL: redirectURI,
 	}
 
-	var opts []oauth2.AuthCodeOption
+	opts := ParamsToOptions(extraParams)
 	if codeVerifier != "" {
 		opts = append(opts, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
 	}
@@ -59,16 +128,56 @@ func ExchangeCodeForTokens(tokenEndpoint, clientID, clientSecret, code, redirect
 	return OAuth2TokenToCache(token), nil
 }
 
-// GetClientCredentialsToken obtains a token using the client credentials grant
-func GetClientCredentialsToken(tokenEndpoint, clientID, clientSecret string, scopes []string) (*TokenCache, error) {
-	ctx := context.Background()
+// Delegation asks for a token on behalf of another principal instead of the
+// client itself (impersonation or delegation, RFC 8693)
+type Delegation struct {
+	Subject        string // Principal the token is issued for, sent as requested_subject
+	ActorToken     string // Token of the party acting for the subject, sent as actor_token
+	ActorTokenType string // Defaults to TokenTypeAccessToken
+}
 
+// GetClientCredentialsToken obtains a token using the client credentials grant
+// extraParams are sent as additional form values on the token request
+// With a delegation the client authenticates the same way but the request uses
+// the token exchange grant, which carries requested_subject and actor_token
+// Without a client secret, client_id is sent in the request body and the client
+// authenticates otherwise, e.g. with a TLS client certificate configured on the
+// context's HTTP client (RFC 8705 tls_client_auth)
+func GetClientCredentialsToken(ctx context.Context, tokenEndpoint, clientID, clientSecret string, scopes []string, extraParams map[string]string, delegation *Delegation) (*TokenCache, error) {
 	config := &clientcredentials.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		TokenURL:     tokenEndpoint,
 		Scopes:       scopes,
 	}
+	if clientSecret == "" {
+		config.AuthStyle = oauth2.AuthStyleInParams
+	}
+
+	if len(extraParams) > 0 {
+		config.EndpointParams = url.Values{}
+		for key, value := range extraParams {
+			config.EndpointParams.Set(key, value)
+		}
+	}
+
+	if delegation != nil {
+		if config.EndpointParams == nil {
+			config.EndpointParams = url.Values{}
+		}
+		config.EndpointParams.Set("grant_type", GrantTypeTokenExchange)
+		if delegation.Subject != "" {
+			config.EndpointParams.Set("requested_subject", delegation.Subject)
+		}
+		if delegation.ActorToken != "" {
+			actorTokenType := delegation.ActorTokenType
+			if actorTokenType == "" {
+				actorTokenType = TokenTypeAccessToken
+			}
+			config.EndpointParams.Set("actor_token", delegation.ActorToken)
+			config.EndpointParams.Set("actor_token_type", actorTokenType)
+		}
+	}
 
 	token, err := config.Token(ctx)
 	if err != nil {
@@ -77,3 +186,33 @@ func GetClientCredentialsToken(tokenEndpoint, clientID, clientSecret string, sco
 
 	return OAuth2TokenToCache(token), nil
 }
+
+// GetPasswordGrantToken obtains a token using the resource owner password
+// credentials grant (RFC 6749 section 4.3). This grant is legacy and discouraged;
+// it exists for IdPs that support nothing else.
+// extraParams are sent as additional form values on the token request
+func GetPasswordGrantToken(ctx context.Context, tokenEndpoint, clientID, clientSecret, username, password string, scopes []string, extraParams map[string]string) (*TokenCache, error) {
+	// Reuse the client credentials config, which allows overriding grant_type,
+	// sends scopes and handles client authentication
+	config := &clientcredentials.Config{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		TokenURL:       tokenEndpoint,
+		Scopes:         scopes,
+		EndpointParams: url.Values{},
+	}
+
+	for key, value := range extraParams {
+		config.EndpointParams.Set(key, value)
+	}
+	config.EndpointParams.Set("grant_type", "password")
+	config.EndpointParams.Set("username", username)
+	config.EndpointParams.Set("password", password)
+
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password grant token: %w", err)
+	}
+
+	return OAuth2TokenToCache(token), nil
+}
@@ -0,0 +1,211 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newTokenServer returns a test token endpoint that records the last request form
+func newTokenServer(t *testing.T, form *url.Values) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		*form = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access123",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExchangeCodeForTokensExtraParams(t *testing.T) {
+	var form url.Values
+	server := newTokenServer(t, &form)
+
+	tokens, err := ExchangeCodeForTokens(context.Background(), server.URL, "my-client", "", "code123", "http://localhost:8085/callback", "verifier123",
+		map[string]string{"resource": "https://api.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tokens.AccessToken != "access123" {
+		t.Errorf("expected access token %q, got %q", "access123", tokens.AccessToken)
+	}
+	if got := form.Get("resource"); got != "https://api.example.com" {
+		t.Errorf("expected resource param in token request, got %q", got)
+	}
+	if got := form.Get("code_verifier"); got != "verifier123" {
+		t.Errorf("expected code_verifier %q, got %q", "verifier123", got)
+	}
+}
+
+func TestGetClientCredentialsTokenExtraParams(t *testing.T) {
+	var form url.Values
+	server := newTokenServer(t, &form)
+
+	_, err := GetClientCredentialsToken(context.Background(), server.URL, "my-client", "secret", nil,
+		map[string]string{"audience": "https://api.example.com"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := form.Get("audience"); got != "https://api.example.com" {
+		t.Errorf("expected audience param in token request, got %q", got)
+	}
+	if got := form.Get("grant_type"); got != "client_credentials" {
+		t.Errorf("expected grant_type client_credentials, got %q", got)
+	}
+}
+
+func TestGetClientCredentialsTokenDelegation(t *testing.T) {
+	tests := []struct {
+		name       string
+		delegation *Delegation
+		expected   map[string]string
+	}{
+		{
+			name:       "subject only",
+			delegation: &Delegation{Subject: "alice"},
+			expected: map[string]string{
+				"grant_type":        GrantTypeTokenExchange,
+				"requested_subject": "alice",
+				"actor_token":       "",
+				"actor_token_type":  "",
+			},
+		},
+		{
+			name:       "subject and actor token",
+			delegation: &Delegation{Subject: "alice", ActorToken: "admin-token"},
+			expected: map[string]string{
+				"grant_type":        GrantTypeTokenExchange,
+				"requested_subject": "alice",
+				"actor_token":       "admin-token",
+				"actor_token_type":  TokenTypeAccessToken,
+			},
+		},
+		{
+			name:       "actor token type",
+			delegation: &Delegation{ActorToken: "admin-id-token", ActorTokenType: TokenTypeIDToken},
+			expected: map[string]string{
+				"grant_type":        GrantTypeTokenExchange,
+				"requested_subject": "",
+				"actor_token":       "admin-id-token",
+				"actor_token_type":  TokenTypeIDToken,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form url.Values
+			server := newTokenServer(t, &form)
+
+			_, err := GetClientCredentialsToken(context.Background(), server.URL, "my-client", "secret", nil, nil, tt.delegation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, want := range tt.expected {
+				if got := form.Get(key); got != want {
+					t.Errorf("expected %s %q, got %q", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshAccessTokenWithScopes(t *testing.T) {
+	var form url.Values
+	server := newTokenServer(t, &form)
+
+	_, err := RefreshAccessTokenWithScopes(context.Background(), server.URL, "my-client", "", "refresh123", []string{"read"}, map[string]string{"audience": "https://api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := form.Get("grant_type"); got != "refresh_token" {
+		t.Errorf("expected grant_type refresh_token, got %q", got)
+	}
+	if got := form.Get("refresh_token"); got != "refresh123" {
+		t.Errorf("expected refresh_token %q, got %q", "refresh123", got)
+	}
+	if got := form.Get("scope"); got != "read" {
+		t.Errorf("expected scope %q, got %q", "read", got)
+	}
+	if got := form.Get("audience"); got != "https://api" {
+		t.Errorf("expected audience %q, got %q", "https://api", got)
+	}
+}
+
+func TestValidateScopeSubset(t *testing.T) {
+	configured := []string{"read", "write", "admin"}
+
+	if err := ValidateScopeSubset([]string{"read", "write"}, configured); err != nil {
+		t.Errorf("unexpected error for subset: %v", err)
+	}
+	if err := ValidateScopeSubset([]string{"read", "delete"}, configured); err == nil {
+		t.Error("expected error for scope outside configured set")
+	}
+	if err := ValidateScopeSubset([]string{"read"}, nil); err == nil {
+		t.Error("expected error when no scopes are configured")
+	}
+}
+
+func TestGetPasswordGrantToken(t *testing.T) {
+	var form url.Values
+	var clientID, clientSecret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		form = r.PostForm
+		clientID, clientSecret, _ = r.BasicAuth()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access123",
+			"refresh_token": "refresh123",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	tokens, err := GetPasswordGrantToken(context.Background(), server.URL, "my-client", "secret", "alice", "hunter2", []string{"read", "write"},
+		map[string]string{"grant_type": "ignored", "audience": "api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tokens.AccessToken != "access123" || tokens.RefreshToken != "refresh123" {
+		t.Errorf("unexpected tokens: %+v", tokens)
+	}
+
+	expected := map[string]string{
+		"grant_type": "password",
+		"username":   "alice",
+		"password":   "hunter2",
+		"scope":      "read write",
+		"audience":   "api",
+	}
+	for key, want := range expected {
+		if got := form.Get(key); got != want {
+			t.Errorf("form[%q] = %q, want %q", key, got, want)
+		}
+	}
+
+	if clientID != "my-client" || clientSecret != "secret" {
+		t.Errorf("expected client authentication, got %q/%q", clientID, clientSecret)
+	}
+}
@@ -3,6 +3,9 @@ package common
 import (
 	"time"
 
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
+
 	"golang.org/x/oauth2"
 )
 
@@ -14,6 +17,7 @@ type TokenCache struct {
 	TokenType    string
 	ExpiresAt    time.Time
 	IDToken      string // For OIDC flows
+	RawResponse  string // Token endpoint response body, when kept (see TokenFieldMapping.KeepRaw)
 }
 
 func NormalizeExpiresIn(expiresIn int) int {
@@ -36,15 +40,36 @@ func OAuth2TokenToCache(token *oauth2.Token) *TokenCache {
 	if idToken, ok := token.Extra("id_token").(string); ok {
 		cache.IDToken = idToken
 	}
+	if raw, ok := token.Extra(RawResponseField).(string); ok {
+		cache.RawResponse = raw
+	}
+
+	// Without expires_in, fall back to the exp claim of a JWT access token
+	if cache.ExpiresAt.IsZero() {
+		if exp, ok := credentials.JWTExpiry(cache.AccessToken); ok {
+			cache.ExpiresAt = exp
+		}
+	}
 
 	// Normalize expiry if not set
 	if cache.ExpiresAt.IsZero() {
-		cache.ExpiresAt = time.Now().Add(time.Duration(DefaultTokenExpiry) * time.Second)
+		cache.ExpiresAt = clock.Now().Add(time.Duration(DefaultTokenExpiry) * time.Second)
 	}
 
 	return cache
 }
 
+// Authorization returns the value for an HTTP Authorization header,
+// "<TokenType> <AccessToken>". TokenType defaults to "Bearer" when empty.
+func (tc *TokenCache) Authorization() string {
+	if tc.AccessToken == "" {
+		return ""
+	}
+	// oauth2.Token.Type() handles the "Bearer" default and canonical casing
+	tokenType := (&oauth2.Token{TokenType: tc.TokenType}).Type()
+	return tokenType + " " + tc.AccessToken
+}
+
 // ToOAuth2Token converts a TokenCache to an oauth2.Token
 func (tc *TokenCache) ToOAuth2Token() *oauth2.Token {
 	token := &oauth2.Token{
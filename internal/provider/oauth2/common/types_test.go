@@ -0,0 +1,64 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// unsignedJWT builds a JWT with the given claims and a fake signature
+func unsignedJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	signature := base64.RawURLEncoding.EncodeToString([]byte("signature"))
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + signature
+}
+
+func TestOAuth2TokenToCacheExpiry(t *testing.T) {
+	jwtExp := time.Now().Add(15 * time.Minute).Truncate(time.Second)
+	explicit := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		token *oauth2.Token
+		want  time.Time
+	}{
+		{
+			name:  "JWT exp without expires_in",
+			token: &oauth2.Token{AccessToken: unsignedJWT(t, map[string]any{"sub": "user", "exp": jwtExp.Unix()})},
+			want:  jwtExp,
+		},
+		{
+			name:  "expires_in wins over JWT exp",
+			token: &oauth2.Token{AccessToken: unsignedJWT(t, map[string]any{"exp": jwtExp.Unix()}), Expiry: explicit},
+			want:  explicit,
+		},
+		{
+			name:  "opaque token without expires_in gets the default",
+			token: &oauth2.Token{AccessToken: "opaque-token"},
+			want:  time.Now().Add(time.Duration(DefaultTokenExpiry) * time.Second),
+		},
+		{
+			name:  "JWT without exp gets the default",
+			token: &oauth2.Token{AccessToken: unsignedJWT(t, map[string]any{"sub": "user"})},
+			want:  time.Now().Add(time.Duration(DefaultTokenExpiry) * time.Second),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := OAuth2TokenToCache(tt.token)
+			if diff := cache.ExpiresAt.Sub(tt.want); diff < -time.Second || diff > time.Second {
+				t.Errorf("ExpiresAt = %v, want %v", cache.ExpiresAt, tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,16 @@ package oauth2
 import (
 	"context"
 	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"credctl/internal/clock"
 	"credctl/internal/credentials"
 	"credctl/internal/provider"
 	"credctl/internal/provider/oauth2/common"
@@ -16,12 +23,24 @@ const (
 	FlowDevice            = "device"             // Device authorization flow
 	FlowAuthCode          = "auth-code"          // Authorization code flow with PKCE
 	FlowClientCredentials = "client-credentials" // Client credentials flow
+	FlowPassword          = "password"           // Resource owner password credentials (legacy, discouraged)
+)
+
+// Client authentication methods
+const (
+	ClientAuthSecret = "client_secret"   // client_secret (or none for public clients)
+	ClientAuthTLS    = "tls_client_auth" // Mutual TLS with a client certificate (RFC 8705)
 )
 
 // Provider implements a universal OAuth2/OIDC provider that supports multiple grant types
 type Provider struct {
 	// Discovery
-	issuer string // If set, performs OIDC discovery and validates ID tokens
+	issuer              string // If set, performs OIDC discovery and validates ID tokens
+	allowIssuerMismatch bool   // Accept a discovery document reporting another issuer
+	probeDeviceEndpoint bool   // Probe conventional device endpoint paths for any issuer
+	tokenIssuer         string // Issuer reported by discovery when a mismatch is allowed
+
+	allowInsecureEndpoints bool // Accept plain http endpoints on non-loopback hosts
 
 	// Core OAuth2 config
 	clientID      string
@@ -29,21 +48,76 @@ type Provider struct {
 	scopes        []string
 	tokenEndpoint string
 
+	// Client authentication
+	clientAuthMethod string // ClientAuthSecret or ClientAuthTLS
+	clientCert       string // Client certificate file (tls_client_auth)
+	clientKey        string // Client private key file (tls_client_auth)
+
 	// Grant type detection (auto-detected from available endpoints)
 	authEndpoint   string // If set → authorization_code flow
+	parEndpoint    string // If set, authorization requests are pushed first (RFC 9126)
 	deviceEndpoint string // If set → device flow
 	redirectURI    string
 	redirectPort   int
+	callbackHost   string // Interface the callback server binds to (default 127.0.0.1)
+	noBrowser      bool   // Print the authorization URL instead of opening a browser
+	browserCommand string // Command that opens the authorization URL (default: system browser)
+
+	// ID token validation
+	expectedAudiences []string      // Audiences that must all be present in the ID token
+	skipClientIDCheck bool          // Accept ID tokens whose audience is not the client (brokered tokens)
+	acrValues         []string      // Requested authentication context classes; the ID token acr must be one of them
+	allowedClockSkew  time.Duration // Tolerated difference between our clock and the issuer's
 
 	// Flow options
-	flow     string // Explicit flow selection (auto, device, auth-code, client-credentials)
-	usePKCE  bool   // Use PKCE for authorization_code flow
-	template string // Optional Go template for formatting output
-	format   string // Default output format
-	output   string // Default output file path
+	flow        string            // Explicit flow selection (device, auth-code, client-credentials, password)
+	usePKCE     bool              // Use PKCE for authorization_code flow
+	authParams  map[string]string // Extra parameters for the authorization request
+	tokenParams map[string]string // Extra parameters for the token request
+
+	// Named profiles (name@profile): other scopes and audiences for the same client
+	profileSpecs map[string]string // Profile definitions as configured
+	profiles     map[string]profile
+
+	// Password grant credentials (legacy flow)
+	username string
+	password string
+
+	// env: references of secret fields, stored instead of the resolved secrets
+	secretRefs map[string]string
+
+	// Delegation (client credentials flow): act on behalf of another principal
+	subject          string // Principal to impersonate, sent as requested_subject
+	actorTokenSource string // Name of the provider whose credential is the actor token
+	actorTokenType   string
+
+	// Outbound HTTP (discovery, token, refresh and userinfo calls)
+	httpProxy   string            // Proxy URL overriding HTTP_PROXY/HTTPS_PROXY for this provider
+	httpHeaders map[string]string // Static headers added to every request
+	httpClient  *http.Client      // Client with the proxy, client certificate and headers applied
+
+	// Non-standard token response field names (and whether to keep the raw response)
+	tokenFields common.TokenFieldMapping
+	rawResponse bool // Get() returns the whole token endpoint response
+
+	// Output defaults (template, format, output file)
+	outputOpts provider.OutputOptions
+
+	// Daemon settings (access policy, prefetch, on-error command)
+	daemonOpts provider.DaemonOptions
 
 	// Token cache
-	tokens *common.TokenCache
+	expiryBuffer  time.Duration // Renew cached tokens this long before they expire
+	mu            sync.Mutex    // Guards tokens, scopedTokens and profileTokens (the daemon serves requests concurrently)
+	tokens        *common.TokenCache
+	scopedTokens  map[string]*common.TokenCache // Down-scoped tokens keyed by space-joined sorted scopes
+	profileTokens map[string]*common.TokenCache // Tokens of each named profile
+}
+
+// profile is a named scope set and audience requested with the provider's client
+type profile struct {
+	scopes   []string // Defaults to the provider's scopes
+	audience string   // Sent as the audience token request parameter
 }
 
 func init() {
@@ -79,6 +153,26 @@ func (p *Provider) Schema() provider.Schema {
 				Help:     "OAuth2 client secret (required for confidential clients)",
 				Hidden:   true,
 			},
+			{
+				Name:        provider.MetadataClientAuthMethod,
+				Type:        provider.FieldTypeString,
+				Required:    false,
+				Default:     ClientAuthSecret,
+				Help:        "How the client authenticates to the token endpoint",
+				ValidValues: []string{ClientAuthSecret, ClientAuthTLS},
+			},
+			{
+				Name:     provider.MetadataClientCert,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "PEM client certificate file for tls_client_auth",
+			},
+			{
+				Name:     provider.MetadataClientKey,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "PEM private key file for client_cert",
+			},
 			{
 				Name:     provider.MetadataScopes,
 				Type:     provider.FieldTypeStringSlice,
@@ -97,6 +191,12 @@ func (p *Provider) Schema() provider.Schema {
 				Required: false,
 				Help:     "Authorization endpoint URL (enables authorization_code flow)",
 			},
+			{
+				Name:     provider.MetadataPAREndpoint,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Pushed authorization request endpoint URL (auto-discovered for auth-code flow)",
+			},
 			{
 				Name:     provider.MetadataDeviceEndpoint,
 				Type:     provider.FieldTypeString,
@@ -116,6 +216,24 @@ func (p *Provider) Schema() provider.Schema {
 				Required: false,
 				Help:     "Custom redirect URI (overrides redirect_port)",
 			},
+			{
+				Name:     provider.MetadataCallbackHost,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Interface for the local callback server, e.g. ::1 (default: 127.0.0.1)",
+			},
+			{
+				Name:     provider.MetadataNoBrowser,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Print the authorization URL instead of opening a browser (for headless/SSH sessions)",
+			},
+			{
+				Name:     provider.MetadataBrowserCommand,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Command that opens the authorization URL, e.g. wslview (default: ${CREDCTL_BROWSER} or the system browser)",
+			},
 			{
 				Name:     "use_pkce",
 				Type:     provider.FieldTypeBool,
@@ -123,57 +241,300 @@ func (p *Provider) Schema() provider.Schema {
 				Default:  "true",
 				Help:     "Use PKCE extension for authorization_code flow (recommended for public clients)",
 			},
+			{
+				Name:     provider.MetadataAuthParams,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Extra authorization request parameters as key=value (e.g., prompt=consent,login_hint=user@example.com)",
+			},
+			{
+				Name:     provider.MetadataTokenParams,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Extra token request parameters as key=value",
+			},
+			{
+				Name:     provider.MetadataProfiles,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Named profiles as name=scopes, with an optional audience=<aud> (e.g., admin=openid admin:write audience=https://admin.api); select one with name@profile",
+			},
+			{
+				Name:     provider.MetadataUsername,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Username for the password flow (legacy, discouraged)",
+			},
+			{
+				Name:     provider.MetadataPassword,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Hidden:   true,
+				Help:     "Password for the password flow (legacy, discouraged); use @file or - to read it from a file or stdin",
+			},
+			{
+				Name:     provider.MetadataSubject,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Principal to request client-credentials tokens for, sent as requested_subject (impersonation, IdP-specific)",
+			},
+			{
+				Name:     provider.MetadataActorTokenSource,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Name of the credctl provider whose credential is sent as the actor token (delegation)",
+			},
+			{
+				Name:     provider.MetadataActorTokenType,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Default:  common.TokenTypeAccessToken,
+				Help:     "Type of the actor token (RFC 8693 token type URI)",
+			},
+			{
+				Name:     provider.MetadataHTTPProxy,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Proxy URL for this provider's HTTP calls, e.g. http://proxy.corp:3128 (overrides HTTP_PROXY/HTTPS_PROXY)",
+			},
+			{
+				Name:     provider.MetadataHTTPHeaders,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Extra headers for this provider's HTTP calls as name=value (e.g., for an IdP behind an auth gateway)",
+			},
+			{
+				Name:     provider.MetadataAccessTokenField,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Token response field holding the access token, for non-standard endpoints (e.g. jwt, accessToken)",
+			},
+			{
+				Name:     provider.MetadataRefreshTokenField,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Token response field holding the refresh token, for non-standard endpoints",
+			},
+			{
+				Name:     provider.MetadataExpiresInField,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Token response field holding the lifetime in seconds, for non-standard endpoints",
+			},
+			{
+				Name:     provider.MetadataRawResponse,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Default:  "false",
+				Help:     "Return the whole token endpoint response (JSON, vendor fields included) instead of the access token",
+			},
+			{
+				Name:     provider.MetadataExpiryBuffer,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "30",
+				Help:     "Seconds before expiry at which a cached token is renewed (raise for high latency or clock skew)",
+			},
+			{
+				Name:     provider.MetadataExpectedAudiences,
+				Type:     provider.FieldTypeStringSlice,
+				Required: false,
+				Help:     "Audiences that must all be present in the ID token's aud claim",
+			},
+			{
+				Name:     provider.MetadataSkipClientIDCheck,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Don't require client_id in the ID token audience (for brokered tokens)",
+			},
+			{
+				Name:     provider.MetadataAllowedClockSkew,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "0",
+				Help:     "Seconds of clock skew with the issuer tolerated when checking the ID token's exp and nbf claims",
+			},
+			{
+				Name:     provider.MetadataACRValues,
+				Type:     provider.FieldTypeStringSlice,
+				Required: false,
+				Help:     "Authentication context classes to request (e.g. an MFA level); the ID token acr claim must be one of them",
+			},
+			{
+				Name:     provider.MetadataAllowIssuerMismatch,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Accept a discovery document whose issuer differs from the configured issuer (insecure, for off-spec IdPs)",
+			},
+			{
+				Name:     provider.MetadataProbeDeviceEndpoint,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "For the device flow, look for a device endpoint at conventional paths (…/devicecode) when discovery doesn't advertise one (always done for Azure AD)",
+			},
+			{
+				Name:     provider.MetadataAllowInsecureEndpoints,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Allow plain http endpoints on hosts other than localhost (insecure: secrets and tokens are sent in cleartext)",
+			},
 			{
 				Name:     "flow",
 				Type:     provider.FieldTypeString,
 				Required: true,
-				Help:     "OAuth2 flow to use: device, auth-code, client-credentials",
+				Help:     "OAuth2 flow to use: device, auth-code, client-credentials, password",
 			},
 		},
 	}
 }
 
 func (p *Provider) Init(config map[string]any) error {
+	config, secretRefs, err := provider.ResolveSecretRefs(config, p.Schema())
+	if err != nil {
+		return err
+	}
+	p.secretRefs = secretRefs
+
 	p.issuer = provider.GetStringOrDefault(config, provider.MetadataIssuer, "")
 	p.clientID = provider.GetStringOrDefault(config, provider.MetadataClientID, "")
 	p.clientSecret = provider.GetStringOrDefault(config, provider.MetadataClientSecret, "")
+	p.clientAuthMethod = provider.GetStringOrDefault(config, provider.MetadataClientAuthMethod, ClientAuthSecret)
+	p.clientCert = provider.GetStringOrDefault(config, provider.MetadataClientCert, "")
+	p.clientKey = provider.GetStringOrDefault(config, provider.MetadataClientKey, "")
 	p.tokenEndpoint = provider.GetStringOrDefault(config, provider.MetadataTokenEndpoint, "")
 	p.authEndpoint = provider.GetStringOrDefault(config, provider.MetadataAuthEndpoint, "")
+	p.parEndpoint = provider.GetStringOrDefault(config, provider.MetadataPAREndpoint, "")
 	p.deviceEndpoint = provider.GetStringOrDefault(config, provider.MetadataDeviceEndpoint, "")
 	p.redirectPort = provider.GetIntOrDefault(config, provider.MetadataRedirectPort, 8085)
 	p.redirectURI = provider.GetStringOrDefault(config, provider.MetadataRedirectURI, "")
+	p.callbackHost = provider.GetStringOrDefault(config, provider.MetadataCallbackHost, "")
+	p.noBrowser = provider.GetBoolOrDefault(config, provider.MetadataNoBrowser, false)
+	p.browserCommand = provider.GetStringOrDefault(config, provider.MetadataBrowserCommand, "")
+	p.expectedAudiences = provider.GetStringSliceOrDefault(config, provider.MetadataExpectedAudiences, nil)
+	p.skipClientIDCheck = provider.GetBoolOrDefault(config, provider.MetadataSkipClientIDCheck, false)
+	p.acrValues = provider.GetStringSliceOrDefault(config, provider.MetadataACRValues, nil)
+	p.allowIssuerMismatch = provider.GetBoolOrDefault(config, provider.MetadataAllowIssuerMismatch, false)
+	p.probeDeviceEndpoint = provider.GetBoolOrDefault(config, provider.MetadataProbeDeviceEndpoint, false)
+	p.allowInsecureEndpoints = provider.GetBoolOrDefault(config, provider.MetadataAllowInsecureEndpoints, false)
 	p.usePKCE = provider.GetBoolOrDefault(config, "use_pkce", true)
 	p.flow = provider.GetStringOrDefault(config, "flow", "")
-	p.template = provider.GetStringOrDefault(config, provider.MetadataTemplate, "")
-	p.format = provider.GetStringOrDefault(config, provider.MetadataFormat, "text")
-	p.output = provider.GetStringOrDefault(config, provider.MetadataOutput, "")
+	p.authParams = provider.GetStringMapOrDefault(config, provider.MetadataAuthParams, nil)
+	p.tokenParams = provider.GetStringMapOrDefault(config, provider.MetadataTokenParams, nil)
+	p.profileSpecs = provider.GetStringMapOrDefault(config, provider.MetadataProfiles, nil)
+	p.username = provider.GetStringOrDefault(config, provider.MetadataUsername, "")
+	p.password = provider.GetStringOrDefault(config, provider.MetadataPassword, "")
+	p.subject = provider.GetStringOrDefault(config, provider.MetadataSubject, "")
+	p.actorTokenSource = provider.GetStringOrDefault(config, provider.MetadataActorTokenSource, "")
+	p.actorTokenType = provider.GetStringOrDefault(config, provider.MetadataActorTokenType, common.TokenTypeAccessToken)
+	p.httpProxy = provider.GetStringOrDefault(config, provider.MetadataHTTPProxy, "")
+	p.httpHeaders = provider.GetStringMapOrDefault(config, provider.MetadataHTTPHeaders, nil)
+	p.tokenFields = common.TokenFieldMapping{
+		AccessToken:  provider.GetStringOrDefault(config, provider.MetadataAccessTokenField, ""),
+		RefreshToken: provider.GetStringOrDefault(config, provider.MetadataRefreshTokenField, ""),
+		ExpiresIn:    provider.GetStringOrDefault(config, provider.MetadataExpiresInField, ""),
+	}
+	p.rawResponse = provider.GetBoolOrDefault(config, provider.MetadataRawResponse, false)
+	p.tokenFields.KeepRaw = p.rawResponse
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+
+	p.expiryBuffer, err = common.GetExpiryBuffer(config)
+	if err != nil {
+		return err
+	}
+	p.allowedClockSkew, err = common.GetAllowedClockSkew(config)
+	if err != nil {
+		return err
+	}
+	p.profiles, err = parseProfiles(p.profileSpecs)
+	if err != nil {
+		return err
+	}
+
+	if err := common.ValidateCallbackHost(p.callbackHost); err != nil {
+		return err
+	}
+	if err := common.CheckReservedParams(provider.MetadataAuthParams, p.authParams, common.ReservedAuthParams); err != nil {
+		return err
+	}
+	if err := common.CheckReservedParams(provider.MetadataTokenParams, p.tokenParams, common.ReservedTokenParams); err != nil {
+		return err
+	}
+
+	p.httpClient = nil
+	if p.httpProxy != "" {
+		client, err := common.NewProxyHTTPClient(p.httpProxy)
+		if err != nil {
+			return err
+		}
+		p.httpClient = client
+	}
+
+	switch p.clientAuthMethod {
+	case ClientAuthSecret:
+	case ClientAuthTLS:
+		if p.clientCert == "" || p.clientKey == "" {
+			return fmt.Errorf("tls_client_auth requires client_cert and client_key")
+		}
+		if p.clientSecret != "" {
+			return fmt.Errorf("client_secret must not be set with tls_client_auth")
+		}
+	default:
+		return fmt.Errorf("invalid client_auth_method '%s': must be one of: %s, %s", p.clientAuthMethod, ClientAuthSecret, ClientAuthTLS)
+	}
+	if p.clientCert != "" {
+		client, err := common.WithClientCertificate(p.httpClient, p.clientCert, p.clientKey)
+		if err != nil {
+			return err
+		}
+		p.httpClient = client
+	}
+
+	// Identify credctl (and add configured headers) on every call, discovery included
+	if err := common.ValidateRequestHeaders(p.httpHeaders); err != nil {
+		return err
+	}
+	p.httpClient = common.WithRequestHeaders(p.httpClient, p.httpHeaders)
 
 	// Validate flow is provided
 	if p.flow == "" {
-		return fmt.Errorf("flow is required: must be one of: device, auth-code, client-credentials")
+		return fmt.Errorf("flow is required: must be one of: device, auth-code, client-credentials, password")
 	}
 
 	// Validate flow value
 	switch p.flow {
-	case FlowDevice, FlowAuthCode, FlowClientCredentials:
+	case FlowDevice, FlowAuthCode, FlowClientCredentials, FlowPassword:
 		// Valid flow
 	default:
-		return fmt.Errorf("invalid flow '%s': must be one of: device, auth-code, client-credentials", p.flow)
+		return fmt.Errorf("invalid flow '%s': must be one of: device, auth-code, client-credentials, password", p.flow)
 	}
 
-	// Get scopes (default to "openid" if OIDC)
-	if p.issuer != "" {
-		p.scopes = common.GetScopesOrDefault(config)
-	} else {
-		p.scopes = common.GetScopes(config)
+	// Get scopes (default to "openid" if OIDC, except for client credentials)
+	p.scopes = common.GetScopesOrDefault(config, p.issuer != "" && p.flow != FlowClientCredentials)
+
+	// Refuse to send anything to a cleartext issuer before discovery
+	if err := common.ValidateEndpoint(provider.MetadataIssuer, p.issuer, p.allowInsecureEndpoints); err != nil {
+		return err
 	}
 
 	// Perform OIDC discovery if issuer is set
 	if p.issuer != "" {
-		doc, err := common.Discover(p.issuer)
+		doc, err := common.Discover(p.httpContext(context.Background()), p.issuer, p.allowIssuerMismatch)
 		if err != nil {
 			return fmt.Errorf("failed to discover OIDC endpoints: %w", err)
 		}
+		if p.allowIssuerMismatch {
+			// ID tokens are issued under the issuer the document reports
+			p.tokenIssuer = doc.Issuer
+		}
 		// Use discovered endpoints if not explicitly set
 		if p.tokenEndpoint == "" {
 			p.tokenEndpoint = doc.TokenEndpoint
@@ -182,17 +543,28 @@ func (p *Provider) Init(config map[string]any) error {
 		// Only auto-configure endpoints based on explicit flow setting
 		switch p.flow {
 		case FlowAuthCode:
-			// Auth code mode: only configure auth endpoint
+			// Auth code mode: only configure auth and PAR endpoints
 			if p.authEndpoint == "" {
 				p.authEndpoint = doc.AuthorizationEndpoint
 			}
+			if p.parEndpoint == "" {
+				p.parEndpoint = doc.PAREndpoint
+			}
 		case FlowDevice:
 			// Device mode: only configure device endpoint
 			if p.deviceEndpoint == "" && doc.DeviceEndpoint != "" {
 				p.deviceEndpoint = doc.DeviceEndpoint
 			}
-		case FlowClientCredentials:
-			// Client credentials: no interactive endpoints needed
+			// Older Azure AD tenants serve a device endpoint they don't advertise
+			if p.deviceEndpoint == "" && (p.probeDeviceEndpoint || common.IsAzureADIssuer(p.issuer)) {
+				candidates := common.DeviceEndpointCandidates(p.issuer, p.tokenEndpoint)
+				if endpoint := common.ProbeDeviceEndpoint(p.httpContext(context.Background()), candidates); endpoint != "" {
+					log.Printf("discovery for %s has no device_authorization_endpoint, using %s", p.issuer, endpoint)
+					p.deviceEndpoint = endpoint
+				}
+			}
+		case FlowClientCredentials, FlowPassword:
+			// Client credentials / password: no interactive endpoints needed
 			// Only token_endpoint is used
 		}
 	}
@@ -202,6 +574,22 @@ func (p *Provider) Init(config map[string]any) error {
 		return fmt.Errorf("token_endpoint is required (or provide issuer for auto-discovery)")
 	}
 
+	// Configured and discovered endpoints alike must not leak secrets in cleartext
+	if err := common.ValidateEndpoints(map[string]string{
+		provider.MetadataTokenEndpoint:  p.tokenEndpoint,
+		provider.MetadataAuthEndpoint:   p.authEndpoint,
+		provider.MetadataPAREndpoint:    p.parEndpoint,
+		provider.MetadataDeviceEndpoint: p.deviceEndpoint,
+	}, p.allowInsecureEndpoints); err != nil {
+		return err
+	}
+
+	// Rename non-standard fields in token responses before x/oauth2 parses them
+	// (and keep the original body in raw_response mode)
+	if !p.tokenFields.IsZero() {
+		p.httpClient = common.NewTokenFieldClient(p.httpClient, p.tokenEndpoint, p.tokenFields)
+	}
+
 	// Validate flow-specific requirements
 	switch p.flow {
 	case FlowDevice:
@@ -213,8 +601,23 @@ func (p *Provider) Init(config map[string]any) error {
 			return fmt.Errorf("auth-code flow requires auth_endpoint (or issuer for auto-discovery)")
 		}
 	case FlowClientCredentials:
-		if p.clientSecret == "" {
-			return fmt.Errorf("client-credentials flow requires client_secret")
+		if p.clientSecret == "" && p.clientAuthMethod != ClientAuthTLS {
+			return fmt.Errorf("client-credentials flow requires client_secret (or client_auth_method tls_client_auth)")
+		}
+	case FlowPassword:
+		if p.username == "" || p.password == "" {
+			return fmt.Errorf("password flow requires username and password")
+		}
+	}
+	if p.flow != FlowClientCredentials && (p.subject != "" || p.actorTokenSource != "") {
+		return fmt.Errorf("subject and actor_token_source are only supported by the client-credentials flow")
+	}
+	if len(p.acrValues) > 0 {
+		if p.flow != FlowDevice && p.flow != FlowAuthCode {
+			return fmt.Errorf("acr_values is only supported by the device and auth-code flows")
+		}
+		if p.issuer == "" {
+			return fmt.Errorf("acr_values requires issuer (the acr claim is checked in the ID token)")
 		}
 	}
 
@@ -222,17 +625,44 @@ func (p *Provider) Init(config map[string]any) error {
 }
 
 func (p *Provider) Get(ctx context.Context) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.get(ctx)
+}
+
+// get implements Get; callers hold p.mu
+func (p *Provider) get(ctx context.Context) ([]byte, error) {
+	ctx = p.httpContext(ctx)
+
+	// Tokens issued under an earlier configuration (issuer or client changed)
+	// can't be refreshed either: drop them and authenticate again
+	if p.tokens != nil && !p.tokensMatchConfig(p.tokens) {
+		p.tokens = nil
+	}
+
 	// Check if we have valid cached tokens
-	if common.IsTokenValid(p.tokens) {
-		return []byte(p.tokens.AccessToken), nil
+	if common.IsTokenValid(p.tokens, p.expiryBuffer) {
+		return p.credential(p.tokens), nil
+	}
+
+	// Consult the shared cache before hitting the network (tokens from another process)
+	if shared := common.LoadSharedTokens(p.SharedCacheKey()); shared != nil {
+		if !p.tokensMatchConfig(shared) {
+			_ = common.RemoveSharedTokens(p.SharedCacheKey())
+		} else {
+			p.tokens = shared
+			if common.IsTokenValid(p.tokens, p.expiryBuffer) {
+				return p.credential(p.tokens), nil
+			}
+		}
 	}
 
 	// Try to refresh if we have a refresh token
 	if p.tokens != nil && p.tokens.RefreshToken != "" {
-		newTokens, err := common.RefreshAccessToken(p.tokenEndpoint, p.clientID, p.clientSecret, p.tokens.RefreshToken)
+		newTokens, err := common.RefreshAccessToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.tokens.RefreshToken)
 		if err == nil {
-			p.tokens = newTokens
-			return []byte(p.tokens.AccessToken), nil
+			p.storeTokens(newTokens)
+			return p.credential(p.tokens), nil
 		}
 		// Refresh failed, continue to try other flows
 	}
@@ -241,19 +671,31 @@ func (p *Provider) Get(ctx context.Context) ([]byte, error) {
 	switch p.flow {
 	case FlowClientCredentials:
 		// Client credentials flow (non-interactive, machine-to-machine)
-		tokens, err := common.GetClientCredentialsToken(p.tokenEndpoint, p.clientID, p.clientSecret, p.scopes)
+		delegation, err := p.delegation(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := common.GetClientCredentialsToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.scopes, p.tokenParams, delegation)
 		if err != nil {
 			return nil, fmt.Errorf("client credentials grant failed: %w", err)
 		}
-		p.tokens = tokens
-		return []byte(tokens.AccessToken), nil
+		p.storeTokens(tokens)
+		return p.credential(tokens), nil
 
-	case FlowAuthCode:
-		// Authorization Code Flow (PKCE) - automatic, opens browser
-		if err := p.doAuthorizationCodeFlow(ctx); err != nil {
-			return nil, fmt.Errorf("authorization code flow failed: %w", err)
+	case FlowPassword:
+		// Password grant (non-interactive, legacy)
+		tokens, err := common.GetPasswordGrantToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.username, p.password, p.scopes, p.tokenParams)
+		if err != nil {
+			return nil, fmt.Errorf("password grant failed: %w", err)
 		}
-		return []byte(p.tokens.AccessToken), nil
+		p.storeTokens(tokens)
+		return p.credential(tokens), nil
+
+	case FlowAuthCode:
+		// Authorization Code Flow requires explicit login: Get() runs in the
+		// daemon, which has no browser to open. credctl login runs the flow
+		// client-side and sends the tokens to the daemon
+		return nil, provider.ErrAuthenticationRequired
 
 	case FlowDevice:
 		// Device Flow requires explicit login
@@ -270,6 +712,20 @@ func (p *Provider) Login(ctx context.Context) error {
 	// - Device flow (requires user to see code and visit URL)
 	// - Force re-authentication (invalidate current tokens)
 	// - Pre-authenticate before using Get()
+	return p.login(ctx, false)
+}
+
+// StepUpLogin logs in again with prompt=login, so the IdP authenticates the
+// user afresh (and at one of the acr_values) instead of reusing its session
+// This implements the StepUpProvider interface
+func (p *Provider) StepUpLogin(ctx context.Context) error {
+	return p.login(ctx, true)
+}
+
+// login runs the interactive flow; stepUp is passed along rather than stored
+// on the provider, as the daemon may read the parameters concurrently
+func (p *Provider) login(ctx context.Context, stepUp bool) error {
+	ctx = p.httpContext(ctx)
 
 	var tokens *common.TokenCache
 	var err error
@@ -277,10 +733,10 @@ func (p *Provider) Login(ctx context.Context) error {
 	// Handle login based on explicit flow setting
 	switch p.flow {
 	case FlowDevice:
-		tokens, err = common.AuthenticateDeviceFlow(ctx, p.deviceEndpoint, p.tokenEndpoint, p.clientID, p.clientSecret, p.scopes)
+		tokens, err = common.AuthenticateDeviceFlow(ctx, p.deviceEndpoint, p.tokenEndpoint, p.clientID, p.clientSecret, p.scopes, p.authRequestParams(stepUp), p.tokenParams)
 
 	case FlowAuthCode:
-		if err := p.doAuthorizationCodeFlow(ctx); err != nil {
+		if err := p.doAuthorizationCodeFlow(ctx, stepUp); err != nil {
 			return err
 		}
 		return nil
@@ -288,6 +744,9 @@ func (p *Provider) Login(ctx context.Context) error {
 	case FlowClientCredentials:
 		return fmt.Errorf("client-credentials flow does not support interactive login")
 
+	case FlowPassword:
+		return fmt.Errorf("password flow does not support interactive login")
+
 	default:
 		return fmt.Errorf("unsupported flow: %s", p.flow)
 	}
@@ -303,25 +762,53 @@ func (p *Provider) Login(ctx context.Context) error {
 		}
 	}
 
-	p.tokens = tokens
+	p.mu.Lock()
+	p.storeTokens(tokens)
+	p.mu.Unlock()
 	return nil
 }
 
+// authRequestParams returns the extra authorization request parameters:
+// auth_params, acr_values and, for a step-up login, prompt=login
+func (p *Provider) authRequestParams(stepUp bool) map[string]string {
+	if len(p.acrValues) == 0 && !stepUp {
+		return p.authParams
+	}
+
+	params := maps.Clone(p.authParams)
+	if params == nil {
+		params = map[string]string{}
+	}
+	if len(p.acrValues) > 0 {
+		params["acr_values"] = strings.Join(p.acrValues, " ")
+	}
+	if stepUp {
+		params["prompt"] = "login"
+	}
+	return params
+}
+
 // doAuthorizationCodeFlow performs the authorization code flow with optional PKCE
-func (p *Provider) doAuthorizationCodeFlow(ctx context.Context) error {
+func (p *Provider) doAuthorizationCodeFlow(ctx context.Context, stepUp bool) error {
 	code, codeVerifier, redirectURI, err := common.AuthenticateAuthCodeFlow(ctx, common.AuthCodeFlowParams{
-		AuthEndpoint: p.authEndpoint,
-		ClientID:     p.clientID,
-		Scopes:       p.scopes,
-		RedirectURI:  p.redirectURI,
-		RedirectPort: p.redirectPort,
-		UsePKCE:      p.usePKCE,
+		AuthEndpoint:   p.authEndpoint,
+		ClientID:       p.clientID,
+		Scopes:         p.scopes,
+		RedirectURI:    p.redirectURI,
+		RedirectPort:   p.redirectPort,
+		CallbackHost:   p.callbackHost,
+		NoBrowser:      p.noBrowser,
+		BrowserCommand: p.browserCommand,
+		UsePKCE:        p.usePKCE,
+		ExtraParams:    p.authRequestParams(stepUp),
+		PAREndpoint:    p.parEndpoint,
+		ClientSecret:   p.clientSecret,
 	})
 	if err != nil {
 		return err
 	}
 
-	tokens, err := common.ExchangeCodeForTokens(p.tokenEndpoint, p.clientID, p.clientSecret, code, redirectURI, codeVerifier)
+	tokens, err := common.ExchangeCodeForTokens(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, code, redirectURI, codeVerifier, p.tokenParams)
 	if err != nil {
 		return err
 	}
@@ -333,19 +820,45 @@ func (p *Provider) doAuthorizationCodeFlow(ctx context.Context) error {
 		}
 	}
 
-	p.tokens = tokens
+	p.mu.Lock()
+	p.storeTokens(tokens)
+	p.mu.Unlock()
 	return nil
 }
 
+// httpContext routes the OAuth2/OIDC calls made with ctx through the provider's proxy
+func (p *Provider) httpContext(ctx context.Context) context.Context {
+	return common.WithHTTPClient(ctx, p.httpClient)
+}
+
 func (p *Provider) validateIDToken(ctx context.Context, rawIDToken string) error {
-	oidcProvider, err := common.NewOIDCProvider(ctx, p.issuer)
+	oidcProvider, err := common.NewOIDCProvider(ctx, p.issuer, p.tokenIssuer)
+	if err != nil {
+		return err
+	}
+
+	verifier := common.NewIDTokenVerifier(oidcProvider, p.clientID, p.skipClientIDCheck, p.allowedClockSkew)
+	idToken, err := common.VerifyIDToken(ctx, verifier, rawIDToken, p.allowedClockSkew)
 	if err != nil {
 		return err
 	}
+	if err := common.ValidateAudiences(idToken, p.expectedAudiences); err != nil {
+		return err
+	}
+	return common.ValidateACR(idToken, p.acrValues)
+}
 
-	verifier := common.NewIDTokenVerifier(oidcProvider, p.clientID)
-	_, err = common.VerifyIDToken(ctx, verifier, rawIDToken)
-	return err
+// Capabilities reports that tokens come from the network and are renewed with
+// a refresh token or, for the non-interactive grants, a new grant. The device
+// and auth-code flows need the user when no refresh token is available.
+// This implements the CapabilitiesProvider interface
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{
+		NeedsNetwork:    true,
+		SupportsRefresh: true,
+		Interactive:     p.flow == FlowDevice || p.flow == FlowAuthCode,
+		Structured:      true,
+	}
 }
 
 func (p *Provider) Metadata() map[string]any {
@@ -359,6 +872,15 @@ func (p *Provider) Metadata() map[string]any {
 	if p.clientSecret != "" {
 		metadata[provider.MetadataClientSecret] = p.clientSecret
 	}
+	if p.clientAuthMethod != "" && p.clientAuthMethod != ClientAuthSecret {
+		metadata[provider.MetadataClientAuthMethod] = p.clientAuthMethod
+	}
+	if p.clientCert != "" {
+		metadata[provider.MetadataClientCert] = p.clientCert
+	}
+	if p.clientKey != "" {
+		metadata[provider.MetadataClientKey] = p.clientKey
+	}
 	if len(p.scopes) > 0 {
 		metadata[provider.MetadataScopes] = p.scopes
 	}
@@ -368,6 +890,9 @@ func (p *Provider) Metadata() map[string]any {
 	if p.authEndpoint != "" {
 		metadata[provider.MetadataAuthEndpoint] = p.authEndpoint
 	}
+	if p.parEndpoint != "" {
+		metadata[provider.MetadataPAREndpoint] = p.parEndpoint
+	}
 	if p.deviceEndpoint != "" {
 		metadata[provider.MetadataDeviceEndpoint] = p.deviceEndpoint
 	}
@@ -377,41 +902,301 @@ func (p *Provider) Metadata() map[string]any {
 	if p.redirectPort != 0 {
 		metadata[provider.MetadataRedirectPort] = p.redirectPort
 	}
+	if p.callbackHost != "" {
+		metadata[provider.MetadataCallbackHost] = p.callbackHost
+	}
+	if p.noBrowser {
+		metadata[provider.MetadataNoBrowser] = true
+	}
+	if p.browserCommand != "" {
+		metadata[provider.MetadataBrowserCommand] = p.browserCommand
+	}
+	if len(p.expectedAudiences) > 0 {
+		metadata[provider.MetadataExpectedAudiences] = p.expectedAudiences
+	}
+	if len(p.acrValues) > 0 {
+		metadata[provider.MetadataACRValues] = p.acrValues
+	}
+	if p.skipClientIDCheck {
+		metadata[provider.MetadataSkipClientIDCheck] = true
+	}
+	if p.allowIssuerMismatch {
+		metadata[provider.MetadataAllowIssuerMismatch] = true
+	}
+	if p.probeDeviceEndpoint {
+		metadata[provider.MetadataProbeDeviceEndpoint] = true
+	}
+	if p.allowInsecureEndpoints {
+		metadata[provider.MetadataAllowInsecureEndpoints] = true
+	}
+	if p.expiryBuffer != common.DefaultExpiryBuffer {
+		metadata[provider.MetadataExpiryBuffer] = int(p.expiryBuffer / time.Second)
+	}
+	if p.allowedClockSkew > 0 {
+		metadata[provider.MetadataAllowedClockSkew] = int(p.allowedClockSkew / time.Second)
+	}
 	if p.usePKCE {
 		metadata["use_pkce"] = true
 	}
 	if p.flow != "" {
 		metadata["flow"] = p.flow
 	}
-	if p.template != "" {
-		metadata[provider.MetadataTemplate] = p.template
+	if len(p.authParams) > 0 {
+		metadata[provider.MetadataAuthParams] = p.authParams
 	}
-
-	// Preserve format and output from config (set by cmd/add.go global flags)
-	if p.format != "" && p.format != "text" {
-		metadata[provider.MetadataFormat] = p.format
+	if len(p.tokenParams) > 0 {
+		metadata[provider.MetadataTokenParams] = p.tokenParams
 	}
-
-	if p.output != "" {
-		metadata[provider.MetadataOutput] = p.output
+	if len(p.profileSpecs) > 0 {
+		metadata[provider.MetadataProfiles] = p.profileSpecs
+	}
+	if p.username != "" {
+		metadata[provider.MetadataUsername] = p.username
+	}
+	if p.password != "" {
+		metadata[provider.MetadataPassword] = p.password
 	}
+	if p.subject != "" {
+		metadata[provider.MetadataSubject] = p.subject
+	}
+	if p.actorTokenSource != "" {
+		metadata[provider.MetadataActorTokenSource] = p.actorTokenSource
+	}
+	if p.actorTokenType != "" && p.actorTokenType != common.TokenTypeAccessToken {
+		metadata[provider.MetadataActorTokenType] = p.actorTokenType
+	}
+	if p.httpProxy != "" {
+		metadata[provider.MetadataHTTPProxy] = p.httpProxy
+	}
+	if len(p.httpHeaders) > 0 {
+		metadata[provider.MetadataHTTPHeaders] = p.httpHeaders
+	}
+	if p.tokenFields.AccessToken != "" {
+		metadata[provider.MetadataAccessTokenField] = p.tokenFields.AccessToken
+	}
+	if p.tokenFields.RefreshToken != "" {
+		metadata[provider.MetadataRefreshTokenField] = p.tokenFields.RefreshToken
+	}
+	if p.tokenFields.ExpiresIn != "" {
+		metadata[provider.MetadataExpiresInField] = p.tokenFields.ExpiresIn
+	}
+	if p.rawResponse {
+		metadata[provider.MetadataRawResponse] = true
+	}
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+	provider.AddSecretRefsToMetadata(metadata, p.secretRefs)
 
 	return metadata
 }
 
 func (p *Provider) SetTokens(accessToken, refreshToken string, expiresIn int) {
-	p.tokens = &common.TokenCache{
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.storeTokens(&common.TokenCache{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(common.NormalizeExpiresIn(expiresIn)) * time.Second),
+		ExpiresAt:    clock.Now().Add(time.Duration(common.NormalizeExpiresIn(expiresIn)) * time.Second),
+	})
+}
+
+// RefreshTokens exchanges the cached refresh token for new tokens, e.g. to
+// check that an imported refresh token is accepted
+// This implements the RefreshProvider interface
+func (p *Provider) RefreshTokens(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokens == nil || p.tokens.RefreshToken == "" {
+		return fmt.Errorf("no refresh token cached")
 	}
+
+	newTokens, err := common.RefreshAccessToken(p.httpContext(ctx), p.tokenEndpoint, p.clientID, p.clientSecret, p.tokens.RefreshToken)
+	if err != nil {
+		return err
+	}
+	p.storeTokens(newTokens)
+	return nil
+}
+
+// SharedCacheKey identifies this provider's tokens in the shared cache
+// This implements the SharedCacheProvider interface
+func (p *Provider) SharedCacheKey() string {
+	identity := []string{p.clientID, p.tokenEndpoint, strings.Join(p.scopes, " ")}
+	// Tokens issued on behalf of a subject must not be shared with the client's own
+	if p.subject != "" || p.actorTokenSource != "" {
+		identity = append(identity, p.subject, p.actorTokenSource)
+	}
+	return common.SharedCacheKey(identity...)
+}
+
+// delegation returns the subject and actor token to request client credentials
+// tokens for, or nil when the client requests tokens for itself
+func (p *Provider) delegation(ctx context.Context) (*common.Delegation, error) {
+	if p.subject == "" && p.actorTokenSource == "" {
+		return nil, nil
+	}
+
+	delegation := &common.Delegation{Subject: p.subject}
+	if p.actorTokenSource != "" {
+		actorToken, err := common.SourceToken(ctx, provider.MetadataActorTokenSource, p.actorTokenSource)
+		if err != nil {
+			return nil, err
+		}
+		delegation.ActorToken = actorToken
+		delegation.ActorTokenType = p.actorTokenType
+	}
+	return delegation, nil
+}
+
+// tokensMatchConfig reports whether cached tokens were issued for the current
+// configuration: an ID token must come from the configured issuer and be
+// addressed to client_id, and a JWT access token naming a client_id (RFC 9068)
+// must name ours. Missing claims aren't checked. Access token issuers aren't
+// compared, as some IdPs issue them under another issuer than the ID token
+// (Azure AD v1 tokens). Opaque tokens can't be checked and always match.
+// Claims are decoded without verification: this only detects stale tokens,
+// it doesn't authenticate them.
+func (p *Provider) tokensMatchConfig(tokens *common.TokenCache) bool {
+	if claims, ok := credentials.JWTClaims(tokens.IDToken); ok {
+		expectedIssuer := p.issuer
+		if p.tokenIssuer != "" {
+			expectedIssuer = p.tokenIssuer
+		}
+		if iss, ok := claims["iss"].(string); ok && expectedIssuer != "" &&
+			strings.TrimSuffix(iss, "/") != strings.TrimSuffix(expectedIssuer, "/") {
+			return false
+		}
+		if aud, ok := claims["aud"]; ok && !p.skipClientIDCheck && !audienceContains(aud, p.clientID) {
+			return false
+		}
+	}
+
+	if claims, ok := credentials.JWTClaims(tokens.AccessToken); ok {
+		if clientID, ok := claims["client_id"].(string); ok && clientID != p.clientID {
+			return false
+		}
+	}
+
+	return true
+}
+
+// audienceContains reports whether an aud claim (a string or an array) lists clientID
+func audienceContains(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, item := range v {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cachedTokensUsable reports whether the in-memory tokens can be returned as is;
+// callers hold p.mu
+func (p *Provider) cachedTokensUsable() bool {
+	return common.IsTokenValid(p.tokens, p.expiryBuffer) && p.tokensMatchConfig(p.tokens)
+}
+
+// credential returns what Get() hands out for tokens: the access token, or the
+// token endpoint response in raw_response mode (when it was kept)
+func (p *Provider) credential(tokens *common.TokenCache) []byte {
+	if p.rawResponse && tokens.RawResponse != "" {
+		return []byte(tokens.RawResponse)
+	}
+	return []byte(tokens.AccessToken)
+}
+
+// storeTokens caches tokens in memory and in the shared cache (best effort);
+// callers hold p.mu
+func (p *Provider) storeTokens(tokens *common.TokenCache) {
+	p.tokens = tokens
+	_ = common.StoreSharedTokens(p.SharedCacheKey(), tokens)
+}
+
+// InvalidateCache drops the cached access token (keeping any refresh token),
+// so the next Get() refreshes or re-runs the flow
+// This implements the CacheInvalidator interface
+func (p *Provider) InvalidateCache() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tokens := p.tokens
+	if tokens == nil {
+		tokens = common.LoadSharedTokens(p.SharedCacheKey())
+	}
+	p.scopedTokens = nil
+	p.profileTokens = nil
+
+	if tokens == nil || tokens.RefreshToken == "" {
+		p.tokens = nil
+		_ = common.RemoveSharedTokens(p.SharedCacheKey())
+		return
+	}
+
+	p.storeTokens(&common.TokenCache{RefreshToken: tokens.RefreshToken})
+}
+
+// VerifyClientSecret requests new tokens with the configured client secret:
+// a client credentials grant, or a refresh for the other flows
+// This implements the SecretVerifier interface
+func (p *Provider) VerifyClientSecret(ctx context.Context) error {
+	ctx = p.httpContext(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.flow == FlowClientCredentials {
+		delegation, err := p.delegation(ctx)
+		if err != nil {
+			return err
+		}
+		tokens, err := common.GetClientCredentialsToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.scopes, p.tokenParams, delegation)
+		if err != nil {
+			return fmt.Errorf("client credentials grant failed: %w", err)
+		}
+		p.storeTokens(tokens)
+		return nil
+	}
+
+	if p.tokens == nil || p.tokens.RefreshToken == "" {
+		return fmt.Errorf("no refresh token to verify the client secret with: run credctl login first")
+	}
+	tokens, err := common.RefreshAccessToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.tokens.RefreshToken)
+	if err != nil {
+		return err
+	}
+	p.storeTokens(tokens)
+	return nil
+}
+
+// TokenType returns the type of the cached access token
+// This implements the TokenTypeProvider interface
+func (p *Provider) TokenType() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokens == nil || p.tokens.AccessToken == "" {
+		return ""
+	}
+	if p.tokens.TokenType == "" {
+		return "Bearer"
+	}
+	return p.tokens.TokenType
 }
 
 func (p *Provider) GetTokens() (accessToken, refreshToken string, expiresIn int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.tokens == nil {
 		return "", "", 0
 	}
-	remaining := int(time.Until(p.tokens.ExpiresAt).Seconds())
+	remaining := int(clock.Until(p.tokens.ExpiresAt).Seconds())
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -421,10 +1206,13 @@ func (p *Provider) GetTokens() (accessToken, refreshToken string, expiresIn int)
 // GetCredentials returns the credentials in a structured format
 // This implements the CredentialsProvider interface
 func (p *Provider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// Check if we have valid cached tokens
-	if !common.IsTokenValid(p.tokens) {
+	if !p.cachedTokensUsable() {
 		// Try to get fresh tokens using Get() logic
-		_, err := p.Get(ctx)
+		_, err := p.get(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -434,31 +1222,247 @@ func (p *Provider) GetCredentials(ctx context.Context) (*credentials.Credentials
 		return nil, fmt.Errorf("no tokens available")
 	}
 
+	return credentials.New(tokenFields(p.tokens)), nil
+}
+
+// GetWithScopes returns an access token restricted to a subset of the configured scopes
+// This implements the ScopedProvider interface
+func (p *Provider) GetWithScopes(ctx context.Context, scopes []string) ([]byte, error) {
+	tokens, err := p.getScopedTokens(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return p.credential(tokens), nil
+}
+
+// GetCredentialsWithScopes returns structured credentials restricted to a subset of the configured scopes
+// This implements the ScopedProvider interface
+func (p *Provider) GetCredentialsWithScopes(ctx context.Context, scopes []string) (*credentials.Credentials, error) {
+	tokens, err := p.getScopedTokens(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.New(tokenFields(tokens)), nil
+}
+
+// getScopedTokens obtains (or returns cached) tokens for a subset of the configured scopes
+// Down-scoped tokens are cached separately and never replace the provider's main tokens
+func (p *Provider) getScopedTokens(ctx context.Context, scopes []string) (*common.TokenCache, error) {
+	if err := common.ValidateScopeSubset(scopes, p.scopes); err != nil {
+		return nil, err
+	}
+	ctx = p.httpContext(ctx)
+
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, " ")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached := p.scopedTokens[key]; common.IsTokenValid(cached, p.expiryBuffer) {
+		return cached, nil
+	}
+
+	tokens, err := p.fetchTokensFor(ctx, sorted, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.scopedTokens == nil {
+		p.scopedTokens = make(map[string]*common.TokenCache)
+	}
+	p.scopedTokens[key] = tokens
+
+	return tokens, nil
+}
+
+// fetchTokensFor obtains new tokens for the given scopes without touching the
+// main tokens, with extraParams added to the token request
+// Interactive flows redeem the main refresh token, logging in first if needed;
+// callers hold p.mu
+func (p *Provider) fetchTokensFor(ctx context.Context, scopes []string, extraParams map[string]string) (*common.TokenCache, error) {
+	params := maps.Clone(p.tokenParams)
+	if len(extraParams) > 0 {
+		if params == nil {
+			params = make(map[string]string, len(extraParams))
+		}
+		maps.Copy(params, extraParams)
+	}
+
+	if p.flow == FlowClientCredentials {
+		delegation, err := p.delegation(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := common.GetClientCredentialsToken(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, scopes, params, delegation)
+		if err != nil {
+			return nil, fmt.Errorf("client credentials grant failed: %w", err)
+		}
+		return tokens, nil
+	}
+
+	// Interactive flows need a refresh token from a previous login
+	if p.tokens == nil || p.tokens.RefreshToken == "" {
+		if _, err := p.get(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if p.tokens == nil || p.tokens.RefreshToken == "" {
+		return nil, fmt.Errorf("scope override requires a refresh token: the server did not issue one")
+	}
+
+	tokens, err := common.RefreshAccessTokenWithScopes(ctx, p.tokenEndpoint, p.clientID, p.clientSecret, p.tokens.RefreshToken, scopes, extraParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the main refresh token in sync if the server rotated it, in the
+	// shared cache too: the old one may already be revoked
+	if tokens.RefreshToken != "" && tokens.RefreshToken != p.tokens.RefreshToken {
+		rotated := *p.tokens
+		rotated.RefreshToken = tokens.RefreshToken
+		p.storeTokens(&rotated)
+	}
+	return tokens, nil
+}
+
+// parseProfiles parses profile definitions of the form "scope1 scope2 audience=<aud>"
+func parseProfiles(specs map[string]string) (map[string]profile, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	profiles := make(map[string]profile, len(specs))
+	for name, spec := range specs {
+		if name == "" || strings.ContainsAny(name, provider.ProfileSeparator+" \t") {
+			return nil, fmt.Errorf("invalid profile name '%s'", name)
+		}
+
+		var prof profile
+		for _, field := range strings.Fields(spec) {
+			if audience, ok := strings.CutPrefix(field, "audience="); ok {
+				prof.audience = audience
+				continue
+			}
+			prof.scopes = append(prof.scopes, field)
+		}
+		profiles[name] = prof
+	}
+	return profiles, nil
+}
+
+// Profiles returns the names of the configured profiles, sorted
+// This implements the ProfileProvider interface
+func (p *Provider) Profiles() []string {
+	names := make([]string, 0, len(p.profiles))
+	for name := range p.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetWithProfile returns the access token of a named profile
+// This implements the ProfileProvider interface
+func (p *Provider) GetWithProfile(ctx context.Context, name string) ([]byte, error) {
+	tokens, err := p.getProfileTokens(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.credential(tokens), nil
+}
+
+// GetCredentialsWithProfile returns the structured credentials of a named profile
+// This implements the ProfileProvider interface
+func (p *Provider) GetCredentialsWithProfile(ctx context.Context, name string) (*credentials.Credentials, error) {
+	tokens, err := p.getProfileTokens(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.New(tokenFields(tokens)), nil
+}
+
+// getProfileTokens obtains (or returns cached) tokens for a named profile
+// Each profile has its own cache entry and never replaces the provider's main tokens
+func (p *Provider) getProfileTokens(ctx context.Context, name string) (*common.TokenCache, error) {
+	prof, ok := p.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' is not defined (available: %v)", name, p.Profiles())
+	}
+	ctx = p.httpContext(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached := p.profileTokens[name]; common.IsTokenValid(cached, p.expiryBuffer) {
+		return cached, nil
+	}
+
+	scopes := prof.scopes
+	if len(scopes) == 0 {
+		scopes = p.scopes
+	}
+	var params map[string]string
+	if prof.audience != "" {
+		params = map[string]string{"audience": prof.audience}
+	}
+
+	tokens, err := p.fetchTokensFor(ctx, scopes, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.profileTokens == nil {
+		p.profileTokens = make(map[string]*common.TokenCache)
+	}
+	p.profileTokens[name] = tokens
+
+	return tokens, nil
+}
+
+// tokenFields builds structured credential fields from cached tokens
+func tokenFields(tokens *common.TokenCache) map[string]string {
 	// Build structured credentials with all available token fields
 	fields := make(map[string]string)
 
-	if p.tokens.AccessToken != "" {
-		fields["access_token"] = p.tokens.AccessToken
+	if tokens.AccessToken != "" {
+		fields["access_token"] = tokens.AccessToken
 	}
-	if p.tokens.RefreshToken != "" {
-		fields["refresh_token"] = p.tokens.RefreshToken
+	if tokens.RefreshToken != "" {
+		fields["refresh_token"] = tokens.RefreshToken
 	}
-	if p.tokens.IDToken != "" {
-		fields["id_token"] = p.tokens.IDToken
+	if tokens.IDToken != "" {
+		fields["id_token"] = tokens.IDToken
+
+		// Expose the identity claims (email, name, sub, groups) directly
+		if claims, ok := credentials.IdentityClaims(tokens.IDToken); ok {
+			for claim, value := range claims {
+				fields[claim] = value
+			}
+		}
 	}
-	if p.tokens.TokenType != "" {
-		fields["token_type"] = p.tokens.TokenType
+	if tokens.TokenType != "" {
+		fields["token_type"] = tokens.TokenType
+	}
+	if authorization := tokens.Authorization(); authorization != "" {
+		fields["authorization"] = authorization
 	}
 
 	// Add expires_at as ISO8601 timestamp
-	fields["expires_at"] = p.tokens.ExpiresAt.Format(time.RFC3339)
+	fields["expires_at"] = tokens.ExpiresAt.Format(time.RFC3339)
 
 	// Add expires_in as seconds remaining
-	remaining := int(time.Until(p.tokens.ExpiresAt).Seconds())
+	remaining := int(clock.Until(tokens.ExpiresAt).Seconds())
 	if remaining < 0 {
 		remaining = 0
 	}
 	fields["expires_in"] = strconv.Itoa(remaining)
 
-	return credentials.New(fields), nil
+	// The whole token endpoint response, vendor fields included (raw_response mode)
+	if tokens.RawResponse != "" {
+		fields["_raw"] = tokens.RawResponse
+	}
+
+	return fields
 }
@@ -0,0 +1,1393 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"credctl/internal/paths"
+	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
+)
+
+func TestGetCredentialsAuthorization(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		expected  string
+	}{
+		{
+			name:      "defaults to bearer",
+			tokenType: "",
+			expected:  "Bearer abc123",
+		},
+		{
+			name:      "lowercase bearer is canonicalized",
+			tokenType: "bearer",
+			expected:  "Bearer abc123",
+		},
+		{
+			name:      "non-bearer token type",
+			tokenType: "DPoP",
+			expected:  "DPoP abc123",
+		},
+		{
+			name:      "mac token type",
+			tokenType: "mac",
+			expected:  "MAC abc123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{
+				tokens: &common.TokenCache{
+					AccessToken: "abc123",
+					TokenType:   tt.tokenType,
+					ExpiresAt:   time.Now().Add(time.Hour),
+				},
+			}
+
+			creds, err := p.GetCredentials(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := creds.Get("authorization"); got != tt.expected {
+				t.Errorf("expected authorization %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// testIDToken builds an unsigned ID token carrying claims
+func testIDToken(claims map[string]any) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	signature := base64.RawURLEncoding.EncodeToString([]byte("test-signature"))
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + signature
+}
+
+func TestGetCredentialsIDTokenClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		idToken  string
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			name: "identity claims",
+			idToken: testIDToken(map[string]any{
+				"sub":    "user-123",
+				"email":  "jane@example.com",
+				"name":   "Jane Doe",
+				"groups": []any{"admins", "devs"},
+				"iss":    "https://issuer.example.com",
+			}),
+			expected: map[string]string{
+				"sub":    "user-123",
+				"email":  "jane@example.com",
+				"name":   "Jane Doe",
+				"groups": "admins,devs",
+			},
+			absent: []string{"iss"},
+		},
+		{
+			name:     "missing claims are omitted",
+			idToken:  testIDToken(map[string]any{"sub": "user-123"}),
+			expected: map[string]string{"sub": "user-123"},
+			absent:   []string{"email", "name", "groups"},
+		},
+		{
+			name:    "no id token",
+			idToken: "",
+			absent:  []string{"id_token", "sub", "email", "name", "groups"},
+		},
+		{
+			name:     "opaque id token",
+			idToken:  "not-a-jwt",
+			expected: map[string]string{"id_token": "not-a-jwt"},
+			absent:   []string{"sub", "email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{
+				tokens: &common.TokenCache{
+					AccessToken: "abc123",
+					IDToken:     tt.idToken,
+					ExpiresAt:   time.Now().Add(time.Hour),
+				},
+			}
+
+			creds, err := p.GetCredentials(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for field, want := range tt.expected {
+				if got := creds.Get(field); got != want {
+					t.Errorf("expected %s %q, got %q", field, want, got)
+				}
+			}
+			for _, field := range tt.absent {
+				if creds.Has(field) {
+					t.Errorf("expected no %s field, got %q", field, creds.Get(field))
+				}
+			}
+		})
+	}
+}
+
+func TestGetWithScopesClientCredentials(t *testing.T) {
+	var requestedScope string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		requestedScope = r.PostForm.Get("scope")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"scoped-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{
+		clientID:      "my-client",
+		clientSecret:  "secret",
+		tokenEndpoint: server.URL,
+		scopes:        []string{"read", "write"},
+		flow:          FlowClientCredentials,
+		tokens: &common.TokenCache{
+			AccessToken: "broad-token",
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	}
+
+	token, err := p.GetWithScopes(context.Background(), []string{"read"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(token) != "scoped-token" {
+		t.Errorf("expected scoped token, got %q", token)
+	}
+	if requestedScope != "read" {
+		t.Errorf("expected scope %q in token request, got %q", "read", requestedScope)
+	}
+	if p.tokens.AccessToken != "broad-token" {
+		t.Errorf("down-scoped token must not replace the main token, got %q", p.tokens.AccessToken)
+	}
+
+	if _, err := p.GetWithScopes(context.Background(), []string{"admin"}); err == nil {
+		t.Error("expected error for scope outside configured set")
+	}
+}
+
+func TestGetWithScopesPersistsRotatedRefreshToken(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"scoped-token","refresh_token":"rotated","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{
+		clientID:      "my-client",
+		tokenEndpoint: server.URL,
+		scopes:        []string{"read", "write"},
+		flow:          FlowDevice,
+	}
+	p.SetTokens("broad-token", "original", 3600)
+
+	if _, err := p.GetWithScopes(context.Background(), []string{"read"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.tokens.RefreshToken != "rotated" || p.tokens.AccessToken != "broad-token" {
+		t.Errorf("expected the rotated refresh token with the main access token, got %+v", p.tokens)
+	}
+	shared := common.LoadSharedTokens(p.SharedCacheKey())
+	if shared == nil || shared.RefreshToken != "rotated" {
+		t.Errorf("expected the rotated refresh token in the shared cache, got %+v", shared)
+	}
+}
+
+func TestGetWithProfile(t *testing.T) {
+	requests := 0
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = r.ParseForm()
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, requests)
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	err := p.Init(map[string]any{
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataClientSecret:  "secret",
+		provider.MetadataTokenEndpoint: server.URL,
+		provider.MetadataScopes:        []string{"read"},
+		provider.MetadataProfiles: map[string]string{
+			"admin": "read admin:write audience=https://admin.api",
+			"plain": "",
+		},
+		"flow": FlowClientCredentials,
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	if got := p.Profiles(); !slices.Equal(got, []string{"admin", "plain"}) {
+		t.Errorf("Profiles() = %v", got)
+	}
+
+	ctx := context.Background()
+	admin, err := p.GetWithProfile(ctx, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := form.Get("scope"); got != "read admin:write" {
+		t.Errorf("expected admin scopes in token request, got %q", got)
+	}
+	if got := form.Get("audience"); got != "https://admin.api" {
+		t.Errorf("expected admin audience in token request, got %q", got)
+	}
+
+	plain, err := p.GetWithProfile(ctx, "plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := form.Get("scope"); got != "read" {
+		t.Errorf("a profile without scopes should use the provider's scopes, got %q", got)
+	}
+	if form.Has("audience") {
+		t.Errorf("unexpected audience %q for a profile without one", form.Get("audience"))
+	}
+	if string(admin) == string(plain) {
+		t.Errorf("profiles must not share a token, both got %q", admin)
+	}
+
+	// Each profile is served from its own cache entry
+	again, err := p.GetWithProfile(ctx, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(again) != string(admin) || requests != 2 {
+		t.Errorf("expected cached admin token %q without a new request, got %q after %d requests", admin, again, requests)
+	}
+	if p.tokens != nil {
+		t.Errorf("profile tokens must not replace the main tokens, got %+v", p.tokens)
+	}
+
+	creds, err := p.GetCredentialsWithProfile(ctx, "plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.Fields["access_token"] != string(plain) {
+		t.Errorf("expected plain profile credentials, got %v", creds.Fields)
+	}
+
+	p.InvalidateCache()
+	if _, err := p.GetWithProfile(ctx, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected a new request after invalidating the cache, got %d requests", requests)
+	}
+
+	if _, err := p.GetWithProfile(ctx, "missing"); err == nil {
+		t.Error("expected error for an undefined profile")
+	}
+
+	got, _ := p.Metadata()[provider.MetadataProfiles].(map[string]string)
+	if !maps.Equal(got, map[string]string{
+		"admin": "read admin:write audience=https://admin.api",
+		"plain": "",
+	}) {
+		t.Errorf("profiles not persisted in metadata, got %v", got)
+	}
+}
+
+// TestConcurrentTokenAccess runs the token cache paths concurrently, as the
+// daemon does for simultaneous requests (run with -race)
+func TestConcurrentTokenAccess(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	err := p.Init(map[string]any{
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataClientSecret:  "secret",
+		provider.MetadataTokenEndpoint: server.URL,
+		provider.MetadataScopes:        []string{"read", "write"},
+		provider.MetadataProfiles:      map[string]string{"admin": "read audience=https://admin.api"},
+		"flow":                         FlowClientCredentials,
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			switch i % 5 {
+			case 0:
+				_, err = p.GetWithProfile(ctx, "admin")
+			case 1:
+				_, err = p.GetWithScopes(ctx, []string{"read"})
+			case 2:
+				_, err = p.Get(ctx)
+			case 3:
+				p.InvalidateCache()
+			case 4:
+				p.SetTokens("set", "refresh", 3600)
+				_, _, _ = p.GetTokens()
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseProfilesRejectsInvalidNames(t *testing.T) {
+	for _, name := range []string{"a@b", "two words"} {
+		if _, err := parseProfiles(map[string]string{name: "read"}); err == nil {
+			t.Errorf("expected error for profile name %q", name)
+		}
+	}
+}
+
+func TestInitRejectsReservedParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		field       string
+		params      map[string]string
+		shouldError bool
+	}{
+		{name: "auth state", field: provider.MetadataAuthParams, params: map[string]string{"state": "fixed"}, shouldError: true},
+		{name: "auth redirect_uri", field: provider.MetadataAuthParams, params: map[string]string{"redirect_uri": "https://evil.example"}, shouldError: true},
+		{name: "auth scope", field: provider.MetadataAuthParams, params: map[string]string{"scope": "admin"}, shouldError: true},
+		{name: "auth vendor param", field: provider.MetadataAuthParams, params: map[string]string{"prompt": "consent"}},
+		{name: "token grant_type", field: provider.MetadataTokenParams, params: map[string]string{"grant_type": "password"}, shouldError: true},
+		{name: "token code_verifier", field: provider.MetadataTokenParams, params: map[string]string{"code_verifier": "x"}, shouldError: true},
+		{name: "token refresh_token", field: provider.MetadataTokenParams, params: map[string]string{"refresh_token": "x"}, shouldError: true},
+		{name: "token vendor param", field: provider.MetadataTokenParams, params: map[string]string{"resource": "https://api.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{}
+			err := p.Init(map[string]any{
+				"flow":                         FlowAuthCode,
+				provider.MetadataClientID:      "my-client",
+				provider.MetadataAuthEndpoint:  "https://idp.example.com/authorize",
+				provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+				tt.field:                       tt.params,
+			})
+			if tt.shouldError && err == nil {
+				t.Errorf("expected error for %s %v", tt.field, tt.params)
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientSecretFromEnvironment(t *testing.T) {
+	t.Setenv("CREDCTL_TEST_CLIENT_SECRET", "s3cret")
+
+	config := map[string]any{
+		"flow":                         FlowClientCredentials,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataClientSecret:  "env:CREDCTL_TEST_CLIENT_SECRET",
+		provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+	}
+
+	p := &Provider{}
+	if err := p.Init(config); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if p.clientSecret != "s3cret" {
+		t.Errorf("expected the secret from the environment, got %q", p.clientSecret)
+	}
+	if got := p.Metadata()[provider.MetadataClientSecret]; got != "env:CREDCTL_TEST_CLIENT_SECRET" {
+		t.Errorf("metadata must keep the reference, got %v", got)
+	}
+
+	config[provider.MetadataClientSecret] = "env:CREDCTL_TEST_UNSET_SECRET"
+	err := (&Provider{}).Init(config)
+	if err == nil || !strings.Contains(err.Error(), "CREDCTL_TEST_UNSET_SECRET") {
+		t.Errorf("expected an error naming the unset variable, got %v", err)
+	}
+}
+
+func TestVerifyClientSecret(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, secret, _ := r.BasicAuth(); secret != "good" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"fresh-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		flow      string
+		secret    string
+		refresh   string
+		wantErr   string
+		wantToken string
+	}{
+		{name: "client credentials accepted", flow: FlowClientCredentials, secret: "good", wantToken: "fresh-token"},
+		{name: "client credentials rejected", flow: FlowClientCredentials, secret: "bad", wantErr: "invalid_client", wantToken: "cached-token"},
+		{name: "refresh accepted", flow: FlowDevice, secret: "good", refresh: "rt", wantToken: "fresh-token"},
+		{name: "refresh rejected", flow: FlowDevice, secret: "bad", refresh: "rt", wantErr: "invalid_client", wantToken: "cached-token"},
+		{name: "no refresh token", flow: FlowDevice, secret: "good", wantErr: "no refresh token", wantToken: "cached-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{
+				clientID:      "my-client",
+				clientSecret:  tt.secret,
+				tokenEndpoint: server.URL,
+				flow:          tt.flow,
+				tokens: &common.TokenCache{
+					AccessToken:  "cached-token",
+					RefreshToken: tt.refresh,
+					ExpiresAt:    time.Now().Add(time.Hour),
+				},
+			}
+
+			err := p.VerifyClientSecret(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("VerifyClientSecret() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("VerifyClientSecret() unexpected error: %v", err)
+			}
+			if p.tokens.AccessToken != tt.wantToken {
+				t.Errorf("access token = %q, want %q", p.tokens.AccessToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestGetSharesTokensAcrossInstances(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"shared-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	newProvider := func() *Provider {
+		return &Provider{
+			clientID:      "my-client",
+			clientSecret:  "secret",
+			tokenEndpoint: server.URL,
+			scopes:        []string{"read"},
+			flow:          FlowClientCredentials,
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := newProvider().Get(context.Background())
+		if err != nil {
+			t.Fatalf("Get() error: %v", err)
+		}
+		if string(token) != "shared-token" {
+			t.Errorf("Get() = %q, want %q", token, "shared-token")
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("expected 1 token request, got %d", requests)
+	}
+}
+
+func TestInvalidateCacheForcesFreshToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		flow         string
+		refreshToken string
+		grantType    string
+	}{
+		{
+			name:         "refresh token is kept and used",
+			flow:         FlowAuthCode,
+			refreshToken: "refresh",
+			grantType:    "refresh_token",
+		},
+		{
+			name:      "client credentials re-run the grant",
+			flow:      FlowClientCredentials,
+			grantType: "client_credentials",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+			var grantType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				grantType = r.PostForm.Get("grant_type")
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"access_token":"fresh-token","token_type":"Bearer","expires_in":3600}`))
+			}))
+			defer server.Close()
+
+			p := &Provider{
+				clientID:      "my-client",
+				clientSecret:  "secret",
+				tokenEndpoint: server.URL,
+				flow:          tt.flow,
+			}
+			p.storeTokens(&common.TokenCache{
+				AccessToken:  "revoked-token",
+				RefreshToken: tt.refreshToken,
+				ExpiresAt:    time.Now().Add(time.Hour),
+			})
+
+			p.InvalidateCache()
+
+			token, err := p.Get(context.Background())
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if string(token) != "fresh-token" {
+				t.Errorf("Get() = %q, want %q", token, "fresh-token")
+			}
+			if grantType != tt.grantType {
+				t.Errorf("grant_type = %q, want %q", grantType, tt.grantType)
+			}
+			if shared := common.LoadSharedTokens(p.SharedCacheKey()); shared == nil || shared.AccessToken != "fresh-token" {
+				t.Errorf("expected fresh token in shared cache, got %+v", shared)
+			}
+		})
+	}
+}
+
+func TestPasswordFlow(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("grant_type") != "password" || r.PostForm.Get("username") != "alice" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"password-token","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		"flow":                         FlowPassword,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataTokenEndpoint: server.URL,
+	}); err == nil {
+		t.Error("expected error when username and password are missing")
+	}
+
+	if err := p.Init(map[string]any{
+		"flow":                         FlowPassword,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataTokenEndpoint: server.URL,
+		provider.MetadataUsername:      "alice",
+		provider.MetadataPassword:      "hunter2",
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	token, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(token) != "password-token" {
+		t.Errorf("Get() = %q, want %q", token, "password-token")
+	}
+	if p.tokens.RefreshToken != "refresh" {
+		t.Errorf("expected refresh token to be kept, got %q", p.tokens.RefreshToken)
+	}
+
+	if err := p.Login(context.Background()); err == nil {
+		t.Error("expected Login() to be unsupported for the password flow")
+	}
+}
+
+// actorProvider returns a fixed actor token
+type actorProvider struct{ token string }
+
+func (p *actorProvider) Type() string                            { return "static" }
+func (p *actorProvider) Schema() provider.Schema                 { return provider.Schema{} }
+func (p *actorProvider) Init(config map[string]any) error        { return nil }
+func (p *actorProvider) Metadata() map[string]any                { return map[string]any{} }
+func (p *actorProvider) Get(ctx context.Context) ([]byte, error) { return []byte(p.token + "\n"), nil }
+
+func TestClientCredentialsDelegation(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		expected := map[string]string{
+			"grant_type":        common.GrantTypeTokenExchange,
+			"requested_subject": "alice",
+			"actor_token":       "admin-token",
+			"actor_token_type":  common.TokenTypeAccessToken,
+		}
+		for key, want := range expected {
+			if got := r.PostForm.Get(key); got != want {
+				t.Errorf("param %s = %q, want %q", key, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"alice-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	provider.SetLookup(func(name string) (provider.Provider, error) {
+		if name != "admin" {
+			return nil, fmt.Errorf("provider not found: %s", name)
+		}
+		return &actorProvider{token: "admin-token"}, nil
+	})
+	t.Cleanup(func() { provider.SetLookup(provider.Load) })
+
+	config := map[string]any{
+		"flow":                            FlowClientCredentials,
+		provider.MetadataClientID:         "admin-tool",
+		provider.MetadataClientSecret:     "s3cret",
+		provider.MetadataTokenEndpoint:    server.URL,
+		provider.MetadataSubject:          "alice",
+		provider.MetadataActorTokenSource: "admin",
+	}
+	p := &Provider{}
+	if err := p.Init(config); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	token, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(token) != "alice-token" {
+		t.Errorf("Get() = %q, want %q", token, "alice-token")
+	}
+
+	// The client's own tokens and the subject's must not share a cache entry
+	own := &Provider{}
+	if err := own.Init(map[string]any{
+		"flow":                         FlowClientCredentials,
+		provider.MetadataClientID:      "admin-tool",
+		provider.MetadataClientSecret:  "s3cret",
+		provider.MetadataTokenEndpoint: server.URL,
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	if own.SharedCacheKey() == p.SharedCacheKey() {
+		t.Error("expected delegated tokens to use their own shared cache key")
+	}
+
+	metadata := p.Metadata()
+	if metadata[provider.MetadataSubject] != "alice" || metadata[provider.MetadataActorTokenSource] != "admin" {
+		t.Errorf("expected subject and actor_token_source in metadata, got %v", metadata)
+	}
+	if _, ok := metadata[provider.MetadataActorTokenType]; ok {
+		t.Error("expected default actor_token_type to be omitted from metadata")
+	}
+
+	// Delegation only applies to the client credentials grant
+	if err := (&Provider{}).Init(map[string]any{
+		"flow":                         FlowPassword,
+		provider.MetadataClientID:      "admin-tool",
+		provider.MetadataTokenEndpoint: server.URL,
+		provider.MetadataUsername:      "bob",
+		provider.MetadataPassword:      "hunter2",
+		provider.MetadataSubject:       "alice",
+	}); err == nil {
+		t.Error("expected error for subject with the password flow")
+	}
+}
+
+func TestTLSClientAuthConfig(t *testing.T) {
+	base := map[string]any{
+		"flow":                         FlowClientCredentials,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+	}
+	with := func(extra map[string]any) map[string]any {
+		config := map[string]any{}
+		for k, v := range base {
+			config[k] = v
+		}
+		for k, v := range extra {
+			config[k] = v
+		}
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{
+			name:   "client credentials without secret or certificate",
+			config: base,
+		},
+		{
+			name:   "tls_client_auth without certificate",
+			config: with(map[string]any{provider.MetadataClientAuthMethod: ClientAuthTLS}),
+		},
+		{
+			name: "tls_client_auth with a client secret",
+			config: with(map[string]any{
+				provider.MetadataClientAuthMethod: ClientAuthTLS,
+				provider.MetadataClientSecret:     "secret",
+				provider.MetadataClientCert:       "client.crt",
+				provider.MetadataClientKey:        "client.key",
+			}),
+		},
+		{
+			name: "unreadable certificate",
+			config: with(map[string]any{
+				provider.MetadataClientAuthMethod: ClientAuthTLS,
+				provider.MetadataClientCert:       "/nonexistent/client.crt",
+				provider.MetadataClientKey:        "/nonexistent/client.key",
+			}),
+		},
+		{
+			name:   "unknown method",
+			config: with(map[string]any{provider.MetadataClientAuthMethod: "private_key_jwt"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{}
+			if err := p.Init(tt.config); err == nil {
+				t.Error("expected Init() error")
+			}
+		})
+	}
+}
+
+func TestHTTPProxyIsUsed(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	// Stub forward proxy: answers token requests itself and records what it saw
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.URL.Host
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"proxied-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer proxy.Close()
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		"flow":                         FlowClientCredentials,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataClientSecret:  "secret",
+		provider.MetadataTokenEndpoint: "http://idp.invalid/token",
+		provider.MetadataHTTPProxy:     proxy.URL,
+		// A stub forward proxy can't intercept https without CONNECT
+		provider.MetadataAllowInsecureEndpoints: true,
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	token, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(token) != "proxied-token" {
+		t.Errorf("Get() = %q, want %q", token, "proxied-token")
+	}
+	if proxiedHost != "idp.invalid" {
+		t.Errorf("expected the token request to go through the proxy, proxy saw host %q", proxiedHost)
+	}
+
+	if got := p.Metadata()[provider.MetadataHTTPProxy]; got != proxy.URL {
+		t.Errorf("expected http_proxy to be persisted, got %v", got)
+	}
+}
+
+func TestNonStandardTokenFields(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jwt":"homegrown-token","lifetime":900}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		"flow":                             FlowClientCredentials,
+		provider.MetadataClientID:          "my-client",
+		provider.MetadataClientSecret:      "secret",
+		provider.MetadataTokenEndpoint:     server.URL + "/token",
+		provider.MetadataAccessTokenField:  "jwt",
+		provider.MetadataExpiresInField:    "lifetime",
+		provider.MetadataRefreshTokenField: "refresh",
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	token, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(token) != "homegrown-token" {
+		t.Errorf("Get() = %q, want %q", token, "homegrown-token")
+	}
+
+	if _, _, expiresIn := p.GetTokens(); expiresIn < 800 || expiresIn > 900 {
+		t.Errorf("expected expires_in from the lifetime field, got %d", expiresIn)
+	}
+
+	metadata := p.Metadata()
+	if metadata[provider.MetadataAccessTokenField] != "jwt" || metadata[provider.MetadataExpiresInField] != "lifetime" {
+		t.Errorf("expected field mapping to be persisted, got %v", metadata)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	body := `{"access_token":"abc","token_type":"Bearer","expires_in":3600,"tenant":{"id":"t-42"},"session_state":"xyz"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		"flow":                         FlowClientCredentials,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataClientSecret:  "secret",
+		provider.MetadataTokenEndpoint: server.URL + "/token",
+		provider.MetadataRawResponse:   true,
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	raw, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(raw) != body {
+		t.Errorf("Get() = %s, want the token endpoint response %s", raw, body)
+	}
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if got := creds.Fields["_raw"]; got != body {
+		t.Errorf("_raw = %q, want %q", got, body)
+	}
+	if got := creds.Fields["access_token"]; got != "abc" {
+		t.Errorf("access_token = %q, want %q", got, "abc")
+	}
+
+	// Another instance picks the response up from the shared cache
+	other := &Provider{}
+	if err := other.Init(p.Metadata()); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	raw, err = other.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(raw) != body {
+		t.Errorf("Get() from the shared cache = %s, want %s", raw, body)
+	}
+}
+
+func TestDefaultScopesPerFlow(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"issuer": "` + server.URL + `",
+			"authorization_endpoint": "` + server.URL + `/authorize",
+			"token_endpoint": "` + server.URL + `/token",
+			"device_authorization_endpoint": "` + server.URL + `/device"
+		}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		flow   string
+		issuer string
+		scopes []string
+		want   []string
+	}{
+		{name: "device with OIDC", flow: FlowDevice, issuer: server.URL, want: []string{"openid"}},
+		{name: "auth-code with OIDC", flow: FlowAuthCode, issuer: server.URL, want: []string{"openid"}},
+		{name: "password with OIDC", flow: FlowPassword, issuer: server.URL, want: []string{"openid"}},
+		{name: "client-credentials with OIDC", flow: FlowClientCredentials, issuer: server.URL, want: nil},
+		{name: "client-credentials with explicit openid", flow: FlowClientCredentials, issuer: server.URL, scopes: []string{"openid", "api"}, want: []string{"openid", "api"}},
+		{name: "auth-code without OIDC", flow: FlowAuthCode, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]any{
+				"flow":                        tt.flow,
+				provider.MetadataClientID:     "my-client",
+				provider.MetadataClientSecret: "secret",
+				provider.MetadataUsername:     "user",
+				provider.MetadataPassword:     "pass",
+			}
+			if tt.issuer != "" {
+				config[provider.MetadataIssuer] = tt.issuer
+			} else {
+				config[provider.MetadataTokenEndpoint] = server.URL + "/token"
+				config[provider.MetadataAuthEndpoint] = server.URL + "/authorize"
+			}
+			if tt.scopes != nil {
+				config[provider.MetadataScopes] = tt.scopes
+			}
+
+			p := &Provider{}
+			if err := p.Init(config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+			if !slices.Equal(p.scopes, tt.want) {
+				t.Errorf("scopes = %v, want %v", p.scopes, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepUpLoginAuthParams(t *testing.T) {
+	var deviceForm url.Values
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/device" {
+			// Record the device authorization request, then stop the login there
+			_ = r.ParseForm()
+			deviceForm = r.PostForm
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"issuer": "` + server.URL + `",
+			"token_endpoint": "` + server.URL + `/token",
+			"device_authorization_endpoint": "` + server.URL + `/device"
+		}`))
+	}))
+	defer server.Close()
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		"flow":                      FlowDevice,
+		provider.MetadataIssuer:     server.URL,
+		provider.MetadataClientID:   "my-client",
+		provider.MetadataACRValues:  []string{"urn:mfa", "urn:hwk"},
+		provider.MetadataAuthParams: map[string]string{"login_hint": "alice"},
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		login  func(context.Context) error
+		prompt string
+	}{
+		{name: "login", login: p.Login, prompt: ""},
+		{name: "step-up login", login: p.StepUpLogin, prompt: "login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deviceForm = nil
+			if err := tt.login(context.Background()); err == nil {
+				t.Fatal("expected the stub device endpoint to fail the login")
+			}
+			if deviceForm == nil {
+				t.Fatal("device authorization request was not sent")
+			}
+			if got := deviceForm.Get("acr_values"); got != "urn:mfa urn:hwk" {
+				t.Errorf("acr_values = %q, want %q", got, "urn:mfa urn:hwk")
+			}
+			if got := deviceForm.Get("login_hint"); got != "alice" {
+				t.Errorf("login_hint = %q, want %q", got, "alice")
+			}
+			if got := deviceForm.Get("prompt"); got != tt.prompt {
+				t.Errorf("prompt = %q, want %q", got, tt.prompt)
+			}
+		})
+	}
+
+	// The step-up only applies to the login that asked for it
+	if p.authRequestParams(false)["prompt"] != "" {
+		t.Error("expected prompt=login to be dropped after the step-up login")
+	}
+	if p.authParams["acr_values"] != "" {
+		t.Error("expected the configured auth_params to be left untouched")
+	}
+}
+
+func TestACRValuesRequireOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{
+			name: "without issuer",
+			config: map[string]any{
+				"flow":                         FlowAuthCode,
+				provider.MetadataAuthEndpoint:  "https://idp.example.com/authorize",
+				provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+			},
+		},
+		{
+			name: "client-credentials flow",
+			config: map[string]any{
+				"flow":                         FlowClientCredentials,
+				provider.MetadataClientSecret:  "secret",
+				provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config[provider.MetadataClientID] = "my-client"
+			tt.config[provider.MetadataACRValues] = []string{"urn:mfa"}
+
+			if err := (&Provider{}).Init(tt.config); err == nil || !strings.Contains(err.Error(), "acr_values") {
+				t.Errorf("expected an acr_values error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		flow            string
+		config          map[string]any
+		wantInteractive bool
+	}{
+		{flow: FlowClientCredentials, config: map[string]any{provider.MetadataClientSecret: "secret"}},
+		{flow: FlowPassword, config: map[string]any{provider.MetadataUsername: "alice", provider.MetadataPassword: "hunter2"}},
+		{flow: FlowDevice, config: map[string]any{provider.MetadataDeviceEndpoint: "https://idp.example.com/device"}, wantInteractive: true},
+		{flow: FlowAuthCode, config: map[string]any{provider.MetadataAuthEndpoint: "https://idp.example.com/authorize"}, wantInteractive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flow, func(t *testing.T) {
+			config := map[string]any{
+				"flow":                         tt.flow,
+				provider.MetadataClientID:      "my-client",
+				provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+			}
+			maps.Copy(config, tt.config)
+
+			p := &Provider{}
+			if err := p.Init(config); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			want := provider.Capabilities{NeedsNetwork: true, SupportsRefresh: true, Interactive: tt.wantInteractive, Structured: true}
+			if got := provider.CapabilitiesOf(p); got != want {
+				t.Errorf("CapabilitiesOf() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGetAuthCodeRequiresLogin(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	p := &Provider{}
+	err := p.Init(map[string]any{
+		"flow":                         FlowAuthCode,
+		provider.MetadataClientID:      "my-client",
+		provider.MetadataAuthEndpoint:  "https://idp.example.com/authorize",
+		provider.MetadataTokenEndpoint: "https://idp.example.com/token",
+	})
+	if err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	if _, err := p.Get(context.Background()); !errors.Is(err, provider.ErrAuthenticationRequired) {
+		t.Fatalf("Get() error = %v, want ErrAuthenticationRequired", err)
+	}
+
+	// Tokens from credctl login are served without another login
+	p.SetTokens("at", "", 3600)
+	output, err := p.Get(context.Background())
+	if err != nil || string(output) != "at" {
+		t.Errorf("Get() = %q, %v, want the stored access token", output, err)
+	}
+}
+
+func TestInitRequiresHTTPSEndpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		shouldError bool
+	}{
+		{
+			name:   "https",
+			config: map[string]any{provider.MetadataTokenEndpoint: "https://idp.example.com/token"},
+		},
+		{
+			name:   "http localhost",
+			config: map[string]any{provider.MetadataTokenEndpoint: "http://127.0.0.1:9999/token"},
+		},
+		{
+			name:        "http remote",
+			config:      map[string]any{provider.MetadataTokenEndpoint: "http://idp.example.com/token"},
+			shouldError: true,
+		},
+		{
+			name: "http remote allowed",
+			config: map[string]any{
+				provider.MetadataTokenEndpoint:          "http://idp.example.com/token",
+				provider.MetadataAllowInsecureEndpoints: true,
+			},
+		},
+		{
+			name:        "http issuer",
+			config:      map[string]any{provider.MetadataIssuer: "http://idp.example.com"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]any{
+				"flow":                        FlowClientCredentials,
+				provider.MetadataClientID:     "my-client",
+				provider.MetadataClientSecret: "secret",
+			}
+			maps.Copy(config, tt.config)
+
+			err := (&Provider{}).Init(config)
+			if tt.shouldError && err == nil {
+				t.Error("expected Init() error")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected Init() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTokensMatchConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *Provider
+		tokens   *common.TokenCache
+		expected bool
+	}{
+		{
+			name:     "opaque tokens",
+			provider: &Provider{issuer: "https://idp.example.com", clientID: "my-client"},
+			tokens:   &common.TokenCache{AccessToken: "opaque", IDToken: "opaque"},
+			expected: true,
+		},
+		{
+			name:     "matching id token",
+			provider: &Provider{issuer: "https://idp.example.com/", clientID: "my-client"},
+			tokens:   &common.TokenCache{IDToken: testIDToken(map[string]any{"iss": "https://idp.example.com", "aud": []any{"other", "my-client"}})},
+			expected: true,
+		},
+		{
+			name:     "id token from another issuer",
+			provider: &Provider{issuer: "https://new-idp.example.com", clientID: "my-client"},
+			tokens:   &common.TokenCache{IDToken: testIDToken(map[string]any{"iss": "https://idp.example.com", "aud": "my-client"})},
+			expected: false,
+		},
+		{
+			name:     "id token for another client",
+			provider: &Provider{issuer: "https://idp.example.com", clientID: "new-client"},
+			tokens:   &common.TokenCache{IDToken: testIDToken(map[string]any{"iss": "https://idp.example.com", "aud": "my-client"})},
+			expected: false,
+		},
+		{
+			name:     "id token for another client with the client check skipped",
+			provider: &Provider{issuer: "https://idp.example.com", clientID: "new-client", skipClientIDCheck: true},
+			tokens:   &common.TokenCache{IDToken: testIDToken(map[string]any{"iss": "https://idp.example.com", "aud": "broker"})},
+			expected: true,
+		},
+		{
+			name:     "id token under the issuer reported by discovery",
+			provider: &Provider{issuer: "https://idp.example.com", tokenIssuer: "https://tenant.idp.example.com", clientID: "my-client"},
+			tokens:   &common.TokenCache{IDToken: testIDToken(map[string]any{"iss": "https://tenant.idp.example.com", "aud": "my-client"})},
+			expected: true,
+		},
+		{
+			name:     "access token issuer is not compared",
+			provider: &Provider{issuer: "https://login.example.com/v2.0", clientID: "my-client"},
+			tokens:   &common.TokenCache{AccessToken: testIDToken(map[string]any{"iss": "https://sts.example.com/"})},
+			expected: true,
+		},
+		{
+			name:     "access token for another client",
+			provider: &Provider{clientID: "new-client"},
+			tokens:   &common.TokenCache{AccessToken: testIDToken(map[string]any{"client_id": "my-client"})},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.provider.tokensMatchConfig(tt.tokens); got != tt.expected {
+				t.Errorf("tokensMatchConfig() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetDiscardsTokensOfAnotherClient(t *testing.T) {
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"fresh-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	// A token still valid for an hour, issued before client_id was changed
+	stale := testIDToken(map[string]any{"client_id": "old-client"})
+	p := &Provider{
+		clientID:      "new-client",
+		clientSecret:  "secret",
+		tokenEndpoint: server.URL,
+		flow:          FlowClientCredentials,
+		tokens: &common.TokenCache{
+			AccessToken:  stale,
+			RefreshToken: "old-refresh-token",
+			ExpiresAt:    time.Now().Add(time.Hour),
+		},
+	}
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if got := creds.Get("access_token"); got != "fresh-token" {
+		t.Errorf("expected the stale token to be replaced, got %q", got)
+	}
+	if p.tokens.RefreshToken == "old-refresh-token" {
+		t.Error("expected the stale refresh token to be dropped")
+	}
+}
+
+func TestDeviceEndpointFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		advertised    bool // Discovery lists device_authorization_endpoint
+		probe         bool
+		serveFallback bool // The conventional devicecode path exists
+		want          string
+		wantProbes    int
+		wantErr       bool
+	}{
+		{name: "discovery present", advertised: true, probe: true, serveFallback: true, want: "/device"},
+		{name: "discovery absent with fallback", probe: true, serveFallback: true, want: "/oauth2/v2.0/devicecode", wantProbes: 1},
+		{name: "discovery absent without probing", serveFallback: true, wantErr: true},
+		{name: "discovery absent and no fallback", probe: true, wantErr: true, wantProbes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			probes := 0
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/.well-known/openid-configuration":
+					doc := map[string]string{
+						"issuer":         server.URL,
+						"token_endpoint": server.URL + "/oauth2/v2.0/token",
+					}
+					if tt.advertised {
+						doc["device_authorization_endpoint"] = server.URL + "/device"
+					}
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(doc)
+				case r.Method == http.MethodHead:
+					probes++
+					if tt.serveFallback && r.URL.Path == "/oauth2/v2.0/devicecode" {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			p := &Provider{}
+			err := p.Init(map[string]any{
+				"flow":                               FlowDevice,
+				provider.MetadataClientID:            "my-client",
+				provider.MetadataIssuer:              server.URL,
+				provider.MetadataProbeDeviceEndpoint: tt.probe,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Init() succeeded with device endpoint %q, want an error", p.deviceEndpoint)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Init() error: %v", err)
+				}
+				if p.deviceEndpoint != server.URL+tt.want {
+					t.Errorf("device endpoint = %q, want %q", p.deviceEndpoint, server.URL+tt.want)
+				}
+			}
+			if probes != tt.wantProbes {
+				t.Errorf("probes = %d, want %d", probes, tt.wantProbes)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"credctl/internal/clock"
 	"credctl/internal/credentials"
 	"credctl/internal/provider"
 	"credctl/internal/provider/oauth2/common"
@@ -14,12 +15,18 @@ import (
 // Provider implements a provider for OAuth2 transparent proxies
 // These proxies handle the OAuth flow internally and return tokens in the callback URL
 type Provider struct {
-	authURL      string // Full URL of the proxy (including callback_url parameter)
-	tokenField   string // Which token to return: "token", "access_token", or "both"
-	redirectPort int    // Local port for callback server
-	template     string // Optional Go template for formatting output
-	format       string // Default output format
-	output       string // Default output file path
+	authURL        string // Full URL of the proxy (including callback_url parameter)
+	tokenField     string // Which token to return: "token", "access_token", or "both"
+	redirectPort   int    // Local port for callback server
+	callbackHost   string // Interface the callback server binds to (default 127.0.0.1)
+	noBrowser      bool   // Print the authentication URL instead of opening a browser
+	browserCommand string // Command that opens the authentication URL (default: system browser)
+
+	expiryBuffer time.Duration // Renew cached tokens this long before they expire
+
+	// Output defaults (template, format, output file)
+	outputOpts provider.OutputOptions
+	daemonOpts provider.DaemonOptions
 
 	tokens *common.TokenCache // Cached tokens
 }
@@ -57,6 +64,31 @@ func (p *Provider) Schema() provider.Schema {
 				Default:  "8085",
 				Help:     "Local port for OAuth callback server",
 			},
+			{
+				Name:     provider.MetadataCallbackHost,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Interface for the local callback server, e.g. ::1 (default: 127.0.0.1)",
+			},
+			{
+				Name:     provider.MetadataNoBrowser,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Print the authentication URL instead of opening a browser (for headless/SSH sessions)",
+			},
+			{
+				Name:     provider.MetadataBrowserCommand,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Command that opens the authentication URL, e.g. wslview (default: ${CREDCTL_BROWSER} or the system browser)",
+			},
+			{
+				Name:     provider.MetadataExpiryBuffer,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "30",
+				Help:     "Seconds before expiry at which a cached token is renewed (raise for high latency or clock skew)",
+			},
 		},
 	}
 }
@@ -65,15 +97,34 @@ func (p *Provider) Init(config map[string]any) error {
 	p.authURL = provider.GetStringOrDefault(config, "auth_url", "")
 	p.tokenField = provider.GetStringOrDefault(config, "token_field", "token")
 	p.redirectPort = provider.GetIntOrDefault(config, provider.MetadataRedirectPort, 8085)
-	p.template = provider.GetStringOrDefault(config, provider.MetadataTemplate, "")
-	p.format = provider.GetStringOrDefault(config, provider.MetadataFormat, "text")
-	p.output = provider.GetStringOrDefault(config, provider.MetadataOutput, "")
+	p.callbackHost = provider.GetStringOrDefault(config, provider.MetadataCallbackHost, "")
+	p.noBrowser = provider.GetBoolOrDefault(config, provider.MetadataNoBrowser, false)
+	p.browserCommand = provider.GetStringOrDefault(config, provider.MetadataBrowserCommand, "")
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+
+	p.expiryBuffer, err = common.GetExpiryBuffer(config)
+	if err != nil {
+		return err
+	}
 
 	// Validate required fields
 	if p.authURL == "" {
 		return fmt.Errorf("auth_url is required")
 	}
 
+	if err := common.ValidateCallbackHost(p.callbackHost); err != nil {
+		return err
+	}
+
 	// Validate token_field
 	switch p.tokenField {
 	case "token", "access_token", "both":
@@ -86,8 +137,10 @@ func (p *Provider) Init(config map[string]any) error {
 }
 
 func (p *Provider) Get(ctx context.Context) ([]byte, error) {
+	p.loadSharedTokens()
+
 	// Check if we have valid cached tokens
-	if !common.IsTokenValid(p.tokens) {
+	if !common.IsTokenValid(p.tokens, p.expiryBuffer) {
 		// No valid token, perform authentication flow
 		if err := p.doProxyAuthFlow(ctx); err != nil {
 			return nil, err
@@ -100,8 +153,10 @@ func (p *Provider) Get(ctx context.Context) ([]byte, error) {
 // GetCredentials returns the credentials in a structured format
 // This implements the CredentialsProvider interface
 func (p *Provider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	p.loadSharedTokens()
+
 	// Check if we have valid cached tokens
-	if !common.IsTokenValid(p.tokens) {
+	if !common.IsTokenValid(p.tokens, p.expiryBuffer) {
 		// No valid token, perform authentication flow
 		if err := p.doProxyAuthFlow(ctx); err != nil {
 			return nil, err
@@ -119,6 +174,7 @@ func (p *Provider) GetCredentials(ctx context.Context) (*credentials.Credentials
 	}
 	if p.tokens.AccessToken != "" {
 		fields["access_token"] = p.tokens.AccessToken
+		fields["authorization"] = p.tokens.Authorization()
 	}
 
 	return credentials.New(fields), nil
@@ -130,6 +186,16 @@ func (p *Provider) Login(ctx context.Context) error {
 	return p.doProxyAuthFlow(ctx)
 }
 
+// Capabilities reports that tokens are only obtained by a browser login
+// through the proxy; credctl itself makes no outbound calls
+// This implements the CapabilitiesProvider interface
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{
+		Interactive: true,
+		Structured:  true,
+	}
+}
+
 func (p *Provider) Metadata() map[string]any {
 	metadata := map[string]any{
 		"auth_url":    p.authURL,
@@ -139,20 +205,23 @@ func (p *Provider) Metadata() map[string]any {
 	if p.redirectPort != 0 {
 		metadata[provider.MetadataRedirectPort] = p.redirectPort
 	}
-
-	if p.template != "" {
-		metadata[provider.MetadataTemplate] = p.template
+	if p.callbackHost != "" {
+		metadata[provider.MetadataCallbackHost] = p.callbackHost
 	}
-
-	// Preserve format and output from config (set by cmd/add.go global flags)
-	if p.format != "" && p.format != "text" {
-		metadata[provider.MetadataFormat] = p.format
+	if p.noBrowser {
+		metadata[provider.MetadataNoBrowser] = true
 	}
-
-	if p.output != "" {
-		metadata[provider.MetadataOutput] = p.output
+	if p.browserCommand != "" {
+		metadata[provider.MetadataBrowserCommand] = p.browserCommand
+	}
+	if p.expiryBuffer != common.DefaultExpiryBuffer {
+		metadata[provider.MetadataExpiryBuffer] = int(p.expiryBuffer / time.Second)
 	}
 
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+
 	return metadata
 }
 
@@ -160,12 +229,12 @@ func (p *Provider) Metadata() map[string]any {
 // It reuses the callback server infrastructure from oauth2/common
 func (p *Provider) doProxyAuthFlow(ctx context.Context) error {
 	// Open browser with the authentication URL (already includes callback_url)
-	if err := common.OpenBrowser(p.authURL); err != nil {
-		return fmt.Errorf("failed to open browser: %w", err)
+	if err := common.PresentAuthURL(p.authURL, p.noBrowser, p.browserCommand); err != nil {
+		return err
 	}
 
 	// Start callback server and wait for the redirect
-	result, err := common.StartCallbackServer(ctx, p.redirectPort, "/callback")
+	result, err := common.StartCallbackServer(ctx, p.callbackHost, p.redirectPort, "/callback")
 	if err != nil {
 		return err
 	}
@@ -181,15 +250,44 @@ func (p *Provider) doProxyAuthFlow(ctx context.Context) error {
 
 	// Cache the tokens
 	// Since the proxy doesn't provide expires_in, we set a reasonable default (1 hour)
-	p.tokens = &common.TokenCache{
+	p.storeTokens(&common.TokenCache{
 		AccessToken: accessToken,
 		IDToken:     token, // Store token in IDToken field
-		ExpiresAt:   time.Now().Add(1 * time.Hour),
-	}
+		ExpiresAt:   clock.Now().Add(1 * time.Hour),
+	})
 
 	return nil
 }
 
+// SharedCacheKey identifies this provider's tokens in the shared cache
+// This implements the SharedCacheProvider interface
+func (p *Provider) SharedCacheKey() string {
+	return common.SharedCacheKey(p.Type(), p.authURL)
+}
+
+// loadSharedTokens picks up tokens cached by another process if ours are not valid
+func (p *Provider) loadSharedTokens() {
+	if common.IsTokenValid(p.tokens, p.expiryBuffer) {
+		return
+	}
+	if shared := common.LoadSharedTokens(p.SharedCacheKey()); shared != nil {
+		p.tokens = shared
+	}
+}
+
+// InvalidateCache drops cached tokens so the next Get() re-authenticates
+// This implements the CacheInvalidator interface
+func (p *Provider) InvalidateCache() {
+	p.tokens = nil
+	_ = common.RemoveSharedTokens(p.SharedCacheKey())
+}
+
+// storeTokens caches tokens in memory and in the shared cache (best effort)
+func (p *Provider) storeTokens(tokens *common.TokenCache) {
+	p.tokens = tokens
+	_ = common.StoreSharedTokens(p.SharedCacheKey(), tokens)
+}
+
 // formatToken returns the token according to the token_field configuration
 func (p *Provider) formatToken() ([]byte, error) {
 	if p.tokens == nil {
@@ -227,11 +325,11 @@ func (p *Provider) formatToken() ([]byte, error) {
 
 // SetTokens sets the cached tokens (used by daemon for persistence)
 func (p *Provider) SetTokens(accessToken, refreshToken string, expiresIn int) {
-	p.tokens = &common.TokenCache{
+	p.storeTokens(&common.TokenCache{
 		AccessToken: accessToken,
 		IDToken:     refreshToken, // We use RefreshToken field to store the main token
-		ExpiresAt:   time.Now().Add(time.Duration(expiresIn) * time.Second),
-	}
+		ExpiresAt:   clock.Now().Add(time.Duration(expiresIn) * time.Second),
+	})
 }
 
 // GetTokens returns the cached tokens (used by daemon for persistence)
@@ -239,7 +337,7 @@ func (p *Provider) GetTokens() (accessToken, refreshToken string, expiresIn int)
 	if p.tokens == nil {
 		return "", "", 0
 	}
-	remaining := int(time.Until(p.tokens.ExpiresAt).Seconds())
+	remaining := int(clock.Until(p.tokens.ExpiresAt).Seconds())
 	if remaining < 0 {
 		remaining = 0
 	}
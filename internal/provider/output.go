@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+
+	"credctl/internal/credentials"
+)
+
+// OutputOptions are a provider's default output settings, set at add-time via
+// the global --template, --format, --output and --claims-mapping flags and
+// applied by `credctl get` unless overridden on the command line
+type OutputOptions struct {
+	Template      string            // Go template for output formatting
+	Format        string            // Default output format
+	Output        string            // Default output file path
+	ClaimsMapping map[string]string // Template names for credential fields and JWT claims
+}
+
+// LoadOutputOptions reads output settings from provider config
+// The template is parsed up front so mistakes surface when the provider is added.
+func LoadOutputOptions(config map[string]any) (OutputOptions, error) {
+	opts := OutputOptions{
+		Template:      GetStringOrDefault(config, MetadataTemplate, ""),
+		Format:        GetStringOrDefault(config, MetadataFormat, "text"),
+		Output:        GetStringOrDefault(config, MetadataOutput, ""),
+		ClaimsMapping: GetStringMapOrDefault(config, MetadataClaimsMapping, nil),
+	}
+
+	if opts.Template != "" {
+		if err := credentials.ValidateTemplate(opts.Template); err != nil {
+			return OutputOptions{}, fmt.Errorf("invalid %s: %w", MetadataTemplate, err)
+		}
+	}
+
+	return opts, nil
+}
+
+// AddToMetadata stores non-default output settings in provider metadata
+func (o OutputOptions) AddToMetadata(metadata map[string]any) {
+	if o.Template != "" {
+		metadata[MetadataTemplate] = o.Template
+	}
+
+	if o.Format != "" && o.Format != "text" {
+		metadata[MetadataFormat] = o.Format
+	}
+
+	if o.Output != "" {
+		metadata[MetadataOutput] = o.Output
+	}
+
+	if len(o.ClaimsMapping) > 0 {
+		metadata[MetadataClaimsMapping] = o.ClaimsMapping
+	}
+}
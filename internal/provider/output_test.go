@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadOutputOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		want        OutputOptions
+		metadata    map[string]any
+		shouldError bool
+	}{
+		{
+			name:     "defaults",
+			config:   map[string]any{},
+			want:     OutputOptions{Format: "text"},
+			metadata: map[string]any{},
+		},
+		{
+			name: "all settings",
+			config: map[string]any{
+				MetadataTemplate: "export TOKEN={{.token}}",
+				MetadataFormat:   "json",
+				MetadataOutput:   "/tmp/token",
+			},
+			want: OutputOptions{Template: "export TOKEN={{.token}}", Format: "json", Output: "/tmp/token"},
+			metadata: map[string]any{
+				MetadataTemplate: "export TOKEN={{.token}}",
+				MetadataFormat:   "json",
+				MetadataOutput:   "/tmp/token",
+			},
+		},
+		{
+			name: "claims mapping decoded from JSON",
+			config: map[string]any{
+				MetadataClaimsMapping: map[string]any{"user": "token_sub"},
+			},
+			want: OutputOptions{Format: "text", ClaimsMapping: map[string]string{"user": "token_sub"}},
+			metadata: map[string]any{
+				MetadataClaimsMapping: map[string]string{"user": "token_sub"},
+			},
+		},
+		{
+			name:        "invalid template",
+			config:      map[string]any{MetadataTemplate: "{{.token"},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadOutputOptions(tt.config)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadOutputOptions() = %+v, want %+v", got, tt.want)
+			}
+
+			metadata := map[string]any{}
+			got.AddToMetadata(metadata)
+			if len(metadata) != len(tt.metadata) {
+				t.Fatalf("AddToMetadata() = %v, want %v", metadata, tt.metadata)
+			}
+			for k, v := range tt.metadata {
+				if !reflect.DeepEqual(metadata[k], v) {
+					t.Errorf("metadata[%q] = %v, want %v", k, metadata[k], v)
+				}
+			}
+		})
+	}
+}
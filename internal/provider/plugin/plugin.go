@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
+	"credctl/internal/provider"
+)
+
+// ProtocolVersion is the plugin protocol version spoken by credctl.
+// Plugins must echo it back in every response.
+const ProtocolVersion = "1"
+
+// defaultTimeout bounds a single plugin invocation (seconds)
+const defaultTimeout = 30
+
+// Request is the JSON document written to the plugin's stdin
+type Request struct {
+	ProtocolVersion string            `json:"protocol_version"`
+	Action          string            `json:"action"`
+	Config          map[string]string `json:"config"`
+}
+
+// Response is the JSON document a plugin writes to stdout
+type Response struct {
+	ProtocolVersion string            `json:"protocol_version"`
+	Output          string            `json:"output,omitempty"`
+	Fields          map[string]string `json:"fields,omitempty"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"`
+}
+
+// PluginProvider executes an external binary that speaks the plugin protocol
+type PluginProvider struct {
+	pluginPath string
+	config     map[string]string
+	timeout    int
+	outputOpts provider.OutputOptions
+	daemonOpts provider.DaemonOptions
+
+	// cached holds the last response until its expiry
+	cached *Response
+}
+
+func init() {
+	provider.Register("plugin", func() provider.Provider {
+		return &PluginProvider{}
+	})
+}
+
+func (p *PluginProvider) Type() string {
+	return "plugin"
+}
+
+func (p *PluginProvider) Schema() provider.Schema {
+	return provider.Schema{
+		Fields: []provider.FieldDef{
+			{
+				Name:     provider.MetadataPluginPath,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Help:     "Path to the plugin executable",
+			},
+			{
+				Name:     provider.MetadataPluginConfig,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Configuration passed through to the plugin (key=value, repeatable)",
+			},
+			{
+				Name:     provider.MetadataPluginTimeout,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "30",
+				Help:     "Plugin execution timeout in seconds",
+			},
+		},
+	}
+}
+
+// Init initializes the provider with the given configuration
+func (p *PluginProvider) Init(config map[string]any) error {
+	if err := provider.ValidateConfig(config, p.Schema()); err != nil {
+		return err
+	}
+
+	p.pluginPath = provider.GetStringOrDefault(config, provider.MetadataPluginPath, "")
+	if p.pluginPath == "" {
+		return fmt.Errorf("plugin_path is required")
+	}
+	p.config = provider.GetStringMapOrDefault(config, provider.MetadataPluginConfig, nil)
+	p.timeout = provider.GetIntOrDefault(config, provider.MetadataPluginTimeout, defaultTimeout)
+	if p.timeout <= 0 {
+		return fmt.Errorf("plugin_timeout must be positive")
+	}
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+	return nil
+}
+
+// Get retrieves the credential by executing the plugin
+func (p *PluginProvider) Get(ctx context.Context) ([]byte, error) {
+	resp, err := p.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Output != "" {
+		return []byte(resp.Output), nil
+	}
+	if token, ok := resp.Fields["token"]; ok {
+		return []byte(token), nil
+	}
+	if len(resp.Fields) > 1 {
+		// e.g. username and password: no single value to print
+		return nil, provider.ErrCredentialBundle
+	}
+
+	data, err := json.Marshal(resp.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plugin fields: %w", err)
+	}
+	return data, nil
+}
+
+// GetCredentials returns the fields reported by the plugin
+// This implements the CredentialsProvider interface
+func (p *PluginProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	resp, err := p.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(resp.Fields)+2)
+	for k, v := range resp.Fields {
+		fields[k] = v
+	}
+	if resp.Output != "" {
+		fields["output"] = resp.Output
+	}
+	if resp.ExpiresAt != nil {
+		fields["expires_at"] = resp.ExpiresAt.Format(time.RFC3339)
+	}
+
+	return credentials.New(fields), nil
+}
+
+// Capabilities reports what credctl knows of a plugin: it returns fields, and
+// whatever it does to produce them is up to the plugin
+// This implements the CapabilitiesProvider interface
+func (p *PluginProvider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Structured: true}
+}
+
+func (p *PluginProvider) Metadata() map[string]any {
+	metadata := map[string]any{
+		provider.MetadataPluginPath: p.pluginPath,
+	}
+
+	if len(p.config) > 0 {
+		metadata[provider.MetadataPluginConfig] = p.config
+	}
+
+	if p.timeout != 0 && p.timeout != defaultTimeout {
+		metadata[provider.MetadataPluginTimeout] = p.timeout
+	}
+
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+
+	return metadata
+}
+
+// InvalidateCache drops the cached plugin response
+// This implements the CacheInvalidator interface
+func (p *PluginProvider) InvalidateCache() {
+	p.cached = nil
+}
+
+// fetch returns the cached response if it has not expired, otherwise runs the plugin
+func (p *PluginProvider) fetch(ctx context.Context) (*Response, error) {
+	if p.cached != nil && p.cached.ExpiresAt != nil && clock.Now().Before(*p.cached.ExpiresAt) {
+		return p.cached, nil
+	}
+
+	resp, err := p.run(ctx, "get")
+	if err != nil {
+		return nil, err
+	}
+
+	p.cached = nil
+	if resp.ExpiresAt != nil {
+		p.cached = resp
+	}
+	return resp, nil
+}
+
+// run executes the plugin for a single action and decodes its response
+// A nonzero exit status means the plugin needs the user to authenticate.
+func (p *PluginProvider) run(ctx context.Context, action string) (*Response, error) {
+	request, err := json.Marshal(Request{
+		ProtocolVersion: ProtocolVersion,
+		Action:          action,
+		Config:          p.config,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plugin request: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(p.timeout)*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(timeoutCtx, p.pluginPath)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if timeoutCtx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin timed out after %ds: %w", p.timeout, context.DeadlineExceeded)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", provider.ErrAuthenticationRequired, msg)
+			}
+			return nil, provider.ErrAuthenticationRequired
+		}
+		return nil, fmt.Errorf("failed to run plugin: %w", err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin response: %w", err)
+	}
+
+	if resp.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported plugin protocol version %q (expected %q)", resp.ProtocolVersion, ProtocolVersion)
+	}
+
+	if resp.Output == "" && len(resp.Fields) == 0 {
+		return nil, fmt.Errorf("plugin returned no credentials")
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"credctl/internal/provider"
+)
+
+// writePlugin writes an executable shell script plugin and returns its path
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func newPlugin(t *testing.T, config map[string]any) *PluginProvider {
+	t.Helper()
+	p := &PluginProvider{}
+	if err := p.Init(config); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	return p
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		shouldError bool
+	}{
+		{
+			name:   "valid config",
+			config: map[string]any{provider.MetadataPluginPath: "/usr/bin/true"},
+		},
+		{
+			name:        "missing plugin_path",
+			config:      map[string]any{},
+			shouldError: true,
+		},
+		{
+			name: "non-positive timeout",
+			config: map[string]any{
+				provider.MetadataPluginPath:    "/usr/bin/true",
+				provider.MetadataPluginTimeout: -1,
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&PluginProvider{}).Init(tt.config)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		expected    string
+		shouldError bool
+		authError   bool
+	}{
+		{
+			name:     "explicit output",
+			script:   `echo '{"protocol_version":"1","output":"secret"}'`,
+			expected: "secret",
+		},
+		{
+			name:     "token field",
+			script:   `echo '{"protocol_version":"1","fields":{"token":"abc","user":"bob"}}'`,
+			expected: "abc",
+		},
+		{
+			name:     "fields as json",
+			script:   `echo '{"protocol_version":"1","fields":{"user":"bob"}}'`,
+			expected: `{"user":"bob"}`,
+		},
+		{
+			name:        "several fields are a bundle",
+			script:      `echo '{"protocol_version":"1","fields":{"username":"bob","password":"pw"}}'`,
+			shouldError: true,
+		},
+		{
+			name:        "protocol version mismatch",
+			script:      `echo '{"protocol_version":"2","output":"secret"}'`,
+			shouldError: true,
+		},
+		{
+			name:        "invalid json",
+			script:      `echo 'not json'`,
+			shouldError: true,
+		},
+		{
+			name:        "empty response",
+			script:      `echo '{"protocol_version":"1"}'`,
+			shouldError: true,
+		},
+		{
+			name:        "nonzero exit requires auth",
+			script:      "echo 'session expired' >&2\nexit 1",
+			shouldError: true,
+			authError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPlugin(t, map[string]any{provider.MetadataPluginPath: writePlugin(t, tt.script)})
+
+			output, err := p.Get(context.Background())
+			if tt.shouldError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if got := errors.Is(err, provider.ErrAuthenticationRequired); got != tt.authError {
+					t.Errorf("errors.Is(err, ErrAuthenticationRequired) = %v, want %v (err: %v)", got, tt.authError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(output) != tt.expected {
+				t.Errorf("Get() = %q, want %q", output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRequestPassesConfig(t *testing.T) {
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+	script := `cat > "` + requestFile + `"
+echo '{"protocol_version":"1","output":"ok"}'`
+
+	p := newPlugin(t, map[string]any{
+		provider.MetadataPluginPath:   writePlugin(t, script),
+		provider.MetadataPluginConfig: map[string]string{"vault": "prod"},
+	})
+
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+
+	data, err := os.ReadFile(requestFile)
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+	for _, want := range []string{`"protocol_version":"1"`, `"action":"get"`, `"vault":"prod"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("request %s missing %s", data, want)
+		}
+	}
+}
+
+func TestGetCredentialsCachesUntilExpiry(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "calls")
+	script := `echo x >> "` + counter + `"
+echo '{"protocol_version":"1","fields":{"token":"abc"},"expires_at":"2999-01-01T00:00:00Z"}'`
+
+	p := newPlugin(t, map[string]any{provider.MetadataPluginPath: writePlugin(t, script)})
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if creds.Get("token") != "abc" {
+		t.Errorf("token = %q, want %q", creds.Get("token"), "abc")
+	}
+	if creds.Get("expires_at") != "2999-01-01T00:00:00Z" {
+		t.Errorf("expires_at = %q", creds.Get("expires_at"))
+	}
+
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(counter)
+	if calls := strings.Count(string(data), "x"); calls != 1 {
+		t.Errorf("plugin executed %d times, want 1", calls)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	p := newPlugin(t, map[string]any{
+		provider.MetadataPluginPath:    writePlugin(t, "exec sleep 5"),
+		provider.MetadataPluginTimeout: 1,
+	})
+
+	_, err := p.Get(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
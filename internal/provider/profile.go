@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"credctl/internal/credentials"
+)
+
+// ProfileSeparator separates a provider name from a profile: name@profile
+const ProfileSeparator = "@"
+
+// ProfileProvider is an optional interface for providers that define named
+// profiles (e.g. another scope set or audience for the same client), each
+// with its own cached credential
+type ProfileProvider interface {
+	Provider
+
+	// Profiles returns the names of the defined profiles
+	Profiles() []string
+
+	// GetWithProfile retrieves the credential of a profile
+	GetWithProfile(ctx context.Context, profile string) ([]byte, error)
+
+	// GetCredentialsWithProfile returns the structured credentials of a profile
+	GetCredentialsWithProfile(ctx context.Context, profile string) (*credentials.Credentials, error)
+}
+
+// SplitProfile splits name@profile at its last separator into the provider
+// name and the profile, and returns false when name selects no profile
+func SplitProfile(name string) (base, profile string, ok bool) {
+	i := strings.LastIndex(name, ProfileSeparator)
+	if i <= 0 || i == len(name)-len(ProfileSeparator) {
+		return name, "", false
+	}
+	return name[:i], name[i+len(ProfileSeparator):], true
+}
@@ -0,0 +1,28 @@
+package provider
+
+import "testing"
+
+func TestSplitProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantBase    string
+		wantProfile string
+		wantOK      bool
+	}{
+		{name: "corp", wantBase: "corp"},
+		{name: "corp@admin", wantBase: "corp", wantProfile: "admin", wantOK: true},
+		{name: "me@example.com@admin", wantBase: "me@example.com", wantProfile: "admin", wantOK: true},
+		{name: "@admin", wantBase: "@admin"},
+		{name: "corp@", wantBase: "corp@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, profile, ok := SplitProfile(tt.name)
+			if base != tt.wantBase || profile != tt.wantProfile || ok != tt.wantOK {
+				t.Errorf("SplitProfile(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.name, base, profile, ok, tt.wantBase, tt.wantProfile, tt.wantOK)
+			}
+		})
+	}
+}
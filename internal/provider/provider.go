@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"errors"
+	"reflect"
 
 	"credctl/internal/credentials"
 )
@@ -13,6 +14,11 @@ var (
 
 	// ErrDeviceFlowRequiresLogin is returned when device flow requires explicit login
 	ErrDeviceFlowRequiresLogin = errors.New("device flow requires explicit authentication: run 'credctl login' first")
+
+	// ErrCredentialBundle is returned by Get() when the credential is a set of
+	// related fields (e.g. an access key, secret and session token) with no single
+	// value standing for all of them. Such providers must implement CredentialsProvider.
+	ErrCredentialBundle = errors.New("provider returns several credential fields: select one with credctl get --field, or use --template")
 )
 
 // Provider is the interface that all credential providers must implement
@@ -41,6 +47,15 @@ type LoginProvider interface {
 	Login(ctx context.Context) error
 }
 
+// StepUpProvider is an optional interface for providers whose login can force
+// the identity provider to authenticate the user again (e.g. for a stronger acr)
+type StepUpProvider interface {
+	LoginProvider
+
+	// StepUpLogin performs Login without reusing the identity provider's session
+	StepUpLogin(ctx context.Context) error
+}
+
 // TokenCacheProvider is an optional interface for providers that cache tokens in memory
 type TokenCacheProvider interface {
 	Provider
@@ -52,6 +67,24 @@ type TokenCacheProvider interface {
 	GetTokens() (accessToken, refreshToken string, expiresIn int)
 }
 
+// RefreshProvider is an optional interface for token caches that can renew
+// their tokens from the cached refresh token without user interaction
+type RefreshProvider interface {
+	TokenCacheProvider
+
+	// RefreshTokens exchanges the cached refresh token for new tokens
+	RefreshTokens(ctx context.Context) error
+}
+
+// TokenTypeProvider is an optional interface for token caches that record
+// the token type reported by the issuer (e.g. "Bearer", "DPoP")
+type TokenTypeProvider interface {
+	TokenCacheProvider
+
+	// TokenType returns the cached token's type, or "" if unknown
+	TokenType() string
+}
+
 // CredentialsProvider is an optional interface for providers that support
 // exposing credentials in a structured format for template-based formatting
 type CredentialsProvider interface {
@@ -60,3 +93,56 @@ type CredentialsProvider interface {
 	// GetCredentials returns the credentials in a structured format
 	GetCredentials(ctx context.Context) (*credentials.Credentials, error)
 }
+
+// CacheInvalidator is an optional interface for providers that cache credentials
+// and can be told to discard them so the next Get() performs a fresh fetch
+type CacheInvalidator interface {
+	Provider
+
+	// InvalidateCache discards cached credentials (refresh tokens may be kept)
+	InvalidateCache()
+}
+
+// ScopedProvider is an optional interface for providers that can issue
+// credentials for a subset of their configured scopes on a single request
+type ScopedProvider interface {
+	Provider
+
+	// GetWithScopes retrieves a credential restricted to the given scopes
+	GetWithScopes(ctx context.Context, scopes []string) ([]byte, error)
+
+	// GetCredentialsWithScopes returns structured credentials restricted to the given scopes
+	GetCredentialsWithScopes(ctx context.Context, scopes []string) (*credentials.Credentials, error)
+}
+
+// SharedCacheProvider is an optional interface for providers that persist
+// tokens in the on-disk shared token cache, so the daemon can tell which
+// cache entries are still in use
+type SharedCacheProvider interface {
+	Provider
+
+	// SharedCacheKey returns the key of this provider's shared cache entry
+	SharedCacheKey() string
+}
+
+// SecretVerifier is an optional interface for providers that can check their
+// client secret against the token endpoint, e.g. before a rotated secret is
+// committed
+type SecretVerifier interface {
+	Provider
+
+	// VerifyClientSecret obtains new tokens with the configured client secret,
+	// bypassing cached access tokens, and keeps them on success
+	VerifyClientSecret(ctx context.Context) error
+}
+
+// AuthConfigChanged reports whether any auth-relevant metadata differs between
+// two provider configurations
+func AuthConfigChanged(oldMetadata, newMetadata map[string]any) bool {
+	for _, key := range AuthRelevantKeys {
+		if !reflect.DeepEqual(oldMetadata[key], newMetadata[key]) {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ const (
 	FieldTypeBool        FieldType = "bool"
 	FieldTypeInt         FieldType = "int"
 	FieldTypeStringSlice FieldType = "[]string"
+	FieldTypeStringMap   FieldType = "map[string]string"
 )
 
 // FieldDef defines a configuration field for a provider
@@ -80,6 +81,25 @@ func GetStringSliceOrDefault(config map[string]any, key string, defaultValue []s
 	return defaultValue
 }
 
+func GetStringMapOrDefault(config map[string]any, key string, defaultValue map[string]string) map[string]string {
+	if val, ok := config[key]; ok {
+		if m, ok := val.(map[string]string); ok {
+			return m
+		}
+		// Handle map[string]interface{} from JSON unmarshaling
+		if iMap, ok := val.(map[string]interface{}); ok {
+			result := make(map[string]string, len(iMap))
+			for k, item := range iMap {
+				if str, ok := item.(string); ok {
+					result[k] = str
+				}
+			}
+			return result
+		}
+	}
+	return defaultValue
+}
+
 // ValidateConfig validates a config map against a schema
 func ValidateConfig(config map[string]any, schema Schema) error {
 	// Check required fields
@@ -281,6 +281,59 @@ func TestGetStringSliceOrDefault(t *testing.T) {
 	}
 }
 
+func TestGetStringMapOrDefault(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       map[string]any
+		key          string
+		defaultValue map[string]string
+		want         map[string]string
+	}{
+		{
+			name:   "key exists with map[string]string",
+			config: map[string]any{"params": map[string]string{"prompt": "consent"}},
+			key:    "params",
+			want:   map[string]string{"prompt": "consent"},
+		},
+		{
+			name:   "key exists with map[string]interface{} (JSON unmarshal)",
+			config: map[string]any{"params": map[string]interface{}{"prompt": "consent", "max_age": 10}},
+			key:    "params",
+			want:   map[string]string{"prompt": "consent"},
+		},
+		{
+			name:         "key does not exist",
+			config:       map[string]any{},
+			key:          "params",
+			defaultValue: map[string]string{"default": "value"},
+			want:         map[string]string{"default": "value"},
+		},
+		{
+			name:         "key exists but wrong type",
+			config:       map[string]any{"params": "prompt=consent"},
+			key:          "params",
+			defaultValue: nil,
+			want:         nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetStringMapOrDefault(tt.config, tt.key, tt.defaultValue)
+
+			if len(got) != len(tt.want) {
+				t.Errorf("GetStringMapOrDefault() = %v, want %v", got, tt.want)
+				return
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("GetStringMapOrDefault()[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
 func TestValidateConfig(t *testing.T) {
 	tests := []struct {
 		name    string
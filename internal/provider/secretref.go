@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// SecretEnvPrefix marks a Hidden field value as a reference to an environment
+// variable (env:MY_SECRET), resolved when the provider is initialized
+const SecretEnvPrefix = "env:"
+
+// ResolveSecret returns value, or the content of the environment variable it
+// references with env:VAR
+func ResolveSecret(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, SecretEnvPrefix)
+	if !ok {
+		return value, nil
+	}
+	if name == "" {
+		return "", fmt.Errorf("secret reference '%s' names no environment variable", value)
+	}
+
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s referenced by a secret is not set", name)
+	}
+	return secret, nil
+}
+
+// ResolveSecretRefs returns a copy of config with the env: references of
+// Hidden string fields replaced by their values, along with the references
+// (by field name), so Metadata can store them instead of the secrets
+func ResolveSecretRefs(config map[string]any, schema Schema) (map[string]any, map[string]string, error) {
+	var resolved map[string]any
+	var refs map[string]string
+
+	for _, field := range schema.Fields {
+		if !field.Hidden || field.Type != FieldTypeString {
+			continue
+		}
+		value, ok := config[field.Name].(string)
+		if !ok || !strings.HasPrefix(value, SecretEnvPrefix) {
+			continue
+		}
+
+		secret, err := ResolveSecret(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", field.Name, err)
+		}
+		if resolved == nil {
+			resolved = maps.Clone(config)
+			refs = make(map[string]string)
+		}
+		resolved[field.Name] = secret
+		refs[field.Name] = value
+	}
+
+	if resolved == nil {
+		return config, nil, nil
+	}
+	return resolved, refs, nil
+}
+
+// AddSecretRefsToMetadata stores env: references in place of the secrets
+// they were resolved to
+func AddSecretRefsToMetadata(metadata map[string]any, refs map[string]string) {
+	for field, ref := range refs {
+		metadata[field] = ref
+	}
+}
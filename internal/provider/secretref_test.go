@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("CREDCTL_TEST_SECRET", "s3cret")
+	t.Setenv("CREDCTL_TEST_EMPTY", "")
+
+	tests := []struct {
+		name        string
+		value       string
+		want        string
+		errContains string
+	}{
+		{name: "plain value", value: "s3cret", want: "s3cret"},
+		{name: "env reference", value: "env:CREDCTL_TEST_SECRET", want: "s3cret"},
+		{name: "empty variable", value: "env:CREDCTL_TEST_EMPTY", want: ""},
+		{name: "unset variable", value: "env:CREDCTL_TEST_UNSET", errContains: "CREDCTL_TEST_UNSET referenced by a secret is not set"},
+		{name: "no variable name", value: "env:", errContains: "names no environment variable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSecret(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecretRefs(t *testing.T) {
+	t.Setenv("CREDCTL_TEST_SECRET", "s3cret")
+
+	schema := Schema{Fields: []FieldDef{
+		{Name: "client_id", Type: FieldTypeString},
+		{Name: "client_secret", Type: FieldTypeString, Hidden: true},
+		{Name: "password", Type: FieldTypeString, Hidden: true},
+	}}
+	config := map[string]any{
+		"client_id":     "env:NOT_A_SECRET",
+		"client_secret": "env:CREDCTL_TEST_SECRET",
+		"password":      "literal",
+	}
+
+	resolved, refs, err := ResolveSecretRefs(config, schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved["client_secret"] != "s3cret" || resolved["password"] != "literal" {
+		t.Errorf("unexpected resolved secrets: %v", resolved)
+	}
+	if resolved["client_id"] != "env:NOT_A_SECRET" {
+		t.Errorf("fields that aren't Hidden must not be resolved, got %v", resolved["client_id"])
+	}
+	if config["client_secret"] != "env:CREDCTL_TEST_SECRET" {
+		t.Errorf("the caller's config must not be modified, got %v", config["client_secret"])
+	}
+	if len(refs) != 1 || refs["client_secret"] != "env:CREDCTL_TEST_SECRET" {
+		t.Errorf("refs = %v, want the client_secret reference", refs)
+	}
+
+	metadata := map[string]any{"client_secret": "s3cret"}
+	AddSecretRefsToMetadata(metadata, refs)
+	if metadata["client_secret"] != "env:CREDCTL_TEST_SECRET" {
+		t.Errorf("metadata must store the reference, got %v", metadata["client_secret"])
+	}
+
+	config["password"] = "env:CREDCTL_TEST_UNSET"
+	if _, _, err := ResolveSecretRefs(config, schema); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected an error naming the field, got %v", err)
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
+
+	"credctl/internal/encryption"
+	"credctl/internal/paths"
 )
 
 // StoredProvider represents a provider as stored in JSON
@@ -17,11 +22,7 @@ type StoredProvider struct {
 
 // ProvidersDir returns the directory where providers are stored
 func ProvidersDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(homeDir, ".credctl", "providers"), nil
+	return paths.ProvidersDir()
 }
 
 // Save persists a provider to disk
@@ -46,14 +47,69 @@ func Save(name string, prov Provider) error {
 		return fmt.Errorf("failed to marshal provider: %w", err)
 	}
 
+	// Encrypt at rest if the store has a passphrase
+	data, err = encryption.Seal(data)
+	if err != nil {
+		return err
+	}
+
 	filePath, err := getFilePath(name)
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(filePath, data, 0600); err != nil {
+
+	// Serialize writers (daemon and CLI) and replace the file atomically
+	return withStoreLock(func() error {
+		return writeFileAtomic(filePath, data)
+	})
+}
+
+// withStoreLock runs fn holding an exclusive advisory lock on the providers
+// directory, shared by every credctl process
+func withStoreLock(fn func() error) error {
+	dir, err := ProvidersDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create providers directory: %w", err)
+	}
+
+	lockFile, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open providers lock: %w", err)
+	}
+	defer func() { _ = lockFile.Close() }()
+
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock providers directory: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN) }()
+
+	return fn()
+}
+
+// writeFileAtomic writes data to a temp file (0600) and renames it over path,
+// so readers never observe a partial write
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
 		return fmt.Errorf("failed to write provider file: %w", err)
 	}
+	tmpPath := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
 
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return fmt.Errorf("failed to write provider file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write provider file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write provider file: %w", err)
+	}
 	return nil
 }
 
@@ -72,6 +128,11 @@ func Load(name string) (Provider, error) {
 		return nil, fmt.Errorf("failed to read provider file: %w", err)
 	}
 
+	data, err = encryption.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider %s: %w", name, err)
+	}
+
 	// Try to unmarshal as new format first
 	var stored StoredProvider
 	if err := json.Unmarshal(data, &stored); err == nil && stored.Type != "" {
@@ -141,14 +202,15 @@ func Delete(name string) error {
 		return err
 	}
 
-	if err := os.Remove(filePath); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("provider not found: %s", name)
+	return withStoreLock(func() error {
+		if err := os.Remove(filePath); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("provider not found: %s", name)
+			}
+			return fmt.Errorf("failed to delete provider file: %w", err)
 		}
-		return fmt.Errorf("failed to delete provider file: %w", err)
-	}
-
-	return nil
+		return nil
+	})
 }
 
 func List() ([]string, error) {
@@ -175,6 +237,20 @@ func List() ([]string, error) {
 	return names, nil
 }
 
+// ModTime returns when a provider's file was last written (added or modified)
+func ModTime(name string) (time.Time, error) {
+	filePath, err := getFilePath(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat provider file: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
 // Exists checks if a provider exists on disk
 func Exists(name string) (bool, error) {
 	filePath, err := getFilePath(name)
@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"credctl/internal/paths"
+)
+
+func TestStorageUsesCredctlHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnvVar, home)
+
+	Register("storage-test", newMockFactory("storage-test"))
+
+	prov, err := New("storage-test")
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if err := Save("myprov", prov); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, "providers", "myprov.json")); err != nil {
+		t.Errorf("expected provider file under CREDCTL_HOME: %v", err)
+	}
+
+	names, err := List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "myprov" {
+		t.Errorf("List() = %v, want [myprov]", names)
+	}
+
+	loaded, err := Load("myprov")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if loaded.Type() != "storage-test" {
+		t.Errorf("Load() type = %q, want %q", loaded.Type(), "storage-test")
+	}
+
+	// A different CREDCTL_HOME must not see the provider
+	t.Setenv(paths.HomeEnvVar, t.TempDir())
+
+	names, err = List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("List() = %v, want empty", names)
+	}
+
+	if _, err := Load("myprov"); err == nil {
+		t.Error("Load() expected error for provider in another CREDCTL_HOME")
+	}
+}
+
+func TestConcurrentSaveIsConsistent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnvVar, home)
+
+	// Large payloads make torn writes likely if writers weren't serialized
+	writer := func(fill string) Provider {
+		return &MockProvider{
+			providerType: "storage-test",
+			metadata:     map[string]any{"payload": strings.Repeat(fill, 256*1024)},
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for _, fill := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(prov Provider) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := Save("shared", prov); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(writer(fill))
+	}
+
+	var writersDone atomic.Bool
+	readerDone := make(chan struct{})
+
+	// Readers must only ever see a complete file from one writer
+	filePath := filepath.Join(home, "providers", "shared.json")
+	go func() {
+		defer close(readerDone)
+		for !writersDone.Load() {
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				continue
+			}
+			var stored StoredProvider
+			if err := json.Unmarshal(data, &stored); err != nil {
+				errs <- fmt.Errorf("partial write observed: %w", err)
+				return
+			}
+			payload, _ := stored.Data["payload"].(string)
+			if strings.Trim(payload, "a") != "" && strings.Trim(payload, "b") != "" {
+				errs <- fmt.Errorf("mixed payload observed")
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	writersDone.Store(true)
+	<-readerDone
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// No temp files are left behind
+	entries, err := os.ReadDir(filepath.Join(home, "providers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("leftover temp file %s", entry.Name())
+		}
+	}
+}
+
+func TestGroupStorage(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnvVar, home)
+
+	if err := SaveGroup(StoredGroup{Name: "env", Members: []string{"api", "db"}}); err != nil {
+		t.Fatalf("SaveGroup() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "groups", "env.json")); err != nil {
+		t.Errorf("expected group file under CREDCTL_HOME: %v", err)
+	}
+
+	group, err := LoadGroup("env")
+	if err != nil {
+		t.Fatalf("LoadGroup() unexpected error: %v", err)
+	}
+	if strings.Join(group.Members, ",") != "api,db" {
+		t.Errorf("LoadGroup() members = %v, want [api db]", group.Members)
+	}
+
+	if err := DeleteGroup("env"); err != nil {
+		t.Fatalf("DeleteGroup() unexpected error: %v", err)
+	}
+	if _, err := LoadGroup("env"); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("LoadGroup() after delete = %v, want ErrGroupNotFound", err)
+	}
+
+	for _, name := range []string{"", "..", "../providers/api"} {
+		if err := SaveGroup(StoredGroup{Name: name, Members: []string{"api"}}); err == nil {
+			t.Errorf("SaveGroup(%q) expected error", name)
+		}
+	}
+}
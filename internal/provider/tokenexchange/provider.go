@@ -0,0 +1,377 @@
+package tokenexchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"credctl/internal/clock"
+	"credctl/internal/credentials"
+	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
+)
+
+// Provider exchanges the credential of another credctl provider for a token
+// in a different audience or scope (OAuth 2.0 Token Exchange, RFC 8693)
+type Provider struct {
+	tokenEndpoint          string
+	allowInsecureEndpoints bool // Accept a plain http token endpoint on a non-loopback host
+	clientID               string
+	clientSecret           string
+
+	// env: references of secret fields, stored instead of the resolved secrets
+	secretRefs map[string]string
+
+	// Exchange request
+	subjectTokenSource string // Name of the provider whose credential is the subject token
+	subjectTokenType   string
+	audience           string
+	scopes             []string
+	requestedTokenType string
+
+	expiryBuffer time.Duration // Renew cached tokens this long before they expire
+
+	httpHeaders map[string]string // Static headers added to the exchange request
+	httpClient  *http.Client      // Client adding the User-Agent and httpHeaders
+
+	// Output defaults (template, format, output file)
+	outputOpts provider.OutputOptions
+	daemonOpts provider.DaemonOptions
+
+	// mu guards tokens and issuedTokenType (the daemon serves requests
+	// concurrently); it is never held while the subject token is fetched
+	mu              sync.Mutex
+	tokens          *common.TokenCache // Cached exchanged token
+	issuedTokenType string             // issued_token_type of the cached token
+}
+
+func init() {
+	provider.Register("token-exchange", func() provider.Provider {
+		return &Provider{}
+	})
+}
+
+func (p *Provider) Type() string {
+	return "token-exchange"
+}
+
+func (p *Provider) Schema() provider.Schema {
+	return provider.Schema{
+		Fields: []provider.FieldDef{
+			{
+				Name:     provider.MetadataTokenEndpoint,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Help:     "Token endpoint URL that performs the exchange",
+			},
+			{
+				Name:     provider.MetadataClientID,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Help:     "OAuth2 client ID",
+			},
+			{
+				Name:     provider.MetadataClientSecret,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "OAuth2 client secret",
+				Hidden:   true,
+			},
+			{
+				Name:     provider.MetadataSubjectTokenSource,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Help:     "Name of the credctl provider whose credential is exchanged",
+			},
+			{
+				Name:     provider.MetadataSubjectTokenType,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Default:  common.TokenTypeAccessToken,
+				Help:     "Type of the subject token (RFC 8693 token type URI)",
+			},
+			{
+				Name:     provider.MetadataAudience,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Service the issued token is intended for",
+			},
+			{
+				Name:     provider.MetadataScopes,
+				Type:     provider.FieldTypeStringSlice,
+				Required: false,
+				Help:     "Scopes requested for the issued token",
+			},
+			{
+				Name:     provider.MetadataRequestedTokenType,
+				Type:     provider.FieldTypeString,
+				Required: false,
+				Help:     "Type of token to issue (RFC 8693 token type URI, default: chosen by the server)",
+			},
+			{
+				Name:     provider.MetadataExpiryBuffer,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "30",
+				Help:     "Seconds before expiry at which a cached token is renewed (raise for high latency or clock skew)",
+			},
+			{
+				Name:     provider.MetadataHTTPHeaders,
+				Type:     provider.FieldTypeStringMap,
+				Required: false,
+				Help:     "Extra headers for the exchange request as name=value (e.g., for an STS behind an auth gateway)",
+			},
+			{
+				Name:     provider.MetadataAllowInsecureEndpoints,
+				Type:     provider.FieldTypeBool,
+				Required: false,
+				Help:     "Allow a plain http token_endpoint on a host other than localhost (insecure: tokens are sent in cleartext)",
+			},
+		},
+	}
+}
+
+func (p *Provider) Init(config map[string]any) error {
+	config, secretRefs, err := provider.ResolveSecretRefs(config, p.Schema())
+	if err != nil {
+		return err
+	}
+	p.secretRefs = secretRefs
+
+	p.tokenEndpoint = provider.GetStringOrDefault(config, provider.MetadataTokenEndpoint, "")
+	p.clientID = provider.GetStringOrDefault(config, provider.MetadataClientID, "")
+	p.clientSecret = provider.GetStringOrDefault(config, provider.MetadataClientSecret, "")
+	p.subjectTokenSource = provider.GetStringOrDefault(config, provider.MetadataSubjectTokenSource, "")
+	p.subjectTokenType = provider.GetStringOrDefault(config, provider.MetadataSubjectTokenType, common.TokenTypeAccessToken)
+	p.audience = provider.GetStringOrDefault(config, provider.MetadataAudience, "")
+	p.scopes = provider.GetStringSliceOrDefault(config, provider.MetadataScopes, nil)
+	p.requestedTokenType = provider.GetStringOrDefault(config, provider.MetadataRequestedTokenType, "")
+	p.httpHeaders = provider.GetStringMapOrDefault(config, provider.MetadataHTTPHeaders, nil)
+	p.allowInsecureEndpoints = provider.GetBoolOrDefault(config, provider.MetadataAllowInsecureEndpoints, false)
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+
+	p.expiryBuffer, err = common.GetExpiryBuffer(config)
+	if err != nil {
+		return err
+	}
+
+	// Validate required fields
+	if p.tokenEndpoint == "" {
+		return fmt.Errorf("token_endpoint is required")
+	}
+	if err := common.ValidateEndpoint(provider.MetadataTokenEndpoint, p.tokenEndpoint, p.allowInsecureEndpoints); err != nil {
+		return err
+	}
+	if p.clientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if p.subjectTokenSource == "" {
+		return fmt.Errorf("subject_token_source is required")
+	}
+
+	if err := common.ValidateRequestHeaders(p.httpHeaders); err != nil {
+		return err
+	}
+	p.httpClient = common.WithRequestHeaders(nil, p.httpHeaders)
+
+	return nil
+}
+
+func (p *Provider) Get(ctx context.Context) ([]byte, error) {
+	tokens, _, err := p.currentTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(tokens.AccessToken), nil
+}
+
+// GetCredentials returns the credentials in a structured format
+// This implements the CredentialsProvider interface
+func (p *Provider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	tokens, issuedTokenType, err := p.currentTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{
+		"access_token":  tokens.AccessToken,
+		"authorization": tokens.Authorization(),
+		"expires_at":    tokens.ExpiresAt.Format(time.RFC3339),
+	}
+	if tokens.TokenType != "" {
+		fields["token_type"] = tokens.TokenType
+	}
+	if issuedTokenType != "" {
+		fields["issued_token_type"] = issuedTokenType
+	}
+
+	remaining := int(clock.Until(tokens.ExpiresAt).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	fields["expires_in"] = strconv.Itoa(remaining)
+
+	return credentials.New(fields), nil
+}
+
+// currentTokens returns the cached token and its issued_token_type,
+// exchanging a new one once it has expired
+func (p *Provider) currentTokens(ctx context.Context) (*common.TokenCache, string, error) {
+	p.mu.Lock()
+	tokens, issuedTokenType := p.tokens, p.issuedTokenType
+	p.mu.Unlock()
+	if common.IsTokenValid(tokens, p.expiryBuffer) {
+		return tokens, issuedTokenType, nil
+	}
+
+	// Exchange without holding p.mu: a subject token source leading back to
+	// this provider is then reported as a cycle instead of waiting on it
+	tokens, issuedTokenType, err := p.exchange(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	p.mu.Lock()
+	p.tokens, p.issuedTokenType = tokens, issuedTokenType
+	p.mu.Unlock()
+	return tokens, issuedTokenType, nil
+}
+
+// exchange fetches the subject token from the source provider and exchanges it
+func (p *Provider) exchange(ctx context.Context) (*common.TokenCache, string, error) {
+	subjectToken, err := p.subjectToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tokens, issuedTokenType, err := common.ExchangeToken(common.WithHTTPClient(ctx, p.httpClient), p.tokenEndpoint, p.clientID, p.clientSecret, common.TokenExchangeRequest{
+		SubjectToken:       subjectToken,
+		SubjectTokenType:   p.subjectTokenType,
+		Audience:           p.audience,
+		Scopes:             p.scopes,
+		RequestedTokenType: p.requestedTokenType,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return tokens, issuedTokenType, nil
+}
+
+// subjectToken returns the current credential of the subject token source
+func (p *Provider) subjectToken(ctx context.Context) (string, error) {
+	return common.SourceToken(ctx, provider.MetadataSubjectTokenSource, p.subjectTokenSource)
+}
+
+// Capabilities reports that tokens are exchanged at the token endpoint, again
+// whenever they expire (the subject token source may itself be interactive)
+// This implements the CapabilitiesProvider interface
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{
+		NeedsNetwork:    true,
+		SupportsRefresh: true,
+		Structured:      true,
+	}
+}
+
+func (p *Provider) Metadata() map[string]any {
+	metadata := map[string]any{
+		provider.MetadataTokenEndpoint:      p.tokenEndpoint,
+		provider.MetadataClientID:           p.clientID,
+		provider.MetadataSubjectTokenSource: p.subjectTokenSource,
+	}
+
+	if p.clientSecret != "" {
+		metadata[provider.MetadataClientSecret] = p.clientSecret
+	}
+	if p.subjectTokenType != "" && p.subjectTokenType != common.TokenTypeAccessToken {
+		metadata[provider.MetadataSubjectTokenType] = p.subjectTokenType
+	}
+	if p.audience != "" {
+		metadata[provider.MetadataAudience] = p.audience
+	}
+	if len(p.scopes) > 0 {
+		metadata[provider.MetadataScopes] = p.scopes
+	}
+	if p.requestedTokenType != "" {
+		metadata[provider.MetadataRequestedTokenType] = p.requestedTokenType
+	}
+	if p.expiryBuffer != common.DefaultExpiryBuffer {
+		metadata[provider.MetadataExpiryBuffer] = int(p.expiryBuffer / time.Second)
+	}
+	if len(p.httpHeaders) > 0 {
+		metadata[provider.MetadataHTTPHeaders] = p.httpHeaders
+	}
+	if p.allowInsecureEndpoints {
+		metadata[provider.MetadataAllowInsecureEndpoints] = true
+	}
+
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+	provider.AddSecretRefsToMetadata(metadata, p.secretRefs)
+
+	return metadata
+}
+
+// InvalidateCache drops the exchanged token so the next Get() exchanges again
+// This implements the CacheInvalidator interface
+func (p *Provider) InvalidateCache() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tokens = nil
+	p.issuedTokenType = ""
+}
+
+// SetTokens sets the cached token (used by daemon for persistence)
+func (p *Provider) SetTokens(accessToken, refreshToken string, expiresIn int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.tokens = &common.TokenCache{
+		AccessToken: accessToken,
+		ExpiresAt:   clock.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
+// TokenType returns the type of the cached token
+// This implements the TokenTypeProvider interface
+func (p *Provider) TokenType() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokens == nil || p.tokens.AccessToken == "" {
+		return ""
+	}
+	if p.tokens.TokenType == "" {
+		return "Bearer"
+	}
+	return p.tokens.TokenType
+}
+
+// GetTokens returns the cached token (used by daemon for persistence)
+func (p *Provider) GetTokens() (accessToken, refreshToken string, expiresIn int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokens == nil {
+		return "", "", 0
+	}
+	remaining := int(clock.Until(p.tokens.ExpiresAt).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return p.tokens.AccessToken, "", remaining
+}
@@ -0,0 +1,280 @@
+package tokenexchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"credctl/internal/provider"
+	"credctl/internal/provider/oauth2/common"
+)
+
+// staticProvider returns a fixed credential and counts how often it is asked
+type staticProvider struct {
+	token string
+	calls int
+}
+
+func (p *staticProvider) Type() string                     { return "static" }
+func (p *staticProvider) Schema() provider.Schema          { return provider.Schema{} }
+func (p *staticProvider) Init(config map[string]any) error { return nil }
+func (p *staticProvider) Metadata() map[string]any         { return map[string]any{} }
+
+func (p *staticProvider) Get(ctx context.Context) ([]byte, error) {
+	p.calls++
+	return []byte(p.token + "\n"), nil
+}
+
+// useProviders resolves subject token sources from the given map
+func useProviders(t *testing.T, providers map[string]provider.Provider) {
+	t.Helper()
+	provider.SetLookup(func(name string) (provider.Provider, error) {
+		if prov, ok := providers[name]; ok {
+			return prov, nil
+		}
+		return nil, fmt.Errorf("provider not found: %s", name)
+	})
+	t.Cleanup(func() { provider.SetLookup(provider.Load) })
+}
+
+func TestTokenExchange(t *testing.T) {
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+
+		expected := map[string]string{
+			"grant_type":           common.GrantTypeTokenExchange,
+			"subject_token":        "corp-access-token",
+			"subject_token_type":   common.TokenTypeAccessToken,
+			"audience":             "payments-api",
+			"scope":                "payments:read",
+			"requested_token_type": common.TokenTypeJWT,
+		}
+		for key, want := range expected {
+			if got := r.PostForm.Get(key); got != want {
+				t.Errorf("param %s = %q, want %q", key, got, want)
+			}
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "exchanger" || pass != "s3cret" {
+			t.Errorf("basic auth = %q/%q, want exchanger/s3cret", user, pass)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"payments-token","issued_token_type":"` + common.TokenTypeJWT + `","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	source := &staticProvider{token: "corp-access-token"}
+	useProviders(t, map[string]provider.Provider{"corp": source})
+
+	p := &Provider{}
+	err := p.Init(map[string]any{
+		provider.MetadataTokenEndpoint:      server.URL,
+		provider.MetadataClientID:           "exchanger",
+		provider.MetadataClientSecret:       "s3cret",
+		provider.MetadataSubjectTokenSource: "corp",
+		provider.MetadataAudience:           "payments-api",
+		provider.MetadataScopes:             []string{"payments:read"},
+		provider.MetadataRequestedTokenType: common.TokenTypeJWT,
+	})
+	if err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	output, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if string(output) != "payments-token" {
+		t.Errorf("Get() = %q, want %q", output, "payments-token")
+	}
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if creds.Get("issued_token_type") != common.TokenTypeJWT || creds.Get("authorization") != "Bearer payments-token" {
+		t.Errorf("unexpected credentials: %v", creds.Fields)
+	}
+
+	// The exchanged token is cached until it expires
+	if exchanges != 1 || source.calls != 1 {
+		t.Errorf("expected 1 exchange and 1 subject token fetch, got %d and %d", exchanges, source.calls)
+	}
+
+	p.InvalidateCache()
+	if _, err := p.Get(context.Background()); err != nil {
+		t.Fatalf("Get() after InvalidateCache error: %v", err)
+	}
+	if exchanges != 2 {
+		t.Errorf("expected a fresh exchange after InvalidateCache, got %d exchanges", exchanges)
+	}
+}
+
+func TestTokenExchangeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_target"}`))
+	}))
+	defer server.Close()
+
+	newProvider := func(source string) *Provider {
+		p := &Provider{}
+		err := p.Init(map[string]any{
+			provider.MetadataTokenEndpoint:      server.URL,
+			provider.MetadataClientID:           "exchanger",
+			provider.MetadataSubjectTokenSource: source,
+		})
+		if err != nil {
+			t.Fatalf("Init() error: %v", err)
+		}
+		return p
+	}
+
+	a := newProvider("b")
+	b := newProvider("a")
+	useProviders(t, map[string]provider.Provider{
+		"a":    a,
+		"b":    b,
+		"corp": &staticProvider{token: "corp-access-token"},
+	})
+
+	tests := []struct {
+		name        string
+		prov        *Provider
+		errContains string
+	}{
+		{name: "unknown source", prov: newProvider("missing"), errContains: "subject_token_source"},
+		{name: "rejected exchange", prov: newProvider("corp"), errContains: "invalid_target"},
+		{name: "cycle", prov: a, errContains: "cycle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.prov.Get(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Get() error = %v, want to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestInitRequiresFields(t *testing.T) {
+	base := map[string]any{
+		provider.MetadataTokenEndpoint:      "https://sts.example.com/token",
+		provider.MetadataClientID:           "exchanger",
+		provider.MetadataSubjectTokenSource: "corp",
+	}
+
+	for key := range base {
+		t.Run(key, func(t *testing.T) {
+			config := map[string]any{}
+			for k, v := range base {
+				if k != key {
+					config[k] = v
+				}
+			}
+			if err := (&Provider{}).Init(config); err == nil {
+				t.Errorf("expected error without %s", key)
+			}
+		})
+	}
+}
+
+func TestClientSecretIsHidden(t *testing.T) {
+	for _, field := range (&Provider{}).Schema().Fields {
+		if field.Name == provider.MetadataClientSecret && !field.Hidden {
+			t.Errorf("%s must be hidden so describe masks it", field.Name)
+		}
+	}
+}
+
+func TestClientSecretFromEnvironment(t *testing.T) {
+	t.Setenv("CREDCTL_TEST_CLIENT_SECRET", "s3cret")
+
+	config := map[string]any{
+		provider.MetadataTokenEndpoint:      "https://sts.example.com/token",
+		provider.MetadataClientID:           "exchanger",
+		provider.MetadataClientSecret:       "env:CREDCTL_TEST_CLIENT_SECRET",
+		provider.MetadataSubjectTokenSource: "corp",
+	}
+
+	p := &Provider{}
+	if err := p.Init(config); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if p.clientSecret != "s3cret" {
+		t.Errorf("expected the secret from the environment, got %q", p.clientSecret)
+	}
+	if got := p.Metadata()[provider.MetadataClientSecret]; got != "env:CREDCTL_TEST_CLIENT_SECRET" {
+		t.Errorf("metadata must keep the reference, got %v", got)
+	}
+
+	config[provider.MetadataClientSecret] = "env:CREDCTL_TEST_UNSET_SECRET"
+	err := (&Provider{}).Init(config)
+	if err == nil || !strings.Contains(err.Error(), "CREDCTL_TEST_UNSET_SECRET") {
+		t.Errorf("expected an error naming the unset variable, got %v", err)
+	}
+}
+
+// fixedProvider returns a fixed credential and is safe for concurrent use
+type fixedProvider struct{ staticProvider }
+
+func (p *fixedProvider) Get(ctx context.Context) ([]byte, error) {
+	return []byte(p.token), nil
+}
+
+// TestConcurrentTokenAccess runs the token cache paths concurrently, as the
+// daemon does for simultaneous requests (run with -race)
+func TestConcurrentTokenAccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"exchanged","issued_token_type":"` + common.TokenTypeAccessToken + `","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	useProviders(t, map[string]provider.Provider{"corp": &fixedProvider{staticProvider{token: "corp-access-token"}}})
+
+	p := &Provider{}
+	if err := p.Init(map[string]any{
+		provider.MetadataTokenEndpoint:      server.URL,
+		provider.MetadataClientID:           "exchanger",
+		provider.MetadataSubjectTokenSource: "corp",
+	}); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			switch i % 4 {
+			case 0:
+				_, err = p.Get(ctx)
+			case 1:
+				_, err = p.GetCredentials(ctx)
+			case 2:
+				p.InvalidateCache()
+			case 3:
+				p.SetTokens("set", "", 3600)
+				_, _, _ = p.GetTokens()
+				_ = p.TokenType()
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
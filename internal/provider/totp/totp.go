@@ -0,0 +1,206 @@
+package totp
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"credctl/internal/credentials"
+	"credctl/internal/provider"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTPProvider generates RFC 6238 time-based one-time codes
+type TOTPProvider struct {
+	secret     string
+	digits     int
+	period     int
+	algorithm  string
+	outputOpts provider.OutputOptions
+	daemonOpts provider.DaemonOptions
+	secretRefs map[string]string // env: references stored instead of the resolved secret
+
+	// now returns the current time (replaced in tests)
+	now func() time.Time
+}
+
+func init() {
+	provider.Register("totp", func() provider.Provider {
+		return &TOTPProvider{}
+	})
+}
+
+func (p *TOTPProvider) Type() string {
+	return "totp"
+}
+
+func (p *TOTPProvider) Schema() provider.Schema {
+	return provider.Schema{
+		Fields: []provider.FieldDef{
+			{
+				Name:     provider.MetadataTOTPSecret,
+				Type:     provider.FieldTypeString,
+				Required: true,
+				Hidden:   true,
+				Help:     "Shared secret (base32, as shown by the service's MFA setup)",
+			},
+			{
+				Name:     provider.MetadataTOTPDigits,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "6",
+				Help:     "Number of digits in the code (6 or 8)",
+			},
+			{
+				Name:     provider.MetadataTOTPPeriod,
+				Type:     provider.FieldTypeInt,
+				Required: false,
+				Default:  "30",
+				Help:     "Seconds each code is valid",
+			},
+			{
+				Name:        provider.MetadataTOTPAlgorithm,
+				Type:        provider.FieldTypeString,
+				Required:    false,
+				Default:     "SHA1",
+				ValidValues: []string{"SHA1", "SHA256", "SHA512"},
+				Help:        "HMAC algorithm: SHA1 (default), SHA256 or SHA512",
+			},
+		},
+	}
+}
+
+// Init initializes the provider with the given configuration
+func (p *TOTPProvider) Init(config map[string]any) error {
+	if err := provider.ValidateConfig(config, p.Schema()); err != nil {
+		return err
+	}
+	config, secretRefs, err := provider.ResolveSecretRefs(config, p.Schema())
+	if err != nil {
+		return err
+	}
+	p.secretRefs = secretRefs
+
+	p.secret = strings.ToUpper(strings.ReplaceAll(provider.GetStringOrDefault(config, provider.MetadataTOTPSecret, ""), " ", ""))
+	if p.secret == "" {
+		return fmt.Errorf("secret is required")
+	}
+	if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(p.secret, "=")); err != nil {
+		return fmt.Errorf("secret must be base32 encoded: %w", err)
+	}
+
+	p.digits = provider.GetIntOrDefault(config, provider.MetadataTOTPDigits, 6)
+	if p.digits != 6 && p.digits != 8 {
+		return fmt.Errorf("digits must be 6 or 8")
+	}
+
+	p.period = provider.GetIntOrDefault(config, provider.MetadataTOTPPeriod, 30)
+	if p.period <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+
+	p.algorithm = provider.GetStringOrDefault(config, provider.MetadataTOTPAlgorithm, "SHA1")
+
+	outputOpts, err := provider.LoadOutputOptions(config)
+	if err != nil {
+		return err
+	}
+	p.outputOpts = outputOpts
+	daemonOpts, err := provider.LoadDaemonOptions(config)
+	if err != nil {
+		return err
+	}
+	p.daemonOpts = daemonOpts
+	return nil
+}
+
+// Get returns the current TOTP code
+func (p *TOTPProvider) Get(ctx context.Context) ([]byte, error) {
+	code, _, err := p.generate()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(code), nil
+}
+
+// GetCredentials returns the current code and how long it stays valid
+// This implements the CredentialsProvider interface
+func (p *TOTPProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	code, remaining, err := p.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.New(map[string]string{
+		"code":              code,
+		"seconds_remaining": strconv.Itoa(remaining),
+	}), nil
+}
+
+// Capabilities reports that codes are generated locally from the secret
+// This implements the CapabilitiesProvider interface
+func (p *TOTPProvider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Structured: true}
+}
+
+func (p *TOTPProvider) Metadata() map[string]any {
+	metadata := map[string]any{
+		provider.MetadataTOTPSecret: p.secret,
+	}
+
+	if p.digits != 0 && p.digits != 6 {
+		metadata[provider.MetadataTOTPDigits] = p.digits
+	}
+
+	if p.period != 0 && p.period != 30 {
+		metadata[provider.MetadataTOTPPeriod] = p.period
+	}
+
+	if p.algorithm != "" && p.algorithm != "SHA1" {
+		metadata[provider.MetadataTOTPAlgorithm] = p.algorithm
+	}
+
+	// Preserve template, format and output from config (set by cmd/add.go global flags)
+	p.outputOpts.AddToMetadata(metadata)
+	p.daemonOpts.AddToMetadata(metadata)
+	provider.AddSecretRefsToMetadata(metadata, p.secretRefs)
+
+	return metadata
+}
+
+// generate returns the code for the current time step and the seconds left in it
+func (p *TOTPProvider) generate() (string, int, error) {
+	now := time.Now()
+	if p.now != nil {
+		now = p.now()
+	}
+
+	code, err := totp.GenerateCodeCustom(p.secret, now, totp.ValidateOpts{
+		Period:    uint(p.period),
+		Digits:    otp.Digits(p.digits),
+		Algorithm: algorithmFor(p.algorithm),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to generate TOTP code: %w", err)
+	}
+
+	remaining := p.period - int(now.Unix()%int64(p.period))
+	return code, remaining, nil
+}
+
+// algorithmFor maps the configured algorithm name to the otp library constant
+func algorithmFor(name string) otp.Algorithm {
+	switch name {
+	case "SHA256":
+		return otp.AlgorithmSHA256
+	case "SHA512":
+		return otp.AlgorithmSHA512
+	default:
+		return otp.AlgorithmSHA1
+	}
+}
@@ -0,0 +1,143 @@
+package totp
+
+import (
+	"context"
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"credctl/internal/provider"
+)
+
+// RFC 6238 Appendix B seeds (ASCII), one per algorithm
+var rfcSeeds = map[string]string{
+	"SHA1":   "12345678901234567890",
+	"SHA256": "12345678901234567890123456789012",
+	"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+}
+
+func newTOTP(t *testing.T, config map[string]any, now time.Time) *TOTPProvider {
+	t.Helper()
+	p := &TOTPProvider{}
+	if err := p.Init(config); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func TestRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix      int64
+		algorithm string
+		expected  string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"/"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			secret := base32.StdEncoding.EncodeToString([]byte(rfcSeeds[tt.algorithm]))
+			p := newTOTP(t, map[string]any{
+				provider.MetadataTOTPSecret:    secret,
+				provider.MetadataTOTPDigits:    8,
+				provider.MetadataTOTPAlgorithm: tt.algorithm,
+			}, time.Unix(tt.unix, 0))
+
+			code, err := p.Get(context.Background())
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if string(code) != tt.expected {
+				t.Errorf("Get() = %q, want %q", code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetCredentialsSecondsRemaining(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte(rfcSeeds["SHA1"]))
+	p := newTOTP(t, map[string]any{provider.MetadataTOTPSecret: secret}, time.Unix(59, 0))
+
+	creds, err := p.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredentials() error: %v", err)
+	}
+	if creds.Get("code") != "287082" {
+		t.Errorf("code = %q, want %q", creds.Get("code"), "287082")
+	}
+	if creds.Get("seconds_remaining") != "1" {
+		t.Errorf("seconds_remaining = %q, want %q", creds.Get("seconds_remaining"), "1")
+	}
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]any
+		shouldError bool
+	}{
+		{
+			name:   "valid secret",
+			config: map[string]any{provider.MetadataTOTPSecret: "JBSWY3DPEHPK3PXP"},
+		},
+		{
+			name:   "lowercase secret with spaces",
+			config: map[string]any{provider.MetadataTOTPSecret: "jbsw y3dp ehpk 3pxp"},
+		},
+		{
+			name:        "missing secret",
+			config:      map[string]any{},
+			shouldError: true,
+		},
+		{
+			name:        "invalid base32",
+			config:      map[string]any{provider.MetadataTOTPSecret: "not-base32!"},
+			shouldError: true,
+		},
+		{
+			name:        "unsupported digits",
+			config:      map[string]any{provider.MetadataTOTPSecret: "JBSWY3DPEHPK3PXP", provider.MetadataTOTPDigits: 7},
+			shouldError: true,
+		},
+		{
+			name:        "invalid algorithm",
+			config:      map[string]any{provider.MetadataTOTPSecret: "JBSWY3DPEHPK3PXP", provider.MetadataTOTPAlgorithm: "MD5"},
+			shouldError: true,
+		},
+		{
+			name:        "non-positive period",
+			config:      map[string]any{provider.MetadataTOTPSecret: "JBSWY3DPEHPK3PXP", provider.MetadataTOTPPeriod: 0},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&TOTPProvider{}).Init(tt.config)
+			if tt.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
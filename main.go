@@ -1,9 +1,13 @@
 package main
 
 import (
-	_ "credctl/internal/provider/command"     // Import to register providers
-	_ "credctl/internal/provider/oauth2"      // Import to register OAuth2 provider
-	_ "credctl/internal/provider/oauth2proxy" // Import to register OAuth2 Proxy provider
+	_ "credctl/internal/provider/alias"         // Import to register alias provider
+	_ "credctl/internal/provider/command"       // Import to register providers
+	_ "credctl/internal/provider/oauth2"        // Import to register OAuth2 provider
+	_ "credctl/internal/provider/oauth2proxy"   // Import to register OAuth2 Proxy provider
+	_ "credctl/internal/provider/plugin"        // Import to register plugin provider
+	_ "credctl/internal/provider/tokenexchange" // Import to register token exchange provider
+	_ "credctl/internal/provider/totp"          // Import to register TOTP provider
 
 	"credctl/cmd"
 )